@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MAX_WEBHOOK_BODY caps the size of incoming webhook requests.
+const MAX_WEBHOOK_BODY = 1 << 20
+
+// alertmanagerPayload is the part of Alertmanager's webhook (version 4) we
+// relay.
+type alertmanagerPayload struct {
+	Version string              `json:"version"`
+	Status  string              `json:"status"`
+	Alerts  []alertmanagerAlert `json:"alerts"`
+}
+
+type alertmanagerAlert struct {
+	Status      string            `json:"status"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// handleAlertmanagerWebhook relays alerts matching ALERTMANAGER_FILTER into
+// the group chat. Requests must carry ALERTMANAGER_TOKEN as a bearer token;
+// without one set, the webhook is off.
+func handleAlertmanagerWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkBearerToken(r, config.AlertmanagerToken) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload alertmanagerPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY)).Decode(&payload); err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var lines []string
+	for _, alert := range payload.Alerts {
+		if matchesLabels(alert.Labels, config.AlertmanagerFilter) {
+			lines = append(lines, renderAlert(config.Language, alert))
+		}
+	}
+
+	if len(lines) > 0 {
+		text := joinStrings(lines, "\n\n")
+		if _, err := telegram.SendMessage(context.Background(), groupChatID(), text, nil); err != nil {
+			log.Printf("Error relaying Alertmanager alert: %v", err)
+			http.Error(w, "telegram error", http.StatusBadGateway)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkBearerToken reports whether r carries "Authorization: Bearer token".
+// Without a token configured nobody gets in.
+func checkBearerToken(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// parseLabelFilter parses "name=value,name2=value2" into a map.
+func parseLabelFilter(s string) map[string]string {
+	filter := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" {
+			filter[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return filter
+}
+
+// matchesLabels reports whether labels has every name=value in filter.
+func matchesLabels(labels, filter map[string]string) bool {
+	for name, value := range filter {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// renderAlert formats one alert: a status line with the alert name and the
+// instance, then the summary/description annotation if there is one.
+func renderAlert(lang string, alert alertmanagerAlert) string {
+	name := alert.Labels["alertname"]
+	if name == "" {
+		name = "alert"
+	}
+
+	key := "alert.firing"
+	if alert.Status == "resolved" {
+		key = "alert.resolved"
+	}
+	line := tr(lang, key, bold(name))
+	if instance := alert.Labels["instance"]; instance != "" {
+		line += " · " + escapeHtml(instance)
+	}
+
+	for _, annotation := range []string{"summary", "description"} {
+		if text := alert.Annotations[annotation]; text != "" {
+			return line + "\n" + escapeHtml(text)
+		}
+	}
+
+	// Without annotations, show the labels so the alert isn't just a name.
+	var labels []string
+	for name, value := range alert.Labels {
+		if name != "alertname" && name != "instance" {
+			labels = append(labels, escapeHtml(name+"="+value))
+		}
+	}
+	sort.Strings(labels)
+	if len(labels) > 0 {
+		line += "\n<code>" + joinStrings(labels, " ") + "</code>"
+	}
+	return line
+}
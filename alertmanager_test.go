@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const alertmanagerBody = `{
+  "version": "4",
+  "status": "firing",
+  "alerts": [
+    {"status": "firing", "labels": {"alertname": "HostDown", "instance": "router:9100", "team": "dorm"},
+     "annotations": {"summary": "router <1> is down"}},
+    {"status": "firing", "labels": {"alertname": "DiskFull", "team": "other"}}
+  ]
+}`
+
+func TestAlertmanagerWebhookRelaysMatchingAlerts(t *testing.T) {
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AlertmanagerFilter = parseLabelFilter("team=dorm")
+	config.AlertmanagerToken = "secret"
+
+	req := httptest.NewRequest("POST", "/webhook/alertmanager", strings.NewReader(alertmanagerBody))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	newHTTPMux().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	want := "🚨 <b>HostDown</b> · router:9100\nrouter &lt;1&gt; is down"
+	if sent[0].Params["text"] != want {
+		t.Fatalf("text = %q, want %q", sent[0].Params["text"], want)
+	}
+}
+
+func TestAlertmanagerWebhookRequiresToken(t *testing.T) {
+	fake := useFakeTelegram(t)
+
+	cases := []struct{ name, token, authorization string }{
+		{"no header", "secret", ""},
+		{"bare token", "secret", "secret"},
+		{"other scheme", "secret", "Basic secret"},
+		{"no token configured", "", ""},
+		{"no token configured, empty bearer", "", "Bearer "},
+	}
+	for _, c := range cases {
+		config.AlertmanagerToken = c.token
+		req := httptest.NewRequest("POST", "/webhook/alertmanager", strings.NewReader(alertmanagerBody))
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		rec := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized || len(fake.callsTo("sendMessage")) != 0 {
+			t.Errorf("%s: status = %d with %d messages sent", c.name, rec.Code, len(fake.callsTo("sendMessage")))
+		}
+	}
+}
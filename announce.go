@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+// handleAnnounceCommand implements /announce <text>: the text goes to every
+// chat following SERVER_HOST and, with RCON_ADDR, to the players in game,
+// so those online and those in the chat hear the same thing.
+func handleAnnounceCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+	if args == "" {
+		reply(ctx, msg, tr(lang, "announce.usage"))
+		return
+	}
+
+	broadcast(ctx, "", func(lang string) string {
+		return tr(lang, "announce.message", escapeHtml(args))
+	}, nil)
+
+	result := "ok"
+	if config.RCONAddr == "" {
+		reply(ctx, msg, tr(lang, "announce.done"))
+	} else if _, err := runRCON(ctx, "tellraw @a "+announceTellraw(config.Language, args)); err != nil {
+		log.Printf("Error announcing in game: %v", err)
+		result = "rcon failed"
+		reply(ctx, msg, tr(lang, "announce.rcon_failed", escapeHtml(err.Error())))
+	} else {
+		reply(ctx, msg, tr(lang, "announce.done_game"))
+	}
+	recordAudit(auditCommand(msg, "announce", args, result))
+}
+
+// announceTellraw is the tellraw JSON text of an announcement: a gold
+// bold tag, then the text as is.
+func announceTellraw(lang, text string) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode([]map[string]interface{}{
+		{"text": tr(lang, "announce.game_prefix") + " ", "color": "gold", "bold": true},
+		{"text": text, "color": "white"},
+	})
+	return strings.TrimSuffix(b.String(), "\n")
+}
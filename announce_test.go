@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAnnounceCommand(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	rcon := useFakeRCON(t, func(string) string { return "" })
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+
+	handleUpdate(context.Background(), Update{Message: &Message{
+		MessageID: 1, Chat: Chat{ID: 1, Type: "private"}, From: &User{ID: 1}, Text: "/announce Build contest at 8 <3",
+	}})
+
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 2 || sent[0].Params["chat_id"] != "-42" || sent[0].Params["text"] != "📢 <b>Announcement</b>\nBuild contest at 8 &lt;3" {
+		t.Fatalf("messages = %+v", sent)
+	}
+	if sent[1].Params["text"] != "✅ The announcement is sent to the chats and in game." {
+		t.Fatalf("reply = %q", sent[1].Params["text"])
+	}
+	want := `tellraw @a [{"bold":true,"color":"gold","text":"[Announcement] "},{"color":"white","text":"Build contest at 8 <3"}]`
+	if commands := rcon.received(); len(commands) != 1 || commands[0] != want {
+		t.Fatalf("RCON commands = %q, want %q", commands, want)
+	}
+}
+
+func TestAnnounceRepliesInSenderLanguage(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "uk"
+	config.AdminIDs = []int64{1}
+
+	handleUpdate(context.Background(), Update{Message: &Message{
+		MessageID: 1, Chat: Chat{ID: 1, Type: "private"}, From: &User{ID: 1, LanguageCode: "en-GB"}, Text: "/announce Restart at 8",
+	}})
+
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 2 || sent[0].Params["text"] != "📢 <b>Оголошення</b>\nRestart at 8" {
+		t.Fatalf("broadcast = %+v, want it in the group's language", sent)
+	}
+	if sent[1].Params["text"] != "✅ The announcement is sent to the chats." {
+		t.Fatalf("reply = %q, want it in the sender's language", sent[1].Params["text"])
+	}
+}
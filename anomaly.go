@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	// ANOMALY_MIN_SAMPLES is how many checks an hour-of-week bucket needs
+	// before its baseline is trusted: about two weeks at the default
+	// CHECK_INTERVAL.
+	ANOMALY_MIN_SAMPLES = 240
+	// ANOMALY_MIN_STDDEV keeps buckets that are nearly always the same
+	// (say, always empty at night) from flagging a single extra player.
+	ANOMALY_MIN_STDDEV = 1.0
+	// ANOMALY_COOLDOWN is the minimum time between two notes of one kind.
+	ANOMALY_COOLDOWN = time.Hour
+)
+
+// baselineBucket is a running mean and variance (Welford's algorithm) of
+// the player count in one hour of the week.
+type baselineBucket struct {
+	N    int     `json:"n"`
+	Mean float64 `json:"mean"`
+	M2   float64 `json:"m2"`
+}
+
+func (b *baselineBucket) add(x float64) {
+	b.N++
+	delta := x - b.Mean
+	b.Mean += delta / float64(b.N)
+	b.M2 += delta * (x - b.Mean)
+}
+
+func (b *baselineBucket) stddev() float64 {
+	if b.N < 2 {
+		return 0
+	}
+	return math.Sqrt(b.M2 / float64(b.N-1))
+}
+
+// PlayerBaseline is the seasonal baseline of the player count: one bucket
+// per hour of the week, in local time.
+type PlayerBaseline struct {
+	Buckets   [7 * 24]baselineBucket `json:"buckets"`
+	LastCount int                    `json:"lastCount"`
+	// LastNote is when each kind of note was last sent.
+	LastNote map[string]time.Time `json:"lastNote,omitempty"`
+}
+
+func hourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// checkAnomalies compares the player count of a successful check with the
+// previous check and with the baseline for this hour of the week, sends a
+// quiet note to the chats following SERVER_HOST if something looks off, and
+// then adds the count to the baseline.
+func checkAnomalies(ctx context.Context, count int, now time.Time) {
+	state.mu.Lock()
+	if state.Baseline == nil {
+		state.Baseline = &PlayerBaseline{LastCount: -1}
+	}
+	baseline := state.Baseline
+	bucket := &baseline.Buckets[hourOfWeek(now)]
+
+	var kind string
+	var args []interface{}
+	switch {
+	case config.AnomalyDrop > 0 && baseline.LastCount >= config.AnomalyDrop && count == 0:
+		kind, args = "anomaly.drop", []interface{}{baseline.LastCount}
+	case config.AnomalyZ > 0 && bucket.N >= ANOMALY_MIN_SAMPLES:
+		z := (float64(count) - bucket.Mean) / math.Max(bucket.stddev(), ANOMALY_MIN_STDDEV)
+		if math.Abs(z) >= config.AnomalyZ {
+			key := "anomaly.high"
+			if z < 0 {
+				key = "anomaly.low"
+			}
+			kind, args = key, []interface{}{count, now.Format("15:04"), bucket.Mean}
+		}
+	}
+	if kind != "" && now.Sub(baseline.LastNote[kind]) < ANOMALY_COOLDOWN {
+		kind = ""
+	}
+	if kind != "" {
+		if baseline.LastNote == nil {
+			baseline.LastNote = map[string]time.Time{}
+		}
+		baseline.LastNote[kind] = now
+	}
+
+	bucket.add(float64(count))
+	baseline.LastCount = count
+	saveState()
+	state.mu.Unlock()
+
+	if kind == "" {
+		return
+	}
+	log.Printf("Player count anomaly: %s %v", kind, args)
+	broadcast(ctx, "", func(lang string) string {
+		return tr(lang, kind, args...)
+	}, &MessageOptions{DisableNotification: true})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnomalies(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AnomalyDrop = 5
+	config.AnomalyZ = 3
+
+	ctx := context.Background()
+	night := time.Date(2024, 3, 11, 5, 0, 0, 0, time.Local)
+
+	// Two weeks of quiet nights: 0-1 players at 05:00 on Mondays.
+	for i := 0; i < ANOMALY_MIN_SAMPLES; i++ {
+		checkAnomalies(ctx, i%2, night.Add(-14*24*time.Hour))
+	}
+	checkAnomalies(ctx, 1, night)
+	if sent := fake.callsTo("sendMessage"); len(sent) != 0 {
+		t.Fatalf("normal count flagged: %v", sent)
+	}
+
+	checkAnomalies(ctx, 20, night.Add(time.Minute))
+	checkAnomalies(ctx, 21, night.Add(2*time.Minute))
+	checkAnomalies(ctx, 0, night.Add(3*time.Minute))
+
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 2 {
+		t.Fatalf("got %d notes, want 2 (the second high count is in cooldown)", len(sent))
+	}
+	if text := sent[0].Params["text"]; text != "ℹ️ Unusually many players: 20 at 05:01 (usually about 1)." {
+		t.Errorf("high note = %q", text)
+	}
+	if text := sent[1].Params["text"]; text != "ℹ️ All 21 players left at once while the server stayed up. Something may be wrong." {
+		t.Errorf("drop note = %q", text)
+	}
+	if sent[0].Params["disable_notification"] != true {
+		t.Error("anomaly notes should be silent")
+	}
+}
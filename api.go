@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// API_HISTORY_MAX_ENTRIES caps one /api/history response; a day of checks
+// every 30 seconds is under 3000.
+const API_HISTORY_MAX_ENTRIES = 10000
+
+// API_HISTORY_MAX_SPAN_MS caps the range one /api/history request loads.
+// The endpoint is public, so a caller mustn't be able to make the monitor
+// read the whole history just to answer with its newest entries.
+const API_HISTORY_MAX_SPAN_MS = 7 * ONE_DAY_IN_MS
+
+// StatusAPI is the /api/status payload: the latest check of SERVER_HOST.
+type StatusAPI struct {
+	Server string `json:"server"`
+	StatusEntry
+}
+
+// PlayersAPI is the /api/players payload.
+type PlayersAPI struct {
+	Online      bool     `json:"online"`
+	Players     []string `json:"players"`
+	PlayerCount int      `json:"playerCount"`
+	LastChecked int64    `json:"lastChecked"`
+}
+
+// handleStatusAPI serves the latest check as JSON, or 503 before the first
+// one.
+func handleStatusAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	latest := getLatest()
+	if latest == nil {
+		http.Error(w, "no checks yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeAPI(w, StatusAPI{Server: config.ServerHost, StatusEntry: *latest})
+}
+
+// handleHistoryAPI serves the checks between ?since= and ?until= (Unix
+// milliseconds, the last day by default), oldest first. ?limit= keeps only
+// the newest ones. A range longer than API_HISTORY_MAX_SPAN_MS is cut to
+// its newest part.
+func handleHistoryAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	now := time.Now().UnixMilli()
+	since, err := queryInt(r, "since", now-ONE_DAY_IN_MS)
+	if err != nil {
+		http.Error(w, "bad since", http.StatusBadRequest)
+		return
+	}
+	until, err := queryInt(r, "until", now)
+	if err != nil {
+		http.Error(w, "bad until", http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", API_HISTORY_MAX_ENTRIES)
+	if err != nil || limit < 1 {
+		http.Error(w, "bad limit", http.StatusBadRequest)
+		return
+	}
+
+	// Nothing is newer than now, and until+1 would overflow at MaxInt64.
+	until = min(until, now)
+	since = max(since, until-API_HISTORY_MAX_SPAN_MS)
+	entries := getRange(since, until+1)
+	if n := int(min(limit, API_HISTORY_MAX_ENTRIES)); len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	if entries == nil {
+		entries = []StatusEntry{}
+	}
+	writeAPI(w, entries)
+}
+
+// handlePlayersAPI serves who is playing, according to the latest check.
+func handlePlayersAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	players := PlayersAPI{Players: []string{}}
+	if latest := getLatest(); latest != nil {
+		players.Online = latest.Online
+		players.LastChecked = latest.LastChecked
+		if latest.Online {
+			players.Players = dedupePlayers(latest.Players)
+			players.PlayerCount = max(latest.PlayerCount, len(players.Players))
+		}
+	}
+	writeAPI(w, players)
+}
+
+// writeAPI writes v as the JSON answer of a public API endpoint.
+func writeAPI(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(v)
+}
+
+// queryInt reads an integer query parameter, defaultValue if it's missing.
+func queryInt(r *http.Request, name string, defaultValue int64) (int64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
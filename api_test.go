@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStatusAPI(t *testing.T) {
+	useTempStore(t, 0)
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.ServerHost = "mc.example.com"
+
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code, w.Body.String()
+	}
+
+	if code, _ := get("/api/status"); code != 503 {
+		t.Errorf("/api/status before any check = %d", code)
+	}
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 1000, Players: []string{"steve"}, PlayerCount: 1})
+	insertStatus(StatusEntry{Online: true, LastChecked: 2000, Players: []string{"steve", "alex"}, PlayerCount: 3})
+	insertStatus(StatusEntry{Online: false, LastChecked: 3000, Error: "timeout"})
+	id := strconv.Quote(string(getLatest().ID))
+
+	if _, got := get("/api/status"); got != `{"server":"mc.example.com","id":`+id+`,"online":false,"lastChecked":3000,"players":null,"error":"timeout"}`+"\n" {
+		t.Errorf("/api/status = %s", got)
+	}
+	if _, got := get("/api/players"); got != `{"online":false,"players":[],"playerCount":0,"lastChecked":3000}`+"\n" {
+		t.Errorf("/api/players = %s", got)
+	}
+
+	code, got := get("/api/history?since=1500&until=3000&limit=1")
+	if code != 200 || got != `[{"id":`+id+`,"online":false,"lastChecked":3000,"players":null,"error":"timeout"}]`+"\n" {
+		t.Errorf("/api/history = %d %s", code, got)
+	}
+	if code, _ := get("/api/history?since=yesterday"); code != 400 {
+		t.Errorf("/api/history with a bad since = %d", code)
+	}
+	if _, got := get("/api/history?since=5000"); got != "[]\n" {
+		t.Errorf("/api/history after the last check = %s", got)
+	}
+}
+
+func TestHistoryAPIRange(t *testing.T) {
+	useTempStore(t, 0)
+	now := time.Now()
+	insertStatus(StatusEntry{Online: true, LastChecked: now.AddDate(0, 0, -8).UnixMilli()})
+	insertStatus(StatusEntry{Online: true, LastChecked: now.Add(-time.Hour).UnixMilli()})
+
+	for _, query := range []string{"since=0", "since=0&until=" + strconv.FormatInt(math.MaxInt64, 10)} {
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, httptest.NewRequest("GET", "/api/history?"+query, nil))
+		var entries []StatusEntry
+		if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil || w.Code != 200 {
+			t.Fatalf("%s: %d %s", query, w.Code, w.Body)
+		}
+		if len(entries) != 1 || entries[0].LastChecked != now.Add(-time.Hour).UnixMilli() {
+			t.Errorf("%s: %+v, want only the check within API_HISTORY_MAX_SPAN_MS", query, entries)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AUDIT_FILE = "audit.log"
+	// AUDIT_SHOW is how many entries /audit shows without an argument.
+	AUDIT_SHOW     = 10
+	AUDIT_SHOW_MAX = 50
+)
+
+// AuditEntry records one admin action. Denied attempts are recorded too.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Via      string    `json:"via"`
+	UserID   int64     `json:"userId,omitempty"`
+	Username string    `json:"username,omitempty"`
+	Command  string    `json:"command"`
+	Args     string    `json:"args,omitempty"`
+	Result   string    `json:"result"`
+}
+
+// auditMu keeps appends from interleaving with each other and with
+// redactions.
+var auditMu sync.Mutex
+
+// recordAudit appends entry to AUDIT_FILE. The audit log is never
+// compacted or cleaned up; only /forget rewrites it (see redactAudit).
+func recordAudit(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error marshaling audit entry: %v", err)
+		return
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if err := storage.Append(AUDIT_FILE, append(data, '\n')); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+	}
+}
+
+// auditCommand builds the audit entry for a bot command sent by msg.
+func auditCommand(msg *Message, cmd, args, result string) AuditEntry {
+	entry := AuditEntry{Via: "telegram", Command: cmd, Args: args, Result: result}
+	if msg.From != nil {
+		entry.UserID = msg.From.ID
+		entry.Username = msg.From.Username
+	}
+	return entry
+}
+
+// readAudit returns every audit entry, oldest first.
+func readAudit() ([]AuditEntry, error) {
+	data, err := storage.Read(AUDIT_FILE)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("Skipping malformed audit line: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// redactAudit replaces player with FORGOTTEN_PLAYER wherever an entry's
+// arguments name them, e.g. "/whitelist add steve", and reports how many
+// entries changed. The log is rewritten only if something does.
+func redactAudit(player string) (int, error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	entries, err := readAudit()
+	if err != nil {
+		return 0, err
+	}
+	var buf bytes.Buffer
+	redacted := 0
+	for _, entry := range entries {
+		words := strings.Fields(entry.Args)
+		named := false
+		for i, word := range words {
+			if strings.EqualFold(word, player) {
+				words[i] = FORGOTTEN_PLAYER
+				named = true
+			}
+		}
+		if named {
+			entry.Args = strings.Join(words, " ")
+			redacted++
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if redacted == 0 {
+		return 0, nil
+	}
+	return redacted, storage.Write(AUDIT_FILE, buf.Bytes())
+}
+
+// handleAuditCommand implements /audit [n]: the last n admin actions.
+func handleAuditCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+	n := AUDIT_SHOW
+	if args != "" {
+		parsed, err := strconv.Atoi(args)
+		if err != nil || parsed <= 0 {
+			reply(ctx, msg, tr(lang, "audit.usage"))
+			return
+		}
+		n = min(parsed, AUDIT_SHOW_MAX)
+	}
+
+	entries, err := readAudit()
+	if err != nil {
+		log.Printf("Error reading audit log: %v", err)
+		reply(ctx, msg, tr(lang, "audit.error"))
+		return
+	}
+	if len(entries) == 0 {
+		reply(ctx, msg, tr(lang, "audit.empty"))
+		return
+	}
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+
+	lines := []string{tr(lang, "audit.header")}
+	for _, entry := range entries {
+		lines = append(lines, renderAuditEntry(entry))
+	}
+	reply(ctx, msg, strings.Join(lines, "\n"))
+}
+
+// renderAuditEntry formats one entry as a single line: time, who, what,
+// result.
+func renderAuditEntry(entry AuditEntry) string {
+	who := entry.Via
+	switch {
+	case entry.Username != "":
+		who = "@" + entry.Username
+	case entry.UserID != 0:
+		who = strconv.FormatInt(entry.UserID, 10)
+	}
+
+	command := "/" + entry.Command
+	if entry.Args != "" {
+		command += " " + entry.Args
+	}
+	return fmt.Sprintf("<code>%s</code> %s <code>%s</code> → %s",
+		entry.Time.Format("2006-01-02 15:04"), escapeHtml(who), escapeHtml(command), escapeHtml(entry.Result))
+}
+
+// exportData implements the export subcommand, writing the audit log or the
+// status history to w as JSON lines.
+func exportData(w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: export audit|history")
+	}
+
+	encoder := json.NewEncoder(w)
+	switch args[0] {
+	case "audit":
+		entries, err := readAudit()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+	case "history":
+		loadStore()
+		// Everything, including what the SQLite history keeps beyond the
+		// last day.
+		for _, entry := range getRange(0, math.MaxInt64) {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown export %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	useTempStore(t, 1)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{100}
+
+	send := func(from int64, username, text string) string {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: 7}, From: &User{ID: from, Username: username}, Text: text,
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	if text := send(100, "admin", "/audit"); text != "📋 The audit log is empty." {
+		t.Fatalf("empty log: %q", text)
+	}
+
+	send(200, "mallory", "/forget steve")
+	send(100, "admin", "/forget steve")
+	tapConfirmation(t, 100, "confirm")
+
+	text := send(100, "admin", "/audit")
+	lines := strings.Split(text, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("audit reply:\n%s", text)
+	}
+	if !strings.Contains(lines[1], "@mallory <code>/forget [forgotten]</code> → denied") {
+		t.Errorf("denied attempt: %s", lines[1])
+	}
+	if !strings.Contains(lines[2], "@admin <code>/forget [forgotten]</code> → ok, 1 entries") {
+		t.Errorf("forget: %s", lines[2])
+	}
+
+	if text := send(200, "mallory", "/audit"); !strings.Contains(text, "needs the admin role") {
+		t.Fatalf("non-admin read the audit log: %q", text)
+	}
+
+	var out bytes.Buffer
+	if err := exportData(&out, []string{"audit"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 3 {
+		t.Fatalf("exported %d entries, want 3:\n%s", n, out.String())
+	}
+}
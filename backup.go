@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// BACKUP_KEY_FORMAT names backups by UTC time, so lexical order is
+// chronological.
+const BACKUP_KEY_FORMAT = "status-20060102T150405Z.json"
+
+// backupStore uploads a snapshot of the status history to S3_BUCKET and
+// deletes all but the newest BACKUP_RETENTION backups. Does nothing without
+// S3_BUCKET.
+func backupStore(ctx context.Context) {
+	if config.S3Bucket == "" {
+		return
+	}
+
+	store.mu.RLock()
+	data, err := json.Marshal(store)
+	store.mu.RUnlock()
+	if err != nil {
+		log.Printf("Error marshaling backup: %v", err)
+		return
+	}
+
+	// Backups are snapshots too; keep them as private as the local copy.
+	if encrypted, ok := storage.(encryptedStorage); ok {
+		data = encrypted.seal(JSON_FILE, data)
+	}
+
+	client := newS3Client()
+	key := config.S3Prefix + client.Now().UTC().Format(BACKUP_KEY_FORMAT)
+	if err := client.Put(ctx, key, data); err != nil {
+		log.Printf("Error uploading backup: %v", err)
+		return
+	}
+	log.Printf("Uploaded backup %s", key)
+
+	if config.BackupRetention <= 0 {
+		return
+	}
+	keys, err := listBackups(ctx, client)
+	if err != nil {
+		log.Printf("Error listing backups: %v", err)
+		return
+	}
+	for len(keys) > config.BackupRetention {
+		if err := client.Delete(ctx, keys[0]); err != nil {
+			log.Printf("Error deleting old backup %s: %v", keys[0], err)
+		}
+		keys = keys[1:]
+	}
+}
+
+// listBackups returns the backup keys under S3_PREFIX, oldest first.
+func listBackups(ctx context.Context, client *S3Client) ([]string, error) {
+	keys, err := client.List(ctx, config.S3Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := keys[:0]
+	for _, key := range keys {
+		if _, err := time.Parse(BACKUP_KEY_FORMAT, strings.TrimPrefix(key, config.S3Prefix)); err == nil {
+			backups = append(backups, key)
+		}
+	}
+	return backups, nil
+}
+
+// restoreBackup implements the restore subcommand: it downloads the named
+// backup, or the newest one without arguments, and makes it the status
+// snapshot, dropping the journal. Run it with the checker stopped.
+func restoreBackup(ctx context.Context, args []string) error {
+	if config.S3Bucket == "" {
+		return fmt.Errorf("restore needs S3_BUCKET")
+	}
+	client := newS3Client()
+
+	var key string
+	switch len(args) {
+	case 0:
+		keys, err := listBackups(ctx, client)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("no backups found under %q", config.S3Prefix)
+		}
+		key = keys[len(keys)-1]
+	case 1:
+		key = args[0]
+		if !strings.HasPrefix(key, config.S3Prefix) {
+			key = config.S3Prefix + key
+		}
+	default:
+		return fmt.Errorf("usage: restore [backup]")
+	}
+
+	data, err := client.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if encrypted, ok := storage.(encryptedStorage); ok {
+		if data, err = encrypted.open(JSON_FILE, data); err != nil {
+			return err
+		}
+	}
+	var restored StatusStore
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return fmt.Errorf("backup %s is not a status snapshot: %w", key, err)
+	}
+
+	if err := storage.Write(JSON_FILE, data); err != nil {
+		return err
+	}
+	if err := storage.Truncate(JOURNAL_FILE); err != nil {
+		return err
+	}
+	log.Printf("Restored %d entries from %s", len(restored.Entries), key)
+	return nil
+}
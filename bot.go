@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BOT_RETRY_DELAY is the pause after a failed getUpdates/getMe call.
+const BOT_RETRY_DELAY = 5 * time.Second
+
+// botUser is the bot's own account, fetched once when polling starts.
+var botUser *User
+
+// runBot long-polls Telegram for updates and answers commands until ctx is
+// cancelled.
+func runBot(ctx context.Context) {
+	for botUser == nil {
+		me, err := telegram.GetMe(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error getting bot info: %v", err)
+			sleepContext(ctx, BOT_RETRY_DELAY)
+			continue
+		}
+		botUser = me
+	}
+	log.Printf("Listening for commands as @%s", botUser.Username)
+	if config.MiniAppURL != "" {
+		if err := telegram.SetChatMenuButton(ctx, tr(config.Language, "miniapp.button"), config.MiniAppURL); err != nil {
+			log.Printf("Error setting the Mini App menu button: %v", err)
+		}
+	}
+
+	var offset int64
+	for {
+		updates, err := telegram.GetUpdates(ctx, offset)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Error getting Telegram updates: %v", err)
+			sleepContext(ctx, BOT_RETRY_DELAY)
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			handleUpdate(ctx, update)
+		}
+	}
+}
+
+func handleUpdate(ctx context.Context, update Update) {
+	if update.CallbackQuery != nil {
+		if strings.HasPrefix(update.CallbackQuery.Data, "ack:") {
+			handleAckQuery(ctx, update.CallbackQuery)
+		} else {
+			handleCallbackQuery(ctx, update.CallbackQuery)
+		}
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+
+	cmd, args, ok := parseCommand(update.Message.Text)
+	if !ok {
+		return
+	}
+	handleCommand(ctx, update.Message, cmd, args)
+}
+
+// parseCommand splits "/cmd@bot args" into "cmd" and "args". Commands
+// addressed to a different bot are ignored.
+func parseCommand(text string) (cmd, args string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+
+	cmd, args = text[1:], ""
+	if i := strings.IndexFunc(cmd, unicode.IsSpace); i >= 0 {
+		cmd, args = cmd[:i], cmd[i:]
+	}
+	if name, target, found := strings.Cut(cmd, "@"); found {
+		if botUser == nil || !strings.EqualFold(target, botUser.Username) {
+			return "", "", false
+		}
+		cmd = name
+	}
+	return strings.ToLower(cmd), strings.TrimSpace(args), cmd != ""
+}
+
+func handleCommand(ctx context.Context, msg *Message, cmd, args string) {
+	command, ok := commands[cmd]
+	if !ok || command.Run == nil {
+		return
+	}
+	required, _ := requiredRole(cmd)
+	if !throttleCommand(ctx, msg, command) {
+		return
+	}
+	if userRole(msg.From) < required {
+		if required >= RoleOperator {
+			recordAudit(auditCommand(msg, cmd, args, "denied"))
+		}
+		reply(ctx, msg, tr(replyLanguage(msg), "command.denied", required))
+		return
+	}
+
+	if command.Confirm {
+		askConfirmation(ctx, msg, cmd, args)
+		return
+	}
+	command.Run(ctx, msg, args)
+}
+
+// reply answers msg in the chat it came from.
+func reply(ctx context.Context, msg *Message, text string) {
+	chatID := strconv.FormatInt(msg.Chat.ID, 10)
+	_, err := telegram.SendMessage(ctx, chatID, text, &MessageOptions{ReplyToMessageID: msg.MessageID})
+	if err != nil {
+		log.Printf("Error replying to command: %v", err)
+	}
+}
+
+// replyLanguage is the language to answer msg in: its sender's Telegram
+// language if there are messages in it, the chat's otherwise.
+func replyLanguage(msg *Message) string {
+	if msg.From != nil {
+		if lang, ok := userLanguage(msg.From.LanguageCode); ok {
+			return lang
+		}
+	}
+	return chatSettings(strconv.FormatInt(msg.Chat.ID, 10)).language()
+}
+
+// userLanguage maps a Telegram language_code such as "en-US" to one of
+// messages, and false if there are none in it.
+func userLanguage(code string) (string, bool) {
+	lang, _, _ := strings.Cut(strings.ToLower(code), "-")
+	if lang == "" || messages[lang] == nil {
+		return "", false
+	}
+	return lang, true
+}
+
+// sleepContext sleeps for d or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	botUser = &User{Username: "testbot"}
+	t.Cleanup(func() { botUser = nil })
+
+	cases := []struct {
+		text, cmd, args string
+		ok              bool
+	}{
+		{"/diag", "diag", "", true},
+		{"/Diag@TestBot  now ", "diag", "now", true},
+		{"/status\nfoo", "status", "foo", true},
+		{"/status@testbot\tnow", "status", "now", true},
+		{"/diag@otherbot", "", "", false},
+		{"hello /diag", "", "", false},
+		{"/", "", "", false},
+	}
+	for _, c := range cases {
+		cmd, args, ok := parseCommand(c.text)
+		if cmd != c.cmd || args != c.args || ok != c.ok {
+			t.Errorf("parseCommand(%q) = %q, %q, %v; want %q, %q, %v", c.text, cmd, args, ok, c.cmd, c.args, c.ok)
+		}
+	}
+}
+
+func TestDiagCommand(t *testing.T) {
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+
+	handleUpdate(context.Background(), Update{
+		UpdateID: 1,
+		Message:  &Message{MessageID: 9, Chat: Chat{ID: 7}, Text: "/diag"},
+	})
+
+	replies := fake.callsTo("sendMessage")
+	if len(replies) != 1 {
+		t.Fatalf("got %d replies, want 1", len(replies))
+	}
+	text := replies[0].Params["text"].(string)
+	for _, want := range []string{"@testbot", "lnudorm3 (supergroup)", "Change chat info: ✅", "Pin messages: ❌"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("diag reply missing %q:\n%s", want, text)
+		}
+	}
+	if replies[0].Params["chat_id"] != "7" || replies[0].Params["reply_to_message_id"] != float64(9) {
+		t.Errorf("reply went to %v/%v", replies[0].Params["chat_id"], replies[0].Params["reply_to_message_id"])
+	}
+}
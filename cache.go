@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// PingResult is the outcome of one probe of the server (including retries).
+// The scheduled check and on-demand commands share the most recent one
+// through pingCache instead of each pinging the server themselves.
+type PingResult struct {
+	Status    *ServerStatus
+	Err       error
+	CheckedAt time.Time
+	Attempts  int
+}
+
+// Age reports how long ago the result was produced.
+func (r *PingResult) Age() time.Duration {
+	return time.Since(r.CheckedAt)
+}
+
+type statusCache struct {
+	mu       sync.Mutex
+	latest   *PingResult
+	inflight chan struct{}
+}
+
+var (
+	pingCache = &statusCache{}
+
+	// probe is what refreshStatus runs; tests swap it for a fake.
+	probe = probeServer
+)
+
+// probeServer pings the configured server, retrying up to MAX_RETRIES times.
+// Each attempt gets TIMEOUT in total; cancelling ctx stops the retries.
+func probeServer(ctx context.Context) *PingResult {
+	result := &PingResult{}
+	boosted := currentSchedule().Incident > 0 && incidentOpen()
+
+	for attempt := 1; attempt <= MAX_RETRIES; attempt++ {
+		result.Attempts = attempt
+
+		attemptCtx, cancel := context.WithTimeout(ctx, TIMEOUT)
+		result.Status, result.Err = transport.Probe(attemptCtx, config.ServerHost, config.ServerPort)
+		cancel()
+		if result.Err == nil && result.Status != nil {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		if attempt < MAX_RETRIES {
+			log.Printf("Server check attempt %d failed (%s), retrying...", attempt, errorCategory(result.Err))
+			// A timeout has already waited TIMEOUT; don't add the full
+			// retry delay on top of it. During an incident every second
+			// counts towards announcing the recovery, so retry right away.
+			if !errors.Is(result.Err, ErrTimeout) && !boosted {
+				select {
+				case <-ctx.Done():
+				case <-time.After(RETRY_DELAY):
+				}
+			}
+		} else {
+			log.Printf("Server check failed after %d attempts: %v", MAX_RETRIES, result.Err)
+		}
+	}
+
+	result.CheckedAt = time.Now()
+	return result
+}
+
+// refreshStatus probes the server and caches the result. If a probe is
+// already running, it waits for that one instead of starting another.
+func refreshStatus(ctx context.Context) *PingResult {
+	pingCache.mu.Lock()
+	if wait := pingCache.inflight; wait != nil {
+		pingCache.mu.Unlock()
+		<-wait
+		return cachedResult()
+	}
+	done := make(chan struct{})
+	pingCache.inflight = done
+	pingCache.mu.Unlock()
+
+	result := probe(ctx)
+
+	pingCache.mu.Lock()
+	pingCache.latest = result
+	pingCache.inflight = nil
+	pingCache.mu.Unlock()
+	close(done)
+
+	return result
+}
+
+// cachedStatus returns the cached result if it is at most maxAge old and
+// probes the server otherwise.
+func cachedStatus(ctx context.Context, maxAge time.Duration) *PingResult {
+	if result := cachedResult(); result != nil && result.Age() <= maxAge {
+		return result
+	}
+	return refreshStatus(ctx)
+}
+
+// cachedResult returns the most recent result without probing, or nil if the
+// server hasn't been probed yet.
+func cachedResult() *PingResult {
+	pingCache.mu.Lock()
+	defer pingCache.mu.Unlock()
+
+	return pingCache.latest
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func useFakeProbe(t *testing.T, fake func(context.Context) *PingResult) {
+	t.Helper()
+
+	previous := probe
+	probe = fake
+	pingCache = &statusCache{}
+	t.Cleanup(func() {
+		probe = previous
+		pingCache = &statusCache{}
+	})
+}
+
+func TestCachedStatusReusesFreshResult(t *testing.T) {
+	var calls int32
+	useFakeProbe(t, func(context.Context) *PingResult {
+		atomic.AddInt32(&calls, 1)
+		return &PingResult{Status: &ServerStatus{Online: true}, CheckedAt: time.Now()}
+	})
+
+	refreshStatus(context.Background())
+	cachedStatus(context.Background(), time.Minute)
+	if calls != 1 {
+		t.Fatalf("probe ran %d times, want 1", calls)
+	}
+
+	cachedStatus(context.Background(), 0)
+	if calls != 2 {
+		t.Fatalf("probe ran %d times after a stale read, want 2", calls)
+	}
+}
+
+func TestRefreshStatusSharesInflightProbe(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	useFakeProbe(t, func(context.Context) *PingResult {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &PingResult{CheckedAt: time.Now()}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			refreshStatus(context.Background())
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("probe ran %d times for concurrent refreshes, want 1", calls)
+	}
+}
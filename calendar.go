@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GAME_EVENT_REMINDER is how long before an in-game event the chats are
+// reminded of it.
+const GAME_EVENT_REMINDER = 30 * time.Minute
+
+// GAME_EVENT_CHECK_INTERVAL is how often the calendar is looked at.
+const GAME_EVENT_CHECK_INTERVAL = time.Minute
+
+// GAME_EVENT_LAYOUT is how /event add takes the time, in local time.
+const GAME_EVENT_LAYOUT = "2006-01-02 15:04"
+
+// GameEvent is an in-game event admins scheduled with /event add.
+type GameEvent struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// At is when it starts, in Unix seconds.
+	At       int64 `json:"at"`
+	Reminded bool  `json:"reminded,omitempty"`
+}
+
+// handleEventCommand implements /event add "<title>" <date> <time> and
+// /event remove <id>.
+func handleEventCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+
+	sub, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+	switch strings.ToLower(sub) {
+	case "add":
+		title, at, err := parseGameEvent(rest, time.Now())
+		if err != nil {
+			reply(ctx, msg, tr(lang, "event.usage"))
+			return
+		}
+		state.mu.Lock()
+		state.NextGameEventID++
+		event := GameEvent{ID: state.NextGameEventID, Title: title, At: at.Unix()}
+		state.GameEvents = append(state.GameEvents, event)
+		sort.Slice(state.GameEvents, func(i, j int) bool { return state.GameEvents[i].At < state.GameEvents[j].At })
+		saveState()
+		state.mu.Unlock()
+
+		recordAudit(auditCommand(msg, "event", args, "ok"))
+		reply(ctx, msg, tr(lang, "event.added", event.ID, escapeHtml(title), at.Format(GAME_EVENT_LAYOUT)))
+
+	case "remove":
+		id, err := strconv.Atoi(rest)
+		if err != nil {
+			reply(ctx, msg, tr(lang, "event.usage"))
+			return
+		}
+		if !removeGameEvent(id) {
+			reply(ctx, msg, tr(lang, "event.unknown", id))
+			return
+		}
+		recordAudit(auditCommand(msg, "event", args, "ok"))
+		reply(ctx, msg, tr(lang, "event.removed", id))
+
+	default:
+		reply(ctx, msg, tr(lang, "event.usage"))
+	}
+}
+
+// parseGameEvent reads `"<title>" <date> <time>`; the quotes can be left
+// out. The event must be after now.
+func parseGameEvent(args string, now time.Time) (string, time.Time, error) {
+	var title, when string
+	if strings.HasPrefix(args, `"`) {
+		end := strings.Index(args[1:], `"`)
+		if end < 0 {
+			return "", time.Time{}, fmt.Errorf("unterminated title")
+		}
+		title, when = args[1:end+1], strings.TrimSpace(args[end+2:])
+	} else {
+		fields := strings.Fields(args)
+		if len(fields) < 3 {
+			return "", time.Time{}, fmt.Errorf("missing title or time")
+		}
+		title, when = strings.Join(fields[:len(fields)-2], " "), strings.Join(fields[len(fields)-2:], " ")
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "", time.Time{}, fmt.Errorf("empty title")
+	}
+	at, err := time.ParseInLocation(GAME_EVENT_LAYOUT, when, time.Local)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !at.After(now) {
+		return "", time.Time{}, fmt.Errorf("%s is in the past", when)
+	}
+	return title, at, nil
+}
+
+// removeGameEvent drops the event with id, reporting whether there was one.
+func removeGameEvent(id int) bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	for i, event := range state.GameEvents {
+		if event.ID == id {
+			state.GameEvents = append(state.GameEvents[:i], state.GameEvents[i+1:]...)
+			saveState()
+			return true
+		}
+	}
+	return false
+}
+
+// handleEventsCommand implements /events, the upcoming in-game events.
+func handleEventsCommand(ctx context.Context, msg *Message) {
+	lang := replyLanguage(msg)
+
+	state.mu.Lock()
+	events := append([]GameEvent(nil), state.GameEvents...)
+	state.mu.Unlock()
+	reply(ctx, msg, renderGameEvents(lang, events))
+}
+
+func renderGameEvents(lang string, events []GameEvent) string {
+	if len(events) == 0 {
+		return tr(lang, "events.none")
+	}
+	lines := []string{tr(lang, "events.header")}
+	for _, event := range events {
+		lines = append(lines, tr(lang, "events.line", event.ID, time.Unix(event.At, 0).Format(GAME_EVENT_LAYOUT), escapeHtml(event.Title)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkGameEvents reminds the chats of the events starting within
+// GAME_EVENT_REMINDER, and announces the ones starting now, warning the
+// admins if the server isn't up for them.
+func checkGameEvents(ctx context.Context, now time.Time) {
+	var remind, start []GameEvent
+	state.mu.Lock()
+	kept := state.GameEvents[:0]
+	for _, event := range state.GameEvents {
+		at := time.Unix(event.At, 0)
+		switch {
+		case now.Sub(at) > GAME_EVENT_REMINDER:
+			// Missed while the monitor was down; too late to announce.
+			continue
+		case !now.Before(at):
+			start = append(start, event)
+			continue
+		case !event.Reminded && at.Sub(now) <= GAME_EVENT_REMINDER:
+			event.Reminded = true
+			remind = append(remind, event)
+		}
+		kept = append(kept, event)
+	}
+	state.GameEvents = kept
+	if len(remind) > 0 || len(start) > 0 {
+		saveState()
+	}
+	state.mu.Unlock()
+
+	for _, event := range remind {
+		minutes := int(time.Unix(event.At, 0).Sub(now).Round(time.Minute) / time.Minute)
+		broadcast(ctx, "", func(lang string) string {
+			return trn(lang, "event.reminder", minutes, minutes, escapeHtml(event.Title))
+		}, nil)
+	}
+	for _, event := range start {
+		broadcast(ctx, "", func(lang string) string {
+			return tr(lang, "event.starting", escapeHtml(event.Title))
+		}, nil)
+		if latest := getLatest(); latest == nil || !latest.Online {
+			notifyAdmins(ctx, tr(config.Language, "event.server_down", escapeHtml(event.Title)))
+		}
+	}
+}
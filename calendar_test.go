@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGameEvents(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+	config.AdminChatID = "-99"
+
+	ctx := context.Background()
+	send := func(text string) string {
+		handleUpdate(ctx, Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: -42}, From: &User{ID: 1}, Text: text,
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	at := time.Now().Add(48 * time.Hour).Truncate(time.Minute)
+	when := at.Format(GAME_EVENT_LAYOUT)
+	if got := send(`/event add "Nether race" ` + when); !strings.HasPrefix(got, "📅 Event #1 “Nether race”") {
+		t.Fatalf("/event add = %q", got)
+	}
+	send("/event add Build contest " + at.Add(time.Hour).Format(GAME_EVENT_LAYOUT))
+	if got := send("/event add Too late 2000-01-01 10:00"); !strings.HasPrefix(got, "Usage") {
+		t.Fatalf("/event add in the past = %q", got)
+	}
+	want := "📅 <b>Upcoming events</b>\n#1 " + when + " — Nether race\n#2 " + at.Add(time.Hour).Format(GAME_EVENT_LAYOUT) + " — Build contest"
+	if got := send("/events"); got != want {
+		t.Fatalf("/events = %q, want %q", got, want)
+	}
+	send("/event remove 2")
+
+	sent := func() []fakeCall {
+		return fake.callsTo("sendMessage")
+	}
+	before := len(sent())
+	checkGameEvents(ctx, at.Add(-time.Hour))
+	checkGameEvents(ctx, at.Add(-20*time.Minute))
+	checkGameEvents(ctx, at.Add(-10*time.Minute))
+	calls := sent()[before:]
+	if len(calls) != 1 || calls[0].Params["text"] != "⏰ In 20 minutes: <b>Nether race</b>!" {
+		t.Fatalf("reminders = %+v", calls)
+	}
+
+	// The server was never checked: the admins hear about it.
+	checkGameEvents(ctx, at)
+	calls = sent()[before+1:]
+	if len(calls) != 2 || calls[0].Params["text"] != "🎉 <b>Nether race</b> is starting!" || calls[1].Params["chat_id"] != "-99" {
+		t.Fatalf("start messages = %+v", calls)
+	}
+	if len(state.GameEvents) != 0 {
+		t.Fatalf("events left = %+v", state.GameEvents)
+	}
+}
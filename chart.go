@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CHART_WIDTH and CHART_HEIGHT are the size of the /chart image in pixels.
+const (
+	CHART_WIDTH  = 800
+	CHART_HEIGHT = 360
+)
+
+// chartPeriod is a span /chart can draw, with the width of one point: a
+// point per check would be too thin to see on a week.
+type chartPeriod struct {
+	Span time.Duration
+	Step time.Duration
+	// Tick is the distance between labels on the time axis, and Layout
+	// how they're written.
+	Tick   time.Duration
+	Layout string
+}
+
+// chartPeriods are the arguments /chart takes; 24h is the default.
+var chartPeriods = map[string]chartPeriod{
+	"24h": {Span: 24 * time.Hour, Step: 10 * time.Minute, Tick: 3 * time.Hour, Layout: "15:04"},
+	"7d":  {Span: 7 * 24 * time.Hour, Step: time.Hour, Tick: 24 * time.Hour, Layout: "02.01"},
+}
+
+// DAILY_CHART_CHECK_INTERVAL is how often the monitor checks whether the
+// daily chart is due.
+const DAILY_CHART_CHECK_INTERVAL = 5 * time.Minute
+
+// CHART_PERIOD_LAYOUT is how the caption writes the start and end of a
+// chart.
+const CHART_PERIOD_LAYOUT = "02.01 15:04"
+
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartGrid       = color.RGBA{0xe4, 0xe7, 0xeb, 0xff}
+	chartAxis       = color.RGBA{0x6b, 0x72, 0x80, 0xff}
+	chartOffline    = color.RGBA{0xfd, 0xe2, 0xe2, 0xff}
+	chartArea       = color.RGBA{0xc7, 0xef, 0xd4, 0xff}
+	chartLine       = color.RGBA{0x16, 0xa3, 0x4a, 0xff}
+)
+
+// chartMargins leave room for the labels: left of the player axis and
+// under the time axis.
+var chartMargins = struct{ left, right, top, bottom int }{left: 44, right: 16, top: 16, bottom: 30}
+
+// handleChartCommand implements /chart [24h|7d]: the players online over
+// the period as a PNG, from the status history.
+func handleChartCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+	name := strings.ToLower(strings.TrimSpace(args))
+	if name == "" {
+		name = "24h"
+	}
+	period, ok := chartPeriods[name]
+	if !ok {
+		reply(ctx, msg, tr(lang, "chart.usage"))
+		return
+	}
+
+	chatID := strconv.FormatInt(msg.Chat.ID, 10)
+	if err := sendChart(ctx, chatID, lang, period, time.Now()); err != nil {
+		log.Printf("Error sending chart: %v", err)
+		reply(ctx, msg, tr(lang, "chart.error"))
+	}
+}
+
+// sendChart draws the period up to now and sends it to chatID, captioned
+// in lang. Without any checks in the period there is nothing to draw and
+// the chat gets chart.no_data instead.
+func sendChart(ctx context.Context, chatID, lang string, period chartPeriod, now time.Time) error {
+	from := now.Add(-period.Span)
+	points := chartPoints(getRange(from.UnixMilli(), now.UnixMilli()+1), period.Step)
+	if len(points) == 0 {
+		_, err := telegram.SendMessage(ctx, chatID, tr(lang, "chart.no_data"), nil)
+		return err
+	}
+
+	data, err := renderChartPNG(points, from, now, period)
+	if err != nil {
+		return err
+	}
+	caption, err := renderCaption(lang, "chart", struct{ Title, Period string }{
+		Title:  tr(lang, "chart.title"),
+		Period: from.Format(CHART_PERIOD_LAYOUT) + " – " + now.Format(CHART_PERIOD_LAYOUT),
+	})
+	if err != nil {
+		return err
+	}
+	name := "players-" + now.Format("2006-01-02-1504") + ".png"
+	_, err = telegram.SendPhotoFile(ctx, chatID, InputFile{Name: name, Data: data}, caption)
+	return err
+}
+
+// sendDailyChart posts the last day's chart to the chats following
+// SERVER_HOST once a day, at DAILY_CHART_AT.
+func sendDailyChart(ctx context.Context) {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if now.Sub(midnight) < config.DailyChartAt {
+		return
+	}
+	today := now.Format(LAG_DAY_LAYOUT)
+
+	state.mu.Lock()
+	due := state.ChartDay != today
+	if due {
+		state.ChartDay = today
+		saveState()
+	}
+	state.mu.Unlock()
+	if !due {
+		return
+	}
+
+	for _, chatID := range servedChats() {
+		settings := chatSettings(chatID)
+		if settings.server() != "" || settings.snoozed(now) {
+			continue
+		}
+		if err := sendChart(ctx, chatID, settings.language(), chartPeriods["24h"], now); err != nil {
+			log.Printf("Error sending daily chart to %s: %v", chatID, err)
+		}
+	}
+}
+
+// renderChartPNG draws points, spanning from to to, as a filled line of
+// the players online over light red where the server was down.
+func renderChartPNG(points []ChartPoint, from, to time.Time, period chartPeriod) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, CHART_WIDTH, CHART_HEIGHT))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	plot := image.Rect(chartMargins.left, chartMargins.top, CHART_WIDTH-chartMargins.right, CHART_HEIGHT-chartMargins.bottom)
+	span := to.Sub(from)
+	x := func(t time.Time) int {
+		return plot.Min.X + int(float64(plot.Dx())*float64(t.Sub(from))/float64(span))
+	}
+
+	most := 0
+	for _, point := range points {
+		most = max(most, point.Players)
+	}
+	top, step := chartScale(most)
+	y := func(players int) int {
+		return plot.Max.Y - plot.Dy()*players/top
+	}
+
+	// Downtime first, so the grid stays visible over it.
+	for _, point := range points {
+		if !point.Online {
+			start := time.UnixMilli(point.Time)
+			fillRect(img, image.Rect(x(start), plot.Min.Y, x(start.Add(period.Step)), plot.Max.Y), chartOffline)
+		}
+	}
+
+	for players := 0; players <= top; players += step {
+		row := y(players)
+		fillRect(img, image.Rect(plot.Min.X, row, plot.Max.X, row+1), chartGrid)
+		label := strconv.Itoa(players)
+		drawText(img, plot.Min.X-8-textWidth(label), row-textHeight/2, label, chartAxis)
+	}
+	tick := from.Truncate(period.Tick)
+	if period.Tick >= 24*time.Hour {
+		// Truncate counts from UTC; days should start at local midnight.
+		tick = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	}
+	for ; !tick.After(to); tick = tick.Add(period.Tick) {
+		if tick.Before(from) {
+			continue
+		}
+		col := x(tick)
+		fillRect(img, image.Rect(col, plot.Min.Y, col+1, plot.Max.Y), chartGrid)
+		label := tick.Format(period.Layout)
+		left := min(max(col-textWidth(label)/2, 0), CHART_WIDTH-textWidth(label))
+		drawText(img, left, plot.Max.Y+10, label, chartAxis)
+	}
+
+	// Each point is a flat step: the most players seen during it.
+	for i, point := range points {
+		start := time.UnixMilli(point.Time)
+		left, right, row := x(start), x(start.Add(period.Step)), y(point.Players)
+		fillRect(img, image.Rect(left, row, right, plot.Max.Y), chartArea)
+		fillRect(img, image.Rect(left, row-1, right, row+1), chartLine)
+		if i > 0 && points[i-1].Time+period.Step.Milliseconds() == point.Time {
+			previous := y(points[i-1].Players)
+			fillRect(img, image.Rect(left-1, min(previous, row)-1, left+1, max(previous, row)+1), chartLine)
+		}
+	}
+
+	fillRect(img, image.Rect(plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y+1), chartAxis)
+	fillRect(img, image.Rect(plot.Min.X-1, plot.Min.Y, plot.Min.X, plot.Max.Y+1), chartAxis)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chartScale picks the top of the player axis and the distance between
+// its labels: at most five steps, on round numbers.
+func chartScale(most int) (top, step int) {
+	step = 1
+	for _, candidate := range []int{1, 2, 5, 10, 20, 25, 50, 100, 200, 500} {
+		step = candidate
+		if most <= candidate*5 {
+			break
+		}
+	}
+	top = max((most+step-1)/step*step, step)
+	return top, step
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r.Intersect(img.Bounds()), &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+// chartFont is a 3×5 pixel font for the axis labels, each row a bit mask
+// with the leftmost pixel as 4. It covers what the labels use: digits and
+// the separators of times and dates.
+var chartFont = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 2, 2},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	':': {0, 2, 0, 2, 0},
+	'.': {0, 0, 0, 0, 2},
+}
+
+// CHART_FONT_SCALE is how many pixels wide one font pixel is drawn.
+const CHART_FONT_SCALE = 2
+
+const textHeight = 5 * CHART_FONT_SCALE
+
+func textWidth(s string) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (n*4 - 1) * CHART_FONT_SCALE
+}
+
+// drawText writes s with its top left corner at (x, y), skipping
+// characters chartFont lacks.
+func drawText(img *image.RGBA, x, y int, s string, c color.Color) {
+	for _, r := range s {
+		glyph := chartFont[r]
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>col) == 0 {
+					continue
+				}
+				px, py := x+col*CHART_FONT_SCALE, y+row*CHART_FONT_SCALE
+				fillRect(img, image.Rect(px, py, px+CHART_FONT_SCALE, py+CHART_FONT_SCALE), c)
+			}
+		}
+		x += 4 * CHART_FONT_SCALE
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChartCommand(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		insertStatus(StatusEntry{Online: i != 3, LastChecked: now.Add(-time.Duration(i) * time.Hour).UnixMilli(), Players: []string{"steve"}, PlayerCount: 1})
+	}
+
+	send := func(text string) {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: 1, Type: "private"}, From: &User{ID: 1}, Text: text,
+		}})
+	}
+
+	send("/chart")
+	photos := fake.callsTo("sendPhoto")
+	if len(photos) != 1 || !strings.HasPrefix(photos[0].Params["photo"].(string), "upload:players-") {
+		t.Fatalf("sendPhoto calls = %+v", photos)
+	}
+	if caption := photos[0].Params["caption"].(string); !strings.HasPrefix(caption, "📈 <b>Players online</b>\n") {
+		t.Errorf("caption = %q", caption)
+	}
+
+	send("/chart month")
+	if sent := fake.callsTo("sendMessage"); len(sent) != 1 || sent[0].Params["text"] != "Usage: /chart [24h|7d]" {
+		t.Fatalf("messages = %+v", sent)
+	}
+}
+
+func TestRenderChartPNG(t *testing.T) {
+	to := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	from := to.Add(-24 * time.Hour)
+	period := chartPeriods["24h"]
+	down := to.Add(-2 * time.Hour)
+	points := []ChartPoint{
+		{Time: to.Add(-6 * time.Hour).UnixMilli(), Players: 4, Online: true},
+		{Time: down.UnixMilli(), Players: 0, Online: false},
+	}
+
+	data, err := renderChartPNG(points, from, to, period)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != CHART_WIDTH || size.Y != CHART_HEIGHT {
+		t.Fatalf("size = %v", size)
+	}
+
+	plotWidth := CHART_WIDTH - chartMargins.left - chartMargins.right
+	x := chartMargins.left + plotWidth*22/24 + 2
+	if r, g, b, _ := img.At(x, chartMargins.top+10).RGBA(); r>>8 != uint32(chartOffline.R) || g>>8 != uint32(chartOffline.G) || b>>8 != uint32(chartOffline.B) {
+		t.Errorf("downtime pixel = %v, want the offline color", img.At(x, chartMargins.top+10))
+	}
+}
+
+func TestChartScale(t *testing.T) {
+	cases := []struct{ most, top, step int }{
+		{0, 1, 1},
+		{3, 3, 1},
+		{7, 8, 2},
+		{23, 25, 5},
+		{120, 125, 25},
+	}
+	for _, c := range cases {
+		if top, step := chartScale(c.most); top != c.top || step != c.step {
+			t.Errorf("chartScale(%d) = %d, %d; want %d, %d", c.most, top, step, c.top, c.step)
+		}
+	}
+}
+
+func TestDailyChart(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.DailyChartAt = 0
+	insertStatus(StatusEntry{Online: true, LastChecked: time.Now().Add(-time.Hour).UnixMilli(), Players: []string{"steve"}, PlayerCount: 1})
+
+	sendDailyChart(context.Background())
+	sendDailyChart(context.Background())
+	photos := fake.callsTo("sendPhoto")
+	if len(photos) != 1 || photos[0].Params["chat_id"] != "-42" {
+		t.Fatalf("sendPhoto calls = %+v, want one to the group", photos)
+	}
+}
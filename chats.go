@@ -0,0 +1,489 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Toggles a chat can switch off with /chatconfig events: the event kinds
+// that get a message, and "title" for keeping the chat title in sync with
+// the server.
+var chatToggles = []string{string(EventPlayerJoined), string(EventPlayerLeft), string(EventServerDown), string(EventServerUp), string(EventServerEmpty), "title"}
+
+// chatTemplateKeys are the messages a chat can replace with its own
+// template, and TEMPLATES_* for every chat (see messageTemplateNames).
+// Templates get MessageTemplateData.
+var chatTemplateKeys = []string{"players.joined", "players.left", "server.up", "server.down", "title"}
+
+// ChatSettings is the configuration of one group the bot serves. Zero
+// values mean "as configured in the environment".
+type ChatSettings struct {
+	// Server is host[:port] of the server the chat follows.
+	Server         string            `json:"server,omitempty"`
+	Language       string            `json:"language,omitempty"`
+	DisabledEvents []string          `json:"disabledEvents,omitempty"`
+	Templates      map[string]string `json:"templates,omitempty"`
+	// SnoozedUntil (Unix seconds) mutes non-critical notifications.
+	SnoozedUntil int64 `json:"snoozedUntil,omitempty"`
+	// OnlinePolicy is what the chat counts as the server being online,
+	// one of onlinePolicies.
+	OnlinePolicy string `json:"onlinePolicy,omitempty"`
+
+	// routed are the only toggles TELEGRAM_CHATS gives the chat, if any.
+	routed []string
+}
+
+// chatSettings returns a copy of the settings of chatID.
+func chatSettings(chatID string) ChatSettings {
+	route, _ := chatRoute(chatID)
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	settings, ok := state.Chats[chatID]
+	if !ok {
+		return ChatSettings{routed: route.Events}
+	}
+	copied := *settings
+	copied.routed = route.Events
+	copied.DisabledEvents = append([]string(nil), settings.DisabledEvents...)
+	copied.Templates = make(map[string]string, len(settings.Templates))
+	for key, text := range settings.Templates {
+		copied.Templates[key] = text
+	}
+	return copied
+}
+
+// updateChatSettings applies change to the settings of chatID and saves
+// the state.
+func updateChatSettings(chatID string, change func(*ChatSettings)) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.Chats == nil {
+		state.Chats = map[string]*ChatSettings{}
+	}
+	settings, ok := state.Chats[chatID]
+	if !ok {
+		settings = &ChatSettings{}
+		state.Chats[chatID] = settings
+	}
+	change(settings)
+	saveState()
+}
+
+func (s ChatSettings) language() string {
+	if s.Language != "" {
+		return s.Language
+	}
+	return config.Language
+}
+
+func (s ChatSettings) enabled(toggle string) bool {
+	if len(s.routed) > 0 && !containsString(s.routed, toggle) {
+		return false
+	}
+	for _, disabled := range s.DisabledEvents {
+		if disabled == toggle {
+			return false
+		}
+	}
+	return true
+}
+
+// server is the normalized address the chat follows, "" for SERVER_HOST.
+func (s ChatSettings) server() string {
+	if s.Server == "" {
+		return ""
+	}
+	address := normalizeServer(s.Server)
+	if address == normalizeServer(net.JoinHostPort(config.ServerHost, strconv.Itoa(int(config.ServerPort)))) {
+		return ""
+	}
+	return address
+}
+
+// normalizeServer turns host[:port] into host:port with the default
+// Minecraft port.
+func normalizeServer(server string) string {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, strconv.Itoa(MINECRAFT_DEFAULT_PORT)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+// splitServer splits a normalized server address for pingMinecraftServer.
+func splitServer(server string) (string, uint16, error) {
+	host, portText, err := net.SplitHostPort(server)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %q", portText)
+	}
+	return host, uint16(port), nil
+}
+
+// servedChats lists the group chat, the TELEGRAM_CHATS and every chat with
+// its own settings, in a stable order.
+func servedChats() []string {
+	chats := []string{groupChatID()}
+
+	state.mu.Lock()
+	var others []string
+	for chatID := range state.Chats {
+		others = append(others, chatID)
+	}
+	state.mu.Unlock()
+	sort.Strings(others)
+
+	var routed []string
+	for _, route := range config.ChatRoutes {
+		routed = append(routed, route.ChatID)
+	}
+	for _, chatID := range append(routed, others...) {
+		if chatID = resolveChatID(chatID); chatID != chats[0] && !containsString(chats, chatID) {
+			chats = append(chats, chatID)
+		}
+	}
+	return chats
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// announce sends the events of one check of server ("" for SERVER_HOST) to
+// every chat following it, each in its own language, online policy,
+// toggles and templates, and updates the chat titles. reachable and
+// players are what the check found, for the titles.
+func announce(ctx context.Context, server string, reachable bool, players int, events []Event) {
+	for _, chatID := range servedChats() {
+		settings := chatSettings(chatID)
+		if settings.server() != server {
+			continue
+		}
+
+		now := time.Now()
+		snoozed := settings.snoozed(now)
+		var enabled []Event
+		for _, event := range events {
+			if event.policy != "" && event.policy != settings.policy() {
+				continue
+			}
+			if settings.enabled(string(event.Kind)) && (!snoozed || criticalEvent(event)) {
+				enabled = append(enabled, event)
+			}
+		}
+		enabled = freshEvents(chatID, server, enabled, now)
+		// Events that render to nothing, or that only the live status
+		// message shows, still count as announced, so the next opposite
+		// transition is fresh.
+		if message := renderChatEvents(settings, enabled); message == "" || (server == "" && config.LiveStatus == LIVE_ONLY) {
+			rememberEvents(chatID, server, enabled, now)
+		} else {
+			// A scheduled-looking restart on its own isn't worth a
+			// notification sound.
+			opts := &MessageOptions{}
+			if len(enabled) == 1 && enabled[0].Label == LABEL_SCHEDULED_RESTART {
+				opts.DisableNotification = true
+			}
+			// Down alerts for the monitored server carry the incident's
+			// Acknowledge button.
+			alert := server == "" && hasEvent(enabled, EventServerDown)
+			if alert {
+				opts.ReplyMarkup = ackKeyboard(settings.language())
+			}
+			sent, err := telegram.SendMessage(ctx, chatID, message, opts)
+			if err != nil {
+				log.Printf("Error sending Telegram message to %s: %v", chatID, err)
+			} else {
+				rememberEvents(chatID, server, enabled, now)
+				if alert {
+					// With a staging chat the alert may have gone there.
+					trackAlert(strconv.FormatInt(sent.Chat.ID, 10), sent.MessageID, message)
+				}
+			}
+		}
+
+		if settings.enabled("title") {
+			online := serverState(settings.policy(), reachable, players) != StateOffline
+			if err := telegram.SetChatTitle(ctx, chatID, renderChatTitle(settings, online, players)); err != nil {
+				log.Printf("Error updating chat title of %s: %v", chatID, err)
+			}
+		}
+	}
+}
+
+// renderChatEvents renders the events of one check as a single message in
+// the chat's language and with its templates: the server going down, then
+// joins, then leaves. It returns "" when there is nothing to say.
+func renderChatEvents(settings ChatSettings, events []Event) string {
+	var joined, left []string
+	var changes []string
+	for _, event := range events {
+		switch event.Kind {
+		case EventPlayerJoined:
+			joined = append(joined, event.Player)
+		case EventPlayerLeft:
+			left = append(left, event.Player)
+		case EventServerDown:
+			changes = append(changes, renderServerDown(settings, event))
+		case EventServerUp:
+			changes = append(changes, renderServerUp(settings, event))
+		case EventServerEmpty:
+			changes = append(changes, tr(settings.language(), "server.empty"))
+		}
+	}
+
+	if len(joined) > 0 {
+		changes = append(changes, renderPlayersChange(settings, "players.joined", joined))
+	}
+	if len(left) > 0 {
+		changes = append(changes, renderPlayersChange(settings, "players.left", left))
+	}
+	return joinStrings(changes, "\n")
+}
+
+func renderChatTemplate(text string, data MessageTemplateData) (string, error) {
+	tmpl, err := template.New("chat").Funcs(captionFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, data)
+	return sb.String(), err
+}
+
+// checkOtherServers checks every server a chat follows besides SERVER_HOST
+// and announces what changed since its previous check. Only SERVER_HOST has
+// a stored history; for the others the bot remembers the last result in
+// its state.
+func checkOtherServers(ctx context.Context) {
+	servers := map[string]bool{}
+	for _, chatID := range servedChats() {
+		if server := chatSettings(chatID).server(); server != "" {
+			servers[server] = true
+		}
+	}
+
+	for server := range servers {
+		checkOtherServer(ctx, server)
+	}
+}
+
+// checkOtherServer is one server's part of checkOtherServers.
+func checkOtherServer(ctx context.Context, server string) {
+	host, port, err := splitServer(server)
+	if err != nil {
+		log.Printf("Skipping server %s: %v", server, err)
+		return
+	}
+	if !beginServerCheck(server) {
+		return
+	}
+	defer endServerCheck(server)
+
+	pingCtx, cancel := context.WithTimeout(ctx, TIMEOUT)
+	status, err := transport.Probe(pingCtx, host, port)
+	cancel()
+
+	current := ServerSnapshot{}
+	if err == nil {
+		current = ServerSnapshot{Online: true, PlayerCount: status.PlayerCount, Players: dedupePlayers(status.Players)}
+	}
+
+	state.mu.Lock()
+	if state.Servers == nil {
+		state.Servers = map[string]*ServerSnapshot{}
+	}
+	previous, seen := state.Servers[server]
+	state.Servers[server] = &current
+	saveState()
+	state.mu.Unlock()
+
+	var events []Event
+	if seen {
+		now := time.Now()
+		events = transitionEvents(func(policy string) ServerState {
+			return serverState(policy, previous.Online, previous.PlayerCount)
+		}, func(policy string) ServerState {
+			return serverState(policy, current.Online, current.PlayerCount)
+		}, now)
+		joined, left := diffPlayers(previous.Players, current.Players)
+		events = append(events, playerEvents(joined, left, now)...)
+	}
+	announce(ctx, server, current.Online, current.PlayerCount, events)
+	log.Printf("Server %s status: %s", server, map[bool]string{true: "online", false: "offline"}[current.Online])
+}
+
+// ServerSnapshot is the last check of a server without a stored history.
+// PlayerCount is what the server reports; Players is only its sample, for
+// the names.
+type ServerSnapshot struct {
+	Online      bool     `json:"online"`
+	PlayerCount int      `json:"playerCount"`
+	Players     []string `json:"players"`
+}
+
+// handleChatConfigCommand implements /chatconfig for the chat it is sent
+// in:
+//
+//	/chatconfig                              show the settings
+//	/chatconfig server <host[:port]|default>
+//	/chatconfig language <uk|en|default>
+//	/chatconfig events <toggle> <on|off>
+//	/chatconfig template <key> <text|default>
+//	/chatconfig online <reachable|players|three-state|default>
+func handleChatConfigCommand(ctx context.Context, msg *Message, args string) {
+	chatID := resolveChatID(strconv.FormatInt(msg.Chat.ID, 10))
+	lang := replyLanguage(msg)
+	setting, value, _ := strings.Cut(args, " ")
+	value = strings.TrimSpace(value)
+
+	usage := func() {
+		reply(ctx, msg, tr(lang, "chatconfig.usage", strings.Join(chatToggles, ", "), strings.Join(chatTemplateKeys, ", "), strings.Join(onlinePolicies, "|")))
+	}
+
+	switch strings.ToLower(setting) {
+	case "":
+		reply(ctx, msg, renderChatSettings(lang, chatSettings(chatID)))
+		return
+	case "server":
+		if value == "" || strings.ContainsAny(value, " /") {
+			usage()
+			return
+		}
+		if value == "default" {
+			value = ""
+		} else if _, _, err := splitServer(normalizeServer(value)); err != nil {
+			reply(ctx, msg, tr(lang, "chatconfig.invalid", escapeHtml(err.Error())))
+			return
+		}
+		updateChatSettings(chatID, func(s *ChatSettings) { s.Server = value })
+	case "language":
+		if value == "default" {
+			value = ""
+		} else if _, ok := messages[value]; !ok {
+			usage()
+			return
+		}
+		updateChatSettings(chatID, func(s *ChatSettings) { s.Language = value })
+	case "events":
+		toggle, onOff, _ := strings.Cut(value, " ")
+		if !containsString(chatToggles, toggle) || (onOff != "on" && onOff != "off") {
+			usage()
+			return
+		}
+		updateChatSettings(chatID, func(s *ChatSettings) {
+			kept := s.DisabledEvents[:0]
+			for _, disabled := range s.DisabledEvents {
+				if disabled != toggle {
+					kept = append(kept, disabled)
+				}
+			}
+			if onOff == "off" {
+				kept = append(kept, toggle)
+			}
+			s.DisabledEvents = kept
+		})
+	case "online":
+		if value == "default" {
+			value = ""
+		} else if !containsString(onlinePolicies, value) {
+			usage()
+			return
+		}
+		updateChatSettings(chatID, func(s *ChatSettings) { s.OnlinePolicy = value })
+	case "template":
+		key, text, _ := strings.Cut(value, " ")
+		text = strings.TrimSpace(text)
+		if !containsString(chatTemplateKeys, key) || text == "" {
+			usage()
+			return
+		}
+		if text != "default" {
+			if _, err := renderChatTemplate(text, MessageTemplateData{}); err != nil {
+				reply(ctx, msg, tr(lang, "chatconfig.invalid", escapeHtml(err.Error())))
+				return
+			}
+		}
+		updateChatSettings(chatID, func(s *ChatSettings) {
+			if text == "default" {
+				delete(s.Templates, key)
+				return
+			}
+			if s.Templates == nil {
+				s.Templates = map[string]string{}
+			}
+			s.Templates[key] = text
+		})
+	default:
+		usage()
+		return
+	}
+
+	recordAudit(auditCommand(msg, "chatconfig", args, "ok"))
+	settings := chatSettings(chatID)
+	reply(ctx, msg, renderChatSettings(lang, settings))
+}
+
+// renderChatSettings lists the settings of a chat, one per line.
+func renderChatSettings(lang string, settings ChatSettings) string {
+	server := settings.Server
+	if server == "" {
+		server = net.JoinHostPort(config.ServerHost, strconv.Itoa(int(config.ServerPort)))
+	}
+
+	var toggles []string
+	for _, toggle := range chatToggles {
+		mark := "✅"
+		if !settings.enabled(toggle) {
+			mark = "❌"
+		}
+		toggles = append(toggles, toggle+" "+mark)
+	}
+
+	lines := []string{
+		tr(lang, "chatconfig.header"),
+		tr(lang, "chatconfig.server", escapeHtml(server)),
+		tr(lang, "chatconfig.language", escapeHtml(settings.language())),
+		tr(lang, "chatconfig.events", strings.Join(toggles, ", ")),
+		tr(lang, "chatconfig.online", settings.policy()),
+	}
+	for _, key := range chatTemplateKeys {
+		if text, ok := settings.Templates[key]; ok {
+			lines = append(lines, tr(lang, "chatconfig.template", key, escapeHtml(text)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// broadcast sends a message rendered per language to every chat following
+// server ("" for SERVER_HOST). Only notes are broadcast, so snoozed chats
+// are skipped.
+func broadcast(ctx context.Context, server string, render func(lang string) string, opts *MessageOptions) {
+	for _, chatID := range servedChats() {
+		settings := chatSettings(chatID)
+		if settings.server() != server || settings.snoozed(time.Now()) {
+			continue
+		}
+		if _, err := telegram.SendMessage(ctx, chatID, render(settings.language()), opts); err != nil {
+			log.Printf("Error sending Telegram message to %s: %v", chatID, err)
+		}
+	}
+}
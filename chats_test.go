@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatConfigCommand(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "uk"
+	config.ServerHost = "mc.example.com"
+	config.ServerPort = 25565
+	config.AdminIDs = []int64{1}
+
+	send := func(text string) string {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: 8}, From: &User{ID: 1}, Text: text,
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	send("/chatconfig language en")
+	send("/chatconfig events player_left off")
+	send("/chatconfig template players.joined 👋 {{.Players}}")
+	send("/chatconfig online three-state")
+	got := send("/chatconfig")
+	want := "⚙️ <b>Chat settings</b>\n" +
+		"Server: <code>mc.example.com:25565</code>\n" +
+		"Language: en\n" +
+		"Events: player_joined ✅, player_left ❌, server_down ✅, server_up ✅, server_empty ✅, title ✅\n" +
+		"Online: three-state\n" +
+		"Template players.joined: <code>👋 {{.Players}}</code>"
+	if got != want {
+		t.Fatalf("/chatconfig:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := send("/chatconfig template players.left {{.Nope}}"); !strings.HasPrefix(got, "❌") {
+		t.Fatalf("bad template accepted: %q", got)
+	}
+
+	at := time.Now()
+	announce(context.Background(), "", true, 1, []Event{
+		{Kind: EventPlayerJoined, Player: "steve", Time: at},
+		{Kind: EventPlayerLeft, Player: "alex", Time: at},
+	})
+
+	sent := map[string]string{}
+	for _, call := range fake.callsTo("sendMessage") {
+		sent[call.Params["chat_id"].(string)] = call.Params["text"].(string)
+	}
+	if sent["-42"] != "😎 <b>steve</b> зайшов на сервер\n🥺 <b>alex</b> вийшов" {
+		t.Errorf("group chat got %q", sent["-42"])
+	}
+	if sent["8"] != "👋 <b>steve</b>" {
+		t.Errorf("configured chat got %q", sent["8"])
+	}
+	titles := fake.callsTo("setChatTitle")
+	if len(titles) != 2 {
+		t.Fatalf("%d titles set, want 2", len(titles))
+	}
+}
+
+func TestCheckOtherServers(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.ServerHost = "mc.example.com"
+	config.ServerPort = 25565
+
+	port := fakeMinecraftServer(t, samplePacket(1))
+	updateChatSettings("8", func(s *ChatSettings) { s.Server = fmt.Sprintf("127.0.0.1:%d", port) })
+
+	ctx := context.Background()
+	checkOtherServers(ctx)
+	if sent := fake.callsTo("sendMessage"); len(sent) != 0 {
+		t.Fatalf("first check announced %v", sent)
+	}
+
+	state.mu.Lock()
+	state.Servers[fmt.Sprintf("127.0.0.1:%d", port)].Players = nil
+	state.mu.Unlock()
+	checkOtherServers(ctx)
+
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["chat_id"] != "8" {
+		t.Fatalf("announcements = %+v", sent)
+	}
+	if text := sent[0].Params["text"].(string); text != "😎 <b>player0</b> joined the server" {
+		t.Errorf("announcement = %q", text)
+	}
+}
+
+func TestCheckOtherServerCountsWithoutSample(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.ServerHost = "mc.example.com"
+	config.ServerPort = 25565
+
+	// Servers may hide the sample and still report how many are on.
+	port := fakeMinecraftServer(t, statusPacket(`{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":3},"description":{"text":"lnudorm3"}}`))
+	server := fmt.Sprintf("127.0.0.1:%d", port)
+	updateChatSettings("8", func(s *ChatSettings) { s.Server = server })
+
+	checkOtherServers(context.Background())
+	state.mu.Lock()
+	snapshot := *state.Servers[server]
+	state.mu.Unlock()
+	if snapshot.PlayerCount != 3 || len(snapshot.Players) != 0 {
+		t.Errorf("snapshot = %+v, want 3 players and no names", snapshot)
+	}
+	if got := serverState(ONLINE_PLAYERS, snapshot.Online, snapshot.PlayerCount); got == StateOffline {
+		t.Errorf("state under %s = %v, want not offline", ONLINE_PLAYERS, got)
+	}
+}
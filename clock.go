@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// CLOCK_JUMP_THRESHOLD is how far the wall clock may drift from the
+// monotonic clock between two checks before it counts as a jump.
+const CLOCK_JUMP_THRESHOLD = time.Minute
+
+var clock struct {
+	mu sync.Mutex
+	// last is when the previous check started, with its monotonic reading.
+	last time.Time
+}
+
+// checkClock compares how far the wall clock moved since the previous check
+// with how much time passed by the monotonic clock, and warns when they
+// drift apart: NTP stepped the clock, or the host was suspended, which the
+// monotonic clock doesn't count. The two look the same from here, so
+// nothing is corrected. Outages are measured on the wall clock, which does
+// count a suspend; durations are clamped so a clock set back can't make
+// them negative.
+func checkClock(now time.Time) {
+	clock.mu.Lock()
+	last := clock.last
+	clock.last = now
+	clock.mu.Unlock()
+
+	if last.IsZero() {
+		return
+	}
+	// Round(0) strips the monotonic reading, leaving the wall clock.
+	jump := now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+	if jump.Abs() >= CLOCK_JUMP_THRESHOLD {
+		log.Printf("Warning: the clock jumped by %v since the last check (NTP correction or suspend?)", jump)
+		metrics.Inc("minecraft_clock_jumps_total")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordTransitionClockSetBack(t *testing.T) {
+	useTempStore(t, 0)
+	start := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	recordTransition(false, start)
+	recordTransition(true, start.Add(-5*time.Minute))
+	if d := state.Downtimes[0]; d.End < d.Start {
+		t.Errorf("downtime ends before it starts: %+v", d)
+	}
+}
+
+func TestOutageLengthsAgree(t *testing.T) {
+	useTempStore(t, 0)
+
+	// The host slept through most of the outage.
+	began := time.Now()
+	openIncident(began, false)
+	recordTransition(false, began)
+	back := began.Round(0).Add(45 * time.Minute)
+
+	elapsed := state.Incident.elapsed(back)
+	lasted := recordTransition(true, back)
+	if lasted != 45*time.Minute || elapsed.Round(time.Second) != lasted {
+		t.Errorf("downtime lasted %v, incident open for %v; want 45m both", lasted, elapsed)
+	}
+}
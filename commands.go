@@ -0,0 +1,91 @@
+package main
+
+import "context"
+
+// Command is a bot command: who may run it, what it does and how /help
+// describes it.
+type Command struct {
+	// Role is the role the command needs by default; COMMAND_ROLES can
+	// override it, e.g. "diag=operator".
+	Role Role
+	// Run handles the command once the sender's role, the rate limits and,
+	// for Confirm commands, the confirmation checked out. Commands without
+	// one are buttons, such as Acknowledge, that only borrow the role.
+	Run func(ctx context.Context, msg *Message, args string)
+	// Help is the message key of the command's /help line; commands
+	// without one aren't listed.
+	Help string
+	// Confirm commands run only after the sender taps "Confirm" under the
+	// bot's question, so an autocompleted command can't do damage by
+	// accident.
+	Confirm bool
+	// Limit is how often one user may run the command per
+	// COMMAND_LIMIT_WINDOW, on top of COMMAND_USER_LIMIT; 0 for no limit
+	// of its own. For commands that are expensive to answer.
+	Limit int
+
+	limiter *rateLimiter
+}
+
+// commands is the registry of bot commands, by name. It's filled in init
+// because /help reads it: a var initializer listing handleHelpCommand
+// would be an initialization cycle.
+var commands map[string]*Command
+
+func init() {
+	commands = map[string]*Command{
+		// help lists what the sender may run, so anyone can ask.
+		"help":       {Role: RoleNone, Run: noArgs(handleHelpCommand), Help: "help.help"},
+		"status":     {Role: RoleViewer, Run: noArgs(handleStatusCommand), Help: "help.status"},
+		"diag":       {Role: RoleViewer, Run: handleDiagCommand, Help: "help.diag", Limit: 2},
+		"version":    {Role: RoleViewer, Run: noArgs(handleVersionCommand), Help: "help.version"},
+		"jobs":       {Role: RoleOperator, Run: handleJobsCommand, Help: "help.jobs"},
+		"compare":    {Role: RoleViewer, Run: noArgs(handleCompareCommand), Help: "help.compare"},
+		"stats":      {Role: RoleViewer, Run: handleStatsCommand, Help: "help.stats"},
+		"seen":       {Role: RoleViewer, Run: handleSeenCommand, Help: "help.seen"},
+		"chart":      {Role: RoleViewer, Run: handleChartCommand, Help: "help.chart", Limit: 2},
+		"join":       {Role: RoleViewer, Run: noArgs(handleJoinCommand), Help: "help.join"},
+		"setjoin":    {Role: RoleAdmin, Run: handleSetJoinCommand, Help: "help.setjoin"},
+		"setmodpack": {Role: RoleAdmin, Run: handleSetModpackCommand, Help: "help.setmodpack"},
+		"events":     {Role: RoleViewer, Run: noArgs(handleEventsCommand), Help: "help.events"},
+		"event":      {Role: RoleAdmin, Run: handleEventCommand, Help: "help.event"},
+		"announce":   {Role: RoleAdmin, Run: handleAnnounceCommand, Help: "help.announce"},
+		"list":       {Role: RoleOperator, Run: consoleCommand("list"), Help: "help.list"},
+		"say":        {Role: RoleAdmin, Run: consoleCommand("say"), Help: "help.say"},
+		"whitelist":  {Role: RoleAdmin, Run: consoleCommand("whitelist"), Help: "help.whitelist"},
+		"rcon":       {Role: RoleAdmin, Run: consoleCommand("rcon"), Help: "help.rcon"},
+		"forget":     {Role: RoleAdmin, Run: handleForgetCommand, Help: "help.forget", Confirm: true},
+		"audit":      {Role: RoleAdmin, Run: handleAuditCommand, Help: "help.audit"},
+		"grant":      {Role: RoleAdmin, Run: handleGrantCommand, Help: "help.grant"},
+		// ack is the Acknowledge button under down alerts.
+		"ack": {Role: RoleAdmin},
+
+		"chatconfig": {Role: RoleOperator, Run: handleChatConfigCommand, Help: "help.chatconfig"},
+		"snooze":     {Role: RoleOperator, Run: handleSnoozeCommand, Help: "help.snooze"},
+	}
+	for _, command := range commands {
+		command.limiter = newRateLimiter(command.Limit, COMMAND_LIMIT_WINDOW)
+	}
+}
+
+// noArgs adapts a handler of a command that takes no arguments.
+func noArgs(handler func(ctx context.Context, msg *Message)) func(ctx context.Context, msg *Message, args string) {
+	return func(ctx context.Context, msg *Message, _ string) {
+		handler(ctx, msg)
+	}
+}
+
+// consoleCommand is the handler of the console command cmd.
+func consoleCommand(cmd string) func(ctx context.Context, msg *Message, args string) {
+	return func(ctx context.Context, msg *Message, args string) {
+		handleConsoleCommand(ctx, msg, cmd, args)
+	}
+}
+
+func handleDiagCommand(ctx context.Context, msg *Message, _ string) {
+	reply(ctx, msg, runDiagnostics(ctx, replyLanguage(msg)))
+}
+
+func handleJobsCommand(ctx context.Context, msg *Message, _ string) {
+	reply(ctx, msg, renderJobs(replyLanguage(msg), scheduler.Jobs()))
+}
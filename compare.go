@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// pingSecondary pings SECONDARY_SERVER, e.g. a backup server players can
+// move to.
+func pingSecondary(ctx context.Context) (*ServerStatus, error) {
+	host, port, err := splitServer(config.SecondaryServer)
+	if err != nil {
+		return nil, err
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, TIMEOUT)
+	defer cancel()
+	return transport.Probe(pingCtx, host, port)
+}
+
+// secondaryUp reports whether SECONDARY_SERVER answers, for suggesting it
+// in down alerts. False when there is none.
+func secondaryUp(ctx context.Context) bool {
+	if config.SecondaryServer == "" {
+		return false
+	}
+	_, err := pingSecondary(ctx)
+	if err != nil {
+		log.Printf("Secondary server %s is down too: %v", config.SecondaryServer, err)
+	}
+	return err == nil
+}
+
+// handleCompareCommand shows SERVER_HOST and SECONDARY_SERVER side by side.
+func handleCompareCommand(ctx context.Context, msg *Message) {
+	lang := replyLanguage(msg)
+	if config.SecondaryServer == "" {
+		reply(ctx, msg, tr(lang, "compare.unconfigured"))
+		return
+	}
+
+	secondary := make(chan *PingResult, 1)
+	go func() {
+		status, err := pingSecondary(ctx)
+		secondary <- &PingResult{Status: status, Err: err, CheckedAt: time.Now()}
+	}()
+	primary := cachedStatus(ctx, currentSchedule().Interval)
+
+	reply(ctx, msg, renderComparison(lang, []string{
+		fmt.Sprintf("%s:%d", config.ServerHost, config.ServerPort),
+		config.SecondaryServer,
+	}, []*PingResult{primary, <-secondary}))
+}
+
+// renderComparison is one line per server with its state, players and
+// ping time.
+func renderComparison(lang string, servers []string, results []*PingResult) string {
+	lines := []string{tr(lang, "compare.header")}
+	for i, result := range results {
+		server := escapeHtml(servers[i])
+		if result.Err != nil || result.Status == nil {
+			lines = append(lines, tr(lang, "compare.down", server, errorCategory(result.Err)))
+			continue
+		}
+		status := result.Status
+		latency := (status.ConnectTime + status.ProtocolTime).Milliseconds()
+		lines = append(lines, trn(lang, "compare.up", status.PlayerCount, server, status.PlayerCount, latency))
+	}
+	return joinStrings(lines, "\n")
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRenderComparison(t *testing.T) {
+	got := renderComparison("en", []string{"mc.example.com:25565", "backup.example.com:25565"}, []*PingResult{
+		{Err: fmt.Errorf("dial: %w", ErrTimeout)},
+		{Status: &ServerStatus{Online: true, PlayerCount: 1, ConnectTime: 20 * time.Millisecond, ProtocolTime: 5 * time.Millisecond}},
+	})
+	want := "⚖️ <b>Server comparison</b>\n" +
+		"🔴 <code>mc.example.com:25565</code> — down (timeout)\n" +
+		"🟢 <code>backup.example.com:25565</code> — 1 player, 25 ms"
+	if got != want {
+		t.Fatalf("renderComparison:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSecondaryUp(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+
+	if secondaryUp(context.Background()) {
+		t.Fatal("no secondary server, but it's up")
+	}
+	config.SecondaryServer = fmt.Sprintf("127.0.0.1:%d", fakeMinecraftServer(t, samplePacket(1)))
+	if !secondaryUp(context.Background()) {
+		t.Fatal("secondary server isn't up")
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CONFIG_FILE_ENV names the config file when --config isn't given, which
+// is also how an installed service finds it.
+const CONFIG_FILE_ENV = "CONFIG_FILE"
+
+// configFile is the file given with --config or CONFIG_FILE, if any.
+var configFile string
+
+// fileSettings are the settings read from configFile, by environment
+// variable name. The environment still wins over them.
+var fileSettings = map[string]string{}
+
+// loadConfigFile reads a YAML (.yaml, .yml) or TOML (.toml) config file.
+// Its keys are the environment variables' names, in any case, and nested
+// sections join their names with underscores, so
+//
+//	telegram:
+//	  chat_id: "-100123"
+//
+// sets TELEGRAM_CHAT_ID. Lists become comma-separated values. The
+// "messages" section replaces the built-in messages instead: messages.en
+// maps a message key to its text, or to its plural forms (one, few, many,
+// other).
+func loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		tree, err = parseYAMLConfig(string(data))
+	case ".toml":
+		tree, err = parseTOMLConfig(string(data))
+	default:
+		return fmt.Errorf("%s: unknown config format, use .yaml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	settings := map[string]string{}
+	for key, value := range tree {
+		if strings.EqualFold(key, "messages") {
+			if err := applyConfigMessages(value); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		flattenConfig(strings.ToUpper(key), value, settings)
+	}
+	fileSettings = settings
+	return nil
+}
+
+// flattenConfig adds value to settings under key, and a nested section's
+// values under key_name.
+func flattenConfig(key string, value interface{}, settings map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, nested := range v {
+			flattenConfig(key+"_"+strings.ToUpper(name), nested, settings)
+		}
+	case []string:
+		settings[key] = strings.Join(v, ",")
+	case string:
+		settings[key] = v
+	}
+}
+
+// applyConfigMessages replaces built-in messages with the "messages"
+// section of the config file.
+func applyConfigMessages(value interface{}) error {
+	langs, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("messages must be a section of languages")
+	}
+	for lang, keys := range langs {
+		texts, ok := keys.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("messages.%s must be a section of messages", lang)
+		}
+		if messages[lang] == nil {
+			messages[lang] = map[string]Translation{}
+		}
+		for key, text := range texts {
+			switch t := text.(type) {
+			case string:
+				messages[lang][key] = Translation{Other: t}
+			case map[string]interface{}:
+				var translation Translation
+				for form, s := range t {
+					s, _ := s.(string)
+					switch strings.ToLower(form) {
+					case "one":
+						translation.One = s
+					case "few":
+						translation.Few = s
+					case "many":
+						translation.Many = s
+					case "other":
+						translation.Other = s
+					default:
+						return fmt.Errorf("messages.%s.%s: unknown plural form %q", lang, key, form)
+					}
+				}
+				messages[lang][key] = translation
+			default:
+				return fmt.Errorf("messages.%s.%s must be a text or plural forms", lang, key)
+			}
+		}
+	}
+	return nil
+}
+
+// warnUnknownFileSettings logs the config file's settings loadConfig
+// didn't look at, most likely typos.
+func warnUnknownFileSettings() {
+	var unknown []string
+	for key := range fileSettings {
+		if !configKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		log.Printf("Unknown setting %s in %s", key, configFile)
+	}
+}
+
+// configLine is a line of a config file without its comment.
+type configLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// configLines splits data into lines, dropping comments and blank lines.
+func configLines(data string) []configLine {
+	var lines []configLine
+	for i, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		line = stripConfigComment(line)
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		lines = append(lines, configLine{number: i + 1, indent: len(line) - len(strings.TrimLeft(line, " \t")), text: text})
+	}
+	return lines
+}
+
+// stripConfigComment cuts line at the first # outside quotes.
+func stripConfigComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseYAMLConfig parses the part of YAML a config file needs: nested
+// mappings by indentation, block and flow lists of scalars, and plain,
+// single- and double-quoted scalars.
+func parseYAMLConfig(data string) (map[string]interface{}, error) {
+	lines := configLines(data)
+	for len(lines) > 0 && lines[0].text == "---" {
+		lines = lines[1:]
+	}
+	tree, rest, err := parseYAMLMapping(lines, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", rest[0].number)
+	}
+	return tree, nil
+}
+
+func parseYAMLMapping(lines []configLine, indent int) (map[string]interface{}, []configLine, error) {
+	mapping := map[string]interface{}{}
+	for len(lines) > 0 && lines[0].indent == indent {
+		line := lines[0]
+		lines = lines[1:]
+		key, value, ok := cutYAMLKey(line.text)
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected key: value", line.number)
+		}
+		if value != "" {
+			scalar, err := parseConfigValue(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", line.number, err)
+			}
+			mapping[key] = scalar
+			continue
+		}
+
+		// A key without a value opens a nested mapping or a list, which
+		// may start at the key's own indentation.
+		if len(lines) == 0 || lines[0].indent < indent || lines[0].indent == indent && !strings.HasPrefix(lines[0].text, "-") {
+			mapping[key] = ""
+			continue
+		}
+		if strings.HasPrefix(lines[0].text, "-") {
+			var list []string
+			listIndent := lines[0].indent
+			for len(lines) > 0 && lines[0].indent == listIndent && strings.HasPrefix(lines[0].text, "-") {
+				item, err := parseConfigValue(strings.TrimSpace(strings.TrimPrefix(lines[0].text, "-")))
+				if err != nil {
+					return nil, nil, fmt.Errorf("line %d: %w", lines[0].number, err)
+				}
+				s, ok := item.(string)
+				if !ok {
+					return nil, nil, fmt.Errorf("line %d: lists can only hold values", lines[0].number)
+				}
+				list = append(list, s)
+				lines = lines[1:]
+			}
+			mapping[key] = list
+			continue
+		}
+		nested, rest, err := parseYAMLMapping(lines, lines[0].indent)
+		if err != nil {
+			return nil, nil, err
+		}
+		mapping[key] = nested
+		lines = rest
+	}
+	if len(lines) > 0 && lines[0].indent > indent {
+		return nil, nil, fmt.Errorf("line %d: unexpected indentation", lines[0].number)
+	}
+	return mapping, lines, nil
+}
+
+// cutYAMLKey splits "key: value", where key may be quoted.
+func cutYAMLKey(text string) (key, value string, ok bool) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest := text[1:end+1], strings.TrimSpace(text[end+2:])
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	// "a:b" is a plain scalar, not a key: the colon needs a space after it.
+	if i := strings.Index(text, ": "); i >= 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:]), true
+	}
+	if strings.HasSuffix(text, ":") {
+		return strings.TrimSpace(text[:len(text)-1]), "", true
+	}
+	return "", "", false
+}
+
+// parseTOMLConfig parses the part of TOML a config file needs: tables,
+// dotted and quoted keys, strings, numbers, booleans and arrays of them.
+func parseTOMLConfig(data string) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	table := tree
+	lines := configLines(data)
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line.text, "[") {
+			if !strings.HasSuffix(line.text, "]") || strings.HasPrefix(line.text, "[[") {
+				return nil, fmt.Errorf("line %d: bad table header", line.number)
+			}
+			path, err := splitTOMLKey(line.text[1 : len(line.text)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.number, err)
+			}
+			if table, err = tomlTable(tree, path); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.number, err)
+			}
+			continue
+		}
+
+		rawKey, value, ok := cutTOMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", line.number)
+		}
+		// An array may go on over several lines.
+		for strings.HasPrefix(value, "[") && !strings.HasSuffix(value, "]") && i+1 < len(lines) {
+			i++
+			value += " " + lines[i].text
+		}
+		path, err := splitTOMLKey(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		parent, err := tomlTable(table, path[:len(path)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		parsed, err := parseConfigValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		parent[path[len(path)-1]] = parsed
+	}
+	return tree, nil
+}
+
+// cutTOMLKey splits "key = value" at the first = outside quotes.
+func cutTOMLKey(text string) (key, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// splitTOMLKey splits a dotted key, keeping dots inside quoted parts.
+func splitTOMLKey(key string) ([]string, error) {
+	var parts []string
+	for key = strings.TrimSpace(key); ; {
+		var part string
+		if key != "" && (key[0] == '"' || key[0] == '\'') {
+			end := strings.IndexByte(key[1:], key[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated key %q", key)
+			}
+			part, key = key[1:end+1], strings.TrimSpace(key[end+2:])
+		} else {
+			end := strings.IndexByte(key, '.')
+			if end < 0 {
+				end = len(key)
+			}
+			part, key = strings.TrimSpace(key[:end]), key[end:]
+		}
+		if part == "" {
+			return nil, fmt.Errorf("empty key")
+		}
+		parts = append(parts, part)
+		if key == "" {
+			return parts, nil
+		}
+		if key[0] != '.' {
+			return nil, fmt.Errorf("bad key near %q", key)
+		}
+		key = strings.TrimSpace(key[1:])
+	}
+}
+
+// tomlTable returns the table at path under root, creating it if needed.
+func tomlTable(root map[string]interface{}, path []string) (map[string]interface{}, error) {
+	table := root
+	for _, name := range path {
+		switch next := table[name].(type) {
+		case nil:
+			created := map[string]interface{}{}
+			table[name] = created
+			table = created
+		case map[string]interface{}:
+			table = next
+		default:
+			return nil, fmt.Errorf("%s is already a value", name)
+		}
+	}
+	return table, nil
+}
+
+// parseConfigValue parses a scalar, a one-line list or a one-line table
+// ({a = 1} in TOML, {a: 1} in YAML): quoted strings are unquoted,
+// everything else (numbers, booleans, plain YAML text) is kept as written.
+func parseConfigValue(value string) (interface{}, error) {
+	if strings.HasPrefix(value, "{") {
+		if !strings.HasSuffix(value, "}") {
+			return nil, fmt.Errorf("unterminated table")
+		}
+		table := map[string]interface{}{}
+		for _, item := range splitConfigList(value[1 : len(value)-1]) {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, nested, ok := cutInlineKey(item)
+			if !ok {
+				return nil, fmt.Errorf("expected key = value in %s", value)
+			}
+			parsed, err := parseConfigValue(nested)
+			if err != nil {
+				return nil, err
+			}
+			table[key] = parsed
+		}
+		return table, nil
+	}
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return nil, fmt.Errorf("unterminated list")
+		}
+		list := []string{}
+		for _, item := range splitConfigList(value[1 : len(value)-1]) {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			s, err := parseConfigScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+	return parseConfigScalar(value)
+}
+
+func parseConfigScalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("bad string %s", value)
+		}
+		return s, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("bad string %s", value)
+		}
+		// YAML doubles a quote inside single quotes; TOML can't have one.
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	return value, nil
+}
+
+// cutInlineKey splits an item of a one-line table at the first = or :
+// outside quotes.
+func cutInlineKey(item string) (key, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(item); i++ {
+		switch c := item[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=' || c == ':':
+			key, err := parseConfigScalar(strings.TrimSpace(item[:i]))
+			return key, strings.TrimSpace(item[i+1:]), err == nil && key != ""
+		}
+	}
+	return "", "", false
+}
+
+// splitConfigList splits the inside of [...] or {...} at commas outside
+// quotes and brackets.
+func splitConfigList(s string) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const yamlConfig = `---
+server_host: mc.example.com # the dorm server
+server_port: 25566
+telegram:
+  chat_id: "-100123"
+  admin_ids:
+    - 1
+    - 2
+secondary_server: 'backup.example.com:25565'
+LIVE_STATUS: on
+messages:
+  en:
+    server.empty: "Nobody's home #1"
+    players.joined:
+      one: "%s is here"
+      other: "%s are here"
+`
+
+const tomlConfig = `
+server_host = "mc.example.com" # the dorm server
+server_port = 25566
+secondary_server = 'backup.example.com:25565'
+LIVE_STATUS = "on"
+
+[telegram]
+chat_id = "-100123"
+admin_ids = [
+  1,
+  2,
+]
+
+[messages.en]
+"server.empty" = "Nobody's home #1"
+"players.joined" = { one = "%s is here", other = "%s are here" }
+`
+
+func TestLoadConfigFile(t *testing.T) {
+	want := map[string]string{
+		"SERVER_HOST":        "mc.example.com",
+		"SERVER_PORT":        "25566",
+		"TELEGRAM_CHAT_ID":   "-100123",
+		"TELEGRAM_ADMIN_IDS": "1,2",
+		"SECONDARY_SERVER":   "backup.example.com:25565",
+		"LIVE_STATUS":        "on",
+	}
+	for name, data := range map[string]string{"config.yaml": yamlConfig, "config.toml": tomlConfig} {
+		t.Run(name, func(t *testing.T) {
+			previous := messages["en"]
+			messages["en"] = map[string]Translation{}
+			for key, translation := range previous {
+				messages["en"][key] = translation
+			}
+			t.Cleanup(func() { messages["en"], fileSettings = previous, map[string]string{} })
+
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := loadConfigFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fileSettings, want) {
+				t.Fatalf("settings = %v, want %v", fileSettings, want)
+			}
+			if got := tr("en", "server.empty"); got != "Nobody's home #1" {
+				t.Fatalf("server.empty = %q", got)
+			}
+			if got := trn("en", "players.joined", 2, "a, b"); got != "a, b are here" {
+				t.Fatalf("players.joined = %q", got)
+			}
+
+			// The environment wins.
+			t.Setenv("SERVER_PORT", "25567")
+			if host, port := getEnv("SERVER_HOST", ""), getEnvInt("SERVER_PORT", 0); host != "mc.example.com" || port != 25567 {
+				t.Fatalf("SERVER_HOST, SERVER_PORT = %q, %d", host, port)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"config.json":   `{}`,
+		"indent.yaml":   "a: 1\n  b: 2\n",
+		"key.yaml":      "just text\n",
+		"table.toml":    "[a\nb = 1\n",
+		"string.toml":   `a = "unterminated` + "\n",
+		"redefine.toml": "a = 1\n[a]\nb = 2\n",
+	} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := loadConfigFile(path); err == nil {
+			t.Errorf("%s loaded without an error", name)
+		}
+	}
+}
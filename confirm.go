@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CONFIRM_TIMEOUT is how long a destructive command waits for its
+// confirmation tap.
+const CONFIRM_TIMEOUT = 30 * time.Second
+
+// pendingConfirmation is a destructive command waiting for its tap.
+type pendingConfirmation struct {
+	msg     *Message
+	cmd     string
+	args    string
+	prompt  *Message
+	expires time.Time
+}
+
+var (
+	confirmationsMu sync.Mutex
+	confirmations   = map[string]*pendingConfirmation{}
+)
+
+// askConfirmation replies to msg with Confirm/Cancel buttons and parks the
+// command until one of them is tapped.
+func askConfirmation(ctx context.Context, msg *Message, cmd, args string) {
+	var token [8]byte
+	rand.Read(token[:])
+	id := hex.EncodeToString(token[:])
+
+	command := "/" + cmd
+	if args != "" {
+		command += " " + args
+	}
+	lang := replyLanguage(msg)
+	markup := &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{
+		{Text: tr(lang, "confirm.yes"), CallbackData: "confirm:" + id},
+		{Text: tr(lang, "confirm.no"), CallbackData: "cancel:" + id},
+	}}}
+
+	chatID := strconv.FormatInt(msg.Chat.ID, 10)
+	text := tr(lang, "confirm.ask", escapeHtml(command), int(CONFIRM_TIMEOUT/time.Second))
+	prompt, err := telegram.SendMessage(ctx, chatID, text, &MessageOptions{ReplyToMessageID: msg.MessageID, ReplyMarkup: markup})
+	if err != nil {
+		log.Printf("Error asking for confirmation: %v", err)
+		return
+	}
+
+	confirmationsMu.Lock()
+	defer confirmationsMu.Unlock()
+
+	// Drop whatever expired in the meantime; nobody can tap those anymore.
+	now := time.Now()
+	for key, pending := range confirmations {
+		if now.After(pending.expires) {
+			delete(confirmations, key)
+		}
+	}
+	confirmations[id] = &pendingConfirmation{msg: msg, cmd: cmd, args: args, prompt: prompt, expires: now.Add(CONFIRM_TIMEOUT)}
+}
+
+// handleCallbackQuery resolves a Confirm/Cancel tap. Only whoever sent the
+// command can answer; taps after CONFIRM_TIMEOUT are refused.
+func handleCallbackQuery(ctx context.Context, query *CallbackQuery) {
+	action, id, ok := strings.Cut(query.Data, ":")
+	if !ok || (action != "confirm" && action != "cancel") {
+		return
+	}
+
+	// Whoever tapped is the one who sent the command, so answer in their
+	// language too.
+	lang := config.Language
+	if code, ok := userLanguage(query.From.LanguageCode); ok {
+		lang = code
+	} else if query.Message != nil {
+		lang = chatSettings(strconv.FormatInt(query.Message.Chat.ID, 10)).language()
+	}
+
+	confirmationsMu.Lock()
+	pending := confirmations[id]
+	if pending != nil && (pending.msg.From == nil || pending.msg.From.ID != query.From.ID) {
+		confirmationsMu.Unlock()
+		answerCallback(ctx, query, tr(lang, "confirm.not_yours"))
+		return
+	}
+	delete(confirmations, id)
+	confirmationsMu.Unlock()
+
+	var status string
+	switch {
+	case pending == nil || time.Now().After(pending.expires):
+		status = tr(lang, "confirm.expired")
+	case action == "cancel":
+		status = tr(lang, "confirm.cancelled")
+	default:
+		status = tr(lang, "confirm.done")
+	}
+	answerCallback(ctx, query, status)
+
+	if query.Message != nil {
+		chatID := strconv.FormatInt(query.Message.Chat.ID, 10)
+		if err := telegram.EditMessageText(ctx, chatID, query.Message.MessageID, status, nil); err != nil {
+			log.Printf("Error updating confirmation message: %v", err)
+		}
+	}
+
+	if pending != nil && action == "confirm" && time.Now().Before(pending.expires) {
+		// The sender's role may have changed while the question was open.
+		if required, _ := requiredRole(pending.cmd); userRole(pending.msg.From) >= required {
+			commands[pending.cmd].Run(ctx, pending.msg, pending.args)
+		}
+	}
+}
+
+func answerCallback(ctx context.Context, query *CallbackQuery, text string) {
+	if err := telegram.AnswerCallbackQuery(ctx, query.ID, text, false); err != nil {
+		log.Printf("Error answering callback query: %v", err)
+	}
+}
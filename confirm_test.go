@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tapConfirmation taps action ("confirm" or "cancel") on the newest pending
+// confirmation as user from.
+func tapConfirmation(t *testing.T, from int64, action string) {
+	t.Helper()
+
+	confirmationsMu.Lock()
+	var id string
+	var newest *pendingConfirmation
+	for key, pending := range confirmations {
+		if newest == nil || pending.expires.After(newest.expires) {
+			id, newest = key, pending
+		}
+	}
+	confirmationsMu.Unlock()
+	if newest == nil {
+		t.Fatal("no pending confirmation")
+	}
+
+	handleUpdate(context.Background(), Update{CallbackQuery: &CallbackQuery{
+		ID: "q", From: User{ID: from}, Message: newest.prompt, Data: action + ":" + id,
+	}})
+}
+
+func TestConfirmation(t *testing.T) {
+	useTempStore(t, 2)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1, 2}
+	t.Cleanup(func() { confirmations = map[string]*pendingConfirmation{} })
+
+	forget := func() {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 5, Chat: Chat{ID: 7}, From: &User{ID: 1}, Text: "/forget steve",
+		}})
+	}
+	answers := func() []string {
+		var texts []string
+		for _, call := range fake.callsTo("answerCallbackQuery") {
+			texts = append(texts, call.Params["text"].(string))
+		}
+		return texts
+	}
+
+	forget()
+	if len(store.Entries[0].Players) != 2 {
+		t.Fatal("/forget ran before confirmation")
+	}
+	prompt := fake.callsTo("sendMessage")[0].Params
+	if !strings.Contains(prompt["text"].(string), "Confirm within 30 s") || prompt["reply_markup"] == nil {
+		t.Fatalf("prompt = %v", prompt)
+	}
+
+	tapConfirmation(t, 2, "confirm")
+	if got := answers(); len(got) != 1 || got[0] != "Only whoever sent the command can confirm it." {
+		t.Fatalf("someone else's tap: %q", got)
+	}
+	tapConfirmation(t, 1, "cancel")
+	if len(store.Entries[0].Players) != 2 {
+		t.Fatal("/forget ran after cancel")
+	}
+
+	forget()
+	confirmationsMu.Lock()
+	for _, pending := range confirmations {
+		pending.expires = time.Now().Add(-time.Second)
+	}
+	confirmationsMu.Unlock()
+	tapConfirmation(t, 1, "confirm")
+	if len(store.Entries[0].Players) != 2 {
+		t.Fatal("/forget ran after the confirmation expired")
+	}
+
+	forget()
+	tapConfirmation(t, 1, "confirm")
+	if len(store.Entries[0].Players) != 1 {
+		t.Fatal("/forget didn't run after confirmation")
+	}
+	if got := answers(); got[len(got)-1] != "✅ Confirmed." {
+		t.Fatalf("answers = %q", got)
+	}
+	if edits := fake.callsTo("editMessageText"); len(edits) != 3 {
+		t.Fatalf("prompt edited %d times, want 3", len(edits))
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// handleConsoleCommand runs a server command over RCON for /say, /list,
+// /whitelist and /rcon <command>, and replies with the server's answer.
+// Only the commands in RCON_COMMANDS may run, so the chat can't reach
+// /op or /stop.
+func handleConsoleCommand(ctx context.Context, msg *Message, cmd, args string) {
+	lang := replyLanguage(msg)
+	if config.RCONAddr == "" {
+		reply(ctx, msg, tr(lang, "console.disabled"))
+		return
+	}
+	if args == "" && cmd != "list" {
+		reply(ctx, msg, tr(lang, "console.usage_"+cmd))
+		return
+	}
+	command := strings.TrimSpace(cmd + " " + args)
+	if cmd == "rcon" {
+		command = strings.TrimPrefix(args, "/")
+	}
+	if !rconCommandAllowed(command) {
+		reply(ctx, msg, tr(lang, "console.not_allowed", escapeHtml(strings.Join(config.RCONCommands, ", "))))
+		recordAudit(auditCommand(msg, cmd, args, "not allowed"))
+		return
+	}
+
+	output, err := runRCON(ctx, command)
+	if err != nil {
+		log.Printf("Error running %q over RCON: %v", command, err)
+		reply(ctx, msg, tr(lang, "console.failed", escapeHtml(err.Error())))
+		recordAudit(auditCommand(msg, cmd, args, "rcon failed"))
+		return
+	}
+	if output = sanitizeText(output); output == "" {
+		reply(ctx, msg, tr(lang, "console.done", escapeHtml(command)))
+	} else {
+		reply(ctx, msg, tr(lang, "console.output", escapeHtml(command), escapeHtml(output)))
+	}
+	recordAudit(auditCommand(msg, cmd, args, "ok"))
+}
+
+// rconCommandAllowed reports whether the server command's name is one of
+// RCON_COMMANDS.
+func rconCommandAllowed(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	for _, allowed := range config.RCONCommands {
+		if strings.EqualFold(fields[0], allowed) {
+			return true
+		}
+	}
+	return false
+}
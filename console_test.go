@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConsoleCommands(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	rcon := useFakeRCON(t, func(command string) string {
+		if command == "list" {
+			return "There are 2 of a max of 20 players online: §aSteve§r, Alex"
+		}
+		return ""
+	})
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+	config.RCONCommands = []string{"list", "say", "whitelist"}
+
+	send := func(text string) string {
+		t.Helper()
+		before := len(fake.callsTo("sendMessage"))
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: 1, Type: "private"}, From: &User{ID: 1}, Text: text,
+		}})
+		sent := fake.callsTo("sendMessage")
+		if len(sent) != before+1 {
+			t.Fatalf("%s: %d replies", text, len(sent)-before)
+		}
+		return sent[before].Params["text"].(string)
+	}
+
+	if got, want := send("/list"), "<code>/list</code>\n<pre>There are 2 of a max of 20 players online: Steve, Alex</pre>"; got != want {
+		t.Errorf("/list = %q, want %q", got, want)
+	}
+	if got, want := send("/whitelist add Steve"), "✅ <code>/whitelist add Steve</code> done."; got != want {
+		t.Errorf("/whitelist = %q, want %q", got, want)
+	}
+	if got, want := send("/rcon /stop"), "⛔ This command can't be run from the chat. Allowed: list, say, whitelist"; got != want {
+		t.Errorf("/rcon /stop = %q, want %q", got, want)
+	}
+	if got, want := send("/say"), "Usage: /say &lt;text&gt;"; got != want {
+		t.Errorf("/say = %q, want %q", got, want)
+	}
+
+	want := []string{"list", "whitelist add Steve"}
+	if commands := rcon.received(); len(commands) != len(want) || commands[0] != want[0] || commands[1] != want[1] {
+		t.Errorf("RCON commands = %q, want %q", commands, want)
+	}
+}
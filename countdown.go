@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RESTART_COUNTDOWN_TICK is how often the countdown looks at the clock;
+// warnings go out at most this late.
+const RESTART_COUNTDOWN_TICK = 10 * time.Second
+
+// countdown remembers the warnings already sent, as restart time plus
+// warning.
+var countdown struct {
+	mu   sync.Mutex
+	sent map[string]bool
+}
+
+// parseRestartSchedule reads RESTART_SCHEDULE, daily restart times such as
+// "04:00,16:00", as offsets from midnight.
+func parseRestartSchedule(s string) ([]time.Duration, error) {
+	var times []time.Duration
+	for _, item := range splitList(s) {
+		at, err := time.Parse("15:04", item)
+		if err != nil {
+			return nil, fmt.Errorf("bad restart time %q", item)
+		}
+		times = append(times, time.Duration(at.Hour())*time.Hour+time.Duration(at.Minute())*time.Minute)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times, nil
+}
+
+// parseMinutesList reads a list of minutes such as "15,5,1", longest
+// first.
+func parseMinutesList(s string) ([]time.Duration, error) {
+	var durations []time.Duration
+	for _, item := range splitList(s) {
+		n, err := strconv.Atoi(item)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bad number of minutes %q", item)
+		}
+		durations = append(durations, time.Duration(n)*time.Minute)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] > durations[j] })
+	return durations, nil
+}
+
+// nextScheduledRestart is the first RESTART_SCHEDULE time after now.
+func nextScheduledRestart(now time.Time) (time.Time, bool) {
+	y, m, d := now.Date()
+	for day := 0; day <= 1; day++ {
+		midnight := time.Date(y, m, d+day, 0, 0, 0, 0, now.Location())
+		for _, offset := range config.RestartSchedule {
+			if at := midnight.Add(offset); at.After(now) {
+				return at, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// scheduledRestartNear reports the RESTART_SCHEDULE time within
+// RESTART_TOLERANCE of t, if there is one.
+func scheduledRestartNear(t time.Time) (time.Time, bool) {
+	y, m, d := t.Date()
+	for day := -1; day <= 1; day++ {
+		midnight := time.Date(y, m, d+day, 0, 0, 0, 0, t.Location())
+		for _, offset := range config.RestartSchedule {
+			if at := midnight.Add(offset); t.Sub(at).Abs() <= RESTART_TOLERANCE {
+				return at, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// runRestartCountdown warns the chats, and with RESTART_RCON_SAY the
+// players in game, RESTART_WARNINGS minutes before each scheduled restart.
+// A warning that was missed (the monitor was down) is skipped for the
+// next one rather than sent late.
+func runRestartCountdown(ctx context.Context, now time.Time) {
+	restart, ok := nextScheduledRestart(now)
+	if !ok {
+		return
+	}
+	left := restart.Sub(now)
+
+	// The shortest warning whose time has come.
+	var warning time.Duration
+	for _, w := range config.RestartWarnings {
+		if left <= w {
+			warning = w
+		}
+	}
+	if warning == 0 {
+		return
+	}
+
+	key := fmt.Sprintf("%d/%s", restart.Unix(), warning)
+	countdown.mu.Lock()
+	if countdown.sent[key] {
+		countdown.mu.Unlock()
+		return
+	}
+	if countdown.sent == nil {
+		countdown.sent = map[string]bool{}
+	}
+	// Older restarts will never come up again.
+	for sent := range countdown.sent {
+		if !strings.HasPrefix(sent, strconv.FormatInt(restart.Unix(), 10)+"/") {
+			delete(countdown.sent, sent)
+		}
+	}
+	countdown.sent[key] = true
+	countdown.mu.Unlock()
+
+	minutes := int((left + time.Minute - 1) / time.Minute)
+	broadcast(ctx, "", func(lang string) string {
+		return trn(lang, "restart.countdown", minutes, minutes, restart.Format("15:04"))
+	}, nil)
+	if config.RestartRCONSay {
+		text := trn(config.Language, "restart.countdown_game", minutes, minutes)
+		if _, err := runRCON(ctx, "say "+text); err != nil {
+			log.Printf("Error announcing the restart in game: %v", err)
+		}
+	}
+}
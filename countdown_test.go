@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRestartCountdown(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	rcon := useFakeRCON(t, func(string) string { return "" })
+	config.Language = "en"
+	config.RestartRCONSay = true
+	var err error
+	if config.RestartSchedule, err = parseRestartSchedule("16:00, 04:00"); err != nil {
+		t.Fatal(err)
+	}
+	if config.RestartWarnings, err = parseMinutesList("1,15,5"); err != nil {
+		t.Fatal(err)
+	}
+	countdown.sent = nil
+	ctx := context.Background()
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	restart := day.Add(4 * time.Hour)
+	for _, before := range []time.Duration{
+		20 * time.Minute, 15*time.Minute + 30*time.Second, 15 * time.Minute, 10 * time.Minute,
+		8 * time.Minute, 4*time.Minute + 50*time.Second, time.Minute, 30 * time.Second, -30 * time.Second,
+	} {
+		runRestartCountdown(ctx, restart.Add(-before))
+	}
+
+	var texts []string
+	for _, call := range fake.callsTo("sendMessage") {
+		texts = append(texts, call.Params["text"].(string))
+	}
+	want := []string{
+		"⏳ The server restarts in 15 minutes, at 04:00.",
+		"⏳ The server restarts in 5 minutes, at 04:00.",
+		"⏳ The server restarts in 1 minute, at 04:00.",
+	}
+	if len(texts) != len(want) {
+		t.Fatalf("messages = %q, want %q", texts, want)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Errorf("message %d = %q, want %q", i, texts[i], want[i])
+		}
+	}
+	if commands := rcon.received(); len(commands) != 3 || commands[2] != "say The server restarts in 1 minute" {
+		t.Errorf("RCON commands = %q", commands)
+	}
+
+	if at, ok := expectedRestart(day.Add(16*time.Hour + 3*time.Minute)); !ok || at != day.Add(16*time.Hour) {
+		t.Errorf("expectedRestart at 16:03 = %v, %v", at, ok)
+	}
+}
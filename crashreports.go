@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CRASH_EXCEPTION_LENGTH caps the exception quoted in a crash report's
+// caption; the whole report is attached anyway.
+const CRASH_EXCEPTION_LENGTH = 300
+
+// CrashReport is the header of a crash-reports/crash-*.txt file.
+type CrashReport struct {
+	Time        string
+	Description string
+	// Exception is the first line of the stack trace.
+	Exception string
+}
+
+// listCrashReports lists the file names in CRASH_REPORTS_PATH, over SSH
+// when SFTP_ADDR is set; tests swap it for a fake.
+var listCrashReports = func(ctx context.Context) ([]string, error) {
+	if config.SFTPAddr != "" {
+		output, err := runSSH(ctx, "ls -1 -- "+shellQuote(config.CrashReportsPath))
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(string(output)), nil
+	}
+	entries, err := os.ReadDir(config.CrashReportsPath)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// readCrashReport reads one file of CRASH_REPORTS_PATH, over SFTP when
+// SFTP_ADDR is set; tests swap it for a fake.
+var readCrashReport = func(ctx context.Context, name string) ([]byte, error) {
+	if config.SFTPAddr != "" {
+		return sftpReadFile(ctx, path.Join(config.CrashReportsPath, name))
+	}
+	return os.ReadFile(filepath.Join(config.CrashReportsPath, name))
+}
+
+// checkCrashReports forwards the crash reports that appeared since the
+// last check to the admins, each as a document with its header as the
+// caption. The reports there on the very first check are only noted.
+func checkCrashReports(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	names, err := listCrashReports(listCtx)
+	cancel()
+	if err != nil {
+		log.Printf("Error listing crash reports: %v", err)
+		return
+	}
+	var reports []string
+	for _, name := range names {
+		if strings.HasPrefix(name, "crash-") && strings.HasSuffix(name, ".txt") {
+			reports = append(reports, name)
+		}
+	}
+	sort.Strings(reports)
+
+	state.mu.Lock()
+	first := state.CrashReports == nil
+	seen := map[string]bool{}
+	for _, name := range state.CrashReports {
+		seen[name] = true
+	}
+	var added []string
+	for _, name := range reports {
+		if !seen[name] && !first {
+			added = append(added, name)
+		}
+	}
+	// Reports deleted from the folder are forgotten, so the list stays as
+	// long as the folder.
+	if first || len(added) > 0 || len(reports) != len(state.CrashReports) {
+		state.CrashReports = append([]string{}, reports...)
+		saveState()
+	}
+	state.mu.Unlock()
+
+	for _, name := range added {
+		forwardCrashReport(ctx, name)
+	}
+}
+
+func forwardCrashReport(ctx context.Context, name string) {
+	readCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	data, err := readCrashReport(readCtx, name)
+	cancel()
+	if err != nil {
+		log.Printf("Error reading crash report %s: %v", name, err)
+		return
+	}
+	log.Printf("New crash report %s", name)
+	caption := renderCrashReport(config.Language, name, parseCrashReport(string(data)))
+	for _, chatID := range adminChats() {
+		if _, err := telegram.SendDocumentFile(ctx, chatID, InputFile{Name: name, Data: data}, caption); err != nil {
+			log.Printf("Error sending crash report %s to %s: %v", name, chatID, err)
+		}
+	}
+}
+
+// parseCrashReport reads the header of a crash report:
+//
+//	---- Minecraft Crash Report ----
+//	// Witty comment
+//
+//	Time: 2024-03-01 12:00:00
+//	Description: Exception in server tick loop
+//
+//	java.lang.NullPointerException: ...
+func parseCrashReport(data string) CrashReport {
+	var report CrashReport
+	afterDescription := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Time: ") && report.Time == "":
+			report.Time = strings.TrimPrefix(line, "Time: ")
+		case strings.HasPrefix(line, "Description: ") && report.Description == "":
+			report.Description = strings.TrimPrefix(line, "Description: ")
+			afterDescription = true
+		case afterDescription && line != "":
+			report.Exception = line
+			return report
+		}
+	}
+	return report
+}
+
+func renderCrashReport(lang, name string, report CrashReport) string {
+	lines := []string{tr(lang, "crash.title", escapeHtml(name))}
+	if report.Time != "" {
+		lines = append(lines, tr(lang, "crash.time", escapeHtml(report.Time)))
+	}
+	if report.Description != "" {
+		lines = append(lines, tr(lang, "crash.description", escapeHtml(report.Description)))
+	}
+	if report.Exception != "" {
+		lines = append(lines, "<code>"+escapeHtml(truncateRunes(report.Exception, CRASH_EXCEPTION_LENGTH))+"</code>")
+	}
+	return strings.Join(lines, "\n")
+}
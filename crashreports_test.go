@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+const testCrashReport = `---- Minecraft Crash Report ----
+// Don't be sad, have a hug! <3
+
+Time: 2024-03-01 12:00:00
+Description: Exception in server tick loop
+
+java.lang.NullPointerException: Cannot invoke "net.minecraft.world.entity.Entity.tick()" because "entity" is null
+	at net.minecraft.server.level.ServerLevel.tickNonPassenger(ServerLevel.java:1000)
+`
+
+func TestParseCrashReport(t *testing.T) {
+	got := parseCrashReport(testCrashReport)
+	want := CrashReport{
+		Time:        "2024-03-01 12:00:00",
+		Description: "Exception in server tick loop",
+		Exception:   `java.lang.NullPointerException: Cannot invoke "net.minecraft.world.entity.Entity.tick()" because "entity" is null`,
+	}
+	if got != want {
+		t.Errorf("parseCrashReport() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckCrashReportsForwardsNewOnes(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminChatID = "-100"
+
+	previousList, previousRead := listCrashReports, readCrashReport
+	defer func() { listCrashReports, readCrashReport = previousList, previousRead }()
+	files := []string{"crash-2024-02-01_10.00.00-server.txt"}
+	listCrashReports = func(context.Context) ([]string, error) { return files, nil }
+	readCrashReport = func(_ context.Context, name string) ([]byte, error) { return []byte(testCrashReport), nil }
+
+	// The reports already there on the first check aren't forwarded.
+	checkCrashReports(context.Background())
+	if sent := fake.callsTo("sendDocument"); len(sent) != 0 {
+		t.Fatalf("forwarded old reports: %+v", sent)
+	}
+
+	files = append(files, "crash-2024-03-01_12.00.00-server.txt", "notes.md")
+	checkCrashReports(context.Background())
+	checkCrashReports(context.Background())
+	sent := fake.callsTo("sendDocument")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d documents, want 1", len(sent))
+	}
+	if sent[0].Params["chat_id"] != "-100" || sent[0].Params["document"] != "upload:crash-2024-03-01_12.00.00-server.txt" {
+		t.Errorf("document = %+v", sent[0].Params)
+	}
+	want := "💥 <b>New crash report</b>: <code>crash-2024-03-01_12.00.00-server.txt</code>\n" +
+		"Time: 2024-03-01 12:00:00\n" +
+		"Description: Exception in server tick loop\n" +
+		"<code>java.lang.NullPointerException: Cannot invoke &quot;net.minecraft.world.entity.Entity.tick()&quot; because &quot;entity&quot; is null</code>"
+	if sent[0].Params["caption"] != want {
+		t.Errorf("caption:\n%s\nwant:\n%s", sent[0].Params["caption"], want)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+// ENCRYPTED_PREFIX starts every encrypted frame. Lines without it are
+// passed through as they are, so data written before STORAGE_KEY was set
+// stays readable and is encrypted as it gets rewritten.
+const ENCRYPTED_PREFIX = "enc1:"
+
+// encryptedStorage wraps another Storage with AES-256-GCM. Every Write or
+// Append becomes one line, ENCRYPTED_PREFIX followed by base64 of nonce and
+// ciphertext, so appending to the journal never needs the existing
+// content. The object name is authenticated along with the data, so frames
+// can't be moved from one object to another.
+type encryptedStorage struct {
+	Storage
+	aead cipher.AEAD
+}
+
+func newEncryptedStorage(inner Storage, key []byte) (encryptedStorage, error) {
+	if len(key) != 32 {
+		return encryptedStorage{}, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return encryptedStorage{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return encryptedStorage{}, err
+	}
+	return encryptedStorage{Storage: inner, aead: aead}, nil
+}
+
+// loadStorageKey reads the base64 key from STORAGE_KEY, or from the file
+// named by STORAGE_KEY_FILE. It returns nil when neither is set.
+func loadStorageKey() ([]byte, error) {
+	encoded := config.StorageKey
+	if encoded == "" && config.StorageKeyFile != "" {
+		data, err := ioutil.ReadFile(config.StorageKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(data)
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+}
+
+func (s encryptedStorage) seal(name string, data []byte) []byte {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, data, []byte(name))
+
+	frame := make([]byte, len(ENCRYPTED_PREFIX)+base64.StdEncoding.EncodedLen(len(sealed))+1)
+	copy(frame, ENCRYPTED_PREFIX)
+	base64.StdEncoding.Encode(frame[len(ENCRYPTED_PREFIX):], sealed)
+	frame[len(frame)-1] = '\n'
+	return frame
+}
+
+// open decrypts every frame in data and concatenates the plaintexts, keeping
+// unencrypted lines as they are. A frame that doesn't decrypt, most likely
+// one torn by a crash mid-append, is skipped like a malformed plain line, so
+// the rest of a journal survives it; but if none does, the key or the
+// object is wrong and open fails.
+func (s encryptedStorage) open(name string, data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(ENCRYPTED_PREFIX)) {
+		return data, nil
+	}
+
+	result := new(bytes.Buffer)
+	reader := bufio.NewReader(bytes.NewReader(data))
+	opened := 0
+	var failed error
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if !bytes.HasPrefix(line, []byte(ENCRYPTED_PREFIX)) {
+				result.Write(line)
+			} else if plain, err := s.openFrame(name, bytes.TrimSpace(line[len(ENCRYPTED_PREFIX):])); err != nil {
+				log.Printf("Skipping undecryptable frame in %s: %v", name, err)
+				failed = err
+			} else {
+				result.Write(plain)
+				opened++
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if opened == 0 && failed != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", name, failed)
+	}
+	return result.Bytes(), nil
+}
+
+func (s encryptedStorage) openFrame(name string, encoded []byte) ([]byte, error) {
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(sealed, encoded)
+	if err != nil {
+		return nil, err
+	}
+	sealed = sealed[:n]
+
+	nonceSize := s.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("frame too short")
+	}
+	return s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+}
+
+func (s encryptedStorage) Read(name string) ([]byte, error) {
+	data, err := s.Storage.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(name, data)
+}
+
+func (s encryptedStorage) Write(name string, data []byte) error {
+	return s.Storage.Write(name, s.seal(name, data))
+}
+
+func (s encryptedStorage) Append(name string, data []byte) error {
+	return s.Storage.Append(name, s.seal(name, data))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func useEncryptedStorage(t *testing.T) encryptedStorage {
+	t.Helper()
+
+	encrypted, err := newEncryptedStorage(fileStorage{}, bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := storage
+	storage = encrypted
+	t.Cleanup(func() { storage = previous })
+	return encrypted
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	useTempStore(t, 0)
+
+	// A journal line written before encryption was turned on.
+	os.WriteFile(JOURNAL_FILE, []byte(`{"id":1,"online":true,"lastChecked":1000,"players":["alex"]}`+"\n"), 0644)
+	useEncryptedStorage(t)
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 2000, Players: []string{"steve"}})
+	flushStore()
+
+	if data, _ := os.ReadFile(JOURNAL_FILE); bytes.Contains(data, []byte("steve")) {
+		t.Fatalf("%s holds a player name in plain text: %s", JOURNAL_FILE, data)
+	}
+
+	store = &StatusStore{}
+	loadStore()
+	if len(store.Entries) != 2 || store.Entries[0].Players[0] != "alex" || store.Entries[1].Players[0] != "steve" {
+		t.Fatalf("entries after reload = %+v", store.Entries)
+	}
+
+	store.mu.Lock()
+	writeSnapshot()
+	store.mu.Unlock()
+	if data, _ := os.ReadFile(JSON_FILE); bytes.Contains(data, []byte("alex")) {
+		t.Fatalf("%s holds a player name in plain text", JSON_FILE)
+	}
+}
+
+func TestEncryptedStorageRejectsMovedFrames(t *testing.T) {
+	useTempStore(t, 0)
+	encrypted := useEncryptedStorage(t)
+
+	if err := storage.Write(STATE_FILE, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(STATE_FILE)
+	if _, err := encrypted.open(JSON_FILE, data); err == nil {
+		t.Fatal("opened a state frame as the status snapshot")
+	}
+}
+
+func TestEncryptedJournalSkipsTornFrame(t *testing.T) {
+	useTempStore(t, 0)
+	useEncryptedStorage(t)
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 1000, Players: []string{"alex"}})
+	flushStore()
+	insertStatus(StatusEntry{Online: true, LastChecked: 2000, Players: []string{"steve"}})
+	flushStore()
+
+	// The monitor crashed halfway through appending the second frame.
+	data, _ := os.ReadFile(JOURNAL_FILE)
+	if err := os.WriteFile(JOURNAL_FILE, data[:len(data)-20], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store = &StatusStore{}
+	loadStore()
+	if len(store.Entries) != 1 || store.Entries[0].Players[0] != "alex" {
+		t.Fatalf("entries after reload = %+v, want the first check", store.Entries)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//go:embed web/dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+// DASHBOARD_DOWNTIMES is how many recent downtimes the admin dashboard
+// lists.
+const DASHBOARD_DOWNTIMES = 10
+
+type dashboardPage struct {
+	Admin   bool
+	Server  string
+	Online  bool
+	Checked string
+	Players []string
+	Count   int
+	// Uptime is the share of the last day the server was online, in
+	// percent.
+	Uptime string
+
+	// The rest is only filled in for admins.
+	CSRF      string
+	Done      string
+	Error     string
+	Attempts  int
+	Incident  *dashboardIncident
+	Address   string
+	IPs       []IPHealth
+	Downtimes []dashboardDowntime
+}
+
+type dashboardIncident struct {
+	Start, Elapsed, Acked string
+	Steps                 int
+}
+
+type dashboardDowntime struct {
+	Start, Lasted string
+}
+
+// handleDashboard serves the status page at /: what the server is doing,
+// who is playing and the last day's uptime. Requests carrying the admin
+// credentials get the admin variant; /admin asks for them.
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	renderDashboard(w, adminAuthorized(r), "")
+}
+
+// handleAdminDashboard serves the admin dashboard at /admin: the status page
+// plus the open incident, ping diagnostics and recent downtimes, with
+// controls to check right away and to acknowledge the incident.
+func handleAdminDashboard(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminAuth(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		renderDashboard(w, true, r.URL.Query().Get("done"))
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("csrf")), []byte(adminCSRF())) != 1 {
+			http.Error(w, "invalid form token", http.StatusForbidden)
+			return
+		}
+		// The check and the alert edits shouldn't stop halfway because
+		// the browser gave up waiting.
+		ctx := context.WithoutCancel(r.Context())
+		action := r.PostForm.Get("action")
+		result := "ok"
+		switch action {
+		case "check":
+			checkServer(ctx)
+		case "ack":
+			if !ackIncidentFromWeb(ctx) {
+				result = "no incident to acknowledge"
+			}
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		recordAudit(AuditEntry{Via: "web", Command: action, Result: result})
+		http.Redirect(w, r, r.URL.Path+"?done="+action, http.StatusSeeOther)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ackIncidentFromWeb acknowledges the open incident on behalf of the admin
+// dashboard, returning false if there is none or it was already taken.
+func ackIncidentFromWeb(ctx context.Context) bool {
+	state.mu.Lock()
+	incident := state.Incident
+	if incident == nil || incident.Acked != nil {
+		state.mu.Unlock()
+		return false
+	}
+	incident.Acked = &Acknowledgement{Name: "web", Time: time.Now()}
+	saveState()
+	acked := *incident
+	state.mu.Unlock()
+
+	log.Printf("Incident acknowledged from the admin dashboard")
+	updateAlerts(ctx, &acked, nil)
+	return true
+}
+
+func renderDashboard(w http.ResponseWriter, admin bool, done string) {
+	page := dashboardPage{Admin: admin}
+	fillDashboardPage(&page, time.Now())
+	if admin {
+		fillAdminDashboard(&page, time.Now())
+		page.Done = done
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Which variant is served depends on the credentials.
+	w.Header().Set("Cache-Control", "no-store")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		log.Printf("Error rendering the dashboard: %v", err)
+	}
+}
+
+// fillDashboardPage puts what anyone may see into page: nothing about the
+// server's addresses or why pings fail.
+func fillDashboardPage(page *dashboardPage, now time.Time) {
+	page.Server = net.JoinHostPort(config.ServerHost, strconv.Itoa(int(config.ServerPort)))
+	if latest := getLatest(); latest != nil {
+		page.Online = latest.Online
+		page.Checked = time.UnixMilli(latest.LastChecked).Format("2006-01-02 15:04:05")
+		page.Players = dedupePlayers(latest.Players)
+		page.Count = max(latest.PlayerCount, len(page.Players))
+	}
+
+	from := now.Add(-24 * time.Hour)
+	if entries := getRange(from.UnixMilli(), now.UnixMilli()+1); len(entries) > 0 {
+		span := now.Sub(time.UnixMilli(entries[0].LastChecked))
+		if span > 0 {
+			up := 1 - float64(replayDowntime(entries))/float64(span)
+			page.Uptime = fmt.Sprintf("%.2f", 100*max(up, 0))
+		}
+	}
+}
+
+// fillAdminDashboard adds the incident and diagnostics to page.
+func fillAdminDashboard(page *dashboardPage, now time.Time) {
+	page.CSRF = adminCSRF()
+	if result := cachedResult(); result != nil {
+		page.Error = errorCategory(result.Err)
+		page.Attempts = result.Attempts
+	}
+
+	state.mu.Lock()
+	if incident := state.Incident; incident != nil {
+		page.Incident = &dashboardIncident{
+			Start:   incident.Start.Format("2006-01-02 15:04:05"),
+			Elapsed: incident.elapsed(now).Round(time.Second).String(),
+			Steps:   incident.Steps,
+		}
+		if incident.Acked != nil {
+			page.Incident.Acked = incident.Acked.Name
+		}
+	}
+	if state.ServerIP != "" {
+		page.Address = fmt.Sprintf("%s since %s", state.ServerIP, time.Unix(state.ServerIPSince, 0).Format("2006-01-02 15:04"))
+	}
+	downtimes := state.Downtimes[max(len(state.Downtimes)-DASHBOARD_DOWNTIMES, 0):]
+	for i := len(downtimes) - 1; i >= 0; i-- {
+		downtime := dashboardDowntime{Start: time.Unix(downtimes[i].Start, 0).Format("2006-01-02 15:04"), Lasted: "ongoing"}
+		if downtimes[i].End != 0 {
+			downtime.Lasted = (time.Duration(downtimes[i].End-downtimes[i].Start) * time.Second).String()
+		}
+		page.Downtimes = append(page.Downtimes, downtime)
+	}
+	state.mu.Unlock()
+
+	page.IPs = resolvedAddress.healthReport(config.ServerHost)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardVariants(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.APIToken = "secret"
+	state.ServerIP, state.ServerIPSince = "203.0.113.7", time.Now().Unix()
+	state.Incident = &Incident{Start: time.Now().Add(-5 * time.Minute)}
+	insertStatus(StatusEntry{Online: true, LastChecked: time.Now().Add(-time.Minute).UnixMilli(), Players: []string{"steve"}})
+
+	do := func(method, path string, form url.Values, password string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if password != "" {
+			r.SetBasicAuth("admin", password)
+		}
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, r)
+		return w
+	}
+
+	public := do("GET", "/", nil, "").Body.String()
+	if !strings.Contains(public, "steve") || strings.Contains(public, "203.0.113.7") || strings.Contains(public, "Incident") {
+		t.Errorf("public dashboard:\n%s", public)
+	}
+	if w := do("GET", "/admin", nil, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("/admin without credentials: status %d", w.Code)
+	}
+	for _, path := range []string{"/", "/admin"} {
+		if admin := do("GET", path, nil, "secret").Body.String(); !strings.Contains(admin, "203.0.113.7") || !strings.Contains(admin, "Acknowledge") {
+			t.Errorf("admin dashboard at %s:\n%s", path, admin)
+		}
+	}
+	if w := do("GET", "/nope", nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("/nope: status %d", w.Code)
+	}
+
+	if w := do("POST", "/admin", url.Values{"csrf": {"forged"}, "action": {"ack"}}, "secret"); w.Code != http.StatusForbidden {
+		t.Errorf("forged form: status %d", w.Code)
+	}
+	if w := do("POST", "/admin", url.Values{"csrf": {adminCSRF()}, "action": {"ack"}}, "secret"); w.Code != http.StatusSeeOther {
+		t.Fatalf("ack: status %d", w.Code)
+	}
+	if state.Incident.Acked == nil {
+		t.Error("the incident wasn't acknowledged")
+	}
+}
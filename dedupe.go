@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// DEDUPE_WINDOW is how long a sent notification is remembered. A crash
+// before the status history was saved makes the next start see the same
+// transition again; within the window it isn't announced twice.
+const DEDUPE_WINDOW = time.Hour
+
+// AnnouncedEvent is the last event announced about one subject in a chat.
+type AnnouncedEvent struct {
+	Kind EventKind `json:"kind"`
+	Time int64     `json:"time"`
+}
+
+// announcedKey identifies what an event is about: the server itself or
+// one of its players, in one chat.
+func announcedKey(chatID, server string, event Event) string {
+	subject := "server"
+	if event.Player != "" {
+		subject = "player:" + strings.ToLower(event.Player)
+	}
+	return chatID + "|" + server + "|" + subject
+}
+
+// freshEvents drops the events chatID was already told about: the last
+// event announced about the same subject within DEDUPE_WINDOW was of the
+// same kind. Going down, up and down again is three fresh events.
+func freshEvents(chatID, server string, events []Event, now time.Time) []Event {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	var fresh []Event
+	for _, event := range events {
+		last, ok := state.Announced[announcedKey(chatID, server, event)]
+		if ok && last.Kind == event.Kind && now.Sub(time.Unix(last.Time, 0)) < DEDUPE_WINDOW {
+			log.Printf("Not announcing %s to %s again", event.Kind, chatID)
+			continue
+		}
+		fresh = append(fresh, event)
+	}
+	return fresh
+}
+
+// rememberEvents records events sent to chatID and forgets whatever fell
+// out of DEDUPE_WINDOW.
+func rememberEvents(chatID, server string, events []Event, now time.Time) {
+	if len(events) == 0 {
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.Announced == nil {
+		state.Announced = map[string]AnnouncedEvent{}
+	}
+	for key, last := range state.Announced {
+		if now.Sub(time.Unix(last.Time, 0)) >= DEDUPE_WINDOW {
+			delete(state.Announced, key)
+		}
+	}
+	for _, event := range events {
+		state.Announced[announcedKey(chatID, server, event)] = AnnouncedEvent{Kind: event.Kind, Time: now.Unix()}
+	}
+	saveState()
+}
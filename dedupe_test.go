@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnnouncementsSurviveRestartWithoutRepeating(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+
+	ctx := context.Background()
+	now := time.Now()
+	announce(ctx, "", false, 0, []Event{serverEvent(false, now), {Kind: EventPlayerLeft, Player: "steve", Time: now}})
+
+	// A crash-restart sees the same transition again.
+	loadState()
+	announce(ctx, "", false, 0, []Event{serverEvent(false, now), {Kind: EventPlayerLeft, Player: "Steve", Time: now}})
+	if sent := fake.callsTo("sendMessage"); len(sent) != 1 {
+		t.Fatalf("sendMessage calls = %+v, want the alert once", sent)
+	}
+
+	// Coming back up is news, and so is the next outage.
+	announce(ctx, "", true, 1, []Event{serverEvent(true, now)})
+	announce(ctx, "", false, 0, []Event{serverEvent(false, now)})
+	if sent := fake.callsTo("sendMessage"); len(sent) != 3 {
+		t.Fatalf("sendMessage calls = %+v, want both outages and the recovery", sent)
+	}
+}
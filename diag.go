@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runDiagnostics checks what usually breaks the bot — API reachability and
+// its rights in the group — and renders the findings for /diag in lang.
+func runDiagnostics(ctx context.Context, lang string) string {
+	lines := []string{tr(lang, "diag.header")}
+
+	start := time.Now()
+	me, err := telegram.GetMe(ctx)
+	if err != nil {
+		lines = append(lines, tr(lang, "diag.api.fail", escapeHtml(err.Error())))
+		return joinStrings(lines, "\n")
+	}
+	lines = append(lines, tr(lang, "diag.api.ok", time.Since(start).Milliseconds(), escapeHtml(me.Username)))
+
+	chatID := groupChatID()
+	chat, err := telegram.GetChat(ctx, chatID)
+	if err != nil {
+		lines = append(lines, tr(lang, "diag.chat.fail", escapeHtml(chatID), escapeHtml(err.Error())))
+	} else {
+		lines = append(lines, tr(lang, "diag.chat.ok", escapeHtml(chat.Title), escapeHtml(chat.Type)))
+
+		member, err := telegram.GetChatMember(ctx, chatID, me.ID)
+		if err != nil {
+			lines = append(lines, tr(lang, "diag.member.fail", escapeHtml(err.Error())))
+		} else {
+			lines = append(lines, tr(lang, "diag.member.status", escapeHtml(member.Status)))
+
+			creator := member.Status == "creator"
+			lines = append(lines,
+				tr(lang, "diag.right.change_info", checkMark(creator || member.CanChangeInfo)),
+				tr(lang, "diag.right.pin", checkMark(creator || member.CanPinMessages)),
+				tr(lang, "diag.right.delete", checkMark(creator || member.CanDeleteMessages)),
+			)
+		}
+	}
+
+	if result := cachedResult(); result != nil {
+		category := errorCategory(result.Err)
+		if category == "" {
+			category = "ok"
+		}
+		lines = append(lines, tr(lang, "diag.server", escapeHtml(category), result.Age().Round(time.Second)))
+		if status := result.Status; status != nil && status.Software != "" {
+			lines = append(lines, trn(lang, "diag.query", len(status.Plugins), escapeHtml(status.Software), escapeHtml(status.Map), len(status.Plugins)))
+		}
+	} else {
+		lines = append(lines, tr(lang, "diag.server.never"))
+	}
+
+	state.mu.Lock()
+	ip, since := state.ServerIP, state.ServerIPSince
+	state.mu.Unlock()
+	if ip != "" {
+		lines = append(lines, tr(lang, "diag.address", ip, time.Unix(since, 0).Format("2006-01-02 15:04")))
+	}
+	for _, h := range resolvedAddress.healthReport(config.ServerHost) {
+		line := tr(lang, "diag.ip", escapeHtml(h.IP), h.Successes, h.Failures)
+		if !h.LastFailure.IsZero() {
+			line += tr(lang, "diag.ip.last_failure", h.LastFailure.Format("2006-01-02 15:04"))
+		}
+		lines = append(lines, line)
+	}
+
+	return joinStrings(lines, "\n")
+}
+
+func checkMark(ok bool) string {
+	if ok {
+		return "✅"
+	}
+	return "❌"
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DISCORD_MAX_DESCRIPTION is the most characters Discord accepts in an
+// embed's description.
+const DISCORD_MAX_DESCRIPTION = 4096
+
+// Embed colors, by what happened.
+const (
+	DISCORD_COLOR_DOWN   = 0xE74C3C
+	DISCORD_COLOR_UP     = 0x2ECC71
+	DISCORD_COLOR_EMPTY  = 0xF1C40F
+	DISCORD_COLOR_JOINED = 0x3498DB
+	DISCORD_COLOR_LEFT   = 0x95A5A6
+)
+
+var discordHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// DiscordMessage is the body of a Discord webhook execution.
+type DiscordMessage struct {
+	Embeds []DiscordEmbed `json:"embeds"`
+}
+
+type DiscordEmbed struct {
+	Description string `json:"description"`
+	Color       int    `json:"color"`
+	Timestamp   string `json:"timestamp,omitempty"`
+}
+
+// postDiscord sends the events of one check to DISCORD_WEBHOOK_URL as one
+// message, with the same texts as Telegram in LANGUAGE.
+func postDiscord(ctx context.Context, events []Event) {
+	if config.DiscordWebhookURL == "" {
+		return
+	}
+	message := discordMessage(config.Language, events)
+	if len(message.Embeds) == 0 {
+		return
+	}
+	if err := executeDiscordWebhook(ctx, config.DiscordWebhookURL, message); err != nil {
+		log.Printf("Error posting to Discord: %v", err)
+	}
+}
+
+// discordMessage renders events the way renderEvents does, an embed per
+// change: the server's state first, then joins, then leaves.
+func discordMessage(lang string, events []Event) DiscordMessage {
+	settings := ChatSettings{Language: lang}
+	var message DiscordMessage
+	add := func(text string, color int, at time.Time) {
+		embed := DiscordEmbed{Description: truncateRunes(discordMarkdown(text), DISCORD_MAX_DESCRIPTION), Color: color}
+		if !at.IsZero() {
+			embed.Timestamp = at.UTC().Format(time.RFC3339)
+		}
+		message.Embeds = append(message.Embeds, embed)
+	}
+
+	var joined, left []string
+	var at time.Time
+	for _, event := range events {
+		switch event.Kind {
+		case EventPlayerJoined:
+			joined = append(joined, event.Player)
+			at = event.Time
+		case EventPlayerLeft:
+			left = append(left, event.Player)
+			at = event.Time
+		case EventServerDown:
+			add(renderServerDown(settings, event), DISCORD_COLOR_DOWN, event.Time)
+		case EventServerUp:
+			add(renderServerUp(settings, event), DISCORD_COLOR_UP, event.Time)
+		case EventServerEmpty:
+			add(tr(lang, "server.empty"), DISCORD_COLOR_EMPTY, event.Time)
+		}
+	}
+	if len(joined) > 0 {
+		add(renderPlayersChange(settings, "players.joined", joined), DISCORD_COLOR_JOINED, at)
+	}
+	if len(left) > 0 {
+		add(renderPlayersChange(settings, "players.left", left), DISCORD_COLOR_LEFT, at)
+	}
+	return message
+}
+
+var (
+	discordTags    = regexp.MustCompile(`</?(b|i|code)>`)
+	discordEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `_`, `\_`, `~`, `\~`, "`", "\\`", `|`, `\|`, `>`, `\>`)
+)
+
+// discordMarkdown turns the Telegram HTML the renderers produce into
+// Discord markdown. Text is escaped so player names like x_steve_x come
+// through as they are, except inside code spans, where Discord shows
+// backslashes literally.
+func discordMarkdown(text string) string {
+	var b strings.Builder
+	inCode := false
+	writePlain := func(s string) {
+		if s = html.UnescapeString(s); !inCode {
+			s = discordEscaper.Replace(s)
+		}
+		b.WriteString(s)
+	}
+
+	last := 0
+	for _, loc := range discordTags.FindAllStringSubmatchIndex(text, -1) {
+		writePlain(text[last:loc[0]])
+		switch tag := text[loc[2]:loc[3]]; tag {
+		case "b":
+			b.WriteString("**")
+		case "i":
+			b.WriteString("*")
+		case "code":
+			b.WriteString("`")
+			inCode = text[loc[0]+1] != '/'
+		}
+		last = loc[1]
+	}
+	writePlain(text[last:])
+	return b.String()
+}
+
+func executeDiscordWebhook(ctx context.Context, url string, message DiscordMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := discordHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("discord webhook error: %s - %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
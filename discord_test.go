@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDiscordMarkdown(t *testing.T) {
+	for in, want := range map[string]string{
+		"<b>x_steve_x</b> joined": `**x\_steve\_x** joined`,
+		"a &lt;b&gt; &amp; *c*":   `a <b\> & \*c\*`,
+		"run <code>/a_b</code>":   "run `/a_b`",
+		"<i>quiet</i>":            "*quiet*",
+	} {
+		if got := discordMarkdown(in); got != want {
+			t.Errorf("discordMarkdown(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPostDiscord(t *testing.T) {
+	useTempStore(t, 0)
+	var got DiscordMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.DiscordWebhookURL = srv.URL
+	config.Language = "en"
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := append([]Event{{Kind: EventServerUp, Time: at, DowntimeSeconds: 42 * 60}}, playerEvents([]string{"steve"}, []string{"alex"}, at)...)
+	dispatchEvents(context.Background(), events)
+
+	want := []DiscordEmbed{
+		{Description: "🟢 The server is back online after 42 minutes of downtime.", Color: DISCORD_COLOR_UP, Timestamp: "2024-01-02T03:04:05Z"},
+		{Description: tr("en", "players.joined.single", "**steve**"), Color: DISCORD_COLOR_JOINED, Timestamp: "2024-01-02T03:04:05Z"},
+		{Description: tr("en", "players.left.single", "**alex**"), Color: DISCORD_COLOR_LEFT, Timestamp: "2024-01-02T03:04:05Z"},
+	}
+	if len(got.Embeds) != len(want) {
+		t.Fatalf("embeds = %+v", got.Embeds)
+	}
+	for i := range want {
+		if got.Embeds[i] != want[i] {
+			t.Errorf("embed %d = %+v, want %+v", i, got.Embeds[i], want[i])
+		}
+	}
+}
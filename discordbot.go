@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// discordAPI is the Discord REST API the bot talks to.
+var discordAPI = "https://discord.com/api/v10"
+
+var discordBotHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// Gateway opcodes.
+const (
+	discordDispatch       = 0
+	discordHeartbeat      = 1
+	discordIdentify       = 2
+	discordPresenceUpdate = 3
+	discordReconnect      = 7
+	discordInvalidSession = 9
+	discordHello          = 10
+	discordHeartbeatAck   = 11
+)
+
+// discordPresenceChanged wakes the bot to update its presence after a
+// check.
+var discordPresenceChanged = make(chan struct{}, 1)
+
+// updateDiscordPresence asks the Discord bot, if it runs, to show the
+// latest check in its presence.
+func updateDiscordPresence() {
+	select {
+	case discordPresenceChanged <- struct{}{}:
+	default:
+	}
+}
+
+type discordGatewayMessage struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+	S  *int64          `json:"s"`
+	T  string          `json:"t"`
+}
+
+// DiscordInteraction is the part of an INTERACTION_CREATE the bot reads.
+type DiscordInteraction struct {
+	ID            string `json:"id"`
+	ApplicationID string `json:"application_id"`
+	Token         string `json:"token"`
+	Locale        string `json:"locale"`
+	Data          struct {
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+// runDiscordBot keeps a gateway connection to Discord as DISCORD_BOT_TOKEN,
+// answering the /status and /players slash commands and showing the player
+// count as the bot's presence, until ctx is cancelled. Does nothing
+// without DISCORD_BOT_TOKEN.
+func runDiscordBot(ctx context.Context) {
+	if config.DiscordBotToken == "" {
+		return
+	}
+	for {
+		err := discordSession(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Discord gateway connection lost: %v", err)
+		sleepContext(ctx, BOT_RETRY_DELAY)
+	}
+}
+
+// discordSession runs one gateway connection until it fails. Sessions
+// aren't resumed: the bot only answers commands, so a missed event costs
+// nothing.
+func discordSession(ctx context.Context) error {
+	var gateway struct {
+		URL string `json:"url"`
+	}
+	if err := discordRequest(ctx, "GET", "/gateway/bot", nil, &gateway); err != nil {
+		return err
+	}
+	conn, err := dialWebSocket(ctx, gateway.URL+"/?v=10&encoding=json")
+	if err != nil {
+		return err
+	}
+	session, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Closing the connection is what stops a blocked ReadMessage.
+	stop := context.AfterFunc(session, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	var hello struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	message, err := readDiscordGateway(conn)
+	if err != nil {
+		return err
+	}
+	if message.Op != discordHello {
+		return fmt.Errorf("expected Hello, got op %d", message.Op)
+	}
+	if err := json.Unmarshal(message.D, &hello); err != nil {
+		return err
+	}
+
+	if err := sendDiscordGateway(conn, discordIdentify, map[string]interface{}{
+		"token":   config.DiscordBotToken,
+		"intents": 0,
+		"properties": map[string]string{
+			"os":      runtime.GOOS,
+			"browser": "lnudorm3-status",
+			"device":  "lnudorm3-status",
+		},
+		"presence": discordPresence(),
+	}); err != nil {
+		return err
+	}
+
+	var seq atomic.Int64
+	seq.Store(-1)
+	var acked atomic.Bool
+	acked.Store(true)
+	heartbeat := func() error {
+		var d interface{}
+		if s := seq.Load(); s >= 0 {
+			d = s
+		}
+		return sendDiscordGateway(conn, discordHeartbeat, d)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-session.Done():
+				return
+			case <-ticker.C:
+				// A connection that stopped acknowledging heartbeats is
+				// dead even if TCP hasn't noticed.
+				if !acked.Swap(false) {
+					log.Printf("Discord stopped acknowledging heartbeats, reconnecting")
+					conn.Close()
+					return
+				}
+				if err := heartbeat(); err != nil {
+					return
+				}
+			case <-discordPresenceChanged:
+				sendDiscordGateway(conn, discordPresenceUpdate, discordPresence())
+			}
+		}
+	}()
+
+	for {
+		message, err := readDiscordGateway(conn)
+		if err != nil {
+			return err
+		}
+		if message.S != nil {
+			seq.Store(*message.S)
+		}
+		switch message.Op {
+		case discordDispatch:
+			handleDiscordDispatch(ctx, message)
+		case discordHeartbeat:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		case discordHeartbeatAck:
+			acked.Store(true)
+		case discordReconnect:
+			return errors.New("the gateway asked to reconnect")
+		case discordInvalidSession:
+			return errors.New("invalid session")
+		}
+	}
+}
+
+func handleDiscordDispatch(ctx context.Context, message *discordGatewayMessage) {
+	switch message.T {
+	case "READY":
+		var ready struct {
+			User struct {
+				Username string `json:"username"`
+			} `json:"user"`
+			Application struct {
+				ID string `json:"id"`
+			} `json:"application"`
+		}
+		if err := json.Unmarshal(message.D, &ready); err != nil {
+			log.Printf("Error decoding Discord READY: %v", err)
+			return
+		}
+		log.Printf("Connected to Discord as %s", ready.User.Username)
+		go func() {
+			if err := registerDiscordCommands(ctx, ready.Application.ID); err != nil {
+				log.Printf("Error registering Discord commands: %v", err)
+			}
+		}()
+
+	case "INTERACTION_CREATE":
+		var interaction DiscordInteraction
+		if err := json.Unmarshal(message.D, &interaction); err != nil {
+			log.Printf("Error decoding Discord interaction: %v", err)
+			return
+		}
+		go handleDiscordInteraction(ctx, &interaction)
+	}
+}
+
+// discordCommands are the slash commands the bot registers, with their
+// descriptions' i18n keys.
+var discordCommands = []struct{ Name, Description string }{
+	{"status", "discord.command.status"},
+	{"players", "discord.command.players"},
+}
+
+// registerDiscordCommands sets the bot's global slash commands, described
+// in English with the other languages as localizations.
+func registerDiscordCommands(ctx context.Context, applicationID string) error {
+	var commands []map[string]interface{}
+	for _, command := range discordCommands {
+		localized := map[string]string{}
+		for lang := range messages {
+			if lang != "en" {
+				localized[lang] = tr(lang, command.Description)
+			}
+		}
+		commands = append(commands, map[string]interface{}{
+			"name":                      command.Name,
+			"type":                      1,
+			"description":               tr("en", command.Description),
+			"description_localizations": localized,
+		})
+	}
+	return discordRequest(ctx, "PUT", "/applications/"+applicationID+"/commands", commands, nil)
+}
+
+// handleDiscordInteraction answers a slash command. Pinging the server can
+// take longer than the three seconds Discord waits for an answer, so the
+// answer is deferred and filled in afterwards.
+func handleDiscordInteraction(ctx context.Context, interaction *DiscordInteraction) {
+	name := interaction.Data.Name
+	if name != "status" && name != "players" {
+		return
+	}
+	if err := discordRequest(ctx, "POST", "/interactions/"+interaction.ID+"/"+interaction.Token+"/callback",
+		map[string]int{"type": 5}, nil); err != nil {
+		log.Printf("Error answering Discord /%s: %v", name, err)
+		return
+	}
+
+	lang := discordLanguage(interaction.Locale)
+	result := cachedStatus(ctx, currentSchedule().Interval)
+	text := renderStatus(lang, result)
+	if name == "players" {
+		text = renderPlayers(lang, result)
+	}
+	if err := discordRequest(ctx, "PATCH", "/webhooks/"+interaction.ApplicationID+"/"+interaction.Token+"/messages/@original",
+		map[string]string{"content": discordMarkdown(text)}, nil); err != nil {
+		log.Printf("Error answering Discord /%s: %v", name, err)
+	}
+}
+
+// discordLanguage picks the language for a Discord locale such as "uk" or
+// "en-US", LANGUAGE if there are no messages in it.
+func discordLanguage(locale string) string {
+	lang, _, _ := strings.Cut(locale, "-")
+	if messages[lang] != nil {
+		return lang
+	}
+	return config.Language
+}
+
+// discordPresence shows the latest check as the bot's custom status: online
+// while the server is, do not disturb while it's down.
+func discordPresence() map[string]interface{} {
+	status := compactStatus(config.Language)
+	presence := "online"
+	if !status.Online {
+		presence = "dnd"
+	}
+	return map[string]interface{}{
+		"since":      nil,
+		"activities": []map[string]interface{}{{"name": "Custom Status", "type": 4, "state": status.Text}},
+		"status":     presence,
+		"afk":        false,
+	}
+}
+
+func readDiscordGateway(conn *wsConn) (*discordGatewayMessage, error) {
+	data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	var message discordGatewayMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("decoding gateway message: %w", err)
+	}
+	return &message, nil
+}
+
+func sendDiscordGateway(conn *wsConn, op int, d interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{"op": op, "d": d})
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data)
+}
+
+func discordRequest(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, discordAPI+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+config.DiscordBotToken)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := discordBotHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("discord API error: %s - %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if result != nil {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
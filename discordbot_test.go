@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acceptWebSocket upgrades r on the server side. The wsConn it returns
+// masks what it sends, which clients accept too.
+func acceptWebSocket(t *testing.T, w http.ResponseWriter, r *http.Request) *wsConn {
+	sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	rw.Flush()
+	return &wsConn{conn: conn, rd: bufio.NewReader(conn)}
+}
+
+func TestDiscordBot(t *testing.T) {
+	useTempStore(t, 0)
+	previous, previousAPI := config, discordAPI
+	t.Cleanup(func() { config, discordAPI = previous, previousAPI })
+	config.DiscordBotToken = "token"
+	config.Language = "uk"
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, PlayerCount: 1, Players: []string{"x_steve_x"}}, CheckedAt: time.Now()}
+	})
+
+	var mu sync.Mutex
+	requests := map[string]string{}
+	answered := make(chan struct{})
+	identified := make(chan map[string]interface{}, 1)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") == "websocket" {
+			gateway := acceptWebSocket(t, w, r)
+			defer gateway.conn.Close()
+			gateway.WriteText([]byte(`{"op":10,"d":{"heartbeat_interval":45000}}`))
+			data, err := gateway.ReadMessage()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var identify struct {
+				Op int                    `json:"op"`
+				D  map[string]interface{} `json:"d"`
+			}
+			json.Unmarshal(data, &identify)
+			identified <- identify.D
+			gateway.WriteText([]byte(`{"op":0,"s":1,"t":"READY","d":{"user":{"username":"status"},"application":{"id":"app"}}}`))
+			gateway.WriteText([]byte(`{"op":0,"s":2,"t":"INTERACTION_CREATE","d":{"id":"i1","application_id":"app","token":"tok","locale":"en-US","data":{"name":"players"}}}`))
+			gateway.ReadMessage()
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bot token" {
+			t.Errorf("%s %s without the bot token", r.Method, r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path] = string(body)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v10/gateway/bot":
+			json.NewEncoder(w).Encode(map[string]string{"url": "ws" + strings.TrimPrefix(srv.URL, "http")})
+		case "/api/v10/webhooks/app/tok/messages/@original":
+			close(answered)
+		}
+	}))
+	defer srv.Close()
+	discordAPI = srv.URL + "/api/v10"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runDiscordBot(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	identify := <-identified
+	if identify["token"] != "token" || identify["presence"] == nil {
+		t.Errorf("identify = %+v", identify)
+	}
+	select {
+	case <-answered:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no answer to /players; requests: %v", requests)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if body := requests["POST /api/v10/interactions/i1/tok/callback"]; body != `{"type":5}` {
+		t.Errorf("callback = %q, want a deferred answer", body)
+	}
+	if body := requests["PATCH /api/v10/webhooks/app/tok/messages/@original"]; !strings.Contains(body, `Playing: **x\\_steve\\_x**`) {
+		t.Errorf("answer = %s, want the players in English", body)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// trackServerAddress records the IP SERVER_HOST was reached at. The server
+// sits behind a home connection with a dynamic IP, so a change usually
+// explains the "downtime" before it: DNS still pointed at the old address.
+// Changes are logged and noted quietly in the chats.
+func trackServerAddress(ctx context.Context, ip string, now time.Time) {
+	if ip == "" {
+		return
+	}
+
+	state.mu.Lock()
+	previous := state.ServerIP
+	if previous == ip {
+		state.mu.Unlock()
+		return
+	}
+	state.ServerIP = ip
+	state.ServerIPSince = now.Unix()
+	saveState()
+	state.mu.Unlock()
+
+	// The first address seen isn't a change.
+	if previous == "" {
+		return
+	}
+	log.Printf("%s now resolves to %s (was %s)", config.ServerHost, ip, previous)
+	broadcast(ctx, "", func(lang string) string {
+		return tr(lang, "dns.changed", escapeHtml(config.ServerHost), ip, previous)
+	}, &MessageOptions{DisableNotification: true})
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTrackServerAddress(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.ServerHost = "mc.example.com"
+
+	ctx := context.Background()
+	now := time.Now()
+	trackServerAddress(ctx, "203.0.113.7", now)
+	trackServerAddress(ctx, "203.0.113.7", now.Add(time.Minute))
+	if sent := fake.callsTo("sendMessage"); len(sent) != 0 {
+		t.Fatalf("notes without a change: %+v", sent)
+	}
+
+	trackServerAddress(ctx, "198.51.100.4", now.Add(2*time.Minute))
+	sent := fake.callsTo("sendMessage")
+	want := "🌐 mc.example.com now resolves to <code>198.51.100.4</code> (was <code>203.0.113.7</code>)."
+	if len(sent) != 1 || sent[0].Params["text"] != want || sent[0].Params["disable_notification"] != true {
+		t.Fatalf("sendMessage calls = %+v", sent)
+	}
+	if state.ServerIP != "198.51.100.4" || state.ServerIPSince != now.Add(2*time.Minute).Unix() {
+		t.Fatalf("state = %s since %d", state.ServerIP, state.ServerIPSince)
+	}
+}
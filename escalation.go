@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EscalationStep is one rung of the escalation ladder: after the server has
+// been down for After, run Action (mention, group, pushover or email).
+type EscalationStep struct {
+	After  time.Duration
+	Action string
+}
+
+var escalationActions = map[string]bool{"mention": true, "group": true, "pushover": true, "email": true}
+
+// parseEscalation parses ESCALATION_LADDER, e.g. "15m:mention,60m:pushover".
+func parseEscalation(s string) ([]EscalationStep, error) {
+	var steps []EscalationStep
+	for _, item := range splitList(s) {
+		after, action, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("step %q: want duration:action", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(after))
+		if err != nil {
+			return nil, fmt.Errorf("step %q: %v", item, err)
+		}
+		action = strings.TrimSpace(action)
+		if !escalationActions[action] {
+			return nil, fmt.Errorf("step %q: unknown action %q", item, action)
+		}
+		steps = append(steps, EscalationStep{After: d, Action: action})
+	}
+	return steps, nil
+}
+
+// escalate walks the ladder for the current outage, running every step
+// whose time has come. It opens an incident if the server was already down
+// when the bot started, stops once someone acknowledged the incident, and
+// resolves it when the server is reachable again.
+func escalate(ctx context.Context, reachable bool, scheduled bool, now time.Time) {
+	if reachable {
+		resolveIncident(ctx, now)
+		return
+	}
+	openIncident(now, scheduled)
+
+	state.mu.Lock()
+	incident := *state.Incident
+	state.mu.Unlock()
+	if incident.Acked != nil {
+		return
+	}
+
+	elapsed := incident.elapsed(now)
+	delay := time.Duration(0)
+	if incident.Scheduled {
+		delay = RESTART_MAX_DURATION
+	}
+
+	steps := incident.Steps
+	for steps < len(config.Escalation) && elapsed-delay >= config.Escalation[steps].After {
+		runEscalationStep(ctx, config.Escalation[steps].Action, elapsed)
+		steps++
+	}
+	if steps == incident.Steps {
+		return
+	}
+
+	state.mu.Lock()
+	if state.Incident != nil {
+		state.Incident.Steps = steps
+		saveState()
+	}
+	state.mu.Unlock()
+}
+
+func runEscalationStep(ctx context.Context, action string, down time.Duration) {
+	minutes := int(down.Minutes())
+	lang := config.Language
+	server := fmt.Sprintf("%s:%d", config.ServerHost, config.ServerPort)
+
+	var err error
+	switch action {
+	case "group":
+		_, err = telegram.SendMessage(ctx, groupChatID(), trn(lang, "escalation.still_down", minutes, minutes), nil)
+	case "mention":
+		text := trn(lang, "escalation.still_down", minutes, minutes)
+		if mentions := adminMentions(); mentions != "" {
+			text += "\n" + mentions
+		}
+		_, err = telegram.SendMessage(ctx, groupChatID(), text, nil)
+	case "pushover":
+		err = sendPushover(ctx, tr(lang, "escalation.title", server), trn(lang, "escalation.body", minutes, server, minutes))
+	case "email":
+		err = sendEmail(tr(lang, "escalation.title", server), trn(lang, "escalation.body", minutes, server, minutes))
+	}
+	if err != nil {
+		log.Printf("Error escalating outage (%s): %v", action, err)
+		return
+	}
+	log.Printf("Escalated outage after %d min: %s", minutes, action)
+}
+
+// adminMentions mentions every TELEGRAM_ADMIN_IDS user by ID, which works
+// without knowing their usernames.
+func adminMentions() string {
+	mentions := make([]string, len(config.AdminIDs))
+	for i, id := range config.AdminIDs {
+		mentions[i] = `<a href="tg://user?id=` + strconv.FormatInt(id, 10) + `">admin</a>`
+	}
+	return strings.Join(mentions, " ")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEscalation(t *testing.T) {
+	steps, err := parseEscalation("15m:mention, 1h:pushover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0] != (EscalationStep{15 * time.Minute, "mention"}) || steps[1] != (EscalationStep{time.Hour, "pushover"}) {
+		t.Fatalf("steps = %+v", steps)
+	}
+
+	for _, bad := range []string{"15m", "soon:mention", "15m:pager"} {
+		if _, err := parseEscalation(bad); err == nil {
+			t.Errorf("parseEscalation(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+
+	var pushes []string
+	pushover := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		pushes = append(pushes, r.Form.Get("message"))
+	}))
+	defer pushover.Close()
+	previousAPI := PUSHOVER_API
+	PUSHOVER_API = pushover.URL
+	defer func() { PUSHOVER_API = previousAPI }()
+
+	config.Language = "en"
+	config.AdminIDs = []int64{7}
+	config.PushoverToken, config.PushoverUser = "token", "user"
+	config.Escalation = []EscalationStep{{15 * time.Minute, "mention"}, {time.Hour, "pushover"}}
+
+	ctx := context.Background()
+	start := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, minutes := range []int{0, 10, 16, 20, 61, 70} {
+		escalate(ctx, false, false, start.Add(time.Duration(minutes)*time.Minute))
+	}
+
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sendMessage calls = %+v, want one mention", sent)
+	}
+	text, _ := sent[0].Params["text"].(string)
+	if !strings.Contains(text, "16 minutes") || !strings.Contains(text, `tg://user?id=7`) {
+		t.Fatalf("mention = %q", text)
+	}
+	if len(pushes) != 1 || !strings.Contains(pushes[0], "61 minutes") {
+		t.Fatalf("pushover messages = %q", pushes)
+	}
+
+	escalate(ctx, true, false, start.Add(2*time.Hour))
+	if state.Incident != nil {
+		t.Fatalf("incident still open: %+v", state.Incident)
+	}
+}
+
+func TestEscalateWaitsOutScheduledRestart(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Escalation = []EscalationStep{{15 * time.Minute, "group"}}
+
+	ctx := context.Background()
+	start := time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC)
+	escalate(ctx, false, true, start)
+	escalate(ctx, false, true, start.Add(20*time.Minute))
+	if calls := fake.callsTo("sendMessage"); len(calls) != 0 {
+		t.Fatalf("escalated during a scheduled restart: %+v", calls)
+	}
+
+	escalate(ctx, false, true, start.Add(RESTART_MAX_DURATION+15*time.Minute))
+	if calls := fake.callsTo("sendMessage"); len(calls) != 1 {
+		t.Fatalf("sendMessage calls = %+v, want one reminder", calls)
+	}
+}
+
+func TestEmptyServerOpensNoIncident(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.OnlinePolicy = ONLINE_PLAYERS
+	config.AdminIDs = []int64{1}
+	config.Escalation = []EscalationStep{{0, "group"}, {0, "admins"}}
+	hysteresis.pending = 0
+	store.Entries = []StatusEntry{{
+		ID: "1", Online: true, LastChecked: time.Now().Add(-time.Minute).UnixMilli(), Players: []string{"steve"}, PlayerCount: 1,
+	}}
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, Players: []string{}, PlayerCount: 0}, CheckedAt: time.Now()}
+	})
+
+	checkServer(context.Background())
+	checkServer(context.Background())
+
+	if latest := getLatest(); latest.Online {
+		t.Errorf("an empty server is online under ONLINE_PLAYERS")
+	}
+	if state.Incident != nil {
+		t.Fatalf("incident opened for an empty server: %+v", state.Incident)
+	}
+	for _, call := range fake.callsTo("sendMessage") {
+		if text := call.Params["text"].(string); strings.Contains(text, "still") {
+			t.Errorf("escalation message sent: %q", text)
+		}
+	}
+}
+
+func TestIncidentInRestartWindowIsScheduled(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.Language = "en"
+	config.OnlinePolicy = ONLINE_PLAYERS
+	var err error
+	if config.RestartSchedule, err = parseRestartSchedule(time.Now().Format("15:04")); err != nil {
+		t.Fatal(err)
+	}
+	hysteresis.pending = 0
+	// Already offline under ONLINE_PLAYERS: empty, but reachable.
+	store.Entries = []StatusEntry{{ID: "1", Online: false, LastChecked: time.Now().Add(-time.Minute).UnixMilli(), PlayerCount: 0}}
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Err: errors.New("connection refused"), CheckedAt: time.Now()}
+	})
+
+	checkServer(context.Background())
+	if state.Incident == nil || !state.Incident.Scheduled {
+		t.Fatalf("incident = %+v, want a scheduled one", state.Incident)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EVENT_LOG_FILE keeps every join, leave and server transition, one
+	// JSON record per line. Snapshots only tell who was on at each check;
+	// the log says when exactly someone came and went, after the
+	// snapshots are gone.
+	EVENT_LOG_FILE = "events.log"
+	// SEEN_TIME_LAYOUT is how /seen writes times.
+	SEEN_TIME_LAYOUT = "2006-01-02 15:04"
+)
+
+// loggedEvents are the kinds EVENT_LOG_FILE keeps.
+var loggedEvents = []EventKind{EventPlayerJoined, EventPlayerLeft, EventServerUp, EventServerDown}
+
+// EventRecord is one line of EVENT_LOG_FILE.
+type EventRecord struct {
+	Time   time.Time `json:"time"`
+	Kind   EventKind `json:"kind"`
+	Player string    `json:"player,omitempty"`
+}
+
+// Session is a stretch a player spent on the server. End is zero while
+// they're still on.
+type Session struct {
+	Start, End time.Time
+}
+
+// eventLogMu keeps appends from interleaving with cleanups.
+var eventLogMu sync.Mutex
+
+// recordEvents appends the loggable ones among events to EVENT_LOG_FILE.
+func recordEvents(events []Event) {
+	var buf bytes.Buffer
+	for _, event := range events {
+		if !containsEventKind(loggedEvents, event.Kind) {
+			continue
+		}
+		data, err := json.Marshal(EventRecord{Time: event.Time, Kind: event.Kind, Player: event.Player})
+		if err != nil {
+			log.Printf("Error marshaling event record: %v", err)
+			continue
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if buf.Len() == 0 {
+		return
+	}
+
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+
+	if err := storage.Append(EVENT_LOG_FILE, buf.Bytes()); err != nil {
+		log.Printf("Error writing event log: %v", err)
+	}
+}
+
+func containsEventKind(kinds []EventKind, kind EventKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// readEventLog returns every logged event, oldest first.
+func readEventLog() ([]EventRecord, error) {
+	data, err := storage.Read(EVENT_LOG_FILE)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []EventRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record EventRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("Skipping malformed event log line: %v", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// rewriteEventLog keeps only the records keep accepts, and reports how
+// many it dropped. The log is rewritten only if something goes.
+func rewriteEventLog(keep func(EventRecord) bool) (int, error) {
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+
+	records, err := readEventLog()
+	if err != nil {
+		return 0, err
+	}
+	var buf bytes.Buffer
+	dropped := 0
+	for _, record := range records {
+		if !keep(record) {
+			dropped++
+			continue
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if dropped == 0 {
+		return 0, nil
+	}
+	return dropped, storage.Write(EVENT_LOG_FILE, buf.Bytes())
+}
+
+// cleanupEventLog drops records older than HISTORY_RETENTION_DAYS.
+func cleanupEventLog() {
+	if config.HistoryRetention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-config.HistoryRetention)
+	dropped, err := rewriteEventLog(func(record EventRecord) bool {
+		return !record.Time.Before(cutoff)
+	})
+	if err != nil {
+		log.Printf("Error cleaning up event log: %v", err)
+	} else if dropped > 0 {
+		log.Printf("Removed %d old event log records", dropped)
+	}
+}
+
+// forgetEvents drops player's joins and leaves from the event log.
+func forgetEvents(player string) int {
+	dropped, err := rewriteEventLog(func(record EventRecord) bool {
+		return !strings.EqualFold(record.Player, player)
+	})
+	if err != nil {
+		log.Printf("Error forgetting %q in the event log: %v", player, err)
+	}
+	return dropped
+}
+
+// playerSessions pairs player's joins with the leave that ended them. The
+// server going down ends everyone's session; a leave without a logged
+// join (from before the log started) starts at the zero time.
+func playerSessions(records []EventRecord, player string) []Session {
+	var sessions []Session
+	open := false
+	for _, record := range records {
+		mine := strings.EqualFold(record.Player, player)
+		switch {
+		case record.Kind == EventPlayerJoined && mine && !open:
+			sessions = append(sessions, Session{Start: record.Time})
+			open = true
+		case record.Kind == EventPlayerLeft && mine && !open:
+			sessions = append(sessions, Session{End: record.Time})
+		case record.Kind == EventPlayerLeft && mine, record.Kind == EventServerDown && open:
+			sessions[len(sessions)-1].End = record.Time
+			open = false
+		}
+	}
+	return sessions
+}
+
+// handleSeenCommand implements /seen <player>: whether the player is on
+// now, or when they last were and for how long.
+func handleSeenCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+	player := sanitizePlayerName(args)
+	if player == "" || strings.ContainsAny(player, " \t") {
+		reply(ctx, msg, tr(lang, "seen.usage"))
+		return
+	}
+
+	records, err := readEventLog()
+	if err != nil {
+		log.Printf("Error reading event log: %v", err)
+		reply(ctx, msg, tr(lang, "seen.error"))
+		return
+	}
+	reply(ctx, msg, renderSeen(lang, player, playerSessions(records, player), time.Now()))
+}
+
+func renderSeen(lang, player string, sessions []Session, now time.Time) string {
+	if len(sessions) == 0 {
+		return tr(lang, "seen.never", bold(player))
+	}
+	last := sessions[len(sessions)-1]
+	if last.End.IsZero() {
+		return tr(lang, "seen.online", bold(player), last.Start.Format(SEEN_TIME_LAYOUT), renderLasted(lang, now.Sub(last.Start)))
+	}
+	when := last.End.Format(SEEN_TIME_LAYOUT)
+	if last.Start.IsZero() {
+		return tr(lang, "seen.last", bold(player), renderAgo(lang, now.Sub(last.End)), when)
+	}
+	return tr(lang, "seen.last_session", bold(player), renderAgo(lang, now.Sub(last.End)), when, renderLasted(lang, last.End.Sub(last.Start)))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEventLogRecordsCheckEvents(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.Language = "en"
+	config.OnlinePolicy = ONLINE_REACHABLE
+	store.Entries = []StatusEntry{{ID: "1", Online: true, LastChecked: time.Now().Add(-time.Minute).UnixMilli(), Players: []string{}}}
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, Players: []string{"steve"}, PlayerCount: 1}, CheckedAt: time.Now()}
+	})
+
+	checkServer(context.Background())
+
+	records, err := readEventLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Kind != EventPlayerJoined || records[0].Player != "steve" {
+		t.Fatalf("records = %+v", records)
+	}
+}
+
+func TestPlayerSessions(t *testing.T) {
+	at := func(minutes int) time.Time { return time.Date(2024, 3, 1, 12, minutes, 0, 0, time.UTC) }
+	records := []EventRecord{
+		{Time: at(0), Kind: EventPlayerLeft, Player: "steve"},
+		{Time: at(5), Kind: EventPlayerJoined, Player: "Steve"},
+		{Time: at(6), Kind: EventPlayerJoined, Player: "alex"},
+		{Time: at(20), Kind: EventPlayerLeft, Player: "steve"},
+		{Time: at(30), Kind: EventPlayerJoined, Player: "steve"},
+		{Time: at(40), Kind: EventServerDown},
+		{Time: at(45), Kind: EventServerUp},
+		{Time: at(50), Kind: EventPlayerJoined, Player: "steve"},
+	}
+	want := []Session{{End: at(0)}, {Start: at(5), End: at(20)}, {Start: at(30), End: at(40)}, {Start: at(50)}}
+	got := playerSessions(records, "steve")
+	if len(got) != len(want) {
+		t.Fatalf("sessions = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Errorf("session %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSeenCommand(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	now := time.Now()
+	recordEvents([]Event{
+		{Kind: EventPlayerJoined, Player: "steve", Time: now.Add(-3 * time.Hour)},
+		{Kind: EventPlayerLeft, Player: "steve", Time: now.Add(-2 * time.Hour)},
+		{Kind: EventPlayerJoined, Player: "alex", Time: now.Add(-10 * time.Minute)},
+	})
+
+	for _, text := range []string{"/seen steve", "/seen alex", "/seen herobrine", "/seen"} {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: 1, Type: "private"}, From: &User{ID: 1}, Text: text,
+		}})
+	}
+	want := []string{
+		"🕓 <b>steve</b> was last seen 2 hours ago (" + now.Add(-2*time.Hour).Format(SEEN_TIME_LAYOUT) + "), after playing for 1 hour.",
+		"🟢 <b>alex</b> is on the server now — since " + now.Add(-10*time.Minute).Format(SEEN_TIME_LAYOUT) + ", for 10 minutes.",
+		"❔ <b>herobrine</b> hasn't been seen on the server.",
+		"Usage: /seen &lt;player&gt;",
+	}
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != len(want) {
+		t.Fatalf("got %d replies, want %d", len(sent), len(want))
+	}
+	for i, text := range want {
+		if sent[i].Params["text"] != text {
+			t.Errorf("reply %d = %q, want %q", i, sent[i].Params["text"], text)
+		}
+	}
+}
+
+func TestEventLogCleanupAndForget(t *testing.T) {
+	useTempStore(t, 0)
+	defer func(retention time.Duration) { config.HistoryRetention = retention }(config.HistoryRetention)
+	config.HistoryRetention = 24 * time.Hour
+	now := time.Now()
+	recordEvents([]Event{
+		{Kind: EventPlayerJoined, Player: "steve", Time: now.Add(-48 * time.Hour)},
+		{Kind: EventServerDown, Time: now.Add(-2 * time.Hour)},
+		{Kind: EventServerEmpty, Time: now.Add(-90 * time.Minute)},
+		{Kind: EventPlayerJoined, Player: "Steve", Time: now.Add(-time.Hour)},
+		{Kind: EventPlayerJoined, Player: "alex", Time: now.Add(-time.Hour)},
+	})
+
+	cleanupEventLog()
+	if n := forgetEvents("steve"); n != 1 {
+		t.Errorf("forgetEvents dropped %d records, want 1", n)
+	}
+	records, err := readEventLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Kind != EventServerDown || records[1].Player != "alex" {
+		t.Fatalf("records = %+v", records)
+	}
+}
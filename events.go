@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventPlayerJoined EventKind = "player_joined"
+	EventPlayerLeft   EventKind = "player_left"
+	EventServerUp     EventKind = "server_up"
+	EventServerDown   EventKind = "server_down"
+	// EventServerEmpty is the server staying reachable with nobody on it,
+	// under ONLINE_THREE_STATE.
+	EventServerEmpty EventKind = "server_empty"
+)
+
+// Event is something that happened on the server, as detected by comparing
+// consecutive checks.
+type Event struct {
+	Kind   EventKind `json:"kind"`
+	Player string    `json:"player,omitempty"`
+	Time   time.Time `json:"time"`
+	// Label qualifies the event, e.g. LABEL_SCHEDULED_RESTART.
+	Label string `json:"label,omitempty"`
+	// Forecast comes with server_down events when past outages allow one.
+	Forecast *DowntimeForecast `json:"forecast,omitempty"`
+	// Backup is SECONDARY_SERVER when it was up as the server went down.
+	Backup string `json:"backup,omitempty"`
+	// DowntimeSeconds is how long the server was down, on server_up
+	// events that end a known outage.
+	DowntimeSeconds int64 `json:"downtimeSeconds,omitempty"`
+
+	// policy limits a server event to chats with that online policy; see
+	// transitionEvents.
+	policy string
+}
+
+// serverEvent is the event for the server going online or offline.
+func serverEvent(online bool, at time.Time) Event {
+	if online {
+		return Event{Kind: EventServerUp, Time: at}
+	}
+	return Event{Kind: EventServerDown, Time: at}
+}
+
+// dispatchEvents hands the events of one check to every enabled
+// integration. Integrations log their own errors; one failing doesn't stop
+// the others.
+func dispatchEvents(ctx context.Context, events []Event) {
+	publishNATS(ctx, events)
+	publishKafka(ctx, events)
+	postWebhooks(ctx, events)
+	postDiscord(ctx, events)
+
+	for _, event := range events {
+		publishRedis(event)
+
+		switch event.Kind {
+		case EventServerUp, EventServerDown:
+			exportGrafanaAnnotation(ctx, event)
+		}
+	}
+}
+
+// playerEvents turns the result of diffPlayers into events, joins first.
+func playerEvents(joined, left []string, at time.Time) []Event {
+	events := make([]Event, 0, len(joined)+len(left))
+	for _, player := range joined {
+		events = append(events, Event{Kind: EventPlayerJoined, Player: player, Time: at})
+	}
+	for _, player := range left {
+		events = append(events, Event{Kind: EventPlayerLeft, Player: player, Time: at})
+	}
+	return events
+}
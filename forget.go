@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// FORGOTTEN_PLAYER stands in the audit log for the player /forget removed:
+// the entry would otherwise keep the very name it was asked to forget.
+const FORGOTTEN_PLAYER = "[forgotten]"
+
+// purgePlayer removes every trace of player from the stored history, the
+// event log, the audit log and their playtime, and writes the result out
+// right away, rather than leaving the name on disk until the next save.
+func purgePlayer(player string) int {
+	count := forgetPlayer(player)
+	forgetPlaytime(player)
+	forgetEvents(player)
+	if _, err := redactAudit(player); err != nil {
+		log.Printf("Error forgetting %q in the audit log: %v", player, err)
+	}
+	if count > 0 {
+		flushStore()
+	}
+	log.Printf("Forgot player %q (%d entries)", player, count)
+	return count
+}
+
+// handleForgetCommand implements /forget <player>.
+func handleForgetCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+	player := sanitizePlayerName(args)
+	if player == "" || strings.ContainsAny(player, " \t") {
+		reply(ctx, msg, tr(lang, "forget.usage"))
+		return
+	}
+
+	count := purgePlayer(player)
+	recordAudit(auditCommand(msg, "forget", FORGOTTEN_PLAYER, fmt.Sprintf("ok, %d entries", count)))
+	reply(ctx, msg, trn(lang, "forget.done", count, bold(player), count))
+}
+
+// handleForgetAPI is POST /api/forget with {"player": "name"}, guarded by
+// API_TOKEN. It answers with the number of entries that were changed.
+func handleForgetAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Deleting data is never open to anonymous callers.
+	if !checkBearerToken(r, config.APIToken) {
+		recordAudit(AuditEntry{Via: "api", Command: "forget", Result: "denied"})
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Player string `json:"player"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY)).Decode(&request); err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	player := sanitizePlayerName(request.Player)
+	if player == "" {
+		http.Error(w, "player is required", http.StatusBadRequest)
+		return
+	}
+
+	count := purgePlayer(player)
+	recordAudit(AuditEntry{Via: "api", Command: "forget", Args: FORGOTTEN_PLAYER, Result: fmt.Sprintf("ok, %d entries", count)})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"player": player, "entries": count})
+}
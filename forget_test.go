@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestForgetCommand(t *testing.T) {
+	useTempStore(t, 3)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{100}
+	store.Entries[1].Players = []string{"Steve", "notch"}
+
+	send := func(from int64) string {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: 7}, From: &User{ID: from}, Text: "/forget steve",
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	if text := send(200); !strings.Contains(text, "needs the admin role") {
+		t.Fatalf("non-admin got %q", text)
+	}
+	if store.Entries[0].Players[0] != "steve" {
+		t.Fatal("non-admin changed the history")
+	}
+
+	send(100)
+	tapConfirmation(t, 100, "confirm")
+	replies := fake.callsTo("sendMessage")
+	if text := replies[len(replies)-1].Params["text"].(string); text != "🧹 Removed <b>steve</b> from the history (3 entries)." {
+		t.Fatalf("admin got %q", text)
+	}
+	for _, entry := range store.Entries {
+		for _, name := range entry.Players {
+			if strings.EqualFold(name, "steve") {
+				t.Fatalf("steve still in %+v", entry)
+			}
+		}
+	}
+	if data, _ := os.ReadFile(JSON_FILE); strings.Contains(strings.ToLower(string(data)), "steve") {
+		t.Fatal("steve still on disk")
+	}
+	// Including the non-admin's denied attempt.
+	if data, _ := storage.Read(AUDIT_FILE); strings.Contains(strings.ToLower(string(data)), "steve") {
+		t.Fatalf("steve still in the audit log:\n%s", data)
+	}
+}
+
+func TestForgetAPI(t *testing.T) {
+	useTempStore(t, 2)
+	previous := config
+	t.Cleanup(func() { config = previous })
+
+	request := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/forget", strings.NewReader(`{"player":"alex"}`))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, r)
+		return w
+	}
+
+	config.APIToken = ""
+	if w := request(""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("without API_TOKEN: status %d", w.Code)
+	}
+
+	config.APIToken = "secret"
+	if w := request("wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: status %d", w.Code)
+	}
+	w := request("secret")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"entries":2,"player":"alex"}` {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	entries, _ := readAudit()
+	if last := entries[len(entries)-1]; last.Command != "forget" || last.Args != FORGOTTEN_PLAYER {
+		t.Errorf("audit entry = %+v", last)
+	}
+	if data, _ := storage.Read(AUDIT_FILE); strings.Contains(string(data), "alex") {
+		t.Errorf("alex is in the audit log:\n%s", data)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// grafanaHTTP is used for all Grafana API calls.
+var grafanaHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// exportGrafanaAnnotation turns downtime into a region annotation: going
+// down creates an annotation starting at the event, coming back up sets its
+// end. The open annotation's ID is kept in the bot state so a restart in the
+// middle of an outage still closes it. Does nothing without GRAFANA_URL.
+func exportGrafanaAnnotation(ctx context.Context, event Event) {
+	if config.GrafanaURL == "" {
+		return
+	}
+
+	switch event.Kind {
+	case EventServerDown:
+		id, err := createGrafanaAnnotation(ctx, event)
+		if err != nil {
+			log.Printf("Error creating Grafana annotation: %v", err)
+			return
+		}
+		state.mu.Lock()
+		state.GrafanaAnnotationID = id
+		saveState()
+		state.mu.Unlock()
+
+	case EventServerUp:
+		state.mu.Lock()
+		id := state.GrafanaAnnotationID
+		state.mu.Unlock()
+		if id == 0 {
+			return
+		}
+
+		if err := closeGrafanaAnnotation(ctx, id, event.Time); err != nil {
+			log.Printf("Error closing Grafana annotation %d: %v", id, err)
+			return
+		}
+		state.mu.Lock()
+		state.GrafanaAnnotationID = 0
+		saveState()
+		state.mu.Unlock()
+	}
+}
+
+func createGrafanaAnnotation(ctx context.Context, event Event) (int64, error) {
+	payload := map[string]interface{}{
+		"time": event.Time.UnixMilli(),
+		"tags": config.GrafanaTags,
+		"text": grafanaAnnotationText(event),
+	}
+	if config.GrafanaDashboardUID != "" {
+		payload["dashboardUID"] = config.GrafanaDashboardUID
+	}
+
+	var result struct {
+		ID int64 `json:"id"`
+	}
+	if err := grafanaRequest(ctx, "POST", "/api/annotations", payload, &result); err != nil {
+		return 0, err
+	}
+	return result.ID, nil
+}
+
+// grafanaAnnotationText describes the outage event starts.
+func grafanaAnnotationText(event Event) string {
+	text := fmt.Sprintf("%s:%d offline", config.ServerHost, config.ServerPort)
+	if event.Label != "" {
+		text += " (" + event.Label + ")"
+	}
+	return text
+}
+
+func closeGrafanaAnnotation(ctx context.Context, id int64, end time.Time) error {
+	return grafanaRequest(ctx, "PATCH", fmt.Sprintf("/api/annotations/%d", id), map[string]interface{}{
+		"timeEnd": end.UnixMilli(),
+	}, nil)
+}
+
+func grafanaRequest(ctx context.Context, method, path string, payload, result interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(config.GrafanaURL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if config.GrafanaAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+config.GrafanaAPIKey)
+	}
+
+	resp, err := grafanaHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("grafana API error: %s - %s", resp.Status, string(body))
+	}
+	if result != nil {
+		return json.Unmarshal(body, result)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGrafanaAnnotationCoversDowntime(t *testing.T) {
+	useTempStore(t, 0)
+
+	var requests []string
+	var patched map[string]interface{}
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("missing API key on %s", r.URL.Path)
+		}
+		if r.Method == "PATCH" {
+			json.NewDecoder(r.Body).Decode(&patched)
+		}
+		w.Write([]byte(`{"id": 17, "message": "ok"}`))
+	}))
+	defer grafana.Close()
+
+	previous := config
+	config.GrafanaURL = grafana.URL
+	config.GrafanaAPIKey = "key"
+	t.Cleanup(func() { config = previous })
+
+	down := time.UnixMilli(1_700_000_000_000)
+	up := down.Add(42 * time.Minute)
+	exportGrafanaAnnotation(context.Background(), serverEvent(false, down))
+	if state.GrafanaAnnotationID != 17 {
+		t.Fatalf("open annotation = %d, want 17", state.GrafanaAnnotationID)
+	}
+	exportGrafanaAnnotation(context.Background(), serverEvent(true, up))
+
+	if len(requests) != 2 || requests[0] != "POST /api/annotations" || requests[1] != "PATCH /api/annotations/17" {
+		t.Fatalf("requests = %v", requests)
+	}
+	if patched["timeEnd"] != float64(up.UnixMilli()) {
+		t.Fatalf("timeEnd = %v, want %d", patched["timeEnd"], up.UnixMilli())
+	}
+	if state.GrafanaAnnotationID != 0 {
+		t.Fatalf("annotation still open after recovery")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// helpCommands lists the commands user may run that have a Help line,
+// the ones everybody can run first, each group sorted by name.
+func helpCommands(user *User) []string {
+	role := userRole(user)
+	var cmds []string
+	for cmd, command := range commands {
+		required, _ := requiredRole(cmd)
+		if required > role || command.Help == "" {
+			continue
+		}
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		ri, _ := requiredRole(cmds[i])
+		rj, _ := requiredRole(cmds[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return cmds[i] < cmds[j]
+	})
+	return cmds
+}
+
+func renderHelp(lang string, cmds []string) string {
+	lines := []string{tr(lang, "help.header")}
+	for _, cmd := range cmds {
+		lines = append(lines, tr(lang, commands[cmd].Help))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleHelpCommand implements /help: the commands the sender may run,
+// with what they do.
+func handleHelpCommand(ctx context.Context, msg *Message) {
+	reply(ctx, msg, renderHelp(replyLanguage(msg), helpCommands(msg.From)))
+}
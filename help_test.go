@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHelpListsAllowedCommands(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+	config.DefaultRole = RoleViewer
+
+	for _, userID := range []int64{1, 2} {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: userID, Type: "private"}, From: &User{ID: userID}, Text: "/help",
+		}})
+	}
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 2 {
+		t.Fatalf("got %d replies, want 2", len(sent))
+	}
+	admin, viewer := sent[0].Params["text"].(string), sent[1].Params["text"].(string)
+	if !strings.HasPrefix(viewer, "ℹ️ <b>Commands</b>\n/help — this list\n/chart") {
+		t.Errorf("viewer help starts wrong:\n%s", viewer)
+	}
+	for _, cmd := range []string{"/status", "/stats"} {
+		if !strings.Contains(viewer, cmd) {
+			t.Errorf("viewer help lacks %s:\n%s", cmd, viewer)
+		}
+	}
+	for _, cmd := range []string{"/grant", "/snooze", "/ack"} {
+		if strings.Contains(viewer, cmd) {
+			t.Errorf("viewer help lists %s:\n%s", cmd, viewer)
+		}
+	}
+	if !strings.Contains(admin, "/grant") {
+		t.Errorf("admin help lacks /grant:\n%s", admin)
+	}
+}
+
+func TestEveryCommandHasHelp(t *testing.T) {
+	for lang := range messages {
+		for cmd, command := range commands {
+			if command.Run == nil {
+				continue
+			}
+			if command.Help == "" {
+				t.Errorf("/%s has no help line", cmd)
+			} else if _, ok := messages[lang][command.Help]; !ok {
+				t.Errorf("no %s in %s", command.Help, lang)
+			}
+		}
+	}
+}
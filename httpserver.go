@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// newHTTPMux registers every HTTP endpoint the checker serves.
+func newHTTPMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook/alertmanager", handleAlertmanagerWebhook)
+	mux.HandleFunc("/api/forget", handleForgetAPI)
+	mux.HandleFunc("/api/version", handleVersionAPI)
+	mux.HandleFunc("/api/events", handleEventsAPI)
+	mux.HandleFunc("/api/events/schema", handleEventSchemaAPI)
+	mux.HandleFunc("/api/preview", handlePreviewAPI)
+	mux.HandleFunc("/api/status", handleStatusAPI)
+	mux.HandleFunc("/api/status/compact", handleCompactStatusAPI)
+	mux.HandleFunc("/api/history", handleHistoryAPI)
+	mux.HandleFunc("/api/players", handlePlayersAPI)
+	mux.HandleFunc("/status.txt", handleStatusText)
+	mux.HandleFunc("/app", handleMiniApp)
+	mux.HandleFunc("/app/data", handleMiniAppData)
+	mux.HandleFunc("/metrics", handleMetrics)
+	mux.HandleFunc("/", handleDashboard)
+	mux.HandleFunc("/admin", handleAdminDashboard)
+	mux.HandleFunc("/admin/settings", handleSettingsPage)
+	return mux
+}
+
+// runHTTPServer serves newHTTPMux on config.HTTPAddr until ctx is cancelled.
+// It does nothing when HTTP_ADDR isn't set.
+func runHTTPServer(ctx context.Context) {
+	if config.HTTPAddr == "" {
+		return
+	}
+
+	server := &http.Server{
+		Addr:              config.HTTPAddr,
+		Handler:           newHTTPMux(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("HTTP server listening on %s", config.HTTPAddr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("HTTP server error: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// hysteresis counts the checks in a row that disagree with the last
+// recorded one about the server being reachable.
+var hysteresis struct {
+	mu      sync.Mutex
+	pending int
+}
+
+// heldError stands in for a successful check that doesn't count yet: the
+// server stays down with the error it went down with.
+type heldError struct {
+	category string
+}
+
+func (e *heldError) Error() string {
+	return "still " + e.category + " until ONLINE_THRESHOLD checks succeed"
+}
+
+// debounceResult holds the server in its recorded state until
+// OFFLINE_THRESHOLD checks in a row fail, or ONLINE_THRESHOLD succeed,
+// so a lost packet doesn't flip the chat titles. Until then a failed check
+// repeats the last one's players and a successful one keeps the error.
+func debounceResult(latest *StatusEntry, result *PingResult) *PingResult {
+	hysteresis.mu.Lock()
+	defer hysteresis.mu.Unlock()
+
+	reachable := result.Status != nil
+	if latest == nil || reachable == (latest.Online || latest.Error == "") {
+		hysteresis.pending = 0
+		return result
+	}
+	threshold := config.OfflineThreshold
+	if reachable {
+		threshold = config.OnlineThreshold
+	}
+	hysteresis.pending++
+	if hysteresis.pending >= threshold {
+		hysteresis.pending = 0
+		return result
+	}
+
+	held := *result
+	if reachable {
+		log.Printf("Server answered, waiting for %d more successful checks", threshold-hysteresis.pending)
+		held.Status = nil
+		held.Err = &heldError{category: latest.Error}
+	} else {
+		log.Printf("Check failed (%v), waiting for %d more failed checks", result.Err, threshold-hysteresis.pending)
+		held.Err = nil
+		held.Status = &ServerStatus{
+			Online:      true,
+			PlayerCount: latest.PlayerCount,
+			Players:     append([]string{}, latest.Players...),
+		}
+	}
+	return &held
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHysteresisHoldsTransitions(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.OnlinePolicy = ONLINE_REACHABLE
+	config.OfflineThreshold, config.OnlineThreshold = 3, 2
+	hysteresis.pending = 0
+	store.Entries = []StatusEntry{{
+		ID: "1", Online: true, LastChecked: time.Now().Add(-time.Minute).UnixMilli(), Players: []string{"steve"}, PlayerCount: 1,
+	}}
+
+	up := true
+	useFakeProbe(t, func(context.Context) *PingResult {
+		if !up {
+			return &PingResult{Err: ErrTimeout, CheckedAt: time.Now()}
+		}
+		return &PingResult{Status: &ServerStatus{Online: true, Players: []string{"steve"}, PlayerCount: 1}, CheckedAt: time.Now()}
+	})
+	// The title is set on every check; what matters is what it says.
+	check := func() (online bool, title interface{}) {
+		t.Helper()
+		checkServer(context.Background())
+		titles := fake.callsTo("setChatTitle")
+		return getLatest().Online, titles[len(titles)-1].Params["title"]
+	}
+	onlineTitle, offlineTitle := renderChatTitle(ChatSettings{Language: "en"}, true, 1), renderChatTitle(ChatSettings{Language: "en"}, false, 0)
+
+	up = false
+	for i := 1; i <= 2; i++ {
+		if online, title := check(); !online || title != onlineTitle {
+			t.Fatalf("failed check %d: online %v, title %q", i, online, title)
+		}
+	}
+	if latest := getLatest(); len(latest.Players) != 1 || latest.Error != "" {
+		t.Errorf("held check stored %+v, want the last players and no error", latest)
+	}
+	if online, title := check(); online || title != offlineTitle {
+		t.Fatalf("third failed check: online %v, title %q", online, title)
+	}
+	if latest := getLatest(); latest.Error != "timeout" {
+		t.Errorf("stored error %q, want timeout", latest.Error)
+	}
+
+	// A success between failures starts the count again.
+	up = true
+	if online, title := check(); online || title != offlineTitle {
+		t.Fatalf("one successful check: online %v, title %q", online, title)
+	}
+	if latest := getLatest(); latest.Error != "timeout" {
+		t.Errorf("held check stored error %q, want the timeout kept", latest.Error)
+	}
+	up = false
+	check()
+	up = true
+	check()
+	if online, title := check(); !online || title != onlineTitle {
+		t.Fatalf("second successful check in a row: online %v, title %q", online, title)
+	}
+}
@@ -0,0 +1,717 @@
+package main
+
+import (
+	"fmt"
+)
+
+const DEFAULT_LANGUAGE = "uk"
+
+// Translation is one translatable string. Messages that don't depend on a
+// count only set Other; the rest set the plural forms their language uses.
+type Translation struct {
+	One   string
+	Few   string
+	Many  string
+	Other string
+}
+
+// pluralRules picks the plural form for n in each supported language.
+var pluralRules = map[string]func(n int) string{
+	// Ukrainian: 1, 21, 31… гравець; 2–4, 22–24… гравці; the rest гравців.
+	"uk": func(n int) string {
+		if n < 0 {
+			n = -n
+		}
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	},
+	"en": func(n int) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+}
+
+var messages = map[string]map[string]Translation{
+	"uk": {
+		// players.joined and players.left list several players; the
+		// plural "one" form also covers 21, 31..., so a lone player has a
+		// message of their own.
+		"players.joined":        {Other: "😎 на сервер зайшли: %s"},
+		"players.joined.single": {Other: "😎 %s зайшов на сервер"},
+		"players.left":          {Other: "🥺 вийшли: %s"},
+		"players.left.single":   {Other: "🥺 %s вийшов"},
+		"players.count": {
+			One:  "%d гравець",
+			Few:  "%d гравці",
+			Many: "%d гравців",
+		},
+		"title.online":           {Other: "🟢 lnudorm3 minecraft йоу"},
+		"title.offline":          {Other: "🔴 lnudorm3 minecraft йоу"},
+		"diag.header":            {Other: "🩺 <b>Діагностика</b>"},
+		"diag.api.ok":            {Other: "Telegram API: ✅ %d мс (@%s)"},
+		"diag.api.fail":          {Other: "Telegram API: ❌ %s"},
+		"diag.chat.ok":           {Other: "Чат: %s (%s)"},
+		"diag.chat.fail":         {Other: "Чат <code>%s</code>: ❌ %s"},
+		"diag.member.status":     {Other: "Статус бота: %s"},
+		"diag.member.fail":       {Other: "Права бота: ❌ %s"},
+		"diag.right.change_info": {Other: "Змінювати назву: %s"},
+		"diag.right.pin":         {Other: "Закріплювати повідомлення: %s"},
+		"diag.right.delete":      {Other: "Видаляти повідомлення: %s"},
+		"diag.server":            {Other: "Остання перевірка сервера: %s, %v тому"},
+		"diag.server.never":      {Other: "Сервер ще не перевірявся"},
+		"chat.migrated":          {Other: "ℹ️ Групу перетворено на супергрупу: ID чату змінився з <code>%s</code> на <code>%s</code>. Оновіть TELEGRAM_CHAT_ID."},
+		"alert.firing":           {Other: "🚨 %s"},
+		"alert.resolved":         {Other: "✅ %s вирішено"},
+		"command.denied":         {Other: "⛔ Для цієї команди потрібна роль %s."},
+		"forget.usage":           {Other: "Використання: /forget &lt;нікнейм&gt;"},
+		"forget.done": {
+			One:  "🧹 %s видалено з історії (%d запис).",
+			Few:  "🧹 %s видалено з історії (%d записи).",
+			Many: "🧹 %s видалено з історії (%d записів).",
+		},
+		"audit.header":             {Other: "📋 <b>Журнал дій адміністраторів</b>"},
+		"audit.empty":              {Other: "📋 Журнал дій порожній."},
+		"audit.error":              {Other: "❌ Не вдалося прочитати журнал дій."},
+		"audit.usage":              {Other: "Використання: /audit [кількість]"},
+		"grant.usage":              {Other: "Використання: /grant &lt;ID користувача&gt; none|viewer|operator|admin|default"},
+		"grant.done":               {Other: "✅ Користувач <code>%d</code> тепер має роль %s."},
+		"grant.reset":              {Other: "✅ Видану роль користувача <code>%d</code> скасовано; тепер діє роль %s."},
+		"grant.fixed":              {Other: "⚠️ Користувач <code>%d</code> є адміністратором у TELEGRAM_ADMIN_IDS; його роль змінюється лише там."},
+		"confirm.ask":              {Other: "⚠️ Виконати <code>%s</code>? Підтвердьте протягом %d с."},
+		"confirm.yes":              {Other: "✅ Підтвердити"},
+		"confirm.no":               {Other: "✖️ Скасувати"},
+		"confirm.done":             {Other: "✅ Підтверджено."},
+		"confirm.cancelled":        {Other: "✖️ Скасовано."},
+		"confirm.expired":          {Other: "⌛ Час на підтвердження минув."},
+		"confirm.not_yours":        {Other: "Підтвердити може лише автор команди."},
+		"chatconfig.header":        {Other: "⚙️ <b>Налаштування чату</b>"},
+		"chatconfig.server":        {Other: "Сервер: <code>%s</code>"},
+		"chatconfig.language":      {Other: "Мова: %s"},
+		"chatconfig.events":        {Other: "Події: %s"},
+		"chatconfig.template":      {Other: "Шаблон %s: <code>%s</code>"},
+		"chatconfig.invalid":       {Other: "❌ Неправильне значення: %s"},
+		"chatconfig.usage":         {Other: "Використання:\n/chatconfig\n/chatconfig server &lt;хост[:порт]|default&gt;\n/chatconfig language &lt;uk|en|default&gt;\n/chatconfig events &lt;подія&gt; on|off (%s)\n/chatconfig template &lt;ключ&gt; &lt;текст|default&gt; (%s)\n/chatconfig online &lt;%s|default&gt;"},
+		"command.cooldown":         {Other: "⏳ Забагато команд. Спробуйте знову за %d с."},
+		"server.scheduled_restart": {Other: "🔄 Сервер вимкнувся у звичний час перезапуску — схоже на плановий перезапуск."},
+		"anomaly.drop":             {Other: "ℹ️ Усі гравці (%d) раптово вийшли, хоча сервер працює. Можливо, щось не так."},
+		"anomaly.high":             {Other: "ℹ️ Незвично багато гравців: %d о %s (зазвичай близько %.0f)."},
+		"anomaly.low":              {Other: "ℹ️ Незвично мало гравців: %d о %s (зазвичай близько %.0f)."},
+		"server.down":              {Other: "🔴 Сервер недоступний."},
+		"server.forecast": {
+			One:  "Зазвичай подібні збої тривають ~%d хвилину.",
+			Few:  "Зазвичай подібні збої тривають ~%d хвилини.",
+			Many: "Зазвичай подібні збої тривають ~%d хвилин.",
+		},
+		"server.forecast_recent": {
+			One:  "Останній збій тривав (хв): %[2]s.",
+			Few:  "Останні %d збої тривали (хв): %s.",
+			Many: "Останні %d збоїв тривали (хв): %s.",
+		},
+		"escalation.still_down": {
+			One:  "🚨 Сервер недоступний уже %d хвилину.",
+			Few:  "🚨 Сервер недоступний уже %d хвилини.",
+			Many: "🚨 Сервер недоступний уже %d хвилин.",
+		},
+		"escalation.title": {Other: "Minecraft-сервер %s недоступний"},
+		"escalation.body": {
+			One:  "Сервер %s недоступний уже %d хвилину.",
+			Few:  "Сервер %s недоступний уже %d хвилини.",
+			Many: "Сервер %s недоступний уже %d хвилин.",
+		},
+		"incident.ack_button":  {Other: "✋ Беру на себе"},
+		"incident.acked":       {Other: "✋ Взято в роботу: %s"},
+		"incident.ack_done":    {Other: "Прийнято, ескалацію зупинено."},
+		"incident.ack_stale":   {Other: "Цей інцидент уже завершено."},
+		"incident.ack_already": {Other: "Вже взято в роботу: %s."},
+		"incident.resolved": {
+			One:  "✅ Відновлено через %d хвилину.",
+			Few:  "✅ Відновлено через %d хвилини.",
+			Many: "✅ Відновлено через %d хвилин.",
+		},
+		"snooze.usage":   {Other: "Використання: /snooze &lt;тривалість&gt;, напр. /snooze 2h (до 168h), або /snooze off."},
+		"snooze.done":    {Other: "🔕 Некритичні сповіщення вимкнено до %s."},
+		"snooze.active":  {Other: "🔕 Некритичні сповіщення вимкнено до %s."},
+		"snooze.resumed": {Other: "🔔 Сповіщення знову ввімкнено."},
+		"startup.header": {Other: "📡 Моніторинг запущено — сервер зараз %s"},
+		"startup.players": {
+			One:  "%d гравець",
+			Few:  "%d гравці",
+			Many: "%d гравців",
+		},
+		"startup.last_incident": {Other: "останній збій %s"},
+		"startup.no_incidents":  {Other: "збоїв останнім часом не було"},
+		"ago.minutes": {
+			One:  "%d хвилину тому",
+			Few:  "%d хвилини тому",
+			Many: "%d хвилин тому",
+		},
+		"ago.hours": {
+			One:  "%d годину тому",
+			Few:  "%d години тому",
+			Many: "%d годин тому",
+		},
+		"ago.days": {
+			One:  "%d день тому",
+			Few:  "%d дні тому",
+			Many: "%d днів тому",
+		},
+		"update.available": {Other: "🆕 Доступна нова версія монітора: <b>%s</b> (запущено %s)."},
+		"version.info":     {Other: "🏷 Версія <b>%s</b>\nКоміт: <code>%s</code>\nЗібрано: %s\nGo: %s"},
+		"version.unknown":  {Other: "невідомо"},
+		"jobs.header":      {Other: "⏱ <b>Фонові завдання</b>"},
+		"jobs.line":        {Other: "<b>%s</b> кожні %v: %s; наступний запуск %s; запусків %d, пропущено %d"},
+		"jobs.last_run":    {Other: "востаннє о %s (%v)"},
+		"jobs.never":       {Other: "ще не запускалося"},
+		"jobs.running":     {Other: "виконується"},
+		"server.backup_up": {Other: "💡 Резервний сервер <code>%s</code> працює."},
+		"compare.header":   {Other: "⚖️ <b>Порівняння серверів</b>"},
+		"compare.up": {
+			One:  "🟢 <code>%s</code> — %d гравець, %d мс",
+			Few:  "🟢 <code>%s</code> — %d гравці, %d мс",
+			Many: "🟢 <code>%s</code> — %d гравців, %d мс",
+		},
+		"compare.down":         {Other: "🔴 <code>%s</code> — недоступний (%s)"},
+		"compare.unconfigured": {Other: "Другий сервер не налаштовано (SECONDARY_SERVER)."},
+		"dns.changed":          {Other: "🌐 %s тепер має адресу <code>%s</code> (була <code>%s</code>)."},
+		"diag.address":         {Other: "Адреса сервера: <code>%s</code> з %s"},
+		"diag.ip":              {Other: "IP <code>%s</code>: успішних підключень %d, невдалих %d"},
+		"diag.ip.last_failure": {Other: ", остання невдача %s"},
+		"properties.changed":   {Other: "⚙️ <b>Змінилися налаштування сервера</b> (server.properties)"},
+		"properties.change":    {Other: "<code>%s</code>: %s → %s"},
+		"properties.unset":     {Other: "<i>не задано</i>"},
+		"world.summary":        {Other: "🌍 <b>Розмір світу</b>: %s"},
+		"world.growth":         {Other: "За тиждень: %s (≈%s на день)"},
+		"world.free":           {Other: "Вільно на диску: %s"},
+		"world.full_in": {
+			One:  "За такого темпу диск заповниться приблизно за %d день",
+			Few:  "За такого темпу диск заповниться приблизно за %d дні",
+			Many: "За такого темпу диск заповниться приблизно за %d днів",
+		},
+		"world.disk_warning": {
+			One:  "💾 <b>Закінчується місце на диску</b>: вільно %s, світ заповнить його приблизно за %d день.",
+			Few:  "💾 <b>Закінчується місце на диску</b>: вільно %s, світ заповнить його приблизно за %d дні.",
+			Many: "💾 <b>Закінчується місце на диску</b>: вільно %s, світ заповнить його приблизно за %d днів.",
+		},
+		"store.slow":  {Other: "🐢 Збереження історії статусів тривало %v (поріг %v). Якщо це повторюється, варто перейти на STORE_BACKEND=redis."},
+		"store.large": {Other: "📦 Історія статусів займає %s (поріг %s). Варто перейти на STORE_BACKEND=redis."},
+		"status.online": {
+			One:  "🟢 Сервер онлайн, %d гравець",
+			Few:  "🟢 Сервер онлайн, %d гравці",
+			Many: "🟢 Сервер онлайн, %d гравців",
+		},
+		"status.players":    {Other: "Грають: %s"},
+		"status.offline":    {Other: "🔴 Сервер недоступний (%s)"},
+		"chatconfig.online": {Other: "Онлайн: %s"},
+		"server.empty":      {Other: "🟡 Сервер працює, але на ньому нікого немає."},
+		"server.up":         {Other: "🟢 Сервер знову онлайн."},
+		"server.up_after":   {Other: "🟢 Сервер знову онлайн після %s простою."},
+		"lasted.minutes": {
+			One:  "%d хвилини",
+			Few:  "%d хвилин",
+			Many: "%d хвилин",
+		},
+		"lasted.hours": {
+			One:  "%d години",
+			Few:  "%d годин",
+			Many: "%d годин",
+		},
+		"lasted.days": {
+			One:  "%d дня",
+			Few:  "%d днів",
+			Many: "%d днів",
+		},
+		"compact.online": {
+			One:  "🟢 Онлайн: %d гравець",
+			Few:  "🟢 Онлайн: %d гравці",
+			Many: "🟢 Онлайн: %d гравців",
+		},
+		"compact.offline":         {Other: "🔴 Офлайн"},
+		"compact.unknown":         {Other: "⚪ Ще не перевірено"},
+		"players.nobody":          {Other: "Зараз ніхто не грає."},
+		"discord.command.status":  {Other: "Чи працює сервер і скільки на ньому гравців"},
+		"discord.command.players": {Other: "Хто зараз грає на сервері"},
+		"live.checked":            {Other: "Остання перевірка: %s"},
+		"miniapp.button":          {Other: "Статус"},
+		"stats.today":             {Other: "📊 Хто грав сьогодні:"},
+		"stats.days": {
+			One:  "📊 Хто грав за останній %d день:",
+			Few:  "📊 Хто грав за останні %d дні:",
+			Many: "📊 Хто грав за останні %d днів:",
+		},
+		"stats.nobody":    {Other: "Ніхто не грав."},
+		"stats.line":      {Other: "%s — %d год %d хв"},
+		"stats.usage":     {Other: "Використання: /stats [днів], від 1 до %d."},
+		"bot.description": {Other: "Надішліть /status, щоб дізнатися більше про сервер."},
+		"join.header":     {Other: "🎮 <b>Як зайти на сервер</b>"},
+		"join.address":    {Other: "Адреса: <code>%s</code>"},
+		"join.version":    {Other: "Версія: <code>%s</code>"},
+		"join.offline":    {Other: "🔴 Зараз сервер недоступний."},
+		"join.set":        {Other: "✅ Текст для /join оновлено."},
+		"join.cleared":    {Other: "✅ Текст для /join прибрано."},
+		"join.modpack":    {Other: "Модпак: <b>%s</b>"},
+		"modpack.set":     {Other: "✅ Версію модпака оновлено."},
+		"modpack.cleared": {Other: "✅ Версію модпака прибрано."},
+		"modpack.changed": {Other: "📦 Нова версія модпака: <b>%s</b>. Оновіться, перш ніж заходити на сервер."},
+		"event.usage":     {Other: "Використання: /event add \"назва\" 2024-06-01 19:00 або /event remove &lt;номер&gt;."},
+		"event.added":     {Other: "📅 Подію #%d «%s» заплановано на %s."},
+		"event.removed":   {Other: "🗑 Подію #%d скасовано."},
+		"event.unknown":   {Other: "Немає події #%d."},
+		"events.none":     {Other: "📅 Найближчих подій немає."},
+		"events.header":   {Other: "📅 <b>Найближчі події</b>"},
+		"events.line":     {Other: "#%d %s — %s"},
+		"event.reminder": {
+			One:  "⏰ За %d хвилину: <b>%s</b>!",
+			Few:  "⏰ За %d хвилини: <b>%s</b>!",
+			Many: "⏰ За %d хвилин: <b>%s</b>!",
+		},
+		"event.starting":    {Other: "🎉 Починається <b>%s</b>!"},
+		"event.server_down": {Other: "🚨 Зараз починається <b>%s</b>, а сервер недоступний!"},
+		"diag.query": {
+			One:  "🧩 %s, мапа <code>%s</code>, %d плагін",
+			Few:  "🧩 %s, мапа <code>%s</code>, %d плагіни",
+			Many: "🧩 %s, мапа <code>%s</code>, %d плагінів",
+		},
+		"restart.countdown": {
+			One:  "⏳ Сервер перезапуститься за %d хвилину, о %s.",
+			Few:  "⏳ Сервер перезапуститься за %d хвилини, о %s.",
+			Many: "⏳ Сервер перезапуститься за %d хвилин, о %s.",
+		},
+		"restart.countdown_game": {
+			One:  "Сервер перезапуститься за %d хвилину",
+			Few:  "Сервер перезапуститься за %d хвилини",
+			Many: "Сервер перезапуститься за %d хвилин",
+		},
+		"shutdown.notice":      {Other: "⏸ Моніторинг призупинено: бот зупиняється. Сповіщень не буде, доки він не повернеться."},
+		"announce.usage":       {Other: "Використання: /announce &lt;текст&gt;"},
+		"announce.message":     {Other: "📢 <b>Оголошення</b>\n%s"},
+		"announce.done":        {Other: "✅ Оголошення надіслано в чати."},
+		"announce.done_game":   {Other: "✅ Оголошення надіслано в чати й у гру."},
+		"announce.rcon_failed": {Other: "⚠️ Оголошення надіслано в чати, але не в гру: %s"},
+		"announce.game_prefix": {Other: "[Оголошення]"},
+		"status.latency":       {Other: "📶 Затримка: %d мс"},
+		"lag.alert": {
+			One:  "🐢 <b>Сервер не встигає</b>: %d лаг за останні %d хв.",
+			Few:  "🐢 <b>Сервер не встигає</b>: %d лаги за останні %d хв.",
+			Many: "🐢 <b>Сервер не встигає</b>: %d лагів за останні %d хв.",
+		},
+		"lag.summary": {
+			One:  "🐢 <b>Лаги за вчора</b>: %d «Can't keep up!»",
+			Few:  "🐢 <b>Лаги за вчора</b>: %d «Can't keep up!»",
+			Many: "🐢 <b>Лаги за вчора</b>: %d «Can't keep up!»",
+		},
+		"lag.stats":               {Other: "Разом відставання %.1f с; гравців онлайн під час лагів у середньому %.1f, найбільше %d"},
+		"console.disabled":        {Other: "RCON не налаштовано (RCON_ADDR або RCON_HOST)."},
+		"console.usage_say":       {Other: "Використання: /say &lt;текст&gt;"},
+		"console.usage_whitelist": {Other: "Використання: /whitelist add|remove &lt;гравець&gt;, /whitelist list, /whitelist on|off|reload"},
+		"console.usage_rcon":      {Other: "Використання: /rcon &lt;команда&gt;"},
+		"console.not_allowed":     {Other: "⛔ Цю команду не можна запускати з чату. Дозволені: %s"},
+		"console.failed":          {Other: "⚠️ Не вдалося виконати команду: %s"},
+		"console.done":            {Other: "✅ <code>/%s</code> виконано."},
+		"console.output":          {Other: "<code>/%s</code>\n<pre>%s</pre>"},
+		"crash.title":             {Other: "💥 <b>Новий звіт про збій</b>: <code>%s</code>"},
+		"crash.time":              {Other: "Час: %s"},
+		"crash.description":       {Other: "Опис: %s"},
+		"jvm.heap_warning":        {Other: "🧠 <b>Серверу бракує пам'яті</b>: heap зайнятий на %.0f%% (%s з %s). Якщо так і далі, сервер може впасти з OutOfMemoryError."},
+		"jvm.gc":                  {Other: "Збирання сміття забирає %.0f%% часу."},
+		"sla.header":              {Other: "📋 <b>Звіт про доступність</b> за тиждень %s"},
+		"sla.violations": {
+			One:  "⚠️ Порушено %d SLA",
+			Few:  "⚠️ Порушено %d SLA",
+			Many: "⚠️ Порушено %d SLA",
+		},
+		"sla.met":           {Other: "✅ %s: %.1f%% (ціль %g%%)"},
+		"sla.violated":      {Other: "❌ %s: %.1f%% (ціль %g%%)"},
+		"sla.no_data":       {Other: "➖ %s: немає даних"},
+		"chart.title":       {Other: "Гравці онлайн"},
+		"chart.usage":       {Other: "Використання: /chart [24h|7d]"},
+		"chart.no_data":     {Other: "За цей час немає даних."},
+		"chart.error":       {Other: "⚠️ Не вдалося надіслати графік."},
+		"help.header":       {Other: "ℹ️ <b>Команди</b>"},
+		"help.help":         {Other: "/help — цей список"},
+		"help.status":       {Other: "/status — чи працює сервер і хто грає"},
+		"help.diag":         {Other: "/diag — перевірити доступ бота до Telegram і чату"},
+		"help.version":      {Other: "/version — версія бота"},
+		"help.compare":      {Other: "/compare — основний і резервний сервери поруч"},
+		"help.stats":        {Other: "/stats [днів] — хто скільки грав"},
+		"help.chart":        {Other: "/chart [24h|7d] — графік гравців онлайн"},
+		"help.join":         {Other: "/join — як зайти на сервер"},
+		"help.events":       {Other: "/events — найближчі події в грі"},
+		"help.jobs":         {Other: "/jobs — фонові завдання бота"},
+		"help.list":         {Other: "/list — гравці онлайн за даними консолі"},
+		"help.chatconfig":   {Other: "/chatconfig — налаштування цього чату"},
+		"help.snooze":       {Other: "/snooze &lt;тривалість&gt;|off — призупинити сповіщення в чаті"},
+		"help.setjoin":      {Other: "/setjoin &lt;текст&gt; — нотатки до /join"},
+		"help.setmodpack":   {Other: "/setmodpack &lt;версія&gt; — версія модпака для /join"},
+		"help.event":        {Other: "/event add|remove — запланувати або скасувати подію"},
+		"help.announce":     {Other: "/announce &lt;текст&gt; — оголошення в чати й у гру"},
+		"help.say":          {Other: "/say &lt;текст&gt; — написати в чат гри"},
+		"help.whitelist":    {Other: "/whitelist — керувати білим списком"},
+		"help.rcon":         {Other: "/rcon &lt;команда&gt; — виконати дозволену команду консолі"},
+		"help.forget":       {Other: "/forget &lt;гравець&gt; — видалити дані гравця"},
+		"help.audit":        {Other: "/audit [n] — останні дії адміністраторів"},
+		"help.grant":        {Other: "/grant &lt;ID&gt; &lt;роль&gt; — видати роль користувачу"},
+		"seen.usage":        {Other: "Використання: /seen &lt;гравець&gt;"},
+		"seen.error":        {Other: "⚠️ Не вдалося прочитати журнал подій."},
+		"seen.never":        {Other: "❔ %s ще не бачили на сервері."},
+		"seen.online":       {Other: "🟢 %s зараз на сервері — із %s, протягом %s."},
+		"seen.last":         {Other: "🕓 Востаннє %s бачили %s (%s)."},
+		"seen.last_session": {Other: "🕓 Востаннє %s бачили %s (%s), у грі протягом %s."},
+		"help.seen":         {Other: "/seen &lt;гравець&gt; — коли гравець був на сервері"},
+	},
+	"en": {
+		"players.joined":        {Other: "😎 joined the server: %s"},
+		"players.joined.single": {Other: "😎 %s joined the server"},
+		"players.left":          {Other: "🥺 left: %s"},
+		"players.left.single":   {Other: "🥺 %s left"},
+		"players.count": {
+			One:   "%d player",
+			Other: "%d players",
+		},
+		"title.online":           {Other: "🟢 lnudorm3 minecraft yo"},
+		"title.offline":          {Other: "🔴 lnudorm3 minecraft yo"},
+		"diag.header":            {Other: "🩺 <b>Diagnostics</b>"},
+		"diag.api.ok":            {Other: "Telegram API: ✅ %d ms (@%s)"},
+		"diag.api.fail":          {Other: "Telegram API: ❌ %s"},
+		"diag.chat.ok":           {Other: "Chat: %s (%s)"},
+		"diag.chat.fail":         {Other: "Chat <code>%s</code>: ❌ %s"},
+		"diag.member.status":     {Other: "Bot status: %s"},
+		"diag.member.fail":       {Other: "Bot rights: ❌ %s"},
+		"diag.right.change_info": {Other: "Change chat info: %s"},
+		"diag.right.pin":         {Other: "Pin messages: %s"},
+		"diag.right.delete":      {Other: "Delete messages: %s"},
+		"diag.server":            {Other: "Last server check: %s, %v ago"},
+		"diag.server.never":      {Other: "The server hasn't been checked yet"},
+		"chat.migrated":          {Other: "ℹ️ The group was upgraded to a supergroup: chat ID changed from <code>%s</code> to <code>%s</code>. Please update TELEGRAM_CHAT_ID."},
+		"alert.firing":           {Other: "🚨 %s"},
+		"alert.resolved":         {Other: "✅ %s resolved"},
+		"command.denied":         {Other: "⛔ This command needs the %s role."},
+		"forget.usage":           {Other: "Usage: /forget &lt;player&gt;"},
+		"forget.done": {
+			One:   "🧹 Removed %s from the history (%d entry).",
+			Other: "🧹 Removed %s from the history (%d entries).",
+		},
+		"audit.header":             {Other: "📋 <b>Admin audit log</b>"},
+		"audit.empty":              {Other: "📋 The audit log is empty."},
+		"audit.error":              {Other: "❌ Couldn't read the audit log."},
+		"audit.usage":              {Other: "Usage: /audit [count]"},
+		"grant.usage":              {Other: "Usage: /grant &lt;user ID&gt; none|viewer|operator|admin|default"},
+		"grant.done":               {Other: "✅ User <code>%d</code> now has the %s role."},
+		"grant.reset":              {Other: "✅ Dropped the granted role of user <code>%d</code>; they now have the %s role."},
+		"grant.fixed":              {Other: "⚠️ User <code>%d</code> is an admin in TELEGRAM_ADMIN_IDS; their role can only be changed there."},
+		"confirm.ask":              {Other: "⚠️ Run <code>%s</code>? Confirm within %d s."},
+		"confirm.yes":              {Other: "✅ Confirm"},
+		"confirm.no":               {Other: "✖️ Cancel"},
+		"confirm.done":             {Other: "✅ Confirmed."},
+		"confirm.cancelled":        {Other: "✖️ Cancelled."},
+		"confirm.expired":          {Other: "⌛ The confirmation has expired."},
+		"confirm.not_yours":        {Other: "Only whoever sent the command can confirm it."},
+		"chatconfig.header":        {Other: "⚙️ <b>Chat settings</b>"},
+		"chatconfig.server":        {Other: "Server: <code>%s</code>"},
+		"chatconfig.language":      {Other: "Language: %s"},
+		"chatconfig.events":        {Other: "Events: %s"},
+		"chatconfig.template":      {Other: "Template %s: <code>%s</code>"},
+		"chatconfig.invalid":       {Other: "❌ Invalid value: %s"},
+		"chatconfig.usage":         {Other: "Usage:\n/chatconfig\n/chatconfig server &lt;host[:port]|default&gt;\n/chatconfig language &lt;uk|en|default&gt;\n/chatconfig events &lt;event&gt; on|off (%s)\n/chatconfig template &lt;key&gt; &lt;text|default&gt; (%s)\n/chatconfig online &lt;%s|default&gt;"},
+		"command.cooldown":         {Other: "⏳ Too many commands. Please try again in %d s."},
+		"server.scheduled_restart": {Other: "🔄 The server went down at its usual restart time — looks like a scheduled restart."},
+		"anomaly.drop":             {Other: "ℹ️ All %d players left at once while the server stayed up. Something may be wrong."},
+		"anomaly.high":             {Other: "ℹ️ Unusually many players: %d at %s (usually about %.0f)."},
+		"anomaly.low":              {Other: "ℹ️ Unusually few players: %d at %s (usually about %.0f)."},
+		"server.down":              {Other: "🔴 The server is down."},
+		"server.forecast": {
+			One:   "Similar outages typically last ~%d minute.",
+			Other: "Similar outages typically last ~%d minutes.",
+		},
+		"server.forecast_recent": {
+			One:   "The last outage lasted %[2]s min.",
+			Other: "The last %d outages lasted %s min.",
+		},
+		"escalation.still_down": {
+			One:   "🚨 The server has been down for %d minute.",
+			Other: "🚨 The server has been down for %d minutes.",
+		},
+		"escalation.title": {Other: "Minecraft server %s is down"},
+		"escalation.body": {
+			One:   "The server %s has been down for %d minute.",
+			Other: "The server %s has been down for %d minutes.",
+		},
+		"incident.ack_button":  {Other: "✋ Acknowledge"},
+		"incident.acked":       {Other: "✋ Acknowledged by %s"},
+		"incident.ack_done":    {Other: "Acknowledged, escalation stopped."},
+		"incident.ack_stale":   {Other: "This incident is already resolved."},
+		"incident.ack_already": {Other: "Already acknowledged by %s."},
+		"incident.resolved": {
+			One:   "✅ Resolved after %d minute.",
+			Other: "✅ Resolved after %d minutes.",
+		},
+		"snooze.usage":   {Other: "Usage: /snooze &lt;duration&gt;, e.g. /snooze 2h (up to 168h), or /snooze off."},
+		"snooze.done":    {Other: "🔕 Non-critical notifications muted until %s."},
+		"snooze.active":  {Other: "🔕 Non-critical notifications are muted until %s."},
+		"snooze.resumed": {Other: "🔔 Notifications resumed."},
+		"startup.header": {Other: "📡 Monitor online — server currently %s"},
+		"startup.players": {
+			One:   "%d player",
+			Other: "%d players",
+		},
+		"startup.last_incident": {Other: "last incident %s"},
+		"startup.no_incidents":  {Other: "no recent incidents"},
+		"ago.minutes": {
+			One:   "%d minute ago",
+			Other: "%d minutes ago",
+		},
+		"ago.hours": {
+			One:   "%d hour ago",
+			Other: "%d hours ago",
+		},
+		"ago.days": {
+			One:   "%d day ago",
+			Other: "%d days ago",
+		},
+		"update.available": {Other: "🆕 A new version of the monitor is available: <b>%s</b> (running %s)."},
+		"version.info":     {Other: "🏷 Version <b>%s</b>\nCommit: <code>%s</code>\nBuilt: %s\nGo: %s"},
+		"version.unknown":  {Other: "unknown"},
+		"jobs.header":      {Other: "⏱ <b>Background jobs</b>"},
+		"jobs.line":        {Other: "<b>%s</b> every %v: %s; next run %s; %d runs, %d skipped"},
+		"jobs.last_run":    {Other: "last run at %s (%v)"},
+		"jobs.never":       {Other: "not run yet"},
+		"jobs.running":     {Other: "running"},
+		"server.backup_up": {Other: "💡 The backup server <code>%s</code> is up."},
+		"compare.header":   {Other: "⚖️ <b>Server comparison</b>"},
+		"compare.up": {
+			One:   "🟢 <code>%s</code> — %d player, %d ms",
+			Other: "🟢 <code>%s</code> — %d players, %d ms",
+		},
+		"compare.down":         {Other: "🔴 <code>%s</code> — down (%s)"},
+		"compare.unconfigured": {Other: "No second server is configured (SECONDARY_SERVER)."},
+		"dns.changed":          {Other: "🌐 %s now resolves to <code>%s</code> (was <code>%s</code>)."},
+		"diag.address":         {Other: "Server address: <code>%s</code> since %s"},
+		"diag.ip":              {Other: "IP <code>%s</code>: %d connections ok, %d failed"},
+		"diag.ip.last_failure": {Other: ", last failure %s"},
+		"properties.changed":   {Other: "⚙️ <b>Server settings changed</b> (server.properties)"},
+		"properties.change":    {Other: "<code>%s</code>: %s → %s"},
+		"properties.unset":     {Other: "<i>not set</i>"},
+		"world.summary":        {Other: "🌍 <b>World size</b>: %s"},
+		"world.growth":         {Other: "Past week: %s (≈%s per day)"},
+		"world.free":           {Other: "Free disk space: %s"},
+		"world.full_in": {
+			One:   "At this rate the disk fills up in about %d day",
+			Other: "At this rate the disk fills up in about %d days",
+		},
+		"world.disk_warning": {
+			One:   "💾 <b>Running out of disk space</b>: %s free, the world will fill it in about %d day.",
+			Other: "💾 <b>Running out of disk space</b>: %s free, the world will fill it in about %d days.",
+		},
+		"store.slow":  {Other: "🐢 Saving the status history took %v (threshold %v). If this keeps happening, consider STORE_BACKEND=redis."},
+		"store.large": {Other: "📦 The status history takes %s (threshold %s). Consider STORE_BACKEND=redis."},
+		"status.online": {
+			One:   "🟢 The server is up, %d player online",
+			Other: "🟢 The server is up, %d players online",
+		},
+		"status.players":    {Other: "Playing: %s"},
+		"status.offline":    {Other: "🔴 The server is down (%s)"},
+		"chatconfig.online": {Other: "Online: %s"},
+		"server.empty":      {Other: "🟡 The server is up, but nobody is playing."},
+		"server.up":         {Other: "🟢 The server is back online."},
+		"server.up_after":   {Other: "🟢 The server is back online after %s of downtime."},
+		"lasted.minutes": {
+			One:   "%d minute",
+			Other: "%d minutes",
+		},
+		"lasted.hours": {
+			One:   "%d hour",
+			Other: "%d hours",
+		},
+		"lasted.days": {
+			One:   "%d day",
+			Other: "%d days",
+		},
+		"compact.online": {
+			One:   "🟢 Online: %d player",
+			Other: "🟢 Online: %d players",
+		},
+		"compact.offline":         {Other: "🔴 Offline"},
+		"compact.unknown":         {Other: "⚪ Not checked yet"},
+		"players.nobody":          {Other: "Nobody is playing right now."},
+		"discord.command.status":  {Other: "Whether the server is up and how many are playing"},
+		"discord.command.players": {Other: "Who is playing on the server right now"},
+		"live.checked":            {Other: "Last checked: %s"},
+		"miniapp.button":          {Other: "Status"},
+		"stats.today":             {Other: "📊 Who played today:"},
+		"stats.days": {
+			One:   "📊 Who played in the last %d day:",
+			Other: "📊 Who played in the last %d days:",
+		},
+		"stats.nobody":    {Other: "Nobody played."},
+		"stats.line":      {Other: "%s — %dh %dm"},
+		"stats.usage":     {Other: "Usage: /stats [days], from 1 to %d."},
+		"bot.description": {Other: "Send /status for more about the server."},
+		"join.header":     {Other: "🎮 <b>How to join</b>"},
+		"join.address":    {Other: "Address: <code>%s</code>"},
+		"join.version":    {Other: "Version: <code>%s</code>"},
+		"join.offline":    {Other: "🔴 The server is down right now."},
+		"join.set":        {Other: "✅ The /join text is updated."},
+		"join.cleared":    {Other: "✅ The /join text is removed."},
+		"join.modpack":    {Other: "Modpack: <b>%s</b>"},
+		"modpack.set":     {Other: "✅ The modpack version is updated."},
+		"modpack.cleared": {Other: "✅ The modpack version is removed."},
+		"modpack.changed": {Other: "📦 New modpack version: <b>%s</b>. Update before you connect."},
+		"event.usage":     {Other: "Usage: /event add \"title\" 2024-06-01 19:00 or /event remove &lt;number&gt;."},
+		"event.added":     {Other: "📅 Event #%d “%s” is scheduled for %s."},
+		"event.removed":   {Other: "🗑 Event #%d is cancelled."},
+		"event.unknown":   {Other: "There is no event #%d."},
+		"events.none":     {Other: "📅 No upcoming events."},
+		"events.header":   {Other: "📅 <b>Upcoming events</b>"},
+		"events.line":     {Other: "#%d %s — %s"},
+		"event.reminder": {
+			One:   "⏰ In %d minute: <b>%s</b>!",
+			Other: "⏰ In %d minutes: <b>%s</b>!",
+		},
+		"event.starting":    {Other: "🎉 <b>%s</b> is starting!"},
+		"event.server_down": {Other: "🚨 <b>%s</b> is starting now, but the server is down!"},
+		"diag.query": {
+			One:   "🧩 %s, map <code>%s</code>, %d plugin",
+			Other: "🧩 %s, map <code>%s</code>, %d plugins",
+		},
+		"restart.countdown": {
+			One:   "⏳ The server restarts in %d minute, at %s.",
+			Other: "⏳ The server restarts in %d minutes, at %s.",
+		},
+		"restart.countdown_game": {
+			One:   "The server restarts in %d minute",
+			Other: "The server restarts in %d minutes",
+		},
+		"shutdown.notice":      {Other: "⏸ Monitoring is paused: the bot is stopping. There will be no alerts until it's back."},
+		"announce.usage":       {Other: "Usage: /announce &lt;text&gt;"},
+		"announce.message":     {Other: "📢 <b>Announcement</b>\n%s"},
+		"announce.done":        {Other: "✅ The announcement is sent to the chats."},
+		"announce.done_game":   {Other: "✅ The announcement is sent to the chats and in game."},
+		"announce.rcon_failed": {Other: "⚠️ The announcement is sent to the chats, but not in game: %s"},
+		"announce.game_prefix": {Other: "[Announcement]"},
+		"status.latency":       {Other: "📶 Latency: %d ms"},
+		"lag.alert": {
+			One:   "🐢 <b>The server can't keep up</b>: %d lag spike in the last %d min.",
+			Other: "🐢 <b>The server can't keep up</b>: %d lag spikes in the last %d min.",
+		},
+		"lag.summary": {
+			One:   "🐢 <b>Lag yesterday</b>: %d \"Can't keep up!\"",
+			Other: "🐢 <b>Lag yesterday</b>: %d \"Can't keep up!\"s",
+		},
+		"lag.stats":               {Other: "%.1f s behind in total; %.1f players online on average during them, %d at most"},
+		"console.disabled":        {Other: "RCON isn't set up (RCON_ADDR or RCON_HOST)."},
+		"console.usage_say":       {Other: "Usage: /say &lt;text&gt;"},
+		"console.usage_whitelist": {Other: "Usage: /whitelist add|remove &lt;player&gt;, /whitelist list, /whitelist on|off|reload"},
+		"console.usage_rcon":      {Other: "Usage: /rcon &lt;command&gt;"},
+		"console.not_allowed":     {Other: "⛔ This command can't be run from the chat. Allowed: %s"},
+		"console.failed":          {Other: "⚠️ The command failed: %s"},
+		"console.done":            {Other: "✅ <code>/%s</code> done."},
+		"console.output":          {Other: "<code>/%s</code>\n<pre>%s</pre>"},
+		"crash.title":             {Other: "💥 <b>New crash report</b>: <code>%s</code>"},
+		"crash.time":              {Other: "Time: %s"},
+		"crash.description":       {Other: "Description: %s"},
+		"jvm.heap_warning":        {Other: "🧠 <b>The server is running out of memory</b>: the heap is %.0f%% full (%s of %s). If this goes on, it may crash with an OutOfMemoryError."},
+		"jvm.gc":                  {Other: "Garbage collection takes %.0f%% of the time."},
+		"sla.header":              {Other: "📋 <b>Uptime report</b> for week %s"},
+		"sla.violations": {
+			One:   "⚠️ %d SLA violated",
+			Other: "⚠️ %d SLAs violated",
+		},
+		"sla.met":           {Other: "✅ %s: %.1f%% (target %g%%)"},
+		"sla.violated":      {Other: "❌ %s: %.1f%% (target %g%%)"},
+		"sla.no_data":       {Other: "➖ %s: no data"},
+		"chart.title":       {Other: "Players online"},
+		"chart.usage":       {Other: "Usage: /chart [24h|7d]"},
+		"chart.no_data":     {Other: "There is no data for this period."},
+		"chart.error":       {Other: "⚠️ Couldn't send the chart."},
+		"help.header":       {Other: "ℹ️ <b>Commands</b>"},
+		"help.help":         {Other: "/help — this list"},
+		"help.status":       {Other: "/status — whether the server is up and who is playing"},
+		"help.diag":         {Other: "/diag — check the bot's access to Telegram and the chat"},
+		"help.version":      {Other: "/version — the bot's version"},
+		"help.compare":      {Other: "/compare — the main and secondary servers side by side"},
+		"help.stats":        {Other: "/stats [days] — who played and for how long"},
+		"help.chart":        {Other: "/chart [24h|7d] — a chart of the players online"},
+		"help.join":         {Other: "/join — how to get on the server"},
+		"help.events":       {Other: "/events — upcoming in-game events"},
+		"help.jobs":         {Other: "/jobs — the bot's background jobs"},
+		"help.list":         {Other: "/list — the players online according to the console"},
+		"help.chatconfig":   {Other: "/chatconfig — this chat's settings"},
+		"help.snooze":       {Other: "/snooze &lt;duration&gt;|off — pause notifications in this chat"},
+		"help.setjoin":      {Other: "/setjoin &lt;text&gt; — notes for /join"},
+		"help.setmodpack":   {Other: "/setmodpack &lt;version&gt; — the modpack version for /join"},
+		"help.event":        {Other: "/event add|remove — schedule or cancel an event"},
+		"help.announce":     {Other: "/announce &lt;text&gt; — an announcement in the chats and in game"},
+		"help.say":          {Other: "/say &lt;text&gt; — write in the game chat"},
+		"help.whitelist":    {Other: "/whitelist — manage the whitelist"},
+		"help.rcon":         {Other: "/rcon &lt;command&gt; — run an allowed console command"},
+		"help.forget":       {Other: "/forget &lt;player&gt; — delete a player's data"},
+		"help.audit":        {Other: "/audit [n] — the latest admin actions"},
+		"help.grant":        {Other: "/grant &lt;ID&gt; &lt;role&gt; — give a user a role"},
+		"seen.usage":        {Other: "Usage: /seen &lt;player&gt;"},
+		"seen.error":        {Other: "⚠️ Couldn't read the event log."},
+		"seen.never":        {Other: "❔ %s hasn't been seen on the server."},
+		"seen.online":       {Other: "🟢 %s is on the server now — since %s, for %s."},
+		"seen.last":         {Other: "🕓 %s was last seen %s (%s)."},
+		"seen.last_session": {Other: "🕓 %s was last seen %s (%s), after playing for %s."},
+		"help.seen":         {Other: "/seen &lt;player&gt; — when a player was last on the server"},
+	},
+}
+
+// lookupMessage finds key in lang, falling back to DEFAULT_LANGUAGE.
+func lookupMessage(lang, key string) (Translation, bool) {
+	if msg, ok := messages[lang][key]; ok {
+		return msg, true
+	}
+	msg, ok := messages[DEFAULT_LANGUAGE][key]
+	return msg, ok
+}
+
+// tr formats the message key in lang.
+func tr(lang, key string, args ...interface{}) string {
+	msg, ok := lookupMessage(lang, key)
+	if !ok {
+		return key
+	}
+	return fmt.Sprintf(firstNonEmpty(msg.Other, msg.One), args...)
+}
+
+// trn formats the plural form of key that matches n. args are passed to the
+// format as is, so messages that show the number need it in args too.
+func trn(lang, key string, n int, args ...interface{}) string {
+	msg, ok := lookupMessage(lang, key)
+	if !ok {
+		return key
+	}
+
+	rule, ok := pluralRules[lang]
+	if !ok {
+		rule = pluralRules[DEFAULT_LANGUAGE]
+	}
+
+	var form string
+	switch rule(n) {
+	case "one":
+		form = msg.One
+	case "few":
+		form = msg.Few
+	case "many":
+		form = msg.Many
+	}
+	return fmt.Sprintf(firstNonEmpty(form, msg.Other, msg.Many, msg.One), args...)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
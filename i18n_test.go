@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestUkrainianPlurals(t *testing.T) {
+	cases := map[int]string{
+		1:   "1 гравець",
+		2:   "2 гравці",
+		4:   "4 гравці",
+		5:   "5 гравців",
+		11:  "11 гравців",
+		12:  "12 гравців",
+		21:  "21 гравець",
+		22:  "22 гравці",
+		111: "111 гравців",
+		0:   "0 гравців",
+	}
+	for n, want := range cases {
+		if got := trn("uk", "players.count", n, n); got != want {
+			t.Errorf("trn(uk, %d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestTranslationFallback(t *testing.T) {
+	if got := trn("en", "players.count", 3, 3); got != "3 players" {
+		t.Errorf("en plural = %q", got)
+	}
+	if got := trn("de", "players.count", 5, 5); got != "5 гравців" {
+		t.Errorf("unknown language should fall back to %s, got %q", DEFAULT_LANGUAGE, got)
+	}
+	if got := tr("uk", "no.such.key"); got != "no.such.key" {
+		t.Errorf("missing key = %q", got)
+	}
+}
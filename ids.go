@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EntryID identifies a status entry. Older files hold time.Now().UnixNano()
+// numbers, which are read as their decimal text.
+type EntryID string
+
+func (id *EntryID) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		if _, err := strconv.ParseInt(string(data), 10, 64); err != nil {
+			return fmt.Errorf("entry ID %s: %w", data, err)
+		}
+		*id = EntryID(data)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*id = EntryID(s)
+	return nil
+}
+
+// IDGenerator hands out entry IDs; ID_STRATEGY picks one in loadConfig.
+type IDGenerator interface {
+	// Next returns a fresh ID for an entry created at now.
+	Next(now time.Time) EntryID
+	// Observe is told about every ID already in the store, so a restart
+	// doesn't hand out one of them again.
+	Observe(id EntryID)
+}
+
+var entryIDs IDGenerator = &ulidGenerator{}
+
+// CROCKFORD_BASE32 is the ULID alphabet.
+const CROCKFORD_BASE32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator makes ULIDs: a millisecond timestamp and 80 random bits,
+// 26 characters that sort by time. IDs made in the same millisecond
+// increment the random part, so they stay unique and ordered.
+type ulidGenerator struct {
+	mu      sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+func (g *ulidGenerator) Next(now time.Time) EntryID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(now.UnixMilli())
+	if ms > g.lastMs {
+		g.lastMs = ms
+		rand.Read(g.entropy[:])
+	} else {
+		// Same millisecond, or the clock went back: stay after the last
+		// ID.
+		for i := len(g.entropy) - 1; i >= 0; i-- {
+			g.entropy[i]++
+			if g.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+	return EntryID(encodeULID(g.lastMs, g.entropy))
+}
+
+func (g *ulidGenerator) Observe(EntryID) {}
+
+// encodeULID renders the 48-bit timestamp and the entropy as 128 bits of
+// Crockford base32, most significant first.
+func encodeULID(ms uint64, entropy [10]byte) string {
+	hi := ms<<16 | uint64(entropy[0])<<8 | uint64(entropy[1])
+	var lo uint64
+	for _, b := range entropy[2:] {
+		lo = lo<<8 | uint64(b)
+	}
+
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = CROCKFORD_BASE32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// sequenceGenerator numbers entries per node: "<node>-<sequence>", the
+// sequence zero-padded so the IDs of one node sort in order. Several
+// monitors writing to one store each need their own ID_NODE.
+type sequenceGenerator struct {
+	mu   sync.Mutex
+	node string
+	last uint64
+}
+
+func (g *sequenceGenerator) Next(time.Time) EntryID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.last++
+	return EntryID(fmt.Sprintf("%s-%016d", g.node, g.last))
+}
+
+func (g *sequenceGenerator) Observe(id EntryID) {
+	sequence, ok := strings.CutPrefix(string(id), g.node+"-")
+	if !ok {
+		return
+	}
+	n, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.last = max(g.last, n)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestULIDGenerator(t *testing.T) {
+	g := &ulidGenerator{}
+	at := time.UnixMilli(1710072000000)
+
+	first := g.Next(at)
+	if len(first) != 26 || first[:10] != "01HRM3QVG0" {
+		t.Fatalf("ULID %q doesn't start with the encoded timestamp", first)
+	}
+	previous := first
+	for i := 0; i < 1000; i++ {
+		id := g.Next(at)
+		if id <= previous {
+			t.Fatalf("%q doesn't sort after %q", id, previous)
+		}
+		previous = id
+	}
+	// A clock set back still sorts after.
+	if id := g.Next(at.Add(-time.Minute)); id <= previous {
+		t.Fatalf("%q doesn't sort after %q", id, previous)
+	}
+	if id := g.Next(at.Add(time.Millisecond)); id <= previous {
+		t.Fatalf("%q doesn't sort after %q", id, previous)
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	g := &sequenceGenerator{node: "dorm3"}
+	g.Observe("dorm3-0000000000000041")
+	g.Observe("other-0000000000000099")
+	g.Observe("01HRM3QVG0ABCDEFGHJKMNPQRS")
+	if id := g.Next(time.Now()); id != "dorm3-0000000000000042" {
+		t.Errorf("Next() = %q, want dorm3-0000000000000042", id)
+	}
+}
+
+func TestEntryIDReadsLegacyNumbers(t *testing.T) {
+	var entries []StatusEntry
+	if err := json.Unmarshal([]byte(`[{"id": 1710072000123456789}, {"id": "01HRM3QVG0ABCDEFGHJKMNPQRS"}]`), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].ID != "1710072000123456789" || entries[1].ID != "01HRM3QVG0ABCDEFGHJKMNPQRS" {
+		t.Errorf("IDs = %q, %q", entries[0].ID, entries[1].ID)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// KUMA_TIME_LAYOUT is how Uptime Kuma stores heartbeat times (UTC).
+const KUMA_TIME_LAYOUT = "2006-01-02 15:04:05.999"
+
+// importHistory is the import subcommand: `import <format> <file>` reads
+// checks exported by another monitor ("-" reads stdin) into the store.
+// Formats:
+//
+//   - history: this monitor's `export history`, a status.json snapshot, or
+//     the old Convex table (`npx convex export`, status/documents.jsonl);
+//   - kuma: Uptime Kuma heartbeats ({"status": 1, "time": "..."}), as a
+//     JSON array or one per line; pending and maintenance beats are skipped;
+//   - mcstatus: one `mcstatus <host> json` result per line, each with an
+//     added "timestamp" (Unix seconds or RFC 3339).
+//
+// Checks are kept as long as any other: the last day, or with
+// STORE_BACKEND=sqlite HISTORY_RETENTION_DAYS. The downtimes of all of
+// them are merged into the state either way, so restart windows and
+// downtime forecasts carry over.
+func importHistory(w io.Writer, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: import history|kuma|mcstatus <file>")
+	}
+
+	var parse func(json.RawMessage) ([]StatusEntry, error)
+	switch args[0] {
+	case "history":
+		parse = parseHistoryRecord
+	case "kuma":
+		parse = parseKumaRecord
+	case "mcstatus":
+		parse = parseMcstatusRecord
+	default:
+		return fmt.Errorf("unknown import format %q", args[0])
+	}
+
+	input := io.Reader(os.Stdin)
+	if args[1] != "-" {
+		file, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	records, err := readJSONRecords(input)
+	if err != nil {
+		return err
+	}
+	var entries []StatusEntry
+	for i, record := range records {
+		parsed, err := parse(record)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		entries = append(entries, parsed...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastChecked < entries[j].LastChecked
+	})
+
+	loadStore()
+	loadState()
+	added := mergeEntries(entries, time.Now())
+	older, err := mergeOlderEntries(entries, time.Now())
+	if err != nil {
+		return err
+	}
+	downtimes := mergeDowntimes(importedDowntimes(entries), time.Now())
+	flushStore()
+
+	fmt.Fprintf(w, "Read %d checks: %d added to the history, %d downtimes added\n", len(entries), added+older, downtimes)
+	return nil
+}
+
+// readJSONRecords reads a JSON array, or a stream of JSON values such as
+// JSON lines.
+func readJSONRecords(r io.Reader) ([]json.RawMessage, error) {
+	reader := bufio.NewReader(r)
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		reader.UnreadByte()
+		if b == '[' {
+			var records []json.RawMessage
+			err := json.NewDecoder(reader).Decode(&records)
+			return records, err
+		}
+		break
+	}
+
+	var records []json.RawMessage
+	decoder := json.NewDecoder(reader)
+	for {
+		var record json.RawMessage
+		if err := decoder.Decode(&record); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// parseHistoryRecord reads an entry, or a whole snapshot. Convex documents
+// decode as entries too; their _id and _creationTime are ignored.
+func parseHistoryRecord(record json.RawMessage) ([]StatusEntry, error) {
+	var snapshot struct {
+		Entries *[]StatusEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(record, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Entries != nil {
+		return *snapshot.Entries, nil
+	}
+
+	var entry StatusEntry
+	if err := json.Unmarshal(record, &entry); err != nil {
+		return nil, err
+	}
+	if entry.LastChecked == 0 {
+		return nil, fmt.Errorf("missing lastChecked")
+	}
+	if entry.Players == nil {
+		entry.Players = []string{}
+	}
+	return []StatusEntry{entry}, nil
+}
+
+func parseKumaRecord(record json.RawMessage) ([]StatusEntry, error) {
+	var beat struct {
+		Status *int   `json:"status"`
+		Time   string `json:"time"`
+		Msg    string `json:"msg"`
+	}
+	if err := json.Unmarshal(record, &beat); err != nil {
+		return nil, err
+	}
+	if beat.Status == nil {
+		return nil, fmt.Errorf("missing status")
+	}
+	at, err := time.Parse(KUMA_TIME_LAYOUT, beat.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	switch *beat.Status {
+	case 1: // UP
+		return []StatusEntry{{Online: true, LastChecked: at.UnixMilli(), Players: []string{}}}, nil
+	case 0: // DOWN
+		return []StatusEntry{{Online: false, LastChecked: at.UnixMilli(), Players: []string{}, Error: "error"}}, nil
+	default: // PENDING, MAINTENANCE
+		return nil, nil
+	}
+}
+
+func parseMcstatusRecord(record json.RawMessage) ([]StatusEntry, error) {
+	var result struct {
+		Timestamp json.RawMessage `json:"timestamp"`
+		Online    bool            `json:"online"`
+		Players   *struct {
+			Online int `json:"online"`
+			List   []struct {
+				Name string `json:"name"`
+			} `json:"list"`
+			Sample []struct {
+				Name string `json:"name"`
+			} `json:"sample"`
+		} `json:"players"`
+	}
+	if err := json.Unmarshal(record, &result); err != nil {
+		return nil, err
+	}
+	at, err := parseImportTimestamp(result.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := StatusEntry{Online: result.Online, LastChecked: at.UnixMilli(), Players: []string{}}
+	if !result.Online {
+		entry.Error = "error"
+	}
+	if players := result.Players; players != nil {
+		names := players.List
+		if len(names) == 0 {
+			names = players.Sample
+		}
+		for _, player := range names {
+			entry.Players = append(entry.Players, player.Name)
+		}
+	}
+	return []StatusEntry{entry}, nil
+}
+
+// parseImportTimestamp reads Unix seconds (a number or a numeric string)
+// or an RFC 3339 time.
+func parseImportTimestamp(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		text = string(bytes.TrimSpace(raw))
+	}
+	if seconds, err := strconv.ParseFloat(text, 64); err == nil {
+		return time.UnixMilli(int64(seconds * 1000)), nil
+	}
+	return time.Parse(time.RFC3339, text)
+}
+
+// mergeEntries adds the entries from the last day that aren't in the store
+// yet (by check time) and returns how many it added.
+func mergeEntries(entries []StatusEntry, now time.Time) int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	known := make(map[int64]bool, len(store.Entries))
+	for _, entry := range store.Entries {
+		known[entry.LastChecked] = true
+	}
+
+	cutoff := now.UnixMilli() - ONE_DAY_IN_MS
+	added := 0
+	for _, entry := range entries {
+		if entry.LastChecked < cutoff || known[entry.LastChecked] {
+			continue
+		}
+		entry.ID = entryIDs.Next(now)
+		store.Entries = append(store.Entries, entry)
+		known[entry.LastChecked] = true
+		added++
+	}
+	if added > 0 {
+		sort.SliceStable(store.Entries, func(i, j int) bool {
+			return store.Entries[i].LastChecked < store.Entries[j].LastChecked
+		})
+		store.compact = true
+	}
+	return added
+}
+
+// mergeOlderEntries adds the sorted entries from before the last day to
+// the SQLite history, if there is one, within HISTORY_RETENTION_DAYS and
+// unless a check at the same time is stored already, and returns how many
+// it added. The store only holds the last day, so they go to the database
+// directly.
+func mergeOlderEntries(entries []StatusEntry, now time.Time) (int, error) {
+	if history == nil {
+		return 0, nil
+	}
+	dayCutoff := now.UnixMilli() - ONE_DAY_IN_MS
+	retained := now.Add(-config.HistoryRetention).UnixMilli()
+
+	var older []StatusEntry
+	for _, entry := range entries {
+		if entry.LastChecked < dayCutoff && (config.HistoryRetention <= 0 || entry.LastChecked >= retained) {
+			older = append(older, entry)
+		}
+	}
+	if len(older) == 0 {
+		return 0, nil
+	}
+
+	stored, err := history.Range(older[0].LastChecked, dayCutoff)
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[int64]bool, len(stored))
+	for _, entry := range stored {
+		known[entry.LastChecked] = true
+	}
+	var added []StatusEntry
+	for _, entry := range older {
+		if known[entry.LastChecked] {
+			continue
+		}
+		entry.ID = entryIDs.Next(now)
+		added = append(added, entry)
+		known[entry.LastChecked] = true
+	}
+	return len(added), history.Insert(added)
+}
+
+// importedDowntimes finds the downtimes in sorted entries. One still going
+// at the end is left out: the monitor records it when it sees the server
+// itself.
+func importedDowntimes(entries []StatusEntry) []Downtime {
+	var downtimes []Downtime
+	var start int64
+	for _, entry := range entries {
+		switch {
+		case !entry.Online && start == 0:
+			start = entry.LastChecked / 1000
+		case entry.Online && start != 0:
+			downtimes = append(downtimes, Downtime{Start: start, End: entry.LastChecked / 1000})
+			start = 0
+		}
+	}
+	return downtimes
+}
+
+// mergeDowntimes adds the downtimes from the last RESTART_HISTORY_DAYS that
+// don't overlap the recorded ones to the state and returns how many it
+// added.
+func mergeDowntimes(downtimes []Downtime, now time.Time) int {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	cutoff := now.AddDate(0, 0, -RESTART_HISTORY_DAYS).Unix()
+	added := 0
+	for _, downtime := range downtimes {
+		if downtime.Start < cutoff || overlapsDowntime(state.Downtimes, downtime) {
+			continue
+		}
+		state.Downtimes = append(state.Downtimes, downtime)
+		added++
+	}
+	if added > 0 {
+		sort.SliceStable(state.Downtimes, func(i, j int) bool {
+			return state.Downtimes[i].Start < state.Downtimes[j].Start
+		})
+		saveState()
+	}
+	return added
+}
+
+func overlapsDowntime(downtimes []Downtime, d Downtime) bool {
+	for _, other := range downtimes {
+		end := other.End
+		if end == 0 {
+			end = d.End
+		}
+		if d.Start <= end && other.Start <= d.End {
+			return true
+		}
+	}
+	return false
+}
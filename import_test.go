@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadJSONRecords(t *testing.T) {
+	for _, input := range []string{
+		`[{"a": 1}, {"a": 2}]`,
+		"{\"a\": 1}\n{\"a\": 2}\n",
+		"  \n{\"a\": 1} {\"a\": 2}",
+	} {
+		records, err := readJSONRecords(strings.NewReader(input))
+		if err != nil || len(records) != 2 {
+			t.Errorf("readJSONRecords(%q) = %d records, %v", input, len(records), err)
+		}
+	}
+}
+
+func TestImportKuma(t *testing.T) {
+	useTempStore(t, 0)
+
+	now := time.Now().UTC()
+	beat := func(status int, ago time.Duration) string {
+		return fmt.Sprintf(`{"status": %d, "time": %q, "msg": ""}`, status, now.Add(-ago).Format(KUMA_TIME_LAYOUT))
+	}
+	input := "[" + strings.Join([]string{
+		beat(1, 72*time.Hour),
+		beat(0, 71*time.Hour),
+		beat(2, 70*time.Hour+30*time.Minute),
+		beat(1, 70*time.Hour),
+		beat(1, 2*time.Hour),
+		beat(0, time.Hour),
+	}, ",") + "]"
+	path := "kuma.json"
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := importHistory(out, []string{"kuma", path}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "Read 5 checks: 2 added to the history, 1 downtimes added\n" {
+		t.Errorf("output = %q", got)
+	}
+	if len(store.Entries) != 2 || !store.Entries[0].Online || store.Entries[1].Online {
+		t.Errorf("entries = %+v", store.Entries)
+	}
+	want := Downtime{Start: now.Add(-71 * time.Hour).Unix(), End: now.Add(-70 * time.Hour).Unix()}
+	if len(state.Downtimes) != 1 || state.Downtimes[0] != want {
+		t.Errorf("downtimes = %+v, want %+v", state.Downtimes, want)
+	}
+
+	// Importing again adds nothing.
+	out.Reset()
+	if err := importHistory(out, []string{"kuma", path}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "Read 5 checks: 0 added to the history, 0 downtimes added\n" {
+		t.Errorf("second import output = %q", got)
+	}
+}
+
+func TestParseMcstatusRecord(t *testing.T) {
+	entries, err := parseMcstatusRecord([]byte(`{"timestamp": "2024-03-10T12:00:00Z", "online": true, "players": {"online": 1, "max": 20, "list": [{"name": "Steve", "uuid": "x"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !entries[0].Online || entries[0].LastChecked != 1710072000000 || entries[0].Players[0] != "Steve" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestImportIntoSQLiteHistory(t *testing.T) {
+	useSQLiteHistory(t)
+	saved := config
+	t.Cleanup(func() { config = saved })
+	config.HistoryRetention = 30 * 24 * time.Hour
+
+	now := time.Now().UTC()
+	var beats []string
+	for _, ago := range []time.Duration{60 * 24 * time.Hour, 72 * time.Hour, 71 * time.Hour, time.Hour} {
+		beats = append(beats, fmt.Sprintf(`{"status": 1, "time": %q}`, now.Add(-ago).Format(KUMA_TIME_LAYOUT)))
+	}
+	if err := os.WriteFile("kuma.json", []byte("["+strings.Join(beats, ",")+"]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"3 added", "0 added"} {
+		out := new(bytes.Buffer)
+		if err := importHistory(out, []string{"kuma", "kuma.json"}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, want %s", out, want)
+		}
+	}
+	// The one from two months ago is past HISTORY_RETENTION_DAYS.
+	if got := getRange(0, now.UnixMilli()+1); len(got) != 3 {
+		t.Errorf("history holds %d checks, want 3", len(got))
+	}
+}
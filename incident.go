@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Incident is the outage currently being escalated.
+type Incident struct {
+	Start time.Time `json:"start"`
+	// Scheduled incidents started as a scheduled-looking restart; their
+	// ladder starts once such a restart should have been over.
+	Scheduled bool `json:"scheduled,omitempty"`
+	// Steps is how many steps of the ladder have run.
+	Steps int `json:"steps"`
+	// Acked is set once someone took the incident; escalation stops there.
+	Acked *Acknowledgement `json:"acked,omitempty"`
+	// Alerts are the down alerts carrying the Acknowledge button, updated
+	// on acknowledgement and recovery.
+	Alerts []AlertMessage `json:"alerts,omitempty"`
+}
+
+// elapsed is how long the incident has been open, by the wall clock: the
+// monotonic one stops while the host is suspended and is gone once the
+// monitor restarts, and the server stays down through both. A clock set
+// back past Start gives 0 rather than a negative duration.
+func (i *Incident) elapsed(now time.Time) time.Duration {
+	return max(now.Round(0).Sub(i.Start), 0)
+}
+
+// Acknowledgement records who took an incident.
+type Acknowledgement struct {
+	UserID int64     `json:"userId"`
+	Name   string    `json:"name"`
+	Time   time.Time `json:"time"`
+}
+
+// AlertMessage is a sent down alert, with its text so it can be edited.
+type AlertMessage struct {
+	ChatID    string `json:"chatId"`
+	MessageID int64  `json:"messageId"`
+	Text      string `json:"text"`
+}
+
+// openIncident starts a new incident unless one is already open.
+func openIncident(now time.Time, scheduled bool) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.Incident == nil {
+		state.Incident = &Incident{Start: now.Round(0), Scheduled: scheduled}
+		saveState()
+	}
+}
+
+// incidentOpen reports whether the server is in an open incident.
+func incidentOpen() bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.Incident != nil
+}
+
+// incidentKey tells incidents apart in callback data, so a button under an
+// old alert can't acknowledge a newer outage.
+func incidentKey(incident *Incident) string {
+	return strconv.FormatInt(incident.Start.Unix(), 10)
+}
+
+// ackKeyboard is the Acknowledge button for the open incident, nil if there
+// is none.
+func ackKeyboard(lang string) *InlineKeyboardMarkup {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.Incident == nil {
+		return nil
+	}
+	return &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{
+		{Text: tr(lang, "incident.ack_button"), CallbackData: "ack:" + incidentKey(state.Incident)},
+	}}}
+}
+
+// trackAlert remembers a sent down alert of the open incident.
+func trackAlert(chatID string, messageID int64, text string) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.Incident != nil {
+		state.Incident.Alerts = append(state.Incident.Alerts, AlertMessage{ChatID: chatID, MessageID: messageID, Text: text})
+		saveState()
+	}
+}
+
+// handleAckQuery handles a tap on Acknowledge: it records who took the
+// incident, which stops further escalation, and replaces the buttons on
+// every alert with their name.
+func handleAckQuery(ctx context.Context, query *CallbackQuery) {
+	key := query.Data[len("ack:"):]
+	lang := config.Language
+	entry := AuditEntry{Via: "telegram", UserID: query.From.ID, Username: query.From.Username, Command: "ack", Args: key}
+
+	if required, _ := requiredRole("ack"); userRole(&query.From) < required {
+		entry.Result = "denied"
+		recordAudit(entry)
+		answerCallback(ctx, query, tr(lang, "command.denied", required))
+		return
+	}
+
+	state.mu.Lock()
+	incident := state.Incident
+	switch {
+	case incident == nil || incidentKey(incident) != key:
+		state.mu.Unlock()
+		answerCallback(ctx, query, tr(lang, "incident.ack_stale"))
+		return
+	case incident.Acked != nil:
+		state.mu.Unlock()
+		answerCallback(ctx, query, tr(lang, "incident.ack_already", incident.Acked.Name))
+		return
+	}
+	incident.Acked = &Acknowledgement{UserID: query.From.ID, Name: displayName(query.From), Time: time.Now()}
+	saveState()
+	acked := *incident
+	state.mu.Unlock()
+
+	entry.Result = "ok"
+	recordAudit(entry)
+	answerCallback(ctx, query, tr(lang, "incident.ack_done"))
+	log.Printf("Incident acknowledged by %s", acked.Acked.Name)
+
+	updateAlerts(ctx, &acked, nil)
+}
+
+// resolveIncident closes the open incident once the server is back and
+// notes the recovery on its alerts.
+func resolveIncident(ctx context.Context, now time.Time) {
+	state.mu.Lock()
+	incident := state.Incident
+	if incident == nil {
+		state.mu.Unlock()
+		return
+	}
+	state.Incident = nil
+	saveState()
+	state.mu.Unlock()
+
+	minutes := max(int(incident.elapsed(now).Minutes()), 0)
+	updateAlerts(ctx, incident, func(lang string) string {
+		return trn(lang, "incident.resolved", minutes, minutes)
+	})
+}
+
+// updateAlerts rewrites the incident's alerts without their buttons, adding
+// who acknowledged it and, if footer is set, its line.
+func updateAlerts(ctx context.Context, incident *Incident, footer func(lang string) string) {
+	for _, alert := range incident.Alerts {
+		lang := chatSettings(alert.ChatID).language()
+		text := alert.Text
+		if incident.Acked != nil {
+			text += "\n\n" + tr(lang, "incident.acked", escapeHtml(incident.Acked.Name))
+		}
+		if footer != nil {
+			text += "\n" + footer(lang)
+		}
+		if err := telegram.EditMessageText(ctx, alert.ChatID, alert.MessageID, text, nil); err != nil {
+			log.Printf("Error updating alert in %s: %v", alert.ChatID, err)
+		}
+	}
+}
+
+// displayName is @username, or the first name for users without one.
+func displayName(user User) string {
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+	return user.FirstName
+}
+
+func hasEvent(events []Event, kind EventKind) bool {
+	for _, event := range events {
+		if event.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcknowledgeIncident(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+	config.Escalation = []EscalationStep{{15 * time.Minute, "group"}}
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	openIncident(start, false)
+	announce(ctx, "", false, 0, []Event{serverEvent(false, start)})
+
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["reply_markup"] == nil {
+		t.Fatalf("sendMessage calls = %+v, want an alert with a button", sent)
+	}
+	data := "ack:" + incidentKey(state.Incident)
+
+	tap := func(from int64, data string) {
+		handleUpdate(ctx, Update{CallbackQuery: &CallbackQuery{ID: "q", From: User{ID: from, Username: "admin"}, Data: data}})
+	}
+
+	// Viewers can't acknowledge, and stale buttons do nothing.
+	tap(2, data)
+	tap(1, "ack:1")
+	if state.Incident.Acked != nil {
+		t.Fatalf("incident acknowledged: %+v", state.Incident.Acked)
+	}
+
+	tap(1, data)
+	if state.Incident.Acked == nil || state.Incident.Acked.UserID != 1 || state.Incident.Acked.Name != "@admin" {
+		t.Fatalf("acknowledgement = %+v", state.Incident.Acked)
+	}
+	edits := fake.callsTo("editMessageText")
+	if len(edits) != 1 || !strings.Contains(edits[0].Params["text"].(string), "Acknowledged by @admin") {
+		t.Fatalf("editMessageText calls = %+v", edits)
+	}
+
+	// The ladder is over 15 minutes in, but the incident is taken.
+	escalate(ctx, false, false, start.Add(20*time.Minute))
+	if calls := fake.callsTo("sendMessage"); len(calls) != 1 {
+		t.Fatalf("escalated an acknowledged incident: %+v", calls[1:])
+	}
+
+	escalate(ctx, true, false, start.Add(42*time.Minute))
+	if state.Incident != nil {
+		t.Fatalf("incident still open: %+v", state.Incident)
+	}
+	edits = fake.callsTo("editMessageText")
+	if len(edits) != 2 || !strings.HasSuffix(edits[1].Params["text"].(string), "Acknowledged by @admin\n✅ Resolved after 42 minutes.") {
+		t.Fatalf("editMessageText calls = %+v", edits)
+	}
+
+	entries, err := readAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Result != "denied" || entries[1].Result != "ok" {
+		t.Fatalf("audit entries = %+v", entries)
+	}
+}
+
+func TestIncidentElapsedUsesWallClock(t *testing.T) {
+	start := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	incident := &Incident{Start: start}
+
+	// Time the host spent suspended still counts.
+	if got := incident.elapsed(start.Add(8 * time.Hour)); got != 8*time.Hour {
+		t.Errorf("elapsed = %v, want 8h", got)
+	}
+	if got := incident.elapsed(start.Add(-time.Hour)); got != 0 {
+		t.Errorf("elapsed with the clock set back = %v, want 0", got)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// activity remembers when players were last seen online, for picking the
+// check interval.
+var activity struct {
+	mu         sync.Mutex
+	lastPlayer time.Time
+}
+
+// scheduleMu guards the check intervals in config: the settings page
+// rewrites them (see applySchedule) while the scheduler and the bot read
+// them, so read them through currentSchedule.
+var scheduleMu sync.RWMutex
+
+// CheckSchedule is a consistent copy of the check intervals.
+type CheckSchedule struct {
+	Interval, Active, Idle, Incident, IdleAfter time.Duration
+}
+
+func currentSchedule() CheckSchedule {
+	scheduleMu.RLock()
+	defer scheduleMu.RUnlock()
+	return CheckSchedule{
+		Interval:  config.CheckInterval,
+		Active:    config.CheckIntervalActive,
+		Idle:      config.CheckIntervalIdle,
+		Incident:  config.CheckIntervalIncident,
+		IdleAfter: config.IdleAfter,
+	}
+}
+
+// checkInterval is the delay before the next check: CHECK_INTERVAL_ACTIVE
+// while players are online, so joins are announced quickly, and
+// CHECK_INTERVAL_IDLE once the server has been empty for IDLE_AFTER.
+// During an incident CHECK_INTERVAL_INCIDENT wins, so the recovery is
+// announced within seconds. Otherwise, and for whichever of them isn't
+// set, it's CHECK_INTERVAL.
+func checkInterval() time.Duration {
+	return nextCheckInterval(cachedResult(), incidentOpen(), time.Now())
+}
+
+func nextCheckInterval(latest *PingResult, incident bool, now time.Time) time.Duration {
+	schedule := currentSchedule()
+
+	activity.mu.Lock()
+	defer activity.mu.Unlock()
+
+	if incident && schedule.Incident > 0 {
+		return schedule.Incident
+	}
+
+	// Count the monitor starting as activity, so a restart doesn't begin
+	// with slow checks.
+	if activity.lastPlayer.IsZero() {
+		activity.lastPlayer = now
+	}
+	if latest != nil && latest.Err == nil && latest.Status != nil && latest.Status.PlayerCount > 0 {
+		activity.lastPlayer = latest.CheckedAt
+		return positiveOr(schedule.Active, schedule.Interval)
+	}
+	if now.Sub(activity.lastPlayer) >= schedule.IdleAfter {
+		return positiveOr(schedule.Idle, schedule.Interval)
+	}
+	return schedule.Interval
+}
+
+// positiveOr returns d, or fallback when d isn't positive.
+func positiveOr(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextCheckInterval(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	config.CheckInterval = 30 * time.Second
+	config.CheckIntervalActive = 10 * time.Second
+	config.CheckIntervalIdle = 3 * time.Minute
+	config.IdleAfter = 2 * time.Hour
+
+	start := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	activity.lastPlayer = time.Time{}
+	defer func() { activity.lastPlayer = time.Time{} }()
+
+	empty := &PingResult{Status: &ServerStatus{Online: true}, CheckedAt: start}
+	if got := nextCheckInterval(empty, false, start); got != 30*time.Second {
+		t.Errorf("just started: %v, want 30s", got)
+	}
+
+	playing := &PingResult{Status: &ServerStatus{Online: true, PlayerCount: 2}, CheckedAt: start.Add(time.Hour)}
+	if got := nextCheckInterval(playing, false, start.Add(time.Hour)); got != 10*time.Second {
+		t.Errorf("players online: %v, want 10s", got)
+	}
+
+	if got := nextCheckInterval(empty, false, start.Add(2*time.Hour)); got != 30*time.Second {
+		t.Errorf("empty for an hour: %v, want 30s", got)
+	}
+	if got := nextCheckInterval(empty, false, start.Add(3*time.Hour)); got != 3*time.Minute {
+		t.Errorf("empty for two hours: %v, want 3m", got)
+	}
+
+	config.CheckIntervalIdle = 0
+	if got := nextCheckInterval(empty, false, start.Add(3*time.Hour)); got != 30*time.Second {
+		t.Errorf("no idle interval: %v, want 30s", got)
+	}
+
+	config.CheckIntervalIncident = 5 * time.Second
+	down := &PingResult{Err: ErrRefused, CheckedAt: start.Add(3 * time.Hour)}
+	if got := nextCheckInterval(down, true, start.Add(3*time.Hour)); got != 5*time.Second {
+		t.Errorf("during an incident: %v, want 5s", got)
+	}
+	config.CheckIntervalIncident = 0
+	if got := nextCheckInterval(down, true, start.Add(3*time.Hour)); got != 30*time.Second {
+		t.Errorf("incident boost disabled: %v, want 30s", got)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// handleJoinCommand implements /join: how to get on the server the chat
+// follows, whether it's up right now, and the notes admins set with
+// /setjoin.
+func handleJoinCommand(ctx context.Context, msg *Message) {
+	settings := chatSettings(strconv.FormatInt(msg.Chat.ID, 10))
+	lang := replyLanguage(msg)
+
+	address := config.ServerHost
+	if config.ServerPort != MINECRAFT_DEFAULT_PORT {
+		address = net.JoinHostPort(config.ServerHost, strconv.Itoa(int(config.ServerPort)))
+	}
+	var result *PingResult
+	if server := settings.server(); server != "" {
+		address = server
+		result = pingServer(ctx, server)
+	} else {
+		result = cachedStatus(ctx, currentSchedule().Interval)
+	}
+
+	state.mu.Lock()
+	modpack, notes := state.Modpack, state.JoinText
+	state.mu.Unlock()
+	reply(ctx, msg, renderJoin(lang, address, result, modpack, notes))
+}
+
+// renderJoin is the /join reply. modpack and notes are as the admins wrote
+// them.
+func renderJoin(lang, address string, result *PingResult, modpack, notes string) string {
+	lines := []string{tr(lang, "join.header"), tr(lang, "join.address", escapeHtml(address))}
+	if result.Err != nil || result.Status == nil {
+		lines = append(lines, tr(lang, "join.offline"))
+	} else {
+		if result.Status.Version != "" {
+			lines = append(lines, tr(lang, "join.version", escapeHtml(result.Status.Version)))
+		}
+		lines = append(lines, trn(lang, "status.online", result.Status.PlayerCount, result.Status.PlayerCount))
+	}
+	if modpack != "" {
+		lines = append(lines, tr(lang, "join.modpack", escapeHtml(modpack)))
+	}
+	if notes != "" {
+		lines = append(lines, "", escapeHtml(notes))
+	}
+	return joinStrings(lines, "\n")
+}
+
+// handleSetJoinCommand implements /setjoin <text>, which sets the notes
+// /join ends with (a modpack link, the rules), and /setjoin alone, which
+// drops them.
+func handleSetJoinCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+
+	state.mu.Lock()
+	state.JoinText = args
+	saveState()
+	state.mu.Unlock()
+
+	recordAudit(auditCommand(msg, "setjoin", args, "ok"))
+	if args == "" {
+		reply(ctx, msg, tr(lang, "join.cleared"))
+	} else {
+		reply(ctx, msg, tr(lang, "join.set"))
+	}
+}
+
+// handleSetModpackCommand implements /setmodpack <version>, which records
+// the modpack players need for /join and tells the chats when it changed,
+// and /setmodpack alone, which drops it.
+func handleSetModpackCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+
+	state.mu.Lock()
+	changed := state.Modpack != args
+	state.Modpack = args
+	saveState()
+	state.mu.Unlock()
+
+	recordAudit(auditCommand(msg, "setmodpack", args, "ok"))
+	if args == "" {
+		reply(ctx, msg, tr(lang, "modpack.cleared"))
+		return
+	}
+	reply(ctx, msg, tr(lang, "modpack.set"))
+	if changed {
+		broadcast(ctx, "", func(lang string) string {
+			return tr(lang, "modpack.changed", escapeHtml(args))
+		}, nil)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJoinCommand(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+	config.ServerHost, config.ServerPort = "mc.example.com", 25566
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, PlayerCount: 2, Version: "Paper 1.20.4"}, CheckedAt: time.Now()}
+	})
+
+	ctx := context.Background()
+	send := func(from int64, text string) string {
+		handleUpdate(ctx, Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: -42}, From: &User{ID: from}, Text: text,
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	send(2, "/setjoin Modpack: https://example.com/pack")
+	if state.JoinText != "" {
+		t.Fatal("a viewer set the /join text")
+	}
+	send(1, "/setjoin Modpack: <https://example.com/pack>")
+	want := "🎮 <b>How to join</b>\n" +
+		"Address: <code>mc.example.com:25566</code>\n" +
+		"Version: <code>Paper 1.20.4</code>\n" +
+		"🟢 The server is up, 2 players online\n" +
+		"\nModpack: &lt;https://example.com/pack&gt;"
+	if got := send(2, "/join"); got != want {
+		t.Fatalf("/join = %q, want %q", got, want)
+	}
+
+	send(1, "/setjoin")
+	if state.JoinText != "" {
+		t.Fatalf("/join text = %q after clearing it", state.JoinText)
+	}
+}
+
+func TestSetModpackCommand(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+	config.ServerHost, config.ServerPort = "mc.example.com", MINECRAFT_DEFAULT_PORT
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Err: errors.New("connection refused"), CheckedAt: time.Now()}
+	})
+
+	ctx := context.Background()
+	send := func(text string) {
+		handleUpdate(ctx, Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: -42}, From: &User{ID: 1}, Text: text,
+		}})
+	}
+	sentTexts := func() []string {
+		var texts []string
+		for _, call := range fake.callsTo("sendMessage") {
+			texts = append(texts, call.Params["text"].(string))
+		}
+		return texts
+	}
+
+	send("/setmodpack 1.4.2")
+	texts := sentTexts()
+	if len(texts) != 2 || texts[1] != "📦 New modpack version: <b>1.4.2</b>. Update before you connect." {
+		t.Fatalf("messages = %q", texts)
+	}
+	send("/setmodpack 1.4.2")
+	if texts := sentTexts(); len(texts) != 3 {
+		t.Fatalf("setting the same version again announced it: %q", texts)
+	}
+
+	send("/join")
+	texts = sentTexts()
+	want := "🎮 <b>How to join</b>\n" +
+		"Address: <code>mc.example.com</code>\n" +
+		"🔴 The server is down right now.\n" +
+		"Modpack: <b>1.4.2</b>"
+	if got := texts[len(texts)-1]; got != want {
+		t.Fatalf("/join = %q, want %q", got, want)
+	}
+}
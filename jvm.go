@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var jvmHTTP = &http.Client{Timeout: 10 * time.Second}
+
+const (
+	// JVM_WARN_CHECKS is how many checks in a row the heap must stay above
+	// JVM_HEAP_WARN_PERCENT: a full heap just before a collection is normal.
+	JVM_WARN_CHECKS = 3
+	// JVM_WARN_REPEAT keeps the memory pressure warning to once an hour.
+	JVM_WARN_REPEAT = time.Hour
+)
+
+// JVMSample is the memory and garbage collection metrics of one scrape.
+type JVMSample struct {
+	Time      time.Time
+	HeapUsed  float64 // bytes
+	HeapMax   float64 // bytes
+	GCSeconds float64 // total time spent collecting since the JVM started
+}
+
+// jvmSampleMetrics are the names of heap used, heap max and GC time in the
+// JMX exporter and older Prometheus Java clients (jvm_memory_bytes_*) and
+// in newer ones (jvm_memory_*_bytes).
+var jvmSampleMetrics = struct{ used, max, gc []string }{
+	used: []string{"jvm_memory_bytes_used", "jvm_memory_used_bytes"},
+	max:  []string{"jvm_memory_bytes_max", "jvm_memory_max_bytes"},
+	gc:   []string{"jvm_gc_collection_seconds_sum"},
+}
+
+// jvm is the memory pressure watch: the previous sample, for GC time, and
+// how many checks in a row the heap was above the threshold.
+var jvm struct {
+	mu       sync.Mutex
+	previous *JVMSample
+	high     int
+	warnedAt time.Time
+}
+
+// fetchJVMSample scrapes JVM_METRICS_URL; tests swap it for a fake.
+var fetchJVMSample = func(ctx context.Context) (*JVMSample, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", config.JVMMetricsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := jvmHTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JVM metrics: %s", resp.Status)
+	}
+	return parseJVMMetrics(resp.Body)
+}
+
+// parseJVMMetrics reads the heap and GC metrics out of the Prometheus text
+// format. Heap pools are summed; GC time is summed over the collectors.
+func parseJVMMetrics(r io.Reader) (*JVMSample, error) {
+	sample := &JVMSample{}
+	found := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels, value, ok := parsePrometheusLine(line)
+		if !ok {
+			continue
+		}
+		heap := strings.Contains(labels, `area="heap"`)
+		switch {
+		case heap && containsString(jvmSampleMetrics.used, name):
+			sample.HeapUsed += value
+			found = true
+		case heap && containsString(jvmSampleMetrics.max, name) && value > 0:
+			// Pools without a limit report -1.
+			sample.HeapMax += value
+		case containsString(jvmSampleMetrics.gc, name):
+			sample.GCSeconds += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no heap metrics (%s)", strings.Join(jvmSampleMetrics.used, " or "))
+	}
+	return sample, nil
+}
+
+// parsePrometheusLine splits a sample line, `name{labels} value [time]`.
+func parsePrometheusLine(line string) (name, labels string, value float64, ok bool) {
+	rest := line
+	if i := strings.IndexByte(line, '{'); i >= 0 {
+		j := strings.LastIndexByte(line, '}')
+		if j < i {
+			return "", "", 0, false
+		}
+		name, labels, rest = line[:i], line[i+1:j], line[j+1:]
+	} else {
+		var found bool
+		name, rest, found = strings.Cut(line, " ")
+		if !found {
+			return "", "", 0, false
+		}
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", "", 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", "", 0, false
+	}
+	return strings.TrimSpace(name), labels, value, true
+}
+
+// checkJVM scrapes the JVM metrics and warns the admins when the heap
+// stays above JVM_HEAP_WARN_PERCENT for JVM_WARN_CHECKS checks in a row,
+// the sign of a server heading for an OutOfMemoryError.
+func checkJVM(ctx context.Context) {
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	sample, err := fetchJVMSample(fetchCtx)
+	cancel()
+	if err != nil {
+		log.Printf("Error scraping JVM metrics: %v", err)
+		return
+	}
+	sample.Time = time.Now()
+
+	jvm.mu.Lock()
+	previous := jvm.previous
+	jvm.previous = sample
+	if sample.HeapMax > 0 && sample.HeapUsed/sample.HeapMax*100 >= float64(config.JVMHeapWarnPercent) {
+		jvm.high++
+	} else {
+		jvm.high = 0
+	}
+	warn := jvm.high >= JVM_WARN_CHECKS && sample.Time.Sub(jvm.warnedAt) >= JVM_WARN_REPEAT
+	if warn {
+		jvm.warnedAt = sample.Time
+	}
+	jvm.mu.Unlock()
+
+	if warn {
+		log.Printf("JVM heap at %.0f%%", sample.HeapUsed/sample.HeapMax*100)
+		notifyAdmins(ctx, renderJVMWarning(config.Language, previous, sample))
+	}
+}
+
+// gcShare is the part of the time between two samples the JVM spent
+// collecting garbage, and false if it can't be told (a restart resets the
+// counter).
+func gcShare(previous, sample *JVMSample) (float64, bool) {
+	if previous == nil || sample.GCSeconds < previous.GCSeconds {
+		return 0, false
+	}
+	elapsed := sample.Time.Sub(previous.Time).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return (sample.GCSeconds - previous.GCSeconds) / elapsed, true
+}
+
+func renderJVMWarning(lang string, previous, sample *JVMSample) string {
+	percent := sample.HeapUsed / sample.HeapMax * 100
+	text := tr(lang, "jvm.heap_warning", percent, formatBytes(int64(sample.HeapUsed)), formatBytes(int64(sample.HeapMax)))
+	if share, ok := gcShare(previous, sample); ok {
+		text += "\n" + tr(lang, "jvm.gc", share*100)
+	}
+	return text
+}
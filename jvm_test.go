@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJVMMetrics = `# HELP jvm_memory_bytes_used Used bytes of a given JVM memory area.
+# TYPE jvm_memory_bytes_used gauge
+jvm_memory_bytes_used{area="heap",} 3.6507222016E9
+jvm_memory_bytes_used{area="nonheap",} 2.5E8
+# TYPE jvm_memory_bytes_max gauge
+jvm_memory_bytes_max{area="heap",} 4.294967296E9
+jvm_memory_bytes_max{area="nonheap",} -1.0
+# TYPE jvm_gc_collection_seconds summary
+jvm_gc_collection_seconds_count{gc="G1 Young Generation",} 812.0
+jvm_gc_collection_seconds_sum{gc="G1 Young Generation",} 40.5
+jvm_gc_collection_seconds_count{gc="G1 Old Generation",} 2.0
+jvm_gc_collection_seconds_sum{gc="G1 Old Generation",} 1.5
+`
+
+func TestParseJVMMetrics(t *testing.T) {
+	sample, err := parseJVMMetrics(strings.NewReader(testJVMMetrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.HeapUsed != 3.6507222016e9 || sample.HeapMax != 4.294967296e9 || sample.GCSeconds != 42 {
+		t.Errorf("parseJVMMetrics() = %+v", sample)
+	}
+
+	if _, err := parseJVMMetrics(strings.NewReader("up 1\n")); err == nil {
+		t.Error("parseJVMMetrics accepted metrics without the heap")
+	}
+}
+
+func TestCheckJVMWarnsOnSustainedPressure(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminChatID = "-100"
+	config.JVMHeapWarnPercent = 90
+
+	previous := fetchJVMSample
+	defer func() { fetchJVMSample = previous }()
+	jvm.previous, jvm.high, jvm.warnedAt = nil, 0, time.Time{}
+	defer func() { jvm.previous, jvm.high, jvm.warnedAt = nil, 0, time.Time{} }()
+
+	used := 3.0 * (1 << 30)
+	gc := 0.0
+	fetchJVMSample = func(context.Context) (*JVMSample, error) {
+		gc += 0.5
+		return &JVMSample{HeapUsed: used, HeapMax: 4 << 30, GCSeconds: gc}, nil
+	}
+
+	checkJVM(context.Background())
+	used = 3.8 * (1 << 30)
+	for i := 0; i < JVM_WARN_CHECKS-1; i++ {
+		checkJVM(context.Background())
+	}
+	if sent := fake.callsTo("sendMessage"); len(sent) != 0 {
+		t.Fatalf("warned after %d high checks", JVM_WARN_CHECKS-1)
+	}
+	checkJVM(context.Background())
+	checkJVM(context.Background())
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["chat_id"] != "-100" {
+		t.Fatalf("sent %+v, want one warning to the admin chat", sent)
+	}
+	if text, _ := sent[0].Params["text"].(string); !strings.Contains(text, "the heap is 95% full (3.8 GB of 4.0 GB)") {
+		t.Errorf("warning = %q", text)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	// KAFKA_TIMEOUT bounds one produce request, connecting included.
+	KAFKA_TIMEOUT = 10 * time.Second
+
+	kafkaProduceAPIKey     = 0
+	kafkaProduceAPIVersion = 3
+	kafkaClientID          = "lnudorm3-status"
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// publishKafka produces events as JSON records, keyed by event kind, to
+// partition 0 of KAFKA_TOPIC. There's no metadata lookup: KAFKA_BROKERS are
+// tried in order until one accepts the batch, so list the partition
+// leader first or give the topic a single partition. Does nothing without
+// KAFKA_BROKERS.
+func publishKafka(ctx context.Context, events []Event) {
+	if len(config.KafkaBrokers) == 0 || len(events) == 0 {
+		return
+	}
+
+	request, err := kafkaProduceRequest(config.KafkaTopic, events, time.Now())
+	if err != nil {
+		log.Printf("Error encoding Kafka records: %v", err)
+		return
+	}
+
+	for _, broker := range config.KafkaBrokers {
+		if err = kafkaProduce(ctx, broker, request); err == nil {
+			return
+		}
+	}
+	log.Printf("Error publishing events to Kafka: %v", err)
+}
+
+// kafkaProduce sends one encoded Produce request and checks the partition
+// error code in the response.
+func kafkaProduce(ctx context.Context, broker string, request []byte) error {
+	dialer := net.Dialer{Timeout: KAFKA_TIMEOUT}
+	conn, err := dialer.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(phaseDeadline(ctx, KAFKA_TIMEOUT))
+
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	var size int32
+	if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+		return err
+	}
+	if size <= 0 || size > 1<<20 {
+		return fmt.Errorf("invalid response size %d", size)
+	}
+	response := make([]byte, size)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return err
+	}
+	return kafkaProduceError(response)
+}
+
+// kafkaProduceRequest encodes a size-prefixed Produce v3 request carrying
+// events as one record batch.
+func kafkaProduceRequest(topic string, events []Event, now time.Time) ([]byte, error) {
+	batch, err := kafkaRecordBatch(events, now)
+	if err != nil {
+		return nil, err
+	}
+
+	body := new(bytes.Buffer)
+	w := func(v interface{}) { binary.Write(body, binary.BigEndian, v) }
+	w(int16(kafkaProduceAPIKey))
+	w(int16(kafkaProduceAPIVersion))
+	w(int32(1)) // correlation ID; one request per connection
+	kafkaString(body, kafkaClientID)
+	w(int16(-1)) // no transactional ID
+	w(int16(1))  // acks: leader only
+	w(int32(KAFKA_TIMEOUT / time.Millisecond))
+	w(int32(1)) // one topic
+	kafkaString(body, topic)
+	w(int32(1)) // one partition
+	w(int32(0))
+	w(int32(len(batch)))
+	body.Write(batch)
+
+	request := new(bytes.Buffer)
+	binary.Write(request, binary.BigEndian, int32(body.Len()))
+	body.WriteTo(request)
+	return request.Bytes(), nil
+}
+
+// kafkaRecordBatch encodes events as a magic v2 record batch.
+func kafkaRecordBatch(events []Event, now time.Time) ([]byte, error) {
+	records := new(bytes.Buffer)
+	for i, event := range events {
+		value, err := marshalEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		record := new(bytes.Buffer)
+		record.WriteByte(0) // attributes
+		kafkaVarint(record, event.Time.UnixMilli()-now.UnixMilli())
+		kafkaVarint(record, int64(i))
+		kafkaVarint(record, int64(len(event.Kind)))
+		record.WriteString(string(event.Kind))
+		kafkaVarint(record, int64(len(value)))
+		record.Write(value)
+		kafkaVarint(record, 0) // headers
+
+		kafkaVarint(records, int64(record.Len()))
+		record.WriteTo(records)
+	}
+
+	// Everything from attributes on is covered by the CRC.
+	tail := new(bytes.Buffer)
+	w := func(v interface{}) { binary.Write(tail, binary.BigEndian, v) }
+	w(int16(0)) // attributes: no compression
+	w(int32(len(events) - 1))
+	w(now.UnixMilli())
+	w(now.UnixMilli())
+	w(int64(-1)) // producer ID
+	w(int16(-1)) // producer epoch
+	w(int32(-1)) // base sequence
+	w(int32(len(events)))
+	records.WriteTo(tail)
+
+	batch := new(bytes.Buffer)
+	binary.Write(batch, binary.BigEndian, int64(0))            // base offset
+	binary.Write(batch, binary.BigEndian, int32(tail.Len()+9)) // batch length
+	binary.Write(batch, binary.BigEndian, int32(-1))           // partition leader epoch
+	batch.WriteByte(2)                                         // magic
+	binary.Write(batch, binary.BigEndian, crc32.Checksum(tail.Bytes(), crc32c))
+	tail.WriteTo(batch)
+	return batch.Bytes(), nil
+}
+
+// kafkaProduceError reads the error code of the single partition in a
+// Produce v3 response.
+func kafkaProduceError(response []byte) error {
+	r := bytes.NewReader(response)
+	var correlationID, topics, partitions, partition int32
+	var nameLen, code int16
+
+	read := func(v interface{}) error { return binary.Read(r, binary.BigEndian, v) }
+	if err := read(&correlationID); err != nil {
+		return err
+	}
+	if err := read(&topics); err != nil || topics < 1 {
+		return fmt.Errorf("malformed produce response")
+	}
+	if err := read(&nameLen); err != nil || nameLen < 0 {
+		return fmt.Errorf("malformed produce response")
+	}
+	if _, err := r.Seek(int64(nameLen), io.SeekCurrent); err != nil {
+		return err
+	}
+	if err := read(&partitions); err != nil || partitions < 1 {
+		return fmt.Errorf("malformed produce response")
+	}
+	if err := read(&partition); err != nil {
+		return err
+	}
+	if err := read(&code); err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("broker returned error code %d", code)
+	}
+	return nil
+}
+
+func kafkaString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, int16(len(s)))
+	buf.WriteString(s)
+}
+
+// kafkaVarint writes v zigzag-encoded, as record fields are.
+func kafkaVarint(buf *bytes.Buffer, v int64) {
+	var scratch [binary.MaxVarintLen64]byte
+	buf.Write(scratch[:binary.PutVarint(scratch[:], v)])
+}
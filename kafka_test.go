@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestKafkaProduce sends a batch to a fake broker that checks the framing
+// and the record batch CRC, then answers with the given error code.
+func TestKafkaProduce(t *testing.T) {
+	for _, code := range []int16{0, 6} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			var size int32
+			binary.Read(conn, binary.BigEndian, &size)
+			request := make([]byte, size)
+			if _, err := io.ReadFull(conn, request); err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Contains(request, []byte(`{"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","player":"steve"`)) {
+				t.Error("record value missing from request")
+			}
+
+			// The batch is the tail of the request; check its CRC.
+			start := bytes.LastIndex(request, []byte{0xff, 0xff, 0xff, 0xff, 2}) + 4
+			crc := binary.BigEndian.Uint32(request[start+1:])
+			if got := crc32.Checksum(request[start+5:], crc32.MakeTable(crc32.Castagnoli)); got != crc {
+				t.Errorf("batch CRC = %x, want %x", crc, got)
+			}
+
+			response := new(bytes.Buffer)
+			for _, v := range []interface{}{int32(1), int32(1), int16(2), []byte("mc"), int32(1), int32(0), code, int64(0), int64(-1), int32(0)} {
+				binary.Write(response, binary.BigEndian, v)
+			}
+			binary.Write(conn, binary.BigEndian, int32(response.Len()))
+			conn.Write(response.Bytes())
+		}()
+
+		now := time.Now()
+		request, err := kafkaProduceRequest("mc", []Event{{Kind: EventPlayerJoined, Player: "steve", Time: now}}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kafkaProduce(context.Background(), listener.Addr().String(), request)
+		if (err == nil) != (code == 0) {
+			t.Fatalf("error code %d: kafkaProduce() = %v", code, err)
+		}
+	}
+}
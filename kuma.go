@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var kumaHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// pushUptimeKuma reports one check to an Uptime Kuma push monitor. The
+// status, msg and ping query parameters of KUMA_PUSH_URL are replaced with
+// the check's result. Does nothing without KUMA_PUSH_URL.
+func pushUptimeKuma(ctx context.Context, online bool, result *PingResult) {
+	if config.KumaPushURL == "" {
+		return
+	}
+
+	pushURL, err := kumaPushURL(config.KumaPushURL, online, result)
+	if err != nil {
+		log.Printf("Error building Uptime Kuma push URL: %v", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pushURL, nil)
+	if err != nil {
+		log.Printf("Error pushing to Uptime Kuma: %v", err)
+		return
+	}
+	resp, err := kumaHTTP.Do(req)
+	if err != nil {
+		log.Printf("Error pushing to Uptime Kuma: %v", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Error pushing to Uptime Kuma: %s", resp.Status)
+	}
+}
+
+func kumaPushURL(base string, online bool, result *PingResult) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("status", "down")
+	query.Set("ping", "")
+	if online {
+		query.Set("status", "up")
+	}
+
+	switch {
+	case result.Status != nil:
+		latency := result.Status.ConnectTime + result.Status.ProtocolTime
+		query.Set("ping", strconv.FormatInt(latency.Milliseconds(), 10))
+		query.Set("msg", fmt.Sprintf("%d players", result.Status.PlayerCount))
+	case result.Err != nil:
+		query.Set("msg", errorCategory(result.Err))
+	default:
+		query.Set("msg", "OK")
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
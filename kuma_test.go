@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestKumaPushURL(t *testing.T) {
+	result := &PingResult{Status: &ServerStatus{PlayerCount: 3, ConnectTime: 20 * time.Millisecond, ProtocolTime: 15 * time.Millisecond}}
+	got, err := kumaPushURL("https://kuma.local/api/push/abc?status=up&msg=OK&ping=", true, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(got)
+	query := u.Query()
+	if u.Path != "/api/push/abc" || query.Get("status") != "up" || query.Get("ping") != "35" || query.Get("msg") != "3 players" {
+		t.Fatalf("push URL = %s", got)
+	}
+
+	got, _ = kumaPushURL("https://kuma.local/api/push/abc", false, &PingResult{Err: ErrRefused})
+	u, _ = url.Parse(got)
+	if u.Query().Get("status") != "down" || u.Query().Get("msg") != "refused" {
+		t.Fatalf("push URL for a failed check = %s", got)
+	}
+}
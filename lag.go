@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LAG_HISTORY is how long lag spikes are kept, enough for yesterday's
+	// summary.
+	LAG_HISTORY = 48 * time.Hour
+	// LAG_ALERT_WINDOW is the span LAG_ALERT_COUNT spikes must fall in for
+	// the admins to be alerted, and the least time between two alerts.
+	LAG_ALERT_WINDOW = 10 * time.Minute
+	// LAG_DAY_LAYOUT is the local date the daily lag summary covers.
+	LAG_DAY_LAYOUT = "2006-01-02"
+)
+
+// lagPattern matches the server's tick overload warning, e.g. "Can't keep
+// up! Is the server overloaded? Running 5012ms or 100 ticks behind".
+var lagPattern = regexp.MustCompile(`Can't keep up! Is the server overloaded\? Running (\d+)ms or (\d+) ticks behind`)
+
+// LagSpike is one "Can't keep up!" line, with the players online then.
+type LagSpike struct {
+	Time    int64 `json:"time"`    // Unix seconds
+	Behind  int64 `json:"behind"`  // milliseconds
+	Players int   `json:"players"` // as of the latest check
+}
+
+// readServerLog reads LOG_PATH, over SFTP when SFTP_ADDR is set; tests swap
+// it for a fake.
+var readServerLog = func(ctx context.Context) ([]byte, error) {
+	if config.SFTPAddr != "" {
+		return sftpReadFile(ctx, config.LogPath)
+	}
+	return os.ReadFile(config.LogPath)
+}
+
+// logTail is how far into the log the last read got. It isn't saved: after
+// a restart the tail starts again at the end of the log.
+var logTail struct {
+	mu      sync.Mutex
+	offset  int
+	started bool
+}
+
+// tailServerLog reads what was added to the server log since the last
+// call and looks for lag spikes in it. A log shorter than before was
+// rotated and is read from the start.
+func tailServerLog(ctx context.Context) {
+	readCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	data, err := readServerLog(readCtx)
+	cancel()
+	if err != nil {
+		log.Printf("Error reading the server log: %v", err)
+		return
+	}
+
+	logTail.mu.Lock()
+	if !logTail.started {
+		logTail.started = true
+		logTail.offset = len(data)
+	}
+	if len(data) < logTail.offset {
+		logTail.offset = 0
+	}
+	added := data[logTail.offset:]
+	// A line still being written is left for the next read.
+	end := bytes.LastIndexByte(added, '\n') + 1
+	logTail.offset += end
+	logTail.mu.Unlock()
+
+	now := time.Now()
+	recordLagSpikes(ctx, parseLagSpikes(string(added[:end]), now), now)
+}
+
+// parseLagSpikes finds the lag spikes in lines of the log read at now.
+func parseLagSpikes(lines string, now time.Time) []LagSpike {
+	players := 0
+	if latest := getLatest(); latest != nil && latest.Online {
+		players = max(latest.PlayerCount, len(latest.Players))
+	}
+	var spikes []LagSpike
+	for _, line := range strings.Split(lines, "\n") {
+		match := lagPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		behind, _ := strconv.ParseInt(match[1], 10, 64)
+		spikes = append(spikes, LagSpike{Time: now.Unix(), Behind: behind, Players: players})
+	}
+	return spikes
+}
+
+// recordLagSpikes adds spikes to the history, alerts the admins when
+// LAG_ALERT_COUNT of them fell within LAG_ALERT_WINDOW, and sends them
+// yesterday's summary on the first call of a day.
+func recordLagSpikes(ctx context.Context, spikes []LagSpike, now time.Time) {
+	state.mu.Lock()
+	history := append(state.LagSpikes, spikes...)
+	cutoff := now.Add(-LAG_HISTORY).Unix()
+	for len(history) > 0 && history[0].Time < cutoff {
+		history = history[1:]
+	}
+	state.LagSpikes = history
+
+	var recent []LagSpike
+	for _, spike := range history {
+		if spike.Time > now.Add(-LAG_ALERT_WINDOW).Unix() {
+			recent = append(recent, spike)
+		}
+	}
+	alert := len(spikes) > 0 && config.LagAlertCount > 0 && len(recent) >= config.LagAlertCount &&
+		now.Sub(time.Unix(state.LagAlertedAt, 0)) >= LAG_ALERT_WINDOW
+	if alert {
+		state.LagAlertedAt = now.Unix()
+	}
+
+	var yesterday []LagSpike
+	today := now.Format(LAG_DAY_LAYOUT)
+	summary := state.LagSummaryDay != "" && state.LagSummaryDay != today
+	if summary {
+		day := now.AddDate(0, 0, -1).Format(LAG_DAY_LAYOUT)
+		for _, spike := range history {
+			if time.Unix(spike.Time, 0).Format(LAG_DAY_LAYOUT) == day {
+				yesterday = append(yesterday, spike)
+			}
+		}
+	}
+	newDay := state.LagSummaryDay != today
+	state.LagSummaryDay = today
+	if len(spikes) > 0 || newDay || alert {
+		saveState()
+	}
+	state.mu.Unlock()
+
+	if alert {
+		log.Printf("%d lag spikes in the last %v", len(recent), LAG_ALERT_WINDOW)
+		notifyAdmins(ctx, renderLagAlert(config.Language, recent))
+	}
+	if summary && len(yesterday) > 0 {
+		notifyAdmins(ctx, renderLagSummary(config.Language, yesterday))
+	}
+}
+
+func renderLagAlert(lang string, spikes []LagSpike) string {
+	count := len(spikes)
+	minutes := int(LAG_ALERT_WINDOW / time.Minute)
+	return trn(lang, "lag.alert", count, count, minutes) + "\n" + renderLagStats(lang, spikes)
+}
+
+// renderLagSummary sums up a day of lag spikes for the daily summary.
+func renderLagSummary(lang string, spikes []LagSpike) string {
+	count := len(spikes)
+	return trn(lang, "lag.summary", count, count) + "\n" + renderLagStats(lang, spikes)
+}
+
+// renderLagStats correlates spikes with the players online during them.
+func renderLagStats(lang string, spikes []LagSpike) string {
+	var behind int64
+	players, most := 0, 0
+	for _, spike := range spikes {
+		behind += spike.Behind
+		players += spike.Players
+		most = max(most, spike.Players)
+	}
+	average := float64(players) / float64(len(spikes))
+	return tr(lang, "lag.stats", float64(behind)/1000, average, most)
+}
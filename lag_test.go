@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailServerLogAlerts(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminChatID = "-100"
+	config.LagAlertCount = 2
+	insertStatus(StatusEntry{LastChecked: time.Now().UnixMilli(), Online: true, PlayerCount: 7})
+
+	previous := readServerLog
+	defer func() { readServerLog = previous }()
+	logTail.started, logTail.offset = false, 0
+	defer func() { logTail.started, logTail.offset = false, 0 }()
+	serverLog := "[12:00:00] [Server thread/WARN]: Can't keep up! Is the server overloaded? Running 9000ms or 180 ticks behind\n"
+	readServerLog = func(context.Context) ([]byte, error) { return []byte(serverLog), nil }
+
+	// What was logged before the first read is skipped.
+	tailServerLog(context.Background())
+	if len(state.LagSpikes) != 0 {
+		t.Fatalf("spikes from before the first read: %+v", state.LagSpikes)
+	}
+
+	line := "[12:01:00] [Server thread/WARN]: Can't keep up! Is the server overloaded? Running 2500ms or 50 ticks behind\n"
+	serverLog += line + line[:20]
+	tailServerLog(context.Background())
+	if len(fake.callsTo("sendMessage")) != 0 {
+		t.Fatal("alerted after one spike")
+	}
+
+	// The half-written line is read once it's finished.
+	serverLog += line[20:]
+	tailServerLog(context.Background())
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["chat_id"] != "-100" {
+		t.Fatalf("sent %+v, want one alert to the admin chat", sent)
+	}
+	want := "2 lag spikes in the last 10 min.\n5.0 s behind in total; 7.0 players online on average during them, 7 at most"
+	if text, _ := sent[0].Params["text"].(string); !strings.Contains(text, want) {
+		t.Errorf("alert %q doesn't contain %q", text, want)
+	}
+
+	// A rotated log is read from the start; the alert isn't repeated yet.
+	serverLog = line
+	tailServerLog(context.Background())
+	if len(state.LagSpikes) != 3 {
+		t.Errorf("%d spikes after the rotation, want 3", len(state.LagSpikes))
+	}
+	if len(fake.callsTo("sendMessage")) != 1 {
+		t.Error("alerted again within LAG_ALERT_WINDOW")
+	}
+}
+
+func TestRecordLagSpikesDailySummary(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminChatID = "-100"
+	config.LagAlertCount = 0
+
+	now := time.Date(2024, 3, 2, 0, 5, 0, 0, time.Local)
+	state.LagSummaryDay = "2024-03-01"
+	state.LagSpikes = []LagSpike{
+		{Time: now.Add(-30 * time.Hour).Unix(), Behind: 1000, Players: 1},
+		{Time: now.Add(-2 * time.Hour).Unix(), Behind: 3000, Players: 4},
+		{Time: now.Add(-time.Hour).Unix(), Behind: 2000, Players: 2},
+	}
+
+	recordLagSpikes(context.Background(), nil, now)
+	recordLagSpikes(context.Background(), nil, now.Add(time.Hour))
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want one summary", len(sent))
+	}
+	want := "🐢 <b>Lag yesterday</b>: 2 \"Can't keep up!\"s\n5.0 s behind in total; 3.0 players online on average during them, 4 at most"
+	if text := sent[0].Params["text"]; text != want {
+		t.Errorf("summary:\n%s\nwant:\n%s", text, want)
+	}
+}
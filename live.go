@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+)
+
+// LIVE_STATUS modes.
+const (
+	// LIVE_OFF: only event messages.
+	LIVE_OFF = "off"
+	// LIVE_ON: a pinned live status message next to the event messages.
+	LIVE_ON = "on"
+	// LIVE_ONLY: the pinned message instead of event messages.
+	LIVE_ONLY = "only"
+)
+
+var liveModes = []string{LIVE_OFF, LIVE_ON, LIVE_ONLY}
+
+// updateLiveMessages edits the pinned status message in every chat
+// following SERVER_HOST to show the latest check. A chat without one, or
+// whose message was deleted, gets a new one, pinned silently.
+func updateLiveMessages(ctx context.Context) {
+	if config.LiveStatus != LIVE_ON && config.LiveStatus != LIVE_ONLY {
+		return
+	}
+	latest := getLatest()
+	if latest == nil {
+		return
+	}
+
+	for _, chatID := range servedChats() {
+		settings := chatSettings(chatID)
+		if settings.server() != "" {
+			continue
+		}
+		text := renderLiveStatus(settings.language(), latest)
+
+		state.mu.Lock()
+		messageID := state.LiveMessages[chatID]
+		state.mu.Unlock()
+		if messageID != 0 {
+			err := telegram.EditMessageText(ctx, chatID, messageID, text, nil)
+			if err == nil || telegramErrorContains(err, "message is not modified") {
+				continue
+			}
+			if !telegramErrorContains(err, "message to edit not found") {
+				log.Printf("Error updating the live status in %s: %v", chatID, err)
+				continue
+			}
+		}
+
+		sent, err := telegram.SendMessage(ctx, chatID, text, &MessageOptions{DisableNotification: true})
+		if err != nil {
+			log.Printf("Error sending the live status to %s: %v", chatID, err)
+			continue
+		}
+		state.mu.Lock()
+		if state.LiveMessages == nil {
+			state.LiveMessages = map[string]int64{}
+		}
+		state.LiveMessages[chatID] = sent.MessageID
+		saveState()
+		state.mu.Unlock()
+		if err := telegram.PinChatMessage(ctx, chatID, sent.MessageID, true); err != nil {
+			log.Printf("Error pinning the live status in %s: %v", chatID, err)
+		}
+	}
+}
+
+// telegramErrorContains reports whether err is a Bot API error whose
+// description mentions text.
+func telegramErrorContains(err error, text string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.Description, text)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveStatusMessage(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.LiveStatus = LIVE_ONLY
+	players := []string{"steve"}
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, PlayerCount: len(players), Players: players}, CheckedAt: time.Now()}
+	})
+	ctx := context.Background()
+
+	checkServer(ctx)
+	if sent := fake.callsTo("sendMessage"); len(sent) != 1 || !strings.Contains(sent[0].Params["text"].(string), "<b>steve</b>") {
+		t.Fatalf("sendMessage calls = %+v, want only the live status", sent)
+	}
+	if pinned := fake.callsTo("pinChatMessage"); len(pinned) != 1 || pinned[0].Params["disable_notification"] != true {
+		t.Fatalf("pinChatMessage calls = %+v", pinned)
+	}
+
+	// steve leaving edits the pinned message instead of announcing it.
+	players = []string{}
+	checkServer(ctx)
+	edits := fake.callsTo("editMessageText")
+	if len(edits) != 1 || strings.Contains(edits[0].Params["text"].(string), "steve") || len(fake.callsTo("sendMessage")) != 1 {
+		t.Fatalf("editMessageText calls = %+v", edits)
+	}
+
+	// Someone deleted it: a new one replaces it.
+	fake.fail["editMessageText"] = "Bad Request: message to edit not found"
+	updateLiveMessages(ctx)
+	if sent := fake.callsTo("sendMessage"); len(sent) != 2 {
+		t.Errorf("sendMessage calls = %+v, want a new live status", sent)
+	}
+	if id := state.LiveMessages["-42"]; id != 2 {
+		t.Errorf("live message = %d, want the new one", id)
+	}
+}
@@ -1,17 +1,16 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net"
-	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 )
 
@@ -22,45 +21,391 @@ const (
 	CLEANUP_INTERVAL = 24 * time.Hour
 	ONE_DAY_IN_MS    = 24 * 60 * 60 * 1000
 	JSON_FILE        = "status.json"
+	JOURNAL_FILE     = "status.journal"
 	TIMEOUT          = 3 * time.Second
-)
-
-type ServerStatus struct {
-	Online      bool
-	PlayerCount int
-	Players     []string
-}
-
-type StatusEntry struct {
-	ID          int64    `json:"id"`
-	Online      bool     `json:"online"`
-	LastChecked int64    `json:"lastChecked"`
-	Players     []string `json:"players"`
-}
+	DIAL_TIMEOUT     = 2 * time.Second
+	WRITE_TIMEOUT    = 1 * time.Second
+	READ_TIMEOUT     = 2 * time.Second
 
-type StatusStore struct {
-	Entries []StatusEntry `json:"entries"`
-	mu      sync.RWMutex
-}
+	// One day of checks; past this the snapshot is cheaper than replaying.
+	JOURNAL_COMPACT_ENTRIES = 2880
+)
 
 type Config struct {
-	ServerHost     string
-	ServerPort     uint16
+	ServerHost string
+	ServerPort uint16
+	// OnlinePolicy is what counts as the server being online, one of
+	// onlinePolicies; chats can pick their own.
+	OnlinePolicy string
+	// OfflineThreshold failed checks in a row take the server down, and
+	// OnlineThreshold successful ones bring it back up.
+	OfflineThreshold int
+	OnlineThreshold  int
+	// SLAs are the uptime expectations the admins get a weekly report on.
+	SLAs []SLA
+	// DailyChart posts the last day's player chart to the chats every day
+	// at DailyChartAt, an offset from local midnight.
+	DailyChart   bool
+	DailyChartAt time.Duration
+	// LiveStatus is whether chats get a pinned message with the live
+	// status, one of liveModes.
+	LiveStatus string
+	// BotDescription keeps the bot's description and bio showing the
+	// latest check.
+	BotDescription bool
 	TelegramToken  string
 	TelegramChatID string
+	// ChatRoutes are more chats to post to, each with the events it gets
+	// (TELEGRAM_CHATS); TELEGRAM_CHAT_ID defaults to the first of them.
+	ChatRoutes  []ChatRoute
+	AdminChatID string
+	// AdminIDs are the Telegram user IDs allowed to run admin commands.
+	AdminIDs []int64
+	// OperatorIDs and ViewerIDs get those roles unless /grant says
+	// otherwise; everyone else gets DefaultRole.
+	OperatorIDs []int64
+	ViewerIDs   []int64
+	DefaultRole Role
+	// CommandRoles overrides the role a command needs, by command name.
+	CommandRoles map[string]string
+	// CommandUserLimit and CommandChatLimit cap commands per user and per
+	// chat within COMMAND_LIMIT_WINDOW; 0 disables the cap.
+	CommandUserLimit int
+	CommandChatLimit int
+
+	// AnomalyDrop is the player count whose sudden drop to zero on a
+	// reachable server gets a note; AnomalyZ is how many standard
+	// deviations from the hour-of-week baseline do. 0 disables either.
+	AnomalyDrop int
+	AnomalyZ    float64
+
+	// Escalation is the ladder walked while the server stays down.
+	Escalation    []EscalationStep
+	PushoverToken string
+	PushoverUser  string
+	SMTPAddr      string
+	SMTPUsername  string
+	SMTPPassword  string
+	SMTPFrom      string
+	AlertEmailTo  []string
+
+	// StartupSummary posts the server's state when the monitor starts.
+	StartupSummary bool
+	// ShutdownNotice tells the chats monitoring is paused when the monitor
+	// stops.
+	ShutdownNotice bool
+
+	// The release feed is checked every UpdateCheckInterval; zero
+	// disables the check.
+	UpdateFeedURL       string
+	UpdateCheckInterval time.Duration
+
+	// Templates replace built-in messages in every language and every
+	// chat without its own, by key (see chatTemplateKeys).
+	Templates map[string]string
+
+	// StagingChatID gets a [TEST] copy of every message, or with
+	// StagingMode "redirect" gets them instead of the real chats.
+	StagingChatID string
+	StagingMode   string
+
+	// SecondaryServer (host:port), e.g. a backup server, is compared by
+	// /compare and suggested in down alerts while it's up.
+	SecondaryServer string
+
+	// server.properties is read over SFTP from SFTPAddr every
+	// PropertiesCheckInterval (zero disables it); changes to
+	// WatchedProperties are reported to the admins.
+	SFTPAddr                string
+	SFTPUser                string
+	SFTPPassword            string
+	SFTPKeyFile             string
+	SFTPHostKey             string
+	ServerPropertiesPath    string
+	WatchedProperties       []string
+	PropertiesCheckInterval time.Duration
+	// WorldPath is measured over SSH every WorldCheckInterval for the
+	// weekly world size summary; the admins are warned when the disk is
+	// projected to fill up within WorldDiskWarnDays.
+	WorldPath          string
+	WorldCheckInterval time.Duration
+	WorldDiskWarnDays  int
+	// LogPath is the server log, read over SFTP when SFTPAddr is set and
+	// locally otherwise, tailed every LogTailInterval for lag spikes; the
+	// admins are alerted when LagAlertCount of them come within
+	// LAG_ALERT_WINDOW.
+	LogPath         string
+	LogTailInterval time.Duration
+	LagAlertCount   int
+	// CrashReportsPath is the server's crash-reports folder, read like
+	// LogPath every CrashCheckInterval; new reports go to the admins.
+	CrashReportsPath   string
+	CrashCheckInterval time.Duration
+	// JVMMetricsURL is a Prometheus endpoint with the server's JVM metrics
+	// (the JMX exporter's, or a metrics plugin's), scraped every
+	// JVMCheckInterval; the admins are warned when the heap stays above
+	// JVMHeapWarnPercent.
+	JVMMetricsURL      string
+	JVMCheckInterval   time.Duration
+	JVMHeapWarnPercent int
+
+	HTTPAddr string
+	// WebhookURLs get every event as an EventPayload.
+	WebhookURLs []string
+	// DiscordWebhookURL gets the Telegram messages as Discord embeds.
+	DiscordWebhookURL string
+	// MiniAppURL is where Telegram reaches /app, the Mini App the bot's
+	// menu button opens; Telegram wants HTTPS.
+	MiniAppURL string
+	// DiscordBotToken runs the Discord bot, with slash commands and the
+	// player count as its presence.
+	DiscordBotToken string
+	// APIToken guards the admin and settings pages (see adminAuthorized)
+	// and, as a bearer token, the event webhook and the /api endpoints
+	// that act (forget, preview). The read-only /api endpoints are public.
+	APIToken string
+	// AlertmanagerFilter holds the labels an Alertmanager alert must have
+	// to be relayed; empty relays everything.
+	AlertmanagerFilter map[string]string
+	AlertmanagerToken  string
+
+	GrafanaURL          string
+	GrafanaAPIKey       string
+	GrafanaDashboardUID string
+	GrafanaTags         []string
+
+	KumaPushURL string
+
+	StatsdAddr      string
+	StatsdPrefix    string
+	StatsdTags      []string
+	StatsdDogStatsd bool
+
+	// MetricsTextfile is where metrics are written for node_exporter's
+	// textfile collector after each check; empty disables it.
+	MetricsTextfile string
+
+	// StoreBackend is "file", "redis" or "sqlite"; the redis backend keeps
+	// the status history and bot state in REDIS_URL under RedisPrefix, the
+	// sqlite one in the SQLitePath database, where checks are kept for
+	// HistoryRetention instead of a day.
+	StoreBackend     string
+	RedisURL         string
+	RedisPrefix      string
+	SQLitePath       string
+	HistoryRetention time.Duration
+	// Writes slower than StoreSlowWrite, or a history larger than
+	// StoreMaxSize (bytes), make the admins consider another backend;
+	// zero disables either warning.
+	StoreSlowWrite time.Duration
+	StoreMaxSize   int64
+
+	// ProbeTransport is "slp" (the Server List Ping), "query" (the Query
+	// protocol) or the experimental "http", which reads the same JSON as
+	// the ping from ProbeURL.
+	ProbeTransport string
+	ProbeURL       string
+	// RCONAddr (host:port) and RCONPassword reach the server's RCON, for
+	// the features that talk to the game; empty disables them.
+	RCONAddr     string
+	RCONPassword string
+	// RCONCommands are the server commands admins may run from the chat.
+	RCONCommands []string
+	// RestartSchedule are the daily times the server restarts, as offsets
+	// from midnight; RestartWarnings are how long before each one the
+	// chats are warned, longest first, and RestartRCONSay also warns the
+	// players in game.
+	RestartSchedule []time.Duration
+	RestartWarnings []time.Duration
+	RestartRCONSay  bool
+	// PingPong follows the status exchange with the Ping packet and
+	// reports its round trip as the latency.
+	PingPong bool
+	// QueryPort is the server's query.port. With "slp" a non-zero one adds
+	// a query after each ping for the full player list; "query" uses the
+	// server's port when it's zero.
+	QueryPort uint16
+	// RedisChannel is where events are published; empty disables it.
+	RedisChannel string
+
+	NATSURL     string
+	NATSSubject string
+	// KafkaBrokers are host:port addresses tried in order; empty disables
+	// Kafka publishing.
+	KafkaBrokers []string
+	KafkaTopic   string
+
+	// Backups go to S3Bucket on S3Endpoint every BackupInterval; only the
+	// newest BackupRetention are kept. Empty S3Bucket disables them. The
+	// SQLite backend has none (see checkSQLiteOptions).
+	S3Endpoint      string
+	S3Region        string
+	S3Bucket        string
+	S3Prefix        string
+	S3AccessKey     string
+	S3SecretKey     string
+	BackupInterval  time.Duration
+	BackupRetention int
+
+	// StorageKey (or the contents of StorageKeyFile) is a base64 AES-256
+	// key; when set, everything persisted is encrypted with it. The SQLite
+	// backend can't be (see checkSQLiteOptions).
+	StorageKey     string
+	StorageKeyFile string
+
+	SaveInterval  time.Duration
+	Language      string
+	CheckInterval time.Duration
+	// CheckIntervalActive replaces CheckInterval while players are online,
+	// CheckIntervalIdle once nobody has played for IdleAfter; zero keeps
+	// CheckInterval.
+	CheckIntervalActive time.Duration
+	CheckIntervalIdle   time.Duration
+	IdleAfter           time.Duration
+	// CheckIntervalIncident replaces all of them while an incident is
+	// open; zero disables the boost.
+	CheckIntervalIncident time.Duration
+	// AddressCacheTTL is how long a resolved server address is reused
+	// before resolving it again; 0 resolves on every ping.
+	AddressCacheTTL time.Duration
 }
 
 var (
-	store  *StatusStore
-	config Config
+	store    = &StatusStore{Entries: []StatusEntry{}}
+	config   Config
+	telegram *TelegramClient
 )
 
-func init() {
+func loadConfig() {
 	config = Config{
-		ServerHost:     getEnv("SERVER_HOST", ""),
-		ServerPort:     uint16(getEnvInt("SERVER_PORT", 25565)),
-		TelegramToken:  getEnv("TELEGRAM_BOT_TOKEN", ""),
-		TelegramChatID: getEnv("TELEGRAM_CHAT_ID", ""),
+		ServerHost:              getEnv("SERVER_HOST", ""),
+		ServerPort:              uint16(getEnvInt("SERVER_PORT", MINECRAFT_DEFAULT_PORT)),
+		OnlinePolicy:            getEnv("ONLINE_POLICY", ONLINE_REACHABLE),
+		OfflineThreshold:        getEnvInt("OFFLINE_THRESHOLD", 1),
+		OnlineThreshold:         getEnvInt("ONLINE_THRESHOLD", 1),
+		LiveStatus:              getEnv("LIVE_STATUS", LIVE_OFF),
+		BotDescription:          getEnv("BOT_DESCRIPTION", "") == "true",
+		TelegramToken:           getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:          getEnv("TELEGRAM_CHAT_ID", ""),
+		AdminChatID:             getEnv("TELEGRAM_ADMIN_CHAT_ID", ""),
+		AdminIDs:                parseIDList(getEnv("TELEGRAM_ADMIN_IDS", "")),
+		OperatorIDs:             parseIDList(getEnv("TELEGRAM_OPERATOR_IDS", "")),
+		ViewerIDs:               parseIDList(getEnv("TELEGRAM_VIEWER_IDS", "")),
+		CommandRoles:            parseLabelFilter(getEnv("COMMAND_ROLES", "")),
+		CommandUserLimit:        getEnvInt("COMMAND_USER_LIMIT", 5),
+		CommandChatLimit:        getEnvInt("COMMAND_CHAT_LIMIT", 20),
+		AnomalyDrop:             getEnvInt("ANOMALY_DROP", 5),
+		AnomalyZ:                float64(getEnvInt("ANOMALY_Z", 4)),
+		PushoverToken:           getEnv("PUSHOVER_TOKEN", ""),
+		PushoverUser:            getEnv("PUSHOVER_USER", ""),
+		SMTPAddr:                getEnv("SMTP_ADDR", ""),
+		SMTPUsername:            getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:            getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                getEnv("SMTP_FROM", ""),
+		AlertEmailTo:            splitList(getEnv("ALERT_EMAIL_TO", "")),
+		StartupSummary:          getEnv("STARTUP_SUMMARY", "true") != "false",
+		ShutdownNotice:          getEnv("SHUTDOWN_NOTICE", "") == "true",
+		UpdateFeedURL:           getEnv("UPDATE_FEED_URL", UPDATE_FEED_URL),
+		UpdateCheckInterval:     time.Duration(getEnvInt("UPDATE_CHECK_INTERVAL", 24)) * time.Hour,
+		StagingChatID:           getEnv("STAGING_CHAT_ID", ""),
+		StagingMode:             getEnv("STAGING_MODE", STAGING_MIRROR),
+		SecondaryServer:         getEnv("SECONDARY_SERVER", ""),
+		SFTPAddr:                getEnv("SFTP_ADDR", ""),
+		SFTPUser:                getEnv("SFTP_USER", ""),
+		SFTPPassword:            getEnv("SFTP_PASSWORD", ""),
+		SFTPKeyFile:             getEnv("SFTP_KEY_FILE", ""),
+		SFTPHostKey:             getEnv("SFTP_HOST_KEY", ""),
+		ServerPropertiesPath:    getEnv("SERVER_PROPERTIES_PATH", "server.properties"),
+		WatchedProperties:       splitList(getEnv("WATCHED_PROPERTIES", "difficulty,white-list,enforce-whitelist,max-players")),
+		PropertiesCheckInterval: time.Duration(getEnvInt("PROPERTIES_CHECK_INTERVAL", 10)) * time.Minute,
+		WorldPath:               getEnv("WORLD_PATH", ""),
+		WorldCheckInterval:      time.Duration(getEnvInt("WORLD_CHECK_INTERVAL", 6)) * time.Hour,
+		WorldDiskWarnDays:       getEnvInt("WORLD_DISK_WARN_DAYS", 14),
+		LogPath:                 getEnv("LOG_PATH", ""),
+		LogTailInterval:         time.Duration(getEnvInt("LOG_TAIL_INTERVAL", 30)) * time.Second,
+		LagAlertCount:           getEnvInt("LAG_ALERT_COUNT", 5),
+		CrashReportsPath:        getEnv("CRASH_REPORTS_PATH", ""),
+		CrashCheckInterval:      time.Duration(getEnvInt("CRASH_CHECK_INTERVAL", 60)) * time.Second,
+		JVMMetricsURL:           getEnv("JVM_METRICS_URL", ""),
+		JVMCheckInterval:        time.Duration(getEnvInt("JVM_CHECK_INTERVAL", 60)) * time.Second,
+		JVMHeapWarnPercent:      getEnvInt("JVM_HEAP_WARN_PERCENT", 90),
+		WebhookURLs:             splitList(getEnv("WEBHOOK_URLS", "")),
+		DiscordWebhookURL:       getEnv("DISCORD_WEBHOOK_URL", ""),
+		DiscordBotToken:         getEnv("DISCORD_BOT_TOKEN", ""),
+		MiniAppURL:              getEnv("MINI_APP_URL", ""),
+		HTTPAddr:                getEnv("HTTP_ADDR", ""),
+		APIToken:                getEnv("API_TOKEN", ""),
+		AlertmanagerFilter:      parseLabelFilter(getEnv("ALERTMANAGER_FILTER", "")),
+		AlertmanagerToken:       getEnv("ALERTMANAGER_TOKEN", ""),
+		GrafanaURL:              getEnv("GRAFANA_URL", ""),
+		GrafanaAPIKey:           getEnv("GRAFANA_API_KEY", ""),
+		GrafanaDashboardUID:     getEnv("GRAFANA_DASHBOARD_UID", ""),
+		GrafanaTags:             splitList(getEnv("GRAFANA_TAGS", "minecraft,downtime")),
+		KumaPushURL:             getEnv("KUMA_PUSH_URL", ""),
+		StatsdAddr:              getEnv("STATSD_ADDR", ""),
+		StatsdPrefix:            getEnv("STATSD_PREFIX", "minecraft."),
+		StatsdTags:              splitList(getEnv("STATSD_TAGS", "")),
+		StatsdDogStatsd:         getEnv("STATSD_DOGSTATSD", "") == "true",
+		MetricsTextfile:         getEnv("METRICS_TEXTFILE", ""),
+		StoreBackend:            getEnv("STORE_BACKEND", "file"),
+		SQLitePath:              getEnv("SQLITE_PATH", "status.db"),
+		HistoryRetention:        time.Duration(getEnvInt("HISTORY_RETENTION_DAYS", 90)) * 24 * time.Hour,
+		StoreSlowWrite:          time.Duration(getEnvInt("STORE_SLOW_WRITE_MS", 1000)) * time.Millisecond,
+		StoreMaxSize:            int64(getEnvInt("STORE_MAX_SIZE_MB", 50)) << 20,
+		ProbeTransport:          getEnv("PROBE_TRANSPORT", "slp"),
+		QueryPort:               uint16(getEnvInt("QUERY_PORT", 0)),
+		PingPong:                getEnv("PING_PONG", "") == "true",
+		RCONAddr:                getEnv("RCON_ADDR", ""),
+		RCONPassword:            getEnv("RCON_PASSWORD", ""),
+		RCONCommands:            splitList(getEnv("RCON_COMMANDS", "list,say,whitelist")),
+		RestartRCONSay:          getEnv("RESTART_RCON_SAY", "") == "true",
+		ProbeURL:                getEnv("PROBE_URL", ""),
+		RedisURL:                getEnv("REDIS_URL", ""),
+		RedisPrefix:             getEnv("REDIS_PREFIX", "lnudorm3:"),
+		RedisChannel:            getEnv("REDIS_CHANNEL", ""),
+		NATSURL:                 getEnv("NATS_URL", ""),
+		NATSSubject:             getEnv("NATS_SUBJECT", "minecraft.events"),
+		KafkaBrokers:            splitList(getEnv("KAFKA_BROKERS", "")),
+		KafkaTopic:              getEnv("KAFKA_TOPIC", "minecraft-events"),
+		S3Endpoint:              getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+		S3Region:                getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:                getEnv("S3_BUCKET", ""),
+		S3Prefix:                getEnv("S3_PREFIX", "backups/"),
+		S3AccessKey:             getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:             getEnv("S3_SECRET_KEY", ""),
+		BackupInterval:          time.Duration(getEnvInt("BACKUP_INTERVAL", 24)) * time.Hour,
+		BackupRetention:         getEnvInt("BACKUP_RETENTION", 14),
+		StorageKey:              getEnv("STORAGE_KEY", ""),
+		StorageKeyFile:          getEnv("STORAGE_KEY_FILE", ""),
+		SaveInterval:            time.Duration(getEnvInt("SAVE_INTERVAL", 60)) * time.Second,
+		Language:                getEnv("BOT_LANGUAGE", DEFAULT_LANGUAGE),
+		CheckInterval:           time.Duration(getEnvInt("CHECK_INTERVAL", int(CHECK_INTERVAL/time.Second))) * time.Second,
+		CheckIntervalActive:     time.Duration(getEnvInt("CHECK_INTERVAL_ACTIVE", 0)) * time.Second,
+		CheckIntervalIdle:       time.Duration(getEnvInt("CHECK_INTERVAL_IDLE", 0)) * time.Second,
+		IdleAfter:               time.Duration(getEnvInt("IDLE_AFTER", 120)) * time.Minute,
+		CheckIntervalIncident:   time.Duration(getEnvInt("CHECK_INTERVAL_INCIDENT", 5)) * time.Second,
+	}
+
+	// Resolving DNS on every ping is noise at the default interval but adds
+	// up quickly when checking every few seconds.
+	defaultAddressCacheTTL := 0
+	fastest := config.CheckInterval
+	if config.CheckIntervalActive > 0 {
+		fastest = min(fastest, config.CheckIntervalActive)
+	}
+	if fastest < 10*time.Second {
+		defaultAddressCacheTTL = 300
+	}
+	config.AddressCacheTTL = time.Duration(getEnvInt("ADDRESS_CACHE_TTL", defaultAddressCacheTTL)) * time.Second
+
+	defaultRole, ok := parseRole(getEnv("DEFAULT_ROLE", "viewer"))
+	if !ok {
+		log.Fatal("DEFAULT_ROLE must be none, viewer, operator or admin")
+	}
+	config.DefaultRole = defaultRole
+
+	if config.CheckInterval <= 0 {
+		log.Fatal("CHECK_INTERVAL must be positive")
 	}
 
 	if config.ServerHost == "" {
@@ -69,382 +414,210 @@ func init() {
 	if config.TelegramToken == "" {
 		log.Fatal("TELEGRAM_BOT_TOKEN environment variable is required")
 	}
-	if config.TelegramChatID == "" {
-		log.Fatal("TELEGRAM_CHAT_ID environment variable is required")
-	}
-
-	store = &StatusStore{Entries: []StatusEntry{}}
-	loadStore()
-}
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		var result int
-		if _, err := fmt.Sscanf(value, "%d", &result); err == nil {
-			return result
-		}
-	}
-	return defaultValue
-}
-
-func loadStore() {
-	store.mu.Lock()
-	defer store.mu.Unlock()
-
-	data, err := ioutil.ReadFile(JSON_FILE)
-	if err != nil {
-		if os.IsNotExist(err) {
-			store.Entries = []StatusEntry{}
-			return
-		}
-		log.Printf("Error reading status file: %v", err)
-		return
+	if config.SecondaryServer != "" {
+		config.SecondaryServer = normalizeServer(config.SecondaryServer)
 	}
-
-	if err := json.Unmarshal(data, store); err != nil {
-		log.Printf("Error parsing status file: %v", err)
-		store.Entries = []StatusEntry{}
+	if config.StagingMode != STAGING_MIRROR && config.StagingMode != STAGING_REDIRECT {
+		log.Fatal("STAGING_MODE must be mirror or redirect")
 	}
-}
-
-func saveStore() {
-	store.mu.Lock()
-	defer store.mu.Unlock()
-
-	data, err := json.MarshalIndent(store, "", "  ")
+	routes, err := parseChatRoutes(getEnv("TELEGRAM_CHATS", ""))
 	if err != nil {
-		log.Printf("Error marshaling status: %v", err)
-		return
+		log.Fatalf("Invalid TELEGRAM_CHATS: %v", err)
 	}
-
-	if err := ioutil.WriteFile(JSON_FILE, data, 0644); err != nil {
-		log.Printf("Error writing status file: %v", err)
+	config.ChatRoutes = routes
+	if config.TelegramChatID == "" && len(routes) > 0 {
+		config.TelegramChatID = routes[0].ChatID
 	}
-}
-
-func getLatest() *StatusEntry {
-	store.mu.RLock()
-	defer store.mu.RUnlock()
-
-	if len(store.Entries) == 0 {
-		return nil
+	if config.TelegramChatID == "" {
+		log.Fatal("TELEGRAM_CHAT_ID or TELEGRAM_CHATS is required")
 	}
 
-	latest := store.Entries[0]
-	for _, entry := range store.Entries {
-		if entry.LastChecked > latest.LastChecked {
-			latest = entry
+	if config.RedisURL != "" {
+		client, err := newRedisClient(config.RedisURL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
 		}
+		redis = client
 	}
-	return &latest
-}
-
-func insertStatus(online bool, lastChecked int64, players []string) {
-	store.mu.Lock()
-	defer store.mu.Unlock()
-
-	newID := time.Now().UnixNano()
-	entry := StatusEntry{
-		ID:          newID,
-		Online:      online,
-		LastChecked: lastChecked,
-		Players:     players,
-	}
-
-	store.Entries = append(store.Entries, entry)
-}
 
-func cleanupOld() {
-	store.mu.Lock()
-	defer store.mu.Unlock()
-
-	cutoff := time.Now().Unix()*1000 - ONE_DAY_IN_MS
-	filtered := []StatusEntry{}
-
-	for _, entry := range store.Entries {
-		if entry.LastChecked >= cutoff {
-			filtered = append(filtered, entry)
+	switch config.StoreBackend {
+	case "file":
+		storage = fileStorage{}
+	case "redis":
+		if redis == nil {
+			log.Fatal("STORE_BACKEND=redis requires REDIS_URL")
 		}
+		storage = redisStorage{client: redis, prefix: config.RedisPrefix}
+	case "sqlite":
+		db, err := openSQLiteHistory(config.SQLitePath)
+		if err != nil {
+			log.Fatalf("Error opening %s: %v", config.SQLitePath, err)
+		}
+		history = db
+		storage = sqliteStorage{history: db}
+	default:
+		log.Fatalf("Unknown STORE_BACKEND %q", config.StoreBackend)
 	}
 
-	store.Entries = filtered
-}
-
-func escapeHtml(s string) string {
-	result := s
-	replacements := map[string]string{
-		"&":  "&amp;",
-		"<":  "&lt;",
-		">":  "&gt;",
-		"\"": "&quot;",
-		"'":  "&#39;",
+	if !containsString(onlinePolicies, config.OnlinePolicy) {
+		log.Fatalf("Unknown ONLINE_POLICY %q", config.OnlinePolicy)
 	}
-	for old, new := range replacements {
-		result = replaceAll(result, old, new)
+	if !containsString(liveModes, config.LiveStatus) {
+		log.Fatalf("Unknown LIVE_STATUS %q", config.LiveStatus)
 	}
-	return result
-}
-
-func replaceAll(s, old, new string) string {
-	return strings.ReplaceAll(s, old, new)
-}
 
-func httpPost(url string, data []byte) (*http.Response, error) {
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
+	if config.SFTPAddr != "" && config.SFTPHostKey == "" {
+		log.Fatal("SFTP_ADDR requires SFTP_HOST_KEY (the server's SHA256 key fingerprint)")
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	return client.Do(req)
-}
-
-func bold(s string) string {
-	return fmt.Sprintf("<b>%s</b>", escapeHtml(s))
-}
-
-func sendTelegramMessage(text string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", config.TelegramToken)
-
-	payload := map[string]interface{}{
-		"chat_id":    config.TelegramChatID,
-		"text":       text,
-		"parse_mode": "HTML",
+	switch strategy := getEnv("ID_STRATEGY", "ulid"); strategy {
+	case "ulid":
+		entryIDs = &ulidGenerator{}
+	case "sequence":
+		node := getEnv("ID_NODE", "")
+		if node == "" {
+			node, _ = os.Hostname()
+		}
+		entryIDs = &sequenceGenerator{node: node}
+	default:
+		log.Fatalf("Unknown ID_STRATEGY %q", strategy)
 	}
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
+	switch config.ProbeTransport {
+	case "slp":
+		transport = slpTransport{}
+		if config.QueryPort != 0 {
+			transport = slpQueryTransport{Port: config.QueryPort}
+		}
+	case "query":
+		transport = queryTransport{Port: config.QueryPort}
+	case "http":
+		if config.ProbeURL == "" {
+			log.Fatal("PROBE_TRANSPORT=http requires PROBE_URL")
+		}
+		transport = newHTTPTransport(config.ProbeURL)
+	default:
+		log.Fatalf("Unknown PROBE_TRANSPORT %q", config.ProbeTransport)
 	}
 
-	resp, err := httpPost(url, jsonData)
+	escalation, err := parseEscalation(getEnv("ESCALATION_LADDER", "15m:mention,60m:pushover"))
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error: %s - %s", resp.Status, string(body))
+		log.Fatalf("Invalid ESCALATION_LADDER: %v", err)
 	}
+	config.Escalation = escalation
 
-	return nil
-}
-
-func updateChatTitle(title string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/setChatTitle", config.TelegramToken)
-
-	payload := map[string]interface{}{
-		"chat_id": config.TelegramChatID,
-		"title":   title,
+	// RCON_HOST and RCON_PORT are the same as RCON_ADDR.
+	if host := getEnv("RCON_HOST", ""); config.RCONAddr == "" && host != "" {
+		config.RCONAddr = net.JoinHostPort(host, getEnv("RCON_PORT", "25575"))
 	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return err
+	if config.RestartSchedule, err = parseRestartSchedule(getEnv("RESTART_SCHEDULE", "")); err != nil {
+		log.Fatalf("Invalid RESTART_SCHEDULE: %v", err)
 	}
-
-	resp, err := httpPost(url, jsonData)
-	if err != nil {
-		return err
+	if config.RestartWarnings, err = parseMinutesList(getEnv("RESTART_WARNINGS", "15,5,1")); err != nil {
+		log.Fatalf("Invalid RESTART_WARNINGS: %v", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("telegram API error: %s - %s", resp.Status, string(body))
+	if config.SLAs, err = parseSLAs(getEnv("SLA", "")); err != nil {
+		log.Fatalf("Invalid SLA: %v", err)
 	}
-
-	return nil
-}
-
-func pingMinecraftServer(host string, port uint16) (*ServerStatus, error) {
-	address := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-	conn, err := net.DialTimeout("tcp", address, TIMEOUT)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-
-	conn.SetDeadline(time.Now().Add(TIMEOUT))
-
-	hostBytes := []byte(host)
-	packet := new(bytes.Buffer)
-
-	writeVarInt(packet, 0)
-	writeVarInt(packet, 47)
-	writeVarInt(packet, int32(len(hostBytes)))
-	packet.Write(hostBytes)
-	binary.Write(packet, binary.BigEndian, uint16(port))
-	writeVarInt(packet, 1)
-
-	packetData := packet.Bytes()
-	packetLen := new(bytes.Buffer)
-	writeVarInt(packetLen, int32(len(packetData)))
-
-	_, err = conn.Write(append(packetLen.Bytes(), packetData...))
-	if err != nil {
-		return nil, err
+	if at := getEnv("DAILY_CHART_AT", ""); at != "" {
+		if config.DailyChartAt, err = parseClock(at); err != nil {
+			log.Fatalf("Invalid DAILY_CHART_AT: %v", err)
+		}
+		config.DailyChart = true
 	}
-
-	statusReq := new(bytes.Buffer)
-	writeVarInt(statusReq, 0)
-	statusReqData := statusReq.Bytes()
-	statusReqLen := new(bytes.Buffer)
-	writeVarInt(statusReqLen, int32(len(statusReqData)))
-	_, err = conn.Write(append(statusReqLen.Bytes(), statusReqData...))
-	if err != nil {
-		return nil, err
+	if config.Templates, err = loadMessageTemplates(); err != nil {
+		log.Fatalf("Invalid template %v", err)
 	}
 
-	responseLen, err := readVarInt(conn)
+	key, err := loadStorageKey()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response length: %v", err)
+		log.Fatalf("Invalid storage key: %v", err)
 	}
-
-	if responseLen <= 0 || responseLen > 65535 {
-		return nil, fmt.Errorf("invalid response length: %d", responseLen)
+	if config.StoreBackend == "sqlite" {
+		if err := checkSQLiteOptions(key != nil); err != nil {
+			log.Fatalf("Invalid STORE_BACKEND=sqlite: %v", err)
+		}
 	}
-
-	responseData := make([]byte, responseLen)
-	totalRead := 0
-	for totalRead < int(responseLen) {
-		n, err := conn.Read(responseData[totalRead:])
+	if key != nil {
+		encrypted, err := newEncryptedStorage(storage, key)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read response data: %v", err)
+			log.Fatalf("Invalid storage key: %v", err)
 		}
-		totalRead += n
-	}
-
-	responseBuf := bytes.NewBuffer(responseData)
-
-	_, err = readVarInt(responseBuf)
-	if err != nil {
-		return nil, err
-	}
-
-	jsonLen, err := readVarInt(responseBuf)
-	if err != nil {
-		return nil, err
+		storage = encrypted
 	}
 
-	jsonData := make([]byte, jsonLen)
-	_, err = responseBuf.Read(jsonData)
-	if err != nil {
-		return nil, err
-	}
+	userLimiter = newRateLimiter(config.CommandUserLimit, COMMAND_LIMIT_WINDOW)
+	chatLimiter = newRateLimiter(config.CommandChatLimit, COMMAND_LIMIT_WINDOW)
 
-	var statusJSON map[string]interface{}
-	if err := json.Unmarshal(jsonData, &statusJSON); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
-	}
+	telegram = newTelegramClient(config.TelegramToken)
+	telegram.OnMigrate = handleChatMigration
+	telegram.StagingChatID = config.StagingChatID
+	telegram.StagingMode = config.StagingMode
+}
 
-	version, ok := statusJSON["version"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid server response: missing version field")
-	}
-	versionName, ok := version["name"].(string)
-	if !ok || versionName == "" {
-		return nil, fmt.Errorf("invalid server response: missing or empty version name")
+func getEnv(key, defaultValue string) string {
+	configKeys[key] = true
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
-
-	status := &ServerStatus{Online: true}
-
-	if players, ok := statusJSON["players"].(map[string]interface{}); ok {
-		if online, ok := players["online"].(float64); ok {
-			status.PlayerCount = int(online)
-		}
-
-		if sample, ok := players["sample"].([]interface{}); ok {
-			playerList := []string{}
-			for _, p := range sample {
-				if player, ok := p.(map[string]interface{}); ok {
-					if name, ok := player["name"].(string); ok {
-						playerList = append(playerList, name)
-					}
-				}
-			}
-			status.Players = playerList
-		}
+	if value := fileSettings[key]; value != "" {
+		return value
 	}
-
-	return status, nil
+	return defaultValue
 }
 
-func writeVarInt(buf *bytes.Buffer, value int32) {
-	for {
-		if (value & ^0x7F) == 0 {
-			buf.WriteByte(byte(value))
-			return
+// splitList splits a comma-separated setting, dropping empty items.
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
 		}
-		buf.WriteByte(byte((value & 0x7F) | 0x80))
-		value = int32(uint32(value) >> 7)
 	}
+	return result
 }
 
-func readVarInt(reader interface{}) (int32, error) {
-	var b byte
-	var result int32
-	var shift uint
-
-	for {
-		var err error
-		switch r := reader.(type) {
-		case *bytes.Buffer:
-			b, err = r.ReadByte()
-		case net.Conn:
-			var data [1]byte
-			_, err = r.Read(data[:])
-			b = data[0]
-		default:
-			return 0, fmt.Errorf("unsupported reader type")
-		}
-
+// parseIDList parses a comma-separated list of numeric IDs, skipping (and
+// logging) anything that isn't a number.
+func parseIDList(s string) []int64 {
+	var ids []int64
+	for _, item := range splitList(s) {
+		id, err := strconv.ParseInt(item, 10, 64)
 		if err != nil {
-			return 0, err
+			log.Printf("Ignoring invalid ID %q", item)
+			continue
 		}
+		ids = append(ids, id)
+	}
+	return ids
+}
 
-		result |= int32(b&0x7F) << shift
-		if (b & 0x80) == 0 {
-			break
-		}
-		shift += 7
-		if shift >= 32 {
-			return 0, fmt.Errorf("varint too long")
+func getEnvInt(key string, defaultValue int) int {
+	configKeys[key] = true
+	value := os.Getenv(key)
+	if value == "" {
+		value = fileSettings[key]
+	}
+	if value != "" {
+		var result int
+		if _, err := fmt.Sscanf(value, "%d", &result); err == nil {
+			return result
 		}
 	}
-
-	return result, nil
+	return defaultValue
 }
 
-func checkServer() {
+func checkServer(ctx context.Context) {
+	if !beginServerCheck("") {
+		return
+	}
+	defer endServerCheck("")
+
 	latest := getLatest()
 
 	var online bool
-	var statusResponse *ServerStatus
-	var err error
-
-	for attempt := 1; attempt <= MAX_RETRIES; attempt++ {
-		statusResponse, err = pingMinecraftServer(config.ServerHost, config.ServerPort)
-		if err == nil && statusResponse != nil {
-			break
-		}
-
-		if attempt < MAX_RETRIES {
-			log.Printf("Server check attempt %d failed, retrying...", attempt)
-			time.Sleep(RETRY_DELAY)
-		} else {
-			log.Printf("Server check failed after %d attempts: %v", MAX_RETRIES, err)
-		}
-	}
+	result := debounceResult(latest, refreshStatus(ctx))
+	statusResponse := result.Status
 
 	previousPlayers := []string{}
 	if latest != nil {
@@ -456,24 +629,11 @@ func checkServer() {
 	playerCount := 0
 
 	if statusResponse != nil {
-
-		// ⭐⭐⭐ MODIFICATION HERE ⭐⭐⭐
-		online = statusResponse.PlayerCount > 0
-		// ⭐⭐⭐ END MODIFICATION ⭐⭐⭐
-
 		playerCount = statusResponse.PlayerCount
+		online = serverState(config.OnlinePolicy, true, playerCount) != StateOffline
 
 		if len(statusResponse.Players) > 0 {
-			currentPlayers = []string{}
-			playerSet := make(map[string]bool)
-			for _, playerName := range statusResponse.Players {
-				if playerName != "" {
-					if !playerSet[playerName] {
-						currentPlayers = append(currentPlayers, playerName)
-						playerSet[playerName] = true
-					}
-				}
-			}
+			currentPlayers = dedupePlayers(statusResponse.Players)
 			playerDataReliable = len(currentPlayers) > 0
 		}
 
@@ -497,80 +657,166 @@ func checkServer() {
 		online = false
 	}
 
-	currentPlayerSet := make(map[string]bool)
-	for _, p := range currentPlayers {
-		currentPlayerSet[p] = true
-	}
-
-	previousPlayerSet := make(map[string]bool)
-	for _, p := range previousPlayers {
-		previousPlayerSet[p] = true
-	}
-
 	var joinedPlayers []string
 	var leftPlayers []string
 
 	if playerDataReliable {
-		for _, p := range currentPlayers {
-			if !previousPlayerSet[p] {
-				joinedPlayers = append(joinedPlayers, p)
-			}
-		}
-		for _, p := range previousPlayers {
-			if !currentPlayerSet[p] {
-				leftPlayers = append(leftPlayers, p)
-			}
-		}
+		joinedPlayers, leftPlayers = diffPlayers(previousPlayers, currentPlayers)
 	}
 
-	insertStatus(online, time.Now().Unix()*1000, currentPlayers)
+	now := time.Now()
+	checkClock(now)
+	insertStatus(StatusEntry{
+		Online:      online,
+		LastChecked: now.Unix() * 1000,
+		Players:     currentPlayers,
+		PlayerCount: playerCount,
+		Error:       errorCategory(result.Err),
+		LatencyMs:   latencyMs(result),
+	})
 	saveStore()
-
-	if playerDataReliable && (len(joinedPlayers) > 0 || len(leftPlayers) > 0) {
-		var changes []string
-
-		if len(joinedPlayers) > 0 {
-			if len(joinedPlayers) == 1 {
-				changes = append(changes, fmt.Sprintf("😎 %s зайшов на сервер", bold(joinedPlayers[0])))
-			} else {
-				joinedBold := make([]string, len(joinedPlayers))
-				for i, p := range joinedPlayers {
-					joinedBold[i] = bold(p)
+	trackSLA(ctx, statusResponse != nil, now)
+	pushUptimeKuma(ctx, online, result)
+	emitStatsd(online, result)
+	recordCheckMetrics(online, result, now)
+	writeMetricsTextfile()
+	updateDiscordPresence()
+
+	// Chats get the transitions of their own online policy; the history,
+	// incidents and integrations follow ONLINE_POLICY.
+	var events, announced []Event
+	// Coming back closes the open downtime; its length goes on
+	// ONLINE_POLICY's server_up.
+	var lasted time.Duration
+	if latest != nil && latest.Online != online && online {
+		lasted = recordTransition(online, now)
+	}
+	if latest != nil {
+		announced = transitionEvents(func(policy string) ServerState {
+			return entryState(policy, latest)
+		}, func(policy string) ServerState {
+			return serverState(policy, statusResponse != nil, playerCount)
+		}, now)
+		var down *Event
+		for i := range announced {
+			event := &announced[i]
+			if event.Kind == EventServerDown {
+				if down == nil {
+					down = &Event{}
+					describeServerDown(ctx, down, now)
 				}
-				changes = append(changes, fmt.Sprintf("😎 на сервер зайшли: %s", joinStrings(joinedBold, ", ")))
+				event.Label, event.Forecast, event.Backup = down.Label, down.Forecast, down.Backup
 			}
-		}
-
-		if len(leftPlayers) > 0 {
-			if len(leftPlayers) == 1 {
-				changes = append(changes, fmt.Sprintf("🥺 %s вийшов", bold(leftPlayers[0])))
-			} else {
-				leftBold := make([]string, len(leftPlayers))
-				for i, p := range leftPlayers {
-					leftBold[i] = bold(p)
+			if event.Kind == EventServerUp {
+				event.DowntimeSeconds = int64(lasted / time.Second)
+				if event.policy != config.OnlinePolicy {
+					// Other policies' downtimes are only in the history.
+					checks := getRange(now.Add(-24*time.Hour).UnixMilli(), latest.LastChecked+1)
+					if since, ok := offlineSince(event.policy, checks); ok {
+						event.DowntimeSeconds = int64(now.Sub(since) / time.Second)
+					}
 				}
-				changes = append(changes, fmt.Sprintf("🥺 вийшли: %s", joinStrings(leftBold, ", ")))
+			}
+			if event.policy == config.OnlinePolicy {
+				global := *event
+				global.policy = ""
+				events = append(events, global)
 			}
 		}
+	}
+	// Incidents page admins, so they follow reachability alone: a server
+	// that is merely empty is offline for announcements and chat titles
+	// under ONLINE_PLAYERS, but nobody needs to get up for it.
+	// Whether an incident looks scheduled depends on when it opens, not
+	// on whether this check saw a transition: under ONLINE_PLAYERS the
+	// server may have counted as offline since before it went unreachable.
+	reachable := statusResponse != nil
+	scheduled := false
+	if !reachable && !incidentOpen() {
+		_, scheduled = expectedRestart(now)
+		openIncident(now, scheduled)
+	}
+	if latest != nil && latest.Online != online && !online {
+		recordTransition(online, now)
+	}
+	if playerDataReliable {
+		trackPlaytime(joinedPlayers, leftPlayers, now)
+		players := playerEvents(joinedPlayers, leftPlayers, now)
+		events = append(events, players...)
+		announced = append(announced, players...)
+	}
+	resumeSnoozed(ctx, now)
+	announce(ctx, "", statusResponse != nil, playerCount, announced)
+	updateLiveMessages(ctx)
+	updateBotDescription(ctx, now)
+	if statusResponse != nil {
+		checkAnomalies(ctx, statusResponse.PlayerCount, now)
+		trackServerAddress(ctx, statusResponse.IP, now)
+	}
+	escalate(ctx, reachable, scheduled, now)
+	recordEvents(events)
+	dispatchEvents(ctx, events)
 
-		if len(changes) > 0 {
-			message := joinStrings(changes, "\n")
-			if err := sendTelegramMessage(message); err != nil {
-				log.Printf("Error sending Telegram message: %v", err)
-			}
+	if statusResponse != nil {
+		log.Printf("Server status: %s (connect %v, protocol %v)", map[bool]string{true: "online", false: "offline"}[online],
+			statusResponse.ConnectTime.Round(time.Millisecond), statusResponse.ProtocolTime.Round(time.Millisecond))
+	} else {
+		log.Printf("Server status: %s", map[bool]string{true: "online", false: "offline"}[online])
+	}
+
+	checkOtherServers(ctx)
+}
+
+// describeServerDown fills in what is known about the server going down at
+// now: whether it looks like a scheduled restart, how long it will
+// probably take and whether SECONDARY_SERVER is up meanwhile.
+func describeServerDown(ctx context.Context, event *Event, now time.Time) {
+	if _, ok := expectedRestart(now); ok {
+		event.Label = LABEL_SCHEDULED_RESTART
+	}
+	event.Forecast = forecastDowntime()
+	if secondaryUp(ctx) {
+		event.Backup = config.SecondaryServer
+	}
+}
+
+// dedupePlayers drops empty and repeated names, keeping the server's order.
+func dedupePlayers(players []string) []string {
+	result := make([]string, 0, len(players))
+	playerSet := make(map[string]bool, len(players))
+	for _, playerName := range players {
+		if playerName != "" && !playerSet[playerName] {
+			result = append(result, playerName)
+			playerSet[playerName] = true
 		}
 	}
+	return result
+}
 
-	chatTitle := "🔴 lnudorm3 minecraft йоу"
-	if online {
-		chatTitle = "🟢 lnudorm3 minecraft йоу"
+// diffPlayers reports who appears only in current (joined) and who appears
+// only in previous (left).
+func diffPlayers(previous, current []string) (joined, left []string) {
+	currentPlayerSet := make(map[string]bool, len(current))
+	for _, p := range current {
+		currentPlayerSet[p] = true
 	}
 
-	if err := updateChatTitle(chatTitle); err != nil {
-		log.Printf("Error updating chat title: %v", err)
+	previousPlayerSet := make(map[string]bool, len(previous))
+	for _, p := range previous {
+		previousPlayerSet[p] = true
 	}
 
-	log.Printf("Server status: %s", map[bool]string{true: "online", false: "offline"}[online])
+	for _, p := range current {
+		if !previousPlayerSet[p] {
+			joined = append(joined, p)
+		}
+	}
+	for _, p := range previous {
+		if !currentPlayerSet[p] {
+			left = append(left, p)
+		}
+	}
+	return joined, left
 }
 
 func joinStrings(strs []string, sep string) string {
@@ -585,24 +831,110 @@ func joinStrings(strs []string, sep string) string {
 }
 
 func main() {
-	log.Println("Starting Minecraft server status checker...")
+	flag.StringVar(&configFile, "config", os.Getenv(CONFIG_FILE_ENV), "YAML or TOML config file; environment variables override it")
+	flag.Parse()
+	if configFile != "" {
+		if err := loadConfigFile(configFile); err != nil {
+			log.Fatalf("Error loading config: %v", err)
+		}
+	}
+	loadConfig()
+	warnUnknownFileSettings()
+
+	// SIGTERM is what docker stop sends; cancelling ctx stops the jobs and
+	// any ping in flight.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	checkServer()
+	if args := flag.Args(); len(args) > 0 {
+		var err error
+		switch args[0] {
+		case "restore":
+			err = restoreBackup(ctx, args[1:])
+		case "export":
+			err = exportData(os.Stdout, args[1:])
+		case "import":
+			err = importHistory(os.Stdout, args[1:])
+		case "replay":
+			err = replayHistory(os.Stdout, args[1:])
+		case "service":
+			err = runServiceCommand(ctx, args[1:])
+		default:
+			err = fmt.Errorf("unknown command %q", args[0])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	ticker := time.NewTicker(CHECK_INTERVAL)
-	defer ticker.Stop()
+	runMonitor(ctx)
+}
 
-	cleanupTicker := time.NewTicker(CLEANUP_INTERVAL)
-	defer cleanupTicker.Stop()
+// runMonitor checks the server every CHECK_INTERVAL and runs the bot until
+// ctx is cancelled, then writes out pending entries.
+func runMonitor(ctx context.Context) {
+	loadStore()
+	loadState()
+	applySchedule()
 
-	for {
-		select {
-		case <-ticker.C:
-			checkServer()
-		case <-cleanupTicker.C:
-			log.Println("Cleaning up old status entries...")
-			cleanupOld()
-			saveStore()
-		}
+	log.Println("Starting Minecraft server status checker...")
+
+	go runBot(ctx)
+	go runDiscordBot(ctx)
+	go runHTTPServer(ctx)
+
+	checkServer(ctx)
+	announceStartup(ctx)
+
+	scheduler.Add(&Job{Name: "check", Interval: config.CheckInterval, NextInterval: checkInterval, Run: checkServer})
+	// With SAVE_INTERVAL=0 every check writes immediately and there is
+	// nothing left over to flush.
+	if config.SaveInterval > 0 {
+		scheduler.Add(&Job{Name: "save", Interval: config.SaveInterval, Run: func(context.Context) { saveStore() }})
+	}
+	scheduler.Add(&Job{Name: "events", Interval: GAME_EVENT_CHECK_INTERVAL, Run: func(ctx context.Context) {
+		checkGameEvents(ctx, time.Now())
+	}})
+	if len(config.RestartSchedule) > 0 {
+		scheduler.Add(&Job{Name: "countdown", Interval: RESTART_COUNTDOWN_TICK, Run: func(ctx context.Context) {
+			runRestartCountdown(ctx, time.Now())
+		}})
+	}
+	scheduler.Add(&Job{Name: "cleanup", Interval: CLEANUP_INTERVAL, Jitter: time.Minute, Run: func(context.Context) {
+		log.Println("Cleaning up old status entries...")
+		cleanupOld()
+		cleanupHistory()
+		cleanupEventLog()
+		saveStore()
+	}})
+	if config.S3Bucket != "" && config.BackupInterval > 0 {
+		scheduler.Add(&Job{Name: "backup", Interval: config.BackupInterval, Jitter: 5 * time.Minute, Run: backupStore})
+	}
+	if config.UpdateCheckInterval > 0 {
+		scheduler.Add(&Job{Name: "update", Interval: config.UpdateCheckInterval, Jitter: 30 * time.Minute, RunAtStart: true, Run: checkForUpdate})
 	}
+	if config.SFTPAddr != "" && config.PropertiesCheckInterval > 0 {
+		scheduler.Add(&Job{Name: "properties", Interval: config.PropertiesCheckInterval, Jitter: time.Minute, Run: checkServerProperties})
+	}
+	if config.SFTPAddr != "" && config.WorldPath != "" && config.WorldCheckInterval > 0 {
+		scheduler.Add(&Job{Name: "world", Interval: config.WorldCheckInterval, Jitter: 5 * time.Minute, Run: checkWorldSize})
+	}
+	if config.LogPath != "" && config.LogTailInterval > 0 {
+		scheduler.Add(&Job{Name: "log", Interval: config.LogTailInterval, Run: tailServerLog})
+	}
+	if config.CrashReportsPath != "" && config.CrashCheckInterval > 0 {
+		scheduler.Add(&Job{Name: "crashes", Interval: config.CrashCheckInterval, Run: checkCrashReports})
+	}
+	if config.JVMMetricsURL != "" && config.JVMCheckInterval > 0 {
+		scheduler.Add(&Job{Name: "jvm", Interval: config.JVMCheckInterval, Run: checkJVM})
+	}
+	if config.DailyChart {
+		scheduler.Add(&Job{Name: "chart", Interval: DAILY_CHART_CHECK_INTERVAL, Run: sendDailyChart})
+	}
+
+	scheduler.Run(ctx)
+	log.Println("Shutting down...")
+	flushStore()
+	announceShutdown(ctx)
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDiffPlayers(t *testing.T) {
+	joined, left := diffPlayers([]string{"steve", "alex"}, []string{"alex", "notch"})
+	if !reflect.DeepEqual(joined, []string{"notch"}) || !reflect.DeepEqual(left, []string{"steve"}) {
+		t.Fatalf("diffPlayers = %v, %v", joined, left)
+	}
+}
+
+func TestDedupePlayers(t *testing.T) {
+	got := dedupePlayers([]string{"steve", "", "alex", "steve"})
+	if !reflect.DeepEqual(got, []string{"steve", "alex"}) {
+		t.Fatalf("dedupePlayers = %v", got)
+	}
+}
+
+func BenchmarkDiffPlayers(b *testing.B) {
+	previous := make([]string, 20)
+	current := make([]string, 20)
+	for i := range previous {
+		previous[i] = fmt.Sprintf("player%d", i)
+		current[i] = fmt.Sprintf("player%d", i+2)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		diffPlayers(previous, current)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricFamily is one metric in the Prometheus text format. Series are keyed
+// by their rendered label set, e.g. `{reason="timeout"}`.
+type metricFamily struct {
+	name   string
+	kind   string
+	help   string
+	series map[string]float64
+}
+
+type metricSet struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+var metrics = newMetricSet()
+
+func newMetricSet() *metricSet {
+	m := &metricSet{families: map[string]*metricFamily{}}
+	m.register("minecraft_server_online", "gauge", "Whether the last check found the server online.")
+	m.register("minecraft_server_players", "gauge", "Players online at the last check.")
+	m.register("minecraft_ping_connect_seconds", "gauge", "Time to resolve and connect at the last successful check.")
+	m.register("minecraft_ping_protocol_seconds", "gauge", "Time for the status exchange at the last successful check.")
+	m.register("minecraft_ping_seconds", "gauge", "Total ping latency (connect and status exchange) at the last successful check.")
+	m.register("minecraft_last_check_timestamp_seconds", "gauge", "Unix time of the last check.")
+	m.register("minecraft_check_failures_total", "counter", "Failed checks by error category.")
+	m.register("minecraft_checks_skipped_total", "counter", "Checks skipped because the previous check of the server was still running.")
+	m.register("minecraft_clock_jumps_total", "counter", "Wall clock jumps detected between checks.")
+	m.register("minecraft_telegram_errors_total", "counter", "Failed Telegram Bot API calls by method and error code (0 for network errors).")
+	m.register("minecraft_store_write_seconds", "gauge", "Duration of the last status history write, by kind (journal or snapshot).")
+	m.register("minecraft_store_writes_total", "counter", "Status history writes by kind.")
+	m.register("minecraft_store_size_bytes", "gauge", "Size of the persisted status history, snapshot and journal together.")
+	return m
+}
+
+func (m *metricSet) register(name, kind, help string) {
+	m.families[name] = &metricFamily{name: name, kind: kind, help: help, series: map[string]float64{}}
+}
+
+// labelKey renders name/value pairs as a Prometheus label set.
+func labelKey(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// Set sets a gauge; labels are name/value pairs.
+func (m *metricSet) Set(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.families[name].series[labelKey(labels)] = value
+}
+
+// Inc adds one to a counter; labels are name/value pairs.
+func (m *metricSet) Inc(name string, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.families[name].series[labelKey(labels)]++
+}
+
+// WriteTo renders every metric with at least one series in the Prometheus
+// text exposition format, sorted by name for stable output.
+func (m *metricSet) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		family := m.families[name]
+		if len(family.series) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, family.help, name, family.kind)
+
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%s%s %s\n", name, key, formatMetricValue(family.series[key]))
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+func formatMetricValue(v float64) string {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return fmt.Sprint(v)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// recordCheckMetrics updates the metrics after a check.
+func recordCheckMetrics(online bool, result *PingResult, at time.Time) {
+	metrics.Set("minecraft_server_online", map[bool]float64{true: 1, false: 0}[online])
+	metrics.Set("minecraft_last_check_timestamp_seconds", float64(at.Unix()))
+	if result.Status != nil {
+		metrics.Set("minecraft_server_players", float64(result.Status.PlayerCount))
+		metrics.Set("minecraft_ping_connect_seconds", result.Status.ConnectTime.Seconds())
+		metrics.Set("minecraft_ping_protocol_seconds", result.Status.ProtocolTime.Seconds())
+		metrics.Set("minecraft_ping_seconds", (result.Status.ConnectTime + result.Status.ProtocolTime).Seconds())
+	}
+	if result.Err != nil {
+		metrics.Inc("minecraft_check_failures_total", "reason", errorCategory(result.Err))
+	}
+}
+
+// handleMetrics serves the metrics for Prometheus to scrape.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.WriteTo(w)
+}
+
+// writeMetricsTextfile writes the metrics to METRICS_TEXTFILE for the
+// node_exporter textfile collector. The file is replaced atomically so the
+// collector never reads half of it. Does nothing without METRICS_TEXTFILE.
+func writeMetricsTextfile() {
+	if config.MetricsTextfile == "" {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	metrics.WriteTo(buf)
+	if err := writeFileAtomic(config.MetricsTextfile, buf.Bytes()); err != nil {
+		log.Printf("Error writing metrics textfile: %v", err)
+	}
+}
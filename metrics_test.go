@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsTextfile(t *testing.T) {
+	metrics = newMetricSet()
+	previous := config
+	config.MetricsTextfile = filepath.Join(t.TempDir(), "minecraft.prom")
+	t.Cleanup(func() {
+		config = previous
+		metrics = newMetricSet()
+	})
+
+	at := time.Unix(1700000000, 0)
+	recordCheckMetrics(true, &PingResult{Status: &ServerStatus{PlayerCount: 2, ConnectTime: 5 * time.Millisecond}}, at)
+	recordCheckMetrics(false, &PingResult{Err: ErrTimeout}, at)
+	recordCheckMetrics(false, &PingResult{Err: ErrTimeout}, at)
+	writeMetricsTextfile()
+
+	got, err := os.ReadFile(config.MetricsTextfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP minecraft_check_failures_total Failed checks by error category.
+# TYPE minecraft_check_failures_total counter
+minecraft_check_failures_total{reason="timeout"} 2
+# HELP minecraft_last_check_timestamp_seconds Unix time of the last check.
+# TYPE minecraft_last_check_timestamp_seconds gauge
+minecraft_last_check_timestamp_seconds 1.7e+09
+# HELP minecraft_ping_connect_seconds Time to resolve and connect at the last successful check.
+# TYPE minecraft_ping_connect_seconds gauge
+minecraft_ping_connect_seconds 0.005
+# HELP minecraft_ping_protocol_seconds Time for the status exchange at the last successful check.
+# TYPE minecraft_ping_protocol_seconds gauge
+minecraft_ping_protocol_seconds 0
+# HELP minecraft_ping_seconds Total ping latency (connect and status exchange) at the last successful check.
+# TYPE minecraft_ping_seconds gauge
+minecraft_ping_seconds 0.005
+# HELP minecraft_server_online Whether the last check found the server online.
+# TYPE minecraft_server_online gauge
+minecraft_server_online 0
+# HELP minecraft_server_players Players online at the last check.
+# TYPE minecraft_server_players gauge
+minecraft_server_players 2
+`
+	if string(got) != want {
+		t.Fatalf("textfile:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	metrics = newMetricSet()
+	t.Cleanup(func() { metrics = newMetricSet() })
+	fake := useFakeTelegram(t)
+	fake.rateLimit["sendMessage"] = 1
+
+	recordCheckMetrics(true, &PingResult{Status: &ServerStatus{PlayerCount: 3}}, time.Unix(1700000000, 0))
+	telegram.SendMessage(context.Background(), "-42", "hi", nil)
+
+	w := httptest.NewRecorder()
+	newHTTPMux().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"minecraft_server_online 1\n",
+		"minecraft_server_players 3\n",
+		`minecraft_telegram_errors_total{method="sendMessage",code="429"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics is missing %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MINI_APP_INIT_DATA_TTL is how old a Mini App's launch data may be before
+// its requests are refused.
+const MINI_APP_INIT_DATA_TTL = 24 * time.Hour
+
+// MINI_APP_CHART_STEP is the width of one point of the Mini App's charts.
+const MINI_APP_CHART_STEP = 5 * time.Minute
+
+//go:embed web/app.html
+var miniAppHTML []byte
+
+// MiniAppData is what the Mini App shows: the status now and the last day
+// as chart points.
+type MiniAppData struct {
+	Status  CompactStatus `json:"status"`
+	Players []string      `json:"players"`
+	Chart   []ChartPoint  `json:"chart"`
+}
+
+// ChartPoint sums up the checks of one MINI_APP_CHART_STEP.
+type ChartPoint struct {
+	// Time is the start of the step, in Unix milliseconds.
+	Time int64 `json:"t"`
+	// Players is the most players seen in the step.
+	Players int `json:"players"`
+	// Online is whether every check of the step found the server online.
+	Online bool `json:"online"`
+}
+
+// handleMiniApp serves the Mini App page at /app. The page itself is
+// static; its data comes from /app/data.
+func handleMiniApp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(miniAppHTML)
+}
+
+// handleMiniAppData serves MiniAppData to a Mini App Telegram launched: the
+// page sends its init data in X-Telegram-Init-Data, signed with the bot
+// token.
+func handleMiniAppData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	initData, err := validateInitData(r.Header.Get("X-Telegram-Init-Data"), config.TelegramToken, time.Now())
+	if err != nil {
+		http.Error(w, "invalid init data: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	lang := config.Language
+	var user struct {
+		LanguageCode string `json:"language_code"`
+	}
+	if json.Unmarshal([]byte(initData.Get("user")), &user) == nil && messages[user.LanguageCode] != nil {
+		lang = user.LanguageCode
+	}
+
+	now := time.Now()
+	data := MiniAppData{Status: compactStatus(lang), Players: []string{}}
+	if latest := getLatest(); latest != nil && latest.Online {
+		data.Players = dedupePlayers(latest.Players)
+	}
+	data.Chart = chartPoints(getRange(now.Add(-24*time.Hour).UnixMilli(), now.UnixMilli()+1), MINI_APP_CHART_STEP)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// validateInitData checks the signature of a Mini App's init data (a query
+// string) as Telegram documents it, and that it isn't older than
+// MINI_APP_INIT_DATA_TTL at now.
+func validateInitData(raw, botToken string, now time.Time) (url.Values, error) {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, err
+	}
+	hash := values.Get("hash")
+	if hash == "" {
+		return nil, errors.New("no hash")
+	}
+
+	var pairs []string
+	for key := range values {
+		if key != "hash" {
+			pairs = append(pairs, key+"="+values.Get(key))
+		}
+	}
+	sort.Strings(pairs)
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+	want := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(hash), []byte(want)) {
+		return nil, errors.New("bad hash")
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, errors.New("no auth_date")
+	}
+	if now.Sub(time.Unix(authDate, 0)) > MINI_APP_INIT_DATA_TTL {
+		return nil, errors.New("expired")
+	}
+	return values, nil
+}
+
+// chartPoints groups entries (sorted oldest first) into steps of step.
+func chartPoints(entries []StatusEntry, step time.Duration) []ChartPoint {
+	points := []ChartPoint{}
+	for _, entry := range entries {
+		start := time.UnixMilli(entry.LastChecked).Truncate(step).UnixMilli()
+		players := max(entry.PlayerCount, len(entry.Players))
+		if n := len(points); n > 0 && points[n-1].Time == start {
+			points[n-1].Players = max(points[n-1].Players, players)
+			points[n-1].Online = points[n-1].Online && entry.Online
+			continue
+		}
+		points = append(points, ChartPoint{Time: start, Players: players, Online: entry.Online})
+	}
+	return points
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// signInitData signs values the way Telegram signs a Mini App's init data.
+func signInitData(values url.Values, botToken string) string {
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte("auth_date=" + values.Get("auth_date") + "\nuser=" + values.Get("user")))
+	values.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return values.Encode()
+}
+
+func TestMiniAppData(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.TelegramToken = "123:TOKEN"
+	config.Language = "en"
+
+	base := time.Now().Add(-time.Hour).Truncate(MINI_APP_CHART_STEP)
+	insertStatus(StatusEntry{Online: true, LastChecked: base.UnixMilli(), Players: []string{"steve"}})
+	insertStatus(StatusEntry{Online: false, LastChecked: base.Add(time.Minute).UnixMilli(), Players: []string{}})
+	insertStatus(StatusEntry{Online: true, LastChecked: base.Add(MINI_APP_CHART_STEP).UnixMilli(), Players: []string{"steve", "alex"}})
+
+	request := func(initData string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/app/data", nil)
+		r.Header.Set("X-Telegram-Init-Data", initData)
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, r)
+		return w
+	}
+
+	fresh := url.Values{"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)}, "user": {`{"id":1,"language_code":"uk"}`}}
+	w := request(signInitData(fresh, config.TelegramToken))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var data MiniAppData
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if data.Status.Text != "🟢 Онлайн: 2 гравці" || len(data.Players) != 2 {
+		t.Errorf("data = %+v", data)
+	}
+	if len(data.Chart) != 2 || data.Chart[0].Online || data.Chart[0].Players != 1 || !data.Chart[1].Online || data.Chart[1].Players != 2 {
+		t.Errorf("chart = %+v", data.Chart)
+	}
+
+	if w := request(signInitData(fresh, "other:TOKEN")); w.Code != http.StatusUnauthorized {
+		t.Errorf("init data signed by another bot: status %d", w.Code)
+	}
+	stale := url.Values{"auth_date": {strconv.FormatInt(time.Now().Add(-48*time.Hour).Unix(), 10)}, "user": {`{"id":1}`}}
+	if w := request(signInitData(stale, config.TelegramToken)); w.Code != http.StatusUnauthorized {
+		t.Errorf("stale init data: status %d", w.Code)
+	}
+}
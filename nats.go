@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NATS_TIMEOUT bounds one publish, from dialing to the server's PONG.
+const NATS_TIMEOUT = 5 * time.Second
+
+// publishNATS publishes events as JSON on NATS_SUBJECT.<kind>, so
+// subscribers can pick event kinds with subject wildcards. Events are rare,
+// so each batch gets its own short-lived connection instead of a client
+// that has to answer the server's keep-alive PINGs. Does nothing without
+// NATS_URL.
+func publishNATS(ctx context.Context, events []Event) {
+	if config.NATSURL == "" || len(events) == 0 {
+		return
+	}
+
+	if err := natsPublish(ctx, config.NATSURL, config.NATSSubject, events); err != nil {
+		log.Printf("Error publishing events to NATS: %v", err)
+	}
+}
+
+// natsPublish speaks the NATS text protocol: read INFO, send CONNECT and
+// one PUB per event, then PING and wait for PONG so errors aren't lost.
+func natsPublish(ctx context.Context, rawURL, subject string, events []Event) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "nats" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	dialer := net.Dialer{Timeout: NATS_TIMEOUT}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(phaseDeadline(ctx, NATS_TIMEOUT))
+	rd := bufio.NewReader(conn)
+
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("expected INFO, got %q", strings.TrimSpace(line))
+	}
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "lnudorm3-status"}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			options["user"] = u.User.Username()
+			options["pass"] = password
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CONNECT %s\r\n", connect)
+	for _, event := range events {
+		data, err := marshalEvent(event)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "PUB %s.%s %d\r\n%s\r\n", subject, event.Kind, len(data), data)
+	}
+	b.WriteString("PING\r\n")
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return err
+	}
+
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
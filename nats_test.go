@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNATSPublish(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+
+		var lines []string
+		rd := bufio.NewReader(conn)
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			lines = append(lines, line)
+			if line == "PING" {
+				fmt.Fprint(conn, "PONG\r\n")
+				received <- lines
+				return
+			}
+		}
+	}()
+
+	events := []Event{{Kind: EventServerDown, Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}}
+	if err := natsPublish(context.Background(), "nats://token@"+listener.Addr().String(), "mc", events); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := <-received
+	want := []string{
+		`CONNECT {"auth_token":"token","name":"lnudorm3-status","pedantic":false,"verbose":false}`,
+		`PUB mc.server_down 97`,
+		`{"schema":"lnudorm3-status/event","version":1,"kind":"server_down","time":"2024-01-02T03:04:05Z"}`,
+		`PING`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("received:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PUSHOVER_API is the Pushover message endpoint; tests point it elsewhere.
+var PUSHOVER_API = "https://api.pushover.net/1/messages.json"
+
+var notifyHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// sendPushover sends a high-priority Pushover notification to
+// PUSHOVER_USER.
+func sendPushover(ctx context.Context, title, message string) error {
+	if config.PushoverToken == "" || config.PushoverUser == "" {
+		return fmt.Errorf("PUSHOVER_TOKEN and PUSHOVER_USER are required")
+	}
+
+	form := url.Values{
+		"token":    {config.PushoverToken},
+		"user":     {config.PushoverUser},
+		"title":    {title},
+		"message":  {message},
+		"priority": {"1"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", PUSHOVER_API, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := notifyHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pushover: %s", resp.Status)
+	}
+	return nil
+}
+
+// sendEmail mails a plain-text message to ALERT_EMAIL_TO through SMTP_ADDR,
+// authenticating when SMTP_USERNAME is set.
+func sendEmail(subject, body string) error {
+	if config.SMTPAddr == "" || len(config.AlertEmailTo) == 0 {
+		return fmt.Errorf("SMTP_ADDR and ALERT_EMAIL_TO are required")
+	}
+
+	var auth smtp.Auth
+	if config.SMTPUsername != "" {
+		host, _, err := net.SplitHostPort(config.SMTPAddr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, host)
+	}
+
+	message := strings.Join([]string{
+		"From: " + config.SMTPFrom,
+		"To: " + strings.Join(config.AlertEmailTo, ", "),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	return smtp.SendMail(config.SMTPAddr, auth, config.SMTPFrom, config.AlertEmailTo, []byte(message))
+}
@@ -0,0 +1,99 @@
+package main
+
+import "time"
+
+// Online policies decide what counts as the server being online. The
+// monitor's own notion (ONLINE_POLICY) drives the history, incidents and
+// integrations; each chat can pick its own with /chatconfig online.
+const (
+	// ONLINE_REACHABLE: the server answers pings.
+	ONLINE_REACHABLE = "reachable"
+	// ONLINE_PLAYERS: the server answers and someone is playing, so an
+	// empty server counts as offline.
+	ONLINE_PLAYERS = "players"
+	// ONLINE_THREE_STATE: online, empty (reachable, nobody playing) or
+	// offline; going empty is a server_empty event of its own.
+	ONLINE_THREE_STATE = "three-state"
+)
+
+var onlinePolicies = []string{ONLINE_REACHABLE, ONLINE_PLAYERS, ONLINE_THREE_STATE}
+
+// ServerState is what a policy makes of one check.
+type ServerState string
+
+const (
+	StateOnline  ServerState = "online"
+	StateEmpty   ServerState = "empty"
+	StateOffline ServerState = "offline"
+)
+
+// serverState applies policy to a check that did or didn't reach the
+// server and found players online.
+func serverState(policy string, reachable bool, players int) ServerState {
+	switch {
+	case !reachable:
+		return StateOffline
+	case players > 0:
+		return StateOnline
+	case policy == ONLINE_PLAYERS:
+		return StateOffline
+	case policy == ONLINE_THREE_STATE:
+		return StateEmpty
+	}
+	return StateOnline
+}
+
+// entryState applies policy to a stored check. Online is stored under
+// ONLINE_POLICY, so reachability comes from the ping error instead.
+func entryState(policy string, entry *StatusEntry) ServerState {
+	reachable := entry.Online || entry.Error == ""
+	return serverState(policy, reachable, max(entry.PlayerCount, len(entry.Players)))
+}
+
+// stateEvent is the event for the server entering state.
+func stateEvent(state ServerState, at time.Time) Event {
+	switch state {
+	case StateEmpty:
+		return Event{Kind: EventServerEmpty, Time: at}
+	case StateOffline:
+		return Event{Kind: EventServerDown, Time: at}
+	}
+	return Event{Kind: EventServerUp, Time: at}
+}
+
+// transitionEvents returns, for every policy under which the server
+// changed state between previous and current, the event chats with that
+// policy get, tagged with it (see announce).
+func transitionEvents(previous, current func(policy string) ServerState, at time.Time) []Event {
+	var events []Event
+	for _, policy := range onlinePolicies {
+		if state := current(policy); state != previous(policy) {
+			event := stateEvent(state, at)
+			event.policy = policy
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// offlineSince returns when the server went offline under policy, going
+// by the trailing run of offline checks in entries (sorted oldest first),
+// and false if the last of them wasn't offline.
+func offlineSince(policy string, entries []StatusEntry) (time.Time, bool) {
+	i := len(entries)
+	for i > 0 && entryState(policy, &entries[i-1]) == StateOffline {
+		i--
+	}
+	if i == len(entries) {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(entries[i].LastChecked), true
+}
+
+// policy is the chat's online policy, ONLINE_POLICY unless it picked one.
+func (s ChatSettings) policy() string {
+	if s.OnlinePolicy != "" {
+		return s.OnlinePolicy
+	}
+	return config.OnlinePolicy
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerState(t *testing.T) {
+	for _, tt := range []struct {
+		policy    string
+		reachable bool
+		players   int
+		want      ServerState
+	}{
+		{ONLINE_REACHABLE, true, 0, StateOnline},
+		{ONLINE_REACHABLE, false, 0, StateOffline},
+		{ONLINE_PLAYERS, true, 0, StateOffline},
+		{ONLINE_PLAYERS, true, 2, StateOnline},
+		{ONLINE_THREE_STATE, true, 0, StateEmpty},
+		{ONLINE_THREE_STATE, true, 2, StateOnline},
+		{ONLINE_THREE_STATE, false, 0, StateOffline},
+	} {
+		if got := serverState(tt.policy, tt.reachable, tt.players); got != tt.want {
+			t.Errorf("serverState(%s, %v, %d) = %s, want %s", tt.policy, tt.reachable, tt.players, got, tt.want)
+		}
+	}
+}
+
+func TestChatsFollowTheirOnlinePolicy(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.OnlinePolicy = ONLINE_PLAYERS
+	state.Chats = map[string]*ChatSettings{
+		"-7": {OnlinePolicy: ONLINE_REACHABLE},
+		"-8": {OnlinePolicy: ONLINE_THREE_STATE},
+	}
+	store.Entries = []StatusEntry{{
+		ID: "1", Online: true, LastChecked: time.Now().Add(-time.Minute).UnixMilli(), Players: []string{"steve"}, PlayerCount: 1,
+	}}
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, Players: []string{}}, CheckedAt: time.Now()}
+	})
+
+	checkServer(context.Background())
+
+	sent := map[string]string{}
+	for _, call := range fake.callsTo("sendMessage") {
+		sent[call.Params["chat_id"].(string)] = call.Params["text"].(string)
+	}
+	if !strings.Contains(sent["-42"], "down") {
+		t.Errorf("players chat got %q, want the server down", sent["-42"])
+	}
+	if text := sent["-7"]; strings.Contains(text, "down") || !strings.Contains(text, "steve") {
+		t.Errorf("reachable chat got %q, want only steve leaving", text)
+	}
+	if !strings.Contains(sent["-8"], "nobody is playing") {
+		t.Errorf("three-state chat got %q, want the server empty", sent["-8"])
+	}
+	if latest := getLatest(); latest.Online {
+		t.Errorf("stored %+v, want offline under ONLINE_POLICY=players", latest)
+	}
+}
+
+func TestServerUpSaysHowLongItWasDown(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.OnlinePolicy = ONLINE_REACHABLE
+	state.Chats = map[string]*ChatSettings{"-7": {OnlinePolicy: ONLINE_PLAYERS}}
+
+	down := time.Now().Add(-42 * time.Minute)
+	recordTransition(false, down)
+	store.Entries = []StatusEntry{
+		{ID: "1", Online: true, LastChecked: down.Add(-2 * time.Hour).UnixMilli(), Players: []string{}},
+		{ID: "2", Online: false, LastChecked: down.UnixMilli(), Players: []string{}, Error: "timeout"},
+		{ID: "3", Online: false, LastChecked: time.Now().Add(-time.Minute).UnixMilli(), Players: []string{}, Error: "timeout"},
+	}
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, Players: []string{"steve"}, PlayerCount: 1}, CheckedAt: time.Now()}
+	})
+
+	checkServer(context.Background())
+
+	sent := map[string]string{}
+	for _, call := range fake.callsTo("sendMessage") {
+		sent[call.Params["chat_id"].(string)] = call.Params["text"].(string)
+	}
+	if !strings.Contains(sent["-42"], "back online after 42 minutes") {
+		t.Errorf("reachable chat got %q, want the downtime", sent["-42"])
+	}
+	// Empty before the outage, so down for ONLINE_PLAYERS since the
+	// first check.
+	if !strings.Contains(sent["-7"], "back online after 2 hours") {
+		t.Errorf("players chat got %q, want the downtime from the history", sent["-7"])
+	}
+}
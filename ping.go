@@ -0,0 +1,560 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrTimeout means the server didn't answer within TIMEOUT.
+	ErrTimeout = errors.New("ping timed out")
+	// ErrRefused means nothing is listening on the server port.
+	ErrRefused = errors.New("connection refused")
+)
+
+// ErrBadPacket means the server answered, but not with a valid Status
+// Response.
+type ErrBadPacket struct {
+	Reason string
+}
+
+func (e *ErrBadPacket) Error() string {
+	return "bad status packet: " + e.Reason
+}
+
+// badPacket builds an *ErrBadPacket with a formatted reason.
+func badPacket(format string, args ...interface{}) error {
+	return &ErrBadPacket{Reason: fmt.Sprintf(format, args...)}
+}
+
+// classifyNetError maps dial/read/write errors onto ErrTimeout and
+// ErrRefused, keeping the original error in the chain. Anything else is
+// returned as is.
+func classifyNetError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%w: %v", ErrRefused, err)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return badPacket("connection closed mid-response")
+	}
+	return err
+}
+
+// phaseDeadline is now+d, or ctx's deadline if that comes first.
+func phaseDeadline(ctx context.Context, d time.Duration) time.Time {
+	deadline := time.Now().Add(d)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+// errorCategory names the kind of ping failure, for logs and storage.
+func errorCategory(err error) string {
+	var bad *ErrBadPacket
+	var held *heldError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &held):
+		return held.category
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrRefused):
+		return "refused"
+	case errors.As(err, &bad):
+		return "bad_packet"
+	default:
+		return "error"
+	}
+}
+
+type ServerStatus struct {
+	Online      bool
+	PlayerCount int
+	Players     []string
+
+	// RawMOTD keeps the server's § formatting codes; MOTD is the same text
+	// passed through sanitizeText.
+	RawMOTD string
+	MOTD    string
+
+	// ConnectTime covers resolving and dialing; ProtocolTime covers the
+	// handshake and status exchange on the open connection.
+	ConnectTime  time.Duration
+	ProtocolTime time.Duration
+	// Latency is the round trip of the status request, or of the Ping
+	// packet with PING_PONG.
+	Latency time.Duration
+
+	// IP is the address the server was reached at.
+	IP string
+	// Version is the version the server reports, e.g. "Paper 1.20.4".
+	Version string
+
+	// Map, Software and Plugins only come from the Query protocol.
+	Map      string
+	Software string
+	Plugins  []string
+}
+
+const (
+	// MAX_RESPONSE_SIZE caps the Status Response packet, favicon included.
+	MAX_RESPONSE_SIZE = 65535
+	// MAX_PLAYER_SAMPLE is how many names of the player sample are kept.
+	MAX_PLAYER_SAMPLE = 100
+)
+
+// pingDialer is shared by every ping. SLP servers close the connection after
+// answering, so there's nothing to keep alive; TCP keep-alive probes would
+// only add traffic.
+var pingDialer = &net.Dialer{KeepAlive: -1}
+
+// MINECRAFT_DEFAULT_PORT is the port Java Edition assumes when an address
+// has none, and the only one it looks up SRV records for.
+const MINECRAFT_DEFAULT_PORT = 25565
+
+// lookupSRV finds the _minecraft._tcp records of a host.
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// addressCache remembers the resolved addresses of the server for
+// config.AddressCacheTTL, so frequent checks don't hit DNS every time. It
+// also counts dial results per IP, so with several A records (round-robin
+// DNS) the healthiest addresses are tried first.
+type addressCache struct {
+	mu   sync.Mutex
+	host string
+	ips  []string
+	// srvPort is the port of host's SRV record, 0 without one.
+	srvPort uint16
+	expires time.Time
+	health  map[string]map[string]*IPHealth
+}
+
+// IPHealth counts dial results for one resolved IP of a host.
+type IPHealth struct {
+	IP          string
+	Successes   int
+	Failures    int
+	LastFailure time.Time
+}
+
+// score is the smoothed success rate; IPs never dialed score 0.5.
+func (h IPHealth) score() float64 {
+	return float64(h.Successes+1) / float64(h.Successes+h.Failures+2)
+}
+
+var resolvedAddress = &addressCache{}
+
+// resolve returns the addresses to dial for host:port, healthiest first
+// (ties keep the DNS order). host is only looked up again when the cached
+// addresses are missing or expired. On the default port, host's
+// _minecraft._tcp SRV record is followed first, like the game does; without
+// one, host's own addresses are used.
+func (c *addressCache) resolve(ctx context.Context, host string, port uint16) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.host != host || !time.Now().Before(c.expires) {
+		target, srvPort := host, uint16(0)
+		if port == MINECRAFT_DEFAULT_PORT && net.ParseIP(host) == nil {
+			// Records come sorted by priority and shuffled by weight.
+			if _, records, err := lookupSRV(ctx, "minecraft", "tcp", host); err == nil && len(records) > 0 {
+				target, srvPort = strings.TrimSuffix(records[0].Target, "."), records[0].Port
+			}
+		}
+		ips, err := net.DefaultResolver.LookupHost(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no addresses found for %s", target)
+		}
+		c.host = host
+		c.ips = ips
+		c.srvPort = srvPort
+		c.expires = time.Now().Add(config.AddressCacheTTL)
+	}
+	if c.srvPort != 0 {
+		port = c.srvPort
+	}
+
+	ips := append([]string(nil), c.ips...)
+	sort.SliceStable(ips, func(i, j int) bool {
+		return c.healthOf(host, ips[i]).score() > c.healthOf(host, ips[j]).score()
+	})
+	addresses := make([]string, len(ips))
+	for i, ip := range ips {
+		addresses[i] = net.JoinHostPort(ip, strconv.Itoa(int(port)))
+	}
+	return addresses, nil
+}
+
+// healthOf returns the counts for ip of host. The caller must hold c.mu.
+func (c *addressCache) healthOf(host, ip string) IPHealth {
+	if h := c.health[host][ip]; h != nil {
+		return *h
+	}
+	return IPHealth{IP: ip}
+}
+
+// record counts the result of dialing address (ip:port) of host.
+func (c *addressCache) record(host, address string, err error) {
+	ip, _, splitErr := net.SplitHostPort(address)
+	if splitErr != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.health == nil {
+		c.health = map[string]map[string]*IPHealth{}
+	}
+	if c.health[host] == nil {
+		c.health[host] = map[string]*IPHealth{}
+	}
+	h := c.health[host][ip]
+	if h == nil {
+		h = &IPHealth{IP: ip}
+		c.health[host][ip] = h
+	}
+	if err != nil {
+		h.Failures++
+		h.LastFailure = time.Now()
+	} else {
+		h.Successes++
+	}
+}
+
+// healthReport returns the counts for every IP of host dialed so far,
+// sorted by IP.
+func (c *addressCache) healthReport(host string) []IPHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := make([]IPHealth, 0, len(c.health[host]))
+	for _, h := range c.health[host] {
+		report = append(report, *h)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].IP < report[j].IP })
+	return report
+}
+
+// invalidate drops the cached addresses, e.g. after none could be dialed.
+func (c *addressCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expires = time.Time{}
+}
+
+// statusJSON mirrors the parts of the Server List Ping response we use.
+// Decoding into a struct instead of map[string]interface{} keeps the
+// per-check allocation count flat no matter how chatty the server is.
+type statusJSON struct {
+	Version *struct {
+		Name string `json:"name"`
+	} `json:"version"`
+	Players *struct {
+		Online *int `json:"online"`
+		Sample []struct {
+			Name string `json:"name"`
+		} `json:"sample"`
+	} `json:"players"`
+	Description *chatComponent `json:"description"`
+}
+
+// pingMinecraftServer runs one Server List Ping. Dialing, writing the
+// request and reading the response each get their own deadline
+// (DIAL_TIMEOUT, WRITE_TIMEOUT, READ_TIMEOUT), capped by ctx's deadline, so a
+// slow connect can't eat the time the read needs and vice versa. Cancelling
+// ctx aborts whichever phase is running.
+func pingMinecraftServer(ctx context.Context, host string, port uint16) (*ServerStatus, error) {
+	connectStart := time.Now()
+
+	dialCtx, cancel := context.WithTimeout(ctx, DIAL_TIMEOUT)
+	defer cancel()
+
+	addresses, err := resolvedAddress.resolve(dialCtx, host, port)
+	if err != nil {
+		return nil, fmt.Errorf("resolve: %w", classifyNetError(err))
+	}
+
+	var conn net.Conn
+	for _, address := range addresses {
+		conn, err = pingDialer.DialContext(dialCtx, "tcp", address)
+		resolvedAddress.record(host, address, err)
+		if err == nil || dialCtx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		// The server may have moved; resolve again next time.
+		resolvedAddress.invalidate()
+		return nil, fmt.Errorf("dial: %w", classifyNetError(err))
+	}
+	defer conn.Close()
+
+	protocolStart := time.Now()
+	connectTime := protocolStart.Sub(connectStart)
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn.SetWriteDeadline(phaseDeadline(ctx, WRITE_TIMEOUT))
+
+	hostBytes := []byte(host)
+	packet := new(bytes.Buffer)
+
+	writeVarInt(packet, 0)
+	writeVarInt(packet, 47)
+	writeVarInt(packet, int32(len(hostBytes)))
+	packet.Write(hostBytes)
+	binary.Write(packet, binary.BigEndian, uint16(port))
+	writeVarInt(packet, 1)
+
+	packetData := packet.Bytes()
+	packetLen := new(bytes.Buffer)
+	writeVarInt(packetLen, int32(len(packetData)))
+
+	_, err = conn.Write(append(packetLen.Bytes(), packetData...))
+	if err != nil {
+		return nil, fmt.Errorf("write handshake: %w", classifyNetError(err))
+	}
+
+	statusReq := new(bytes.Buffer)
+	writeVarInt(statusReq, 0)
+	statusReqData := statusReq.Bytes()
+	statusReqLen := new(bytes.Buffer)
+	writeVarInt(statusReqLen, int32(len(statusReqData)))
+	requestSent := time.Now()
+	_, err = conn.Write(append(statusReqLen.Bytes(), statusReqData...))
+	if err != nil {
+		return nil, fmt.Errorf("write status request: %w", classifyNetError(err))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(phaseDeadline(ctx, READ_TIMEOUT))
+
+	responseLen, err := readVarInt(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response length: %w", classifyNetError(err))
+	}
+	latency := time.Since(requestSent)
+
+	if responseLen <= 0 || responseLen > MAX_RESPONSE_SIZE {
+		return nil, badPacket("invalid response length: %d", responseLen)
+	}
+
+	responseData := make([]byte, responseLen)
+	totalRead := 0
+	for totalRead < int(responseLen) {
+		n, err := conn.Read(responseData[totalRead:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response data: %w", classifyNetError(err))
+		}
+		totalRead += n
+	}
+
+	status, err := parseStatusPacket(responseData)
+	if err != nil {
+		return nil, err
+	}
+	status.Latency = latency
+	if config.PingPong {
+		// Some servers close the connection instead of answering; the
+		// status exchange's latency will do then.
+		if rtt, err := pingPong(ctx, conn); err == nil {
+			status.Latency = rtt
+		}
+	}
+	status.ConnectTime = connectTime
+	status.ProtocolTime = time.Since(protocolStart)
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		status.IP = addr.IP.String()
+	}
+	return status, nil
+}
+
+// pingPong sends the Ping packet that follows the status exchange and
+// times the server's Pong, which echoes its payload.
+func pingPong(ctx context.Context, conn net.Conn) (time.Duration, error) {
+	conn.SetWriteDeadline(phaseDeadline(ctx, WRITE_TIMEOUT))
+	payload := time.Now().UnixNano()
+	packet := []byte{9, 0x01}
+	packet = binary.BigEndian.AppendUint64(packet, uint64(payload))
+	start := time.Now()
+	if _, err := conn.Write(packet); err != nil {
+		return 0, fmt.Errorf("write ping: %w", classifyNetError(err))
+	}
+
+	conn.SetReadDeadline(phaseDeadline(ctx, READ_TIMEOUT))
+	length, err := readVarInt(conn)
+	if err != nil {
+		return 0, fmt.Errorf("read pong: %w", classifyNetError(err))
+	}
+	rtt := time.Since(start)
+	if length != 9 {
+		return 0, badPacket("pong length %d", length)
+	}
+	var pong [9]byte
+	if _, err := io.ReadFull(conn, pong[:]); err != nil {
+		return 0, fmt.Errorf("read pong: %w", classifyNetError(err))
+	}
+	if pong[0] != 0x01 || int64(binary.BigEndian.Uint64(pong[1:])) != payload {
+		return 0, badPacket("pong doesn't echo the ping")
+	}
+	return rtt, nil
+}
+
+// parseStatusPacket decodes a Status Response packet body (packet ID, then
+// the length-prefixed JSON string) into a ServerStatus.
+func parseStatusPacket(responseData []byte) (*ServerStatus, error) {
+	responseBuf := bytes.NewBuffer(responseData)
+
+	_, err := readVarInt(responseBuf)
+	if err != nil {
+		return nil, badPacket("reading packet ID: %v", err)
+	}
+
+	jsonLen, err := readVarInt(responseBuf)
+	if err != nil {
+		return nil, badPacket("reading JSON length: %v", err)
+	}
+	if jsonLen <= 0 || int(jsonLen) > responseBuf.Len() {
+		return nil, badPacket("JSON length %d doesn't fit the %d bytes left in the packet", jsonLen, responseBuf.Len())
+	}
+
+	jsonData := make([]byte, jsonLen)
+	_, err = responseBuf.Read(jsonData)
+	if err != nil {
+		return nil, badPacket("reading JSON: %v", err)
+	}
+
+	return parseStatusJSON(jsonData)
+}
+
+func parseStatusJSON(jsonData []byte) (*ServerStatus, error) {
+	var response statusJSON
+	if err := json.Unmarshal(jsonData, &response); err != nil {
+		return nil, badPacket("failed to parse JSON response: %v", err)
+	}
+
+	if response.Version == nil {
+		return nil, badPacket("missing version field")
+	}
+	if response.Version.Name == "" {
+		return nil, badPacket("missing or empty version name")
+	}
+
+	status := &ServerStatus{Online: true, Version: sanitizeText(response.Version.Name)}
+
+	if response.Description != nil {
+		status.RawMOTD = legacyText(*response.Description)
+		status.MOTD = sanitizeText(status.RawMOTD)
+	}
+
+	if players := response.Players; players != nil {
+		if players.Online != nil {
+			if *players.Online < 0 {
+				return nil, badPacket("negative player count %d", *players.Online)
+			}
+			status.PlayerCount = *players.Online
+		}
+
+		// Vanilla sends at most 12 names; plugins may list everyone. The
+		// count still says how many are on, so the rest of the names can
+		// go.
+		if len(players.Sample) > MAX_PLAYER_SAMPLE {
+			players.Sample = players.Sample[:MAX_PLAYER_SAMPLE]
+		}
+
+		if players.Sample != nil {
+			playerList := make([]string, 0, len(players.Sample))
+			for _, p := range players.Sample {
+				if name := sanitizePlayerName(p.Name); name != "" {
+					playerList = append(playerList, name)
+				}
+			}
+			status.Players = playerList
+		}
+	}
+
+	return status, nil
+}
+
+func writeVarInt(buf *bytes.Buffer, value int32) {
+	for {
+		if (value & ^0x7F) == 0 {
+			buf.WriteByte(byte(value))
+			return
+		}
+		buf.WriteByte(byte((value & 0x7F) | 0x80))
+		value = int32(uint32(value) >> 7)
+	}
+}
+
+func readVarInt(reader interface{}) (int32, error) {
+	var b byte
+	var result int32
+	var shift uint
+
+	for {
+		var err error
+		switch r := reader.(type) {
+		case *bytes.Buffer:
+			b, err = r.ReadByte()
+		case net.Conn:
+			var data [1]byte
+			_, err = r.Read(data[:])
+			b = data[0]
+		default:
+			return 0, fmt.Errorf("unsupported reader type")
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		result |= int32(b&0x7F) << shift
+		if (b & 0x80) == 0 {
+			break
+		}
+		shift += 7
+		if shift >= 32 {
+			return 0, fmt.Errorf("varint too long")
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func statusPacket(json string) []byte {
+	packet := new(bytes.Buffer)
+	writeVarInt(packet, 0)
+	writeVarInt(packet, int32(len(json)))
+	packet.WriteString(json)
+	return packet.Bytes()
+}
+
+func samplePacket(players int) []byte {
+	sample := new(bytes.Buffer)
+	for i := 0; i < players; i++ {
+		if i > 0 {
+			sample.WriteString(",")
+		}
+		fmt.Fprintf(sample, `{"name":"player%d","id":"00000000-0000-0000-0000-%012d"}`, i, i)
+	}
+	return statusPacket(fmt.Sprintf(
+		`{"version":{"name":"1.20.4","protocol":765},"players":{"max":20,"online":%d,"sample":[%s]},"description":{"text":"lnudorm3"}}`,
+		players, sample.String()))
+}
+
+func TestParseStatusPacket(t *testing.T) {
+	status, err := parseStatusPacket(samplePacket(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Online || status.PlayerCount != 2 || len(status.Players) != 2 || status.Players[1] != "player1" {
+		t.Fatalf("parseStatusPacket = %+v", status)
+	}
+
+	if _, err := parseStatusPacket(statusPacket(`{"players":{"online":1}}`)); err == nil {
+		t.Fatal("expected an error for a response without version")
+	}
+
+	status, err = parseStatusPacket(samplePacket(MAX_PLAYER_SAMPLE + 50))
+	if err != nil {
+		t.Fatalf("huge sample: %v", err)
+	}
+	if status.PlayerCount != MAX_PLAYER_SAMPLE+50 || len(status.Players) != MAX_PLAYER_SAMPLE {
+		t.Fatalf("huge sample: %d players, %d names; want the count and %d names", status.PlayerCount, len(status.Players), MAX_PLAYER_SAMPLE)
+	}
+}
+
+func BenchmarkParseStatusPacket(b *testing.B) {
+	for _, players := range []int{0, 12} {
+		b.Run(fmt.Sprintf("players=%d", players), func(b *testing.B) {
+			packet := samplePacket(players)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := parseStatusPacket(packet); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWriteVarInt(b *testing.B) {
+	buf := new(bytes.Buffer)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		writeVarInt(buf, int32(i))
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	_, err = pingMinecraftServer(context.Background(), "127.0.0.1", port)
+	if !errors.Is(err, ErrRefused) || errorCategory(err) != "refused" {
+		t.Fatalf("ping of a closed port: %v (%q)", err, errorCategory(err))
+	}
+
+	_, err = parseStatusPacket(statusPacket(`not json`))
+	var bad *ErrBadPacket
+	if !errors.As(err, &bad) || errorCategory(err) != "bad_packet" {
+		t.Fatalf("parse of garbage: %v (%q)", err, errorCategory(err))
+	}
+}
+
+func TestPingHonorsContextDeadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		// Accept and never answer.
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = pingMinecraftServer(ctx, "127.0.0.1", port)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("ping of a silent server: %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > READ_TIMEOUT {
+		t.Fatalf("ping took %v, want it cut short by the context", elapsed)
+	}
+}
+
+func TestParseStatusPacketLimits(t *testing.T) {
+	cases := map[string][]byte{
+		"json length past end": func() []byte {
+			packet := new(bytes.Buffer)
+			writeVarInt(packet, 0)
+			writeVarInt(packet, 1000)
+			packet.WriteString(`{"version":{"name":"1.20.4"}}`)
+			return packet.Bytes()
+		}(),
+		"negative count": statusPacket(`{"version":{"name":"1.20.4"},"players":{"online":-5}}`),
+	}
+
+	for name, packet := range cases {
+		var bad *ErrBadPacket
+		if _, err := parseStatusPacket(packet); !errors.As(err, &bad) {
+			t.Errorf("%s: err = %v, want *ErrBadPacket", name, err)
+		}
+	}
+}
+
+// fakeMinecraftServer answers every Server List Ping with packet (a body
+// from statusPacket), and the Ping after it, and returns the port it
+// listens on.
+func fakeMinecraftServer(t *testing.T, packet []byte) uint16 {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	response := new(bytes.Buffer)
+	writeVarInt(response, int32(len(packet)))
+	response.Write(packet)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// Read the handshake and status request first; closing with
+			// them unread would break the client's writes.
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			for i := 0; i < 2; i++ {
+				n, err := readVarInt(conn)
+				if err != nil {
+					break
+				}
+				io.ReadFull(conn, make([]byte, n))
+			}
+			conn.Write(response.Bytes())
+			// Echo a Ping packet as the Pong, if one follows.
+			if n, err := readVarInt(conn); err == nil && n == 9 {
+				ping := make([]byte, n)
+				if _, err := io.ReadFull(conn, ping); err == nil {
+					conn.Write(append([]byte{9}, ping...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestPingRecordsServerIP(t *testing.T) {
+	port := fakeMinecraftServer(t, samplePacket(1))
+
+	status, err := pingMinecraftServer(context.Background(), "127.0.0.1", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.IP != "127.0.0.1" {
+		t.Fatalf("IP = %q, want 127.0.0.1", status.IP)
+	}
+}
+
+func TestPingMeasuresLatency(t *testing.T) {
+	port := fakeMinecraftServer(t, samplePacket(1))
+	previous := config
+	t.Cleanup(func() { config = previous })
+
+	for _, pingPong := range []bool{false, true} {
+		config.PingPong = pingPong
+		status, err := pingMinecraftServer(context.Background(), "127.0.0.1", port)
+		if err != nil {
+			t.Fatalf("PING_PONG=%v: %v", pingPong, err)
+		}
+		if status.Latency <= 0 || status.Latency > status.ProtocolTime {
+			t.Errorf("PING_PONG=%v: latency %v, protocol time %v", pingPong, status.Latency, status.ProtocolTime)
+		}
+	}
+
+	result := &PingResult{Status: &ServerStatus{Online: true, PlayerCount: 1, Latency: 42 * time.Millisecond}}
+	if got := renderStatus("en", result); !strings.HasSuffix(got, "\n📶 Latency: 42 ms") {
+		t.Errorf("renderStatus = %q", got)
+	}
+	if got := latencyMs(result); got != 42 {
+		t.Errorf("latencyMs = %d", got)
+	}
+}
+
+func TestAddressCachePrefersHealthyIPs(t *testing.T) {
+	c := &addressCache{
+		host:    "mc.example.com",
+		ips:     []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		expires: time.Now().Add(time.Hour),
+	}
+
+	c.record("mc.example.com", "10.0.0.1:25565", errors.New("refused"))
+	c.record("mc.example.com", "10.0.0.3:25565", nil)
+
+	addresses, err := c.resolve(context.Background(), "mc.example.com", 25565)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.3:25565", "10.0.0.2:25565", "10.0.0.1:25565"}
+	if strings.Join(addresses, ",") != strings.Join(want, ",") {
+		t.Errorf("resolve() = %v, want %v", addresses, want)
+	}
+
+	report := c.healthReport("mc.example.com")
+	if len(report) != 2 || report[0].IP != "10.0.0.1" || report[0].Failures != 1 || report[1].Successes != 1 {
+		t.Errorf("healthReport() = %+v", report)
+	}
+}
+
+func TestPingFollowsSRVRecord(t *testing.T) {
+	port := fakeMinecraftServer(t, samplePacket(1))
+	previous := lookupSRV
+	t.Cleanup(func() {
+		lookupSRV = previous
+		resolvedAddress.invalidate()
+	})
+
+	var looked []string
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		looked = append(looked, name)
+		if name != "srv-only.example" {
+			return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		return "_minecraft._tcp." + name, []*net.SRV{{Target: "127.0.0.1.", Port: port}}, nil
+	}
+
+	resolvedAddress.invalidate()
+	if _, err := pingMinecraftServer(context.Background(), "srv-only.example", MINECRAFT_DEFAULT_PORT); err != nil {
+		t.Fatalf("ping via SRV: %v", err)
+	}
+
+	// Without a record the host's own address is used; an explicit port
+	// skips the lookup, like in the game.
+	resolvedAddress.invalidate()
+	addresses, err := resolvedAddress.resolve(context.Background(), "localhost", MINECRAFT_DEFAULT_PORT)
+	if err != nil || len(addresses) == 0 || !strings.HasSuffix(addresses[0], ":25565") {
+		t.Errorf("resolve without SRV = %v, %v", addresses, err)
+	}
+	resolvedAddress.invalidate()
+	resolvedAddress.resolve(context.Background(), "localhost", 25566)
+	if len(looked) != 2 {
+		t.Errorf("SRV lookups = %v, want none for an explicit port", looked)
+	}
+}
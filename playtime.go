@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PLAYTIME_DAYS is how many days of playtime are kept, and the longest
+// period /stats covers.
+const PLAYTIME_DAYS = 30
+
+// PLAYTIME_DAY_LAYOUT keys state.Playtime by local date.
+const PLAYTIME_DAY_LAYOUT = "2006-01-02"
+
+// trackPlaytime opens a session for every player who joined at now and
+// adds the sessions of those who left to their playtime.
+func trackPlaytime(joined, left []string, now time.Time) {
+	if len(joined) == 0 && len(left) == 0 {
+		return
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.Sessions == nil {
+		state.Sessions = map[string]int64{}
+	}
+	for _, player := range joined {
+		if _, ok := state.Sessions[player]; !ok {
+			state.Sessions[player] = now.Unix()
+		}
+	}
+	for _, player := range left {
+		if start, ok := state.Sessions[player]; ok {
+			addPlaytime(player, time.Unix(start, 0), now)
+			delete(state.Sessions, player)
+		}
+	}
+
+	oldest := now.AddDate(0, 0, -PLAYTIME_DAYS).Format(PLAYTIME_DAY_LAYOUT)
+	for day := range state.Playtime {
+		if day < oldest {
+			delete(state.Playtime, day)
+		}
+	}
+	saveState()
+}
+
+// addPlaytime adds the session from start to end to player's days, split
+// at local midnight; nothing if the clock was set back past start. The
+// caller must hold state.mu.
+func addPlaytime(player string, start, end time.Time) {
+	if state.Playtime == nil {
+		state.Playtime = map[string]map[string]int64{}
+	}
+	for start.Before(end) {
+		y, m, d := start.Date()
+		midnight := time.Date(y, m, d+1, 0, 0, 0, 0, start.Location())
+		until := end
+		if midnight.Before(end) {
+			until = midnight
+		}
+
+		day := start.Format(PLAYTIME_DAY_LAYOUT)
+		if state.Playtime[day] == nil {
+			state.Playtime[day] = map[string]int64{}
+		}
+		state.Playtime[day][player] += int64(until.Sub(start) / time.Second)
+		start = until
+	}
+}
+
+// PlayerTime is one line of /stats.
+type PlayerTime struct {
+	Player string
+	Time   time.Duration
+}
+
+// playtimeSince sums the playtime of the last days days up to now, today
+// included, counting open sessions up to now. Longest first.
+func playtimeSince(days int, now time.Time) []PlayerTime {
+	y, m, d := now.Date()
+	from := time.Date(y, m, d-days+1, 0, 0, 0, 0, now.Location())
+	fromDay := from.Format(PLAYTIME_DAY_LAYOUT)
+
+	totals := map[string]time.Duration{}
+	state.mu.Lock()
+	for day, players := range state.Playtime {
+		if day >= fromDay {
+			for player, seconds := range players {
+				totals[player] += time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	// Sessions are wall-clock times; one opened before the clock was set
+	// back counts for nothing rather than taking from the rest.
+	for player, start := range state.Sessions {
+		if started := time.Unix(start, 0); started.Before(from) {
+			totals[player] += now.Sub(from)
+		} else {
+			totals[player] += max(now.Sub(started), 0)
+		}
+	}
+	state.mu.Unlock()
+
+	result := make([]PlayerTime, 0, len(totals))
+	for player, total := range totals {
+		if total >= time.Minute {
+			result = append(result, PlayerTime{Player: player, Time: total})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Time != result[j].Time {
+			return result[i].Time > result[j].Time
+		}
+		return result[i].Player < result[j].Player
+	})
+	return result
+}
+
+// forgetPlaytime drops player's playtime and open session.
+func forgetPlaytime(player string) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	for name := range state.Sessions {
+		if strings.EqualFold(name, player) {
+			delete(state.Sessions, name)
+		}
+	}
+	for _, players := range state.Playtime {
+		for name := range players {
+			if strings.EqualFold(name, player) {
+				delete(players, name)
+			}
+		}
+	}
+	saveState()
+}
+
+// handleStatsCommand implements /stats [days]: who played over the last
+// days days (1, today, by default) and for how long.
+func handleStatsCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+	days := 1
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 || n > PLAYTIME_DAYS {
+			reply(ctx, msg, tr(lang, "stats.usage", PLAYTIME_DAYS))
+			return
+		}
+		days = n
+	}
+	reply(ctx, msg, renderStats(lang, days, playtimeSince(days, time.Now())))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlaytimeSplitsAtMidnight(t *testing.T) {
+	useTempStore(t, 0)
+
+	join := time.Date(2024, 5, 1, 23, 30, 0, 0, time.Local)
+	trackPlaytime([]string{"steve", "alex"}, nil, join)
+	trackPlaytime(nil, []string{"steve"}, join.Add(time.Hour))
+
+	if got := state.Playtime["2024-05-01"]["steve"]; got != 1800 {
+		t.Fatalf("steve on May 1 = %ds, want 1800", got)
+	}
+	if got := state.Playtime["2024-05-02"]["steve"]; got != 1800 {
+		t.Fatalf("steve on May 2 = %ds, want 1800", got)
+	}
+
+	// alex is still online: today counts from midnight.
+	times := playtimeSince(1, join.Add(2*time.Hour))
+	if len(times) != 2 || times[0].Player != "alex" || times[0].Time != 90*time.Minute ||
+		times[1].Player != "steve" || times[1].Time != 30*time.Minute {
+		t.Fatalf("today = %+v", times)
+	}
+	times = playtimeSince(2, join.Add(2*time.Hour))
+	if len(times) != 2 || times[0].Time != 2*time.Hour || times[1].Time != time.Hour {
+		t.Fatalf("two days = %+v", times)
+	}
+
+	forgetPlaytime("STEVE")
+	if times := playtimeSince(2, join.Add(2*time.Hour)); len(times) != 1 || times[0].Player != "alex" {
+		t.Fatalf("after forgetting steve = %+v", times)
+	}
+}
+
+func TestPlaytimeClockSetBack(t *testing.T) {
+	useTempStore(t, 0)
+
+	join := time.Date(2024, 5, 1, 20, 0, 0, 0, time.Local)
+	trackPlaytime([]string{"steve"}, nil, join)
+	trackPlaytime(nil, []string{"steve"}, join.Add(time.Hour))
+	trackPlaytime([]string{"steve"}, nil, join.Add(2*time.Hour))
+
+	// The clock was set back an hour and a half while steve was on.
+	now := join.Add(30 * time.Minute)
+	if times := playtimeSince(1, now); len(times) != 1 || times[0].Time != time.Hour {
+		t.Fatalf("today = %+v, want the closed hour and nothing for the session", times)
+	}
+	trackPlaytime(nil, []string{"steve"}, now)
+	if got := state.Playtime["2024-05-01"]["steve"]; got != 3600 {
+		t.Fatalf("steve on May 1 = %ds, want 3600", got)
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+
+	ctx := context.Background()
+	send := func(text string) string {
+		handleUpdate(ctx, Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: -42}, From: &User{ID: 1}, Text: text,
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	if got := send("/stats"); got != "📊 Who played today:\nNobody played." {
+		t.Fatalf("/stats = %q", got)
+	}
+
+	now := time.Now()
+	trackPlaytime([]string{"steve"}, nil, now.Add(-3*time.Hour-5*time.Minute))
+	trackPlaytime(nil, []string{"steve"}, now.Add(-time.Minute))
+	want := "📊 Who played in the last 7 days:\n<b>steve</b> — 3h 4m"
+	if got := send("/stats 7"); got != want {
+		t.Fatalf("/stats 7 = %q, want %q", got, want)
+	}
+	if got := send("/stats 365"); !strings.HasPrefix(got, "Usage") {
+		t.Fatalf("/stats 365 = %q", got)
+	}
+}
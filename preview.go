@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Preview is what one notifier would send for an event.
+type Preview struct {
+	Notifier string `json:"notifier"`
+	Target   string `json:"target"`
+	// Disabled is set when the chat has the event toggled off; Text is
+	// what it would get otherwise.
+	Disabled bool            `json:"disabled,omitempty"`
+	Text     string          `json:"text,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+// handlePreviewAPI renders an event payload the way every configured
+// notifier would send it, without sending anything: each Telegram chat
+// following SERVER_HOST in its language and with its templates, and the
+// payloads of the webhooks, Discord, NATS, Kafka, Redis and Grafana. "templates"
+// next to the event fields replaces the chats' templates for the preview,
+// to try one out before setting it.
+func handlePreviewAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkBearerToken(r, config.APIToken) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY))
+	if err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	payload, err := validateEventPayload(data)
+	if err != nil {
+		http.Error(w, "invalid event: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	var request struct {
+		Templates map[string]string `json:"templates"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for key, text := range request.Templates {
+		if !containsString(chatTemplateKeys, key) {
+			http.Error(w, fmt.Sprintf("unknown template %q", key), http.StatusUnprocessableEntity)
+			return
+		}
+		if _, err := renderChatTemplate(text, MessageTemplateData{}); err != nil {
+			http.Error(w, fmt.Sprintf("invalid template %s: %v", key, err), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	previews, err := previewEvent(payload.Event, request.Templates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"notifiers": previews})
+}
+
+// previewEvent renders event for every configured notifier; templates, if
+// any, override the chats' own.
+func previewEvent(event Event, templates map[string]string) ([]Preview, error) {
+	previews := []Preview{}
+	for _, chatID := range servedChats() {
+		settings := chatSettings(chatID)
+		if settings.server() != "" {
+			continue
+		}
+		for key, text := range templates {
+			if settings.Templates == nil {
+				settings.Templates = map[string]string{}
+			}
+			settings.Templates[key] = text
+		}
+		previews = append(previews, Preview{
+			Notifier: "telegram",
+			Target:   chatID,
+			Disabled: !settings.enabled(string(event.Kind)),
+			Text:     renderChatEvents(settings, []Event{event}),
+		})
+	}
+
+	body, err := marshalEvent(event)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range config.WebhookURLs {
+		previews = append(previews, Preview{Notifier: "webhook", Target: url, Body: body})
+	}
+	if config.DiscordWebhookURL != "" {
+		message, err := json.Marshal(discordMessage(config.Language, []Event{event}))
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, Preview{Notifier: "discord", Target: config.DiscordWebhookURL, Body: message})
+	}
+	if config.NATSURL != "" {
+		previews = append(previews, Preview{Notifier: "nats", Target: config.NATSSubject, Body: body})
+	}
+	if len(config.KafkaBrokers) > 0 {
+		previews = append(previews, Preview{Notifier: "kafka", Target: config.KafkaTopic, Body: body})
+	}
+	if redis != nil && config.RedisChannel != "" {
+		previews = append(previews, Preview{Notifier: "redis", Target: config.RedisChannel, Body: body})
+	}
+	if config.GrafanaURL != "" && event.Kind == EventServerDown {
+		previews = append(previews, Preview{Notifier: "grafana", Target: config.GrafanaURL, Text: grafanaAnnotationText(event)})
+	}
+	return previews, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPreviewAPI(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.APIToken = "secret"
+	config.WebhookURLs = []string{"https://example.com/hook"}
+
+	request := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/preview", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, r)
+		return w
+	}
+
+	w := request(`{"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","player":"steve","time":"2024-01-02T03:04:05Z",
+		"templates":{"players.joined":"{{.Players}} is here"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	var response struct {
+		Notifiers []Preview `json:"notifiers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Notifiers) != 2 {
+		t.Fatalf("notifiers = %+v", response.Notifiers)
+	}
+	if p := response.Notifiers[0]; p.Notifier != "telegram" || p.Target != "-42" || p.Text != "<b>steve</b> is here" {
+		t.Errorf("telegram preview = %+v", p)
+	}
+	if p := response.Notifiers[1]; p.Notifier != "webhook" || !strings.Contains(string(p.Body), `"player":"steve"`) {
+		t.Errorf("webhook preview = %+v", p)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("preview called Telegram: %+v", fake.calls)
+	}
+
+	if w := request(`{"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","player":"steve","time":"2024-01-02T03:04:05Z",
+		"templates":{"players.joined":"{{.Nope"}}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("broken template: status %d", w.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Telegram's limits on the bot's profile texts, in characters.
+const (
+	BOT_DESCRIPTION_MAX       = 512
+	BOT_SHORT_DESCRIPTION_MAX = 120
+)
+
+// BOT_DESCRIPTION_MIN_INTERVAL keeps a busy evening from rewriting the
+// bot's profile every check; Telegram rate-limits these calls hard.
+const BOT_DESCRIPTION_MIN_INTERVAL = 5 * time.Minute
+
+// botProfile remembers what the bot's profile says, per language, so it's
+// only rewritten when the status changes.
+var botProfile struct {
+	mu      sync.Mutex
+	texts   map[string]string
+	updated time.Time
+}
+
+// updateBotDescription shows the latest check in the bot's description,
+// what an empty private chat with the bot shows, and its short description,
+// the bio on its profile, in every language. Does nothing without
+// BOT_DESCRIPTION.
+func updateBotDescription(ctx context.Context, now time.Time) {
+	if !config.BotDescription {
+		return
+	}
+
+	botProfile.mu.Lock()
+	defer botProfile.mu.Unlock()
+	if now.Sub(botProfile.updated) < BOT_DESCRIPTION_MIN_INTERVAL {
+		return
+	}
+	if botProfile.texts == nil {
+		botProfile.texts = map[string]string{}
+	}
+
+	var langs []string
+	for lang := range messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	for _, lang := range langs {
+		status := compactStatus(lang)
+		description := renderBotDescription(lang, status)
+		if botProfile.texts[lang] == description {
+			continue
+		}
+		// LANGUAGE is also what users with any other language see.
+		code := lang
+		if lang == config.Language {
+			code = ""
+		}
+		if err := telegram.SetMyDescription(ctx, description, code); err != nil {
+			log.Printf("Error updating the bot description: %v", err)
+			continue
+		}
+		short := truncateRunes(status.Text, BOT_SHORT_DESCRIPTION_MAX)
+		if err := telegram.SetMyShortDescription(ctx, short, code); err != nil {
+			log.Printf("Error updating the bot short description: %v", err)
+			continue
+		}
+		botProfile.texts[lang] = description
+		botProfile.updated = now
+	}
+}
+
+// renderBotDescription is the bot's description in lang: the status, who's
+// online, and a hint to send /status. Plain text, as Telegram shows it.
+func renderBotDescription(lang string, status CompactStatus) string {
+	lines := []string{status.Text}
+	if latest := getLatest(); latest != nil && latest.Online && len(latest.Players) > 0 {
+		lines = append(lines, strings.Join(dedupePlayers(latest.Players), ", "))
+	}
+	lines = append(lines, "", tr(lang, "bot.description"))
+	return truncateRunes(strings.Join(lines, "\n"), BOT_DESCRIPTION_MAX)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBotDescription(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.BotDescription = true
+	botProfile.texts, botProfile.updated = nil, time.Time{}
+	players := []string{"steve"}
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true, PlayerCount: len(players), Players: players}, CheckedAt: time.Now()}
+	})
+	ctx := context.Background()
+
+	checkServer(ctx)
+	descriptions := fake.callsTo("setMyDescription")
+	if len(descriptions) != len(messages) {
+		t.Fatalf("setMyDescription calls = %+v, want one per language", descriptions)
+	}
+	want := "🟢 Online: 1 player\nsteve\n\nSend /status for more about the server."
+	for _, call := range descriptions {
+		if call.Params["language_code"] == "" && call.Params["description"] != want {
+			t.Fatalf("description = %q, want %q", call.Params["description"], want)
+		}
+	}
+	if short := fake.callsTo("setMyShortDescription"); len(short) != len(messages) {
+		t.Fatalf("setMyShortDescription calls = %+v", short)
+	}
+
+	// Unchanged, or changed too soon: left alone.
+	checkServer(ctx)
+	players = []string{}
+	checkServer(ctx)
+	if got := len(fake.callsTo("setMyDescription")); got != len(messages) {
+		t.Fatalf("setMyDescription called %d times, want %d", got, len(messages))
+	}
+	updateBotDescription(ctx, time.Now().Add(BOT_DESCRIPTION_MIN_INTERVAL))
+	if got := len(fake.callsTo("setMyDescription")); got != 2*len(messages) {
+		t.Fatalf("setMyDescription called %d times after the interval", got)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// PropertyChange is one watched server.properties value that changed.
+type PropertyChange struct {
+	Key    string
+	Before string
+	After  string
+}
+
+// readServerProperties fetches server.properties; tests swap it for a fake.
+var readServerProperties = func(ctx context.Context) ([]byte, error) {
+	return sftpReadFile(ctx, config.ServerPropertiesPath)
+}
+
+// checkServerProperties reads server.properties and reports changes to
+// WATCHED_PROPERTIES to the admins. The first read only records the values.
+func checkServerProperties(ctx context.Context) {
+	readCtx, cancel := context.WithTimeout(ctx, 2*TIMEOUT)
+	data, err := readServerProperties(readCtx)
+	cancel()
+	if err != nil {
+		log.Printf("Error reading server.properties: %v", err)
+		return
+	}
+
+	all := parseProperties(string(data))
+	current := make(map[string]string, len(config.WatchedProperties))
+	for _, key := range config.WatchedProperties {
+		if value, ok := all[key]; ok {
+			current[key] = value
+		}
+	}
+
+	state.mu.Lock()
+	previous := state.Properties
+	state.Properties = current
+	saveState()
+	state.mu.Unlock()
+
+	if previous == nil {
+		return
+	}
+	changes := propertyChanges(previous, current, config.WatchedProperties)
+	if len(changes) == 0 {
+		return
+	}
+	for _, change := range changes {
+		log.Printf("server.properties: %s changed from %q to %q", change.Key, change.Before, change.After)
+	}
+	notifyAdmins(ctx, renderPropertyChanges(config.Language, changes))
+}
+
+// parseProperties reads the key=value lines of a Java properties file as
+// the Minecraft server writes it: no line continuations, and escapes only
+// in front of the character they escape.
+func parseProperties(data string) map[string]string {
+	properties := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			properties[unescapeProperty(line)] = ""
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		properties[unescapeProperty(key)] = unescapeProperty(value)
+	}
+	return properties
+}
+
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// propertyChanges lists the watched keys whose value differs, in the order
+// they are watched. A missing key reads as empty.
+func propertyChanges(before, after map[string]string, watched []string) []PropertyChange {
+	var changes []PropertyChange
+	for _, key := range watched {
+		if before[key] != after[key] {
+			changes = append(changes, PropertyChange{Key: key, Before: before[key], After: after[key]})
+		}
+	}
+	return changes
+}
+
+func renderPropertyChanges(lang string, changes []PropertyChange) string {
+	lines := []string{tr(lang, "properties.changed")}
+	for _, change := range changes {
+		lines = append(lines, tr(lang, "properties.change", escapeHtml(change.Key),
+			renderPropertyValue(lang, change.Before), renderPropertyValue(lang, change.After)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderPropertyValue(lang, value string) string {
+	if value == "" {
+		return tr(lang, "properties.unset")
+	}
+	return "<code>" + escapeHtml(value) + "</code>"
+}
+
+// notifyAdmins sends text to TELEGRAM_ADMIN_CHAT_ID, or privately to each
+// admin without one; it never goes to the public chats.
+func notifyAdmins(ctx context.Context, text string) {
+	for _, chatID := range adminChats() {
+		if _, err := telegram.SendMessage(ctx, chatID, text, nil); err != nil {
+			log.Printf("Error notifying the admins in %s: %v", chatID, err)
+		}
+	}
+}
+
+// adminChats are where notifyAdmins sends: the admin chat, or each
+// admin's private chat.
+func adminChats() []string {
+	if config.AdminChatID != "" {
+		return []string{resolveChatID(config.AdminChatID)}
+	}
+	var chats []string
+	for _, id := range config.AdminIDs {
+		chats = append(chats, strconv.FormatInt(id, 10))
+	}
+	return chats
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	got := parseProperties("#Minecraft server properties\n#Mon Jan 01 00:00:00 UTC 2024\n" +
+		"difficulty=hard\nmax-players = 20\nmotd=Dorm 3\\: survival\nwhite-list=false\nlevel-seed=\n")
+	want := map[string]string{
+		"difficulty":  "hard",
+		"max-players": "20",
+		"motd":        "Dorm 3: survival",
+		"white-list":  "false",
+		"level-seed":  "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseProperties() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckServerProperties(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminChatID = "-100"
+	config.WatchedProperties = []string{"difficulty", "white-list", "max-players"}
+
+	previous := readServerProperties
+	defer func() { readServerProperties = previous }()
+	properties := "difficulty=normal\nwhite-list=false\nmax-players=20\nmotd=hi\n"
+	readServerProperties = func(context.Context) ([]byte, error) {
+		return []byte(properties), nil
+	}
+
+	ctx := context.Background()
+	checkServerProperties(ctx)
+	properties = "difficulty=normal\nwhite-list=false\nmax-players=20\nmotd=changed\n"
+	checkServerProperties(ctx)
+	if sent := fake.callsTo("sendMessage"); len(sent) != 0 {
+		t.Fatalf("notified without a watched change: %+v", sent)
+	}
+
+	properties = "difficulty=hard\nwhite-list=true\n"
+	checkServerProperties(ctx)
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["chat_id"] != "-100" {
+		t.Fatalf("sendMessage calls = %+v, want one to the admin chat", sent)
+	}
+	want := "⚙️ <b>Server settings changed</b> (server.properties)\n" +
+		"<code>difficulty</code>: <code>normal</code> → <code>hard</code>\n" +
+		"<code>white-list</code>: <code>false</code> → <code>true</code>\n" +
+		"<code>max-players</code>: <code>20</code> → <i>not set</i>"
+	if text := sent[0].Params["text"].(string); text != want {
+		t.Errorf("text:\n%s\nwant:\n%s", text, want)
+	}
+}
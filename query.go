@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query protocol (GS4) packet types.
+const (
+	queryTypeStat      = 0x00
+	queryTypeHandshake = 0x09
+)
+
+// MAX_QUERY_RESPONSE caps a full stat answer, which fits in one datagram.
+const MAX_QUERY_RESPONSE = 65535
+
+// QueryStatus is a Query full stat: unlike the Server List Ping it has
+// every player's name, the map and the plugins.
+type QueryStatus struct {
+	MOTD       string
+	GameType   string
+	Version    string
+	Map        string
+	NumPlayers int
+	MaxPlayers int
+	Players    []string
+	// Software is the server software from the plugins field, e.g.
+	// "Paper on 1.20.4"; Plugins are the names and versions after it.
+	Software string
+	Plugins  []string
+}
+
+// queryTransport probes over the Query protocol alone, for servers that
+// set enable-query=true. Port is the query.port, the server's port if 0.
+type queryTransport struct {
+	Port uint16
+}
+
+func (t queryTransport) Probe(ctx context.Context, host string, port uint16) (*ServerStatus, error) {
+	if t.Port != 0 {
+		port = t.Port
+	}
+	start := time.Now()
+	query, err := queryServer(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	status := &ServerStatus{Online: true, PlayerCount: query.NumPlayers, ProtocolTime: time.Since(start)}
+	applyQuery(status, query)
+	return status, nil
+}
+
+// slpQueryTransport pings as usual and then asks Query for what the ping
+// leaves out. A failed query keeps the ping's answer.
+type slpQueryTransport struct {
+	Port uint16
+}
+
+func (t slpQueryTransport) Probe(ctx context.Context, host string, port uint16) (*ServerStatus, error) {
+	status, err := pingMinecraftServer(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	queryPort := t.Port
+	if queryPort == 0 {
+		queryPort = port
+	}
+	query, err := queryServer(ctx, host, queryPort)
+	if err != nil {
+		log.Printf("Query of %s:%d failed, using the ping alone: %v", host, queryPort, err)
+		return status, nil
+	}
+	applyQuery(status, query)
+	return status, nil
+}
+
+// applyQuery fills status in with a query's answers.
+func applyQuery(status *ServerStatus, query *QueryStatus) {
+	status.Players = query.Players
+	status.Map = query.Map
+	status.Software = query.Software
+	status.Plugins = query.Plugins
+	if status.Version == "" {
+		status.Version = query.Version
+	}
+	if status.MOTD == "" {
+		status.RawMOTD = query.MOTD
+		status.MOTD = sanitizeText(query.MOTD)
+	}
+}
+
+// queryServer asks host:port for a Query full stat: a handshake for a
+// challenge token, then the stat request with it. Both are single UDP
+// datagrams, so a lost one costs a READ_TIMEOUT.
+func queryServer(ctx context.Context, host string, port uint16) (*QueryStatus, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, DIAL_TIMEOUT)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(dialCtx, "udp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return nil, fmt.Errorf("query dial: %w", classifyNetError(err))
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	// Servers only look at the low 4 bits of each byte of the session ID.
+	const session = 0x01020304
+
+	answer, err := queryExchange(ctx, conn, queryPacket(queryTypeHandshake, session, nil), queryTypeHandshake, session)
+	if err != nil {
+		return nil, fmt.Errorf("query handshake: %w", err)
+	}
+	token, err := strconv.ParseInt(string(bytes.TrimRight(answer, "\x00")), 10, 32)
+	if err != nil {
+		return nil, badPacket("bad challenge token %q", answer)
+	}
+
+	// The padding asks for the full stat instead of the basic one.
+	payload := binary.BigEndian.AppendUint32(nil, uint32(int32(token)))
+	payload = append(payload, 0, 0, 0, 0)
+	answer, err = queryExchange(ctx, conn, queryPacket(queryTypeStat, session, payload), queryTypeStat, session)
+	if err != nil {
+		return nil, fmt.Errorf("query stat: %w", err)
+	}
+	return parseQueryStat(answer)
+}
+
+func queryPacket(packetType byte, session uint32, payload []byte) []byte {
+	packet := []byte{0xFE, 0xFD, packetType}
+	packet = binary.BigEndian.AppendUint32(packet, session)
+	return append(packet, payload...)
+}
+
+// queryExchange sends packet and returns the payload of the answer, after
+// its type and session ID.
+func queryExchange(ctx context.Context, conn net.Conn, packet []byte, packetType byte, session uint32) ([]byte, error) {
+	conn.SetWriteDeadline(phaseDeadline(ctx, WRITE_TIMEOUT))
+	if _, err := conn.Write(packet); err != nil {
+		return nil, classifyNetError(err)
+	}
+	conn.SetReadDeadline(phaseDeadline(ctx, READ_TIMEOUT))
+	buf := make([]byte, MAX_QUERY_RESPONSE)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, classifyNetError(err)
+	}
+	if n < 5 || buf[0] != packetType || binary.BigEndian.Uint32(buf[1:5]) != session {
+		return nil, badPacket("unexpected answer of %d bytes", n)
+	}
+	return buf[5:n], nil
+}
+
+// parseQueryStat reads a full stat: 11 bytes of padding, the key/value
+// section ending with an empty key, 10 more bytes of padding, then the
+// player names ending with an empty one.
+func parseQueryStat(data []byte) (*QueryStatus, error) {
+	if len(data) < 11 {
+		return nil, badPacket("short full stat")
+	}
+	fields := bytes.Split(data[11:], []byte{0})
+
+	values := map[string]string{}
+	i := 0
+	for ; i+1 < len(fields) && len(fields[i]) > 0; i += 2 {
+		values[string(fields[i])] = string(fields[i+1])
+	}
+	if i >= len(fields) {
+		return nil, errors.New("full stat has no player section")
+	}
+
+	status := &QueryStatus{
+		MOTD:     values["hostname"],
+		GameType: values["gametype"],
+		Version:  values["version"],
+		Map:      values["map"],
+	}
+	status.NumPlayers, _ = strconv.Atoi(values["numplayers"])
+	status.MaxPlayers, _ = strconv.Atoi(values["maxplayers"])
+	if status.NumPlayers < 0 {
+		return nil, badPacket("negative player count %d", status.NumPlayers)
+	}
+	if software, plugins, ok := strings.Cut(values["plugins"], ":"); ok {
+		status.Software = strings.TrimSpace(software)
+		for _, plugin := range strings.Split(plugins, ";") {
+			if plugin = strings.TrimSpace(plugin); plugin != "" {
+				status.Plugins = append(status.Plugins, plugin)
+			}
+		}
+	} else {
+		status.Software = strings.TrimSpace(values["plugins"])
+	}
+
+	// The player section starts with "\x01player_\x00\x00" after the empty
+	// key that ended the values.
+	rest := bytes.Join(fields[i+1:], []byte{0})
+	marker := []byte("\x01player_\x00\x00")
+	start := bytes.Index(rest, marker)
+	if start < 0 {
+		return nil, errors.New("full stat has no player section")
+	}
+	status.Players = []string{}
+	for _, name := range bytes.Split(rest[start+len(marker):], []byte{0}) {
+		if player := sanitizePlayerName(string(name)); player != "" {
+			status.Players = append(status.Players, player)
+		}
+	}
+	return status, nil
+}
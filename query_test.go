@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// fakeQueryServer answers the Query protocol on a local UDP port with a
+// full stat, checking the challenge token.
+func fakeQueryServer(t *testing.T) uint16 {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			packet := buf[:n]
+			if n < 7 || packet[0] != 0xFE || packet[1] != 0xFD {
+				continue
+			}
+			answer := []byte{packet[2]}
+			answer = append(answer, packet[3:7]...)
+			switch packet[2] {
+			case queryTypeHandshake:
+				answer = append(answer, "9513307\x00"...)
+			case queryTypeStat:
+				if n != 15 || binary.BigEndian.Uint32(packet[7:11]) != 9513307 {
+					continue
+				}
+				answer = append(answer, "splitnum\x00\x80\x00"...)
+				for _, kv := range []string{
+					"hostname", "A §aMinecraft§r Server", "gametype", "SMP", "game_id", "MINECRAFT",
+					"version", "1.20.4", "plugins", "Paper on 1.20.4: WorldEdit 7.2; Essentials 2.20",
+					"map", "world", "numplayers", "14", "maxplayers", "20", "hostport", "25565", "hostip", "127.0.0.1",
+				} {
+					answer = append(answer, kv...)
+					answer = append(answer, 0)
+				}
+				answer = append(answer, "\x00\x01player_\x00\x00"...)
+				for i := 0; i < 14; i++ {
+					answer = append(answer, "player"...)
+					answer = append(answer, byte('a'+i), 0)
+				}
+				answer = append(answer, 0)
+			}
+			conn.WriteTo(answer, addr)
+		}
+	}()
+	return uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func TestQueryServer(t *testing.T) {
+	port := fakeQueryServer(t)
+
+	status, err := queryTransport{}.Probe(context.Background(), "127.0.0.1", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PlayerCount != 14 || len(status.Players) != 14 || status.Players[13] != "playern" {
+		t.Errorf("players = %d %q", status.PlayerCount, status.Players)
+	}
+	if status.MOTD != "A Minecraft Server" || status.Version != "1.20.4" || status.Map != "world" || status.Software != "Paper on 1.20.4" {
+		t.Errorf("status = %+v", status)
+	}
+	if want := []string{"WorldEdit 7.2", "Essentials 2.20"}; !reflect.DeepEqual(status.Plugins, want) {
+		t.Errorf("plugins = %q, want %q", status.Plugins, want)
+	}
+}
+
+func TestParseQueryStatRejectsTruncated(t *testing.T) {
+	data := append([]byte("splitnum\x00\x80\x00"), "hostname\x00x\x00numplayers\x00"...)
+	if _, err := parseQueryStat(data); err == nil {
+		t.Error("parsed a full stat without a player section")
+	}
+	if _, err := parseQueryStat(bytes.Repeat([]byte{0}, 4)); err == nil {
+		t.Error("parsed a short full stat")
+	}
+}
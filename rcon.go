@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// RCON packet types.
+const (
+	rconResponse = 0
+	rconCommand  = 2
+	rconAuth     = 3
+)
+
+// RCON_MAX_PACKET is the most a Minecraft server sends in one packet.
+const RCON_MAX_PACKET = 4096 + 14
+
+// RCON_TIMEOUT bounds a whole RCON exchange.
+const RCON_TIMEOUT = 10 * time.Second
+
+var errRCONAuth = errors.New("rcon: wrong password")
+
+// runRCON runs command on RCON_ADDR and returns the server's answer. Each
+// call opens its own connection: commands are rare, and a connection kept
+// open would need its own health checks.
+func runRCON(ctx context.Context, command string) (string, error) {
+	if config.RCONAddr == "" {
+		return "", errors.New("rcon: RCON_ADDR isn't set")
+	}
+	ctx, cancel := context.WithTimeout(ctx, RCON_TIMEOUT)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", config.RCONAddr)
+	if err != nil {
+		return "", fmt.Errorf("rcon: %w", classifyNetError(err))
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	rd := bufio.NewReader(conn)
+
+	if err := writeRCONPacket(conn, 1, rconAuth, config.RCONPassword); err != nil {
+		return "", err
+	}
+	id, _, _, err := readRCONPacket(rd)
+	if err != nil {
+		return "", err
+	}
+	if id == -1 {
+		return "", errRCONAuth
+	}
+
+	if err := writeRCONPacket(conn, 2, rconCommand, command); err != nil {
+		return "", err
+	}
+	id, _, body, err := readRCONPacket(rd)
+	if err != nil {
+		return "", err
+	}
+	if id != 2 {
+		return "", fmt.Errorf("rcon: answer to request %d, expected 2", id)
+	}
+	return body, nil
+}
+
+func writeRCONPacket(w io.Writer, id, packetType int32, body string) error {
+	packet := binary.LittleEndian.AppendUint32(nil, uint32(4+4+len(body)+2))
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(id))
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(packetType))
+	packet = append(packet, body...)
+	packet = append(packet, 0, 0)
+	if _, err := w.Write(packet); err != nil {
+		return fmt.Errorf("rcon: %w", classifyNetError(err))
+	}
+	return nil
+}
+
+func readRCONPacket(r io.Reader) (id, packetType int32, body string, err error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, "", fmt.Errorf("rcon: %w", classifyNetError(err))
+	}
+	length := int32(binary.LittleEndian.Uint32(header[0:4]))
+	if length < 10 || length > RCON_MAX_PACKET {
+		return 0, 0, "", badPacket("rcon packet length %d", length)
+	}
+	id = int32(binary.LittleEndian.Uint32(header[4:8]))
+	packetType = int32(binary.LittleEndian.Uint32(header[8:12]))
+	rest := make([]byte, length-8)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return 0, 0, "", fmt.Errorf("rcon: %w", classifyNetError(err))
+	}
+	return id, packetType, string(rest[:len(rest)-2]), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+)
+
+// fakeRCON is an RCON server on a local port that accepts "secret" and
+// answers every command with answer(command).
+type fakeRCON struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+// useFakeRCON points RCON_ADDR at a fresh fakeRCON.
+func useFakeRCON(t *testing.T, answer func(command string) string) *fakeRCON {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.RCONAddr, config.RCONPassword = ln.Addr().String(), "secret"
+
+	f := &fakeRCON{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn, answer)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRCON) serve(conn net.Conn, answer func(string) string) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		id, packetType, body, err := readRCONPacket(rd)
+		if err != nil {
+			return
+		}
+		switch packetType {
+		case rconAuth:
+			if body != "secret" {
+				id = -1
+			}
+			writeRCONPacket(conn, id, rconCommand, "")
+		case rconCommand:
+			f.mu.Lock()
+			f.commands = append(f.commands, body)
+			f.mu.Unlock()
+			writeRCONPacket(conn, id, rconResponse, answer(body))
+		}
+	}
+}
+
+// received returns the commands run so far.
+func (f *fakeRCON) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestRunRCON(t *testing.T) {
+	fake := useFakeRCON(t, func(command string) string {
+		return "There are 2 of a max of 20 players online: steve, alex"
+	})
+	ctx := context.Background()
+
+	got, err := runRCON(ctx, "list")
+	if err != nil || got != "There are 2 of a max of 20 players online: steve, alex" {
+		t.Fatalf("runRCON = %q, %v", got, err)
+	}
+	if commands := fake.received(); len(commands) != 1 || commands[0] != "list" {
+		t.Fatalf("commands = %q", commands)
+	}
+
+	config.RCONPassword = "wrong"
+	if _, err := runRCON(ctx, "list"); !errors.Is(err, errRCONAuth) {
+		t.Fatalf("wrong password: %v", err)
+	}
+}
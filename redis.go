@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// REDIS_TIMEOUT bounds one command round trip, connecting included.
+const REDIS_TIMEOUT = 5 * time.Second
+
+// RedisError is an error reply from the server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// RedisClient speaks just enough RESP for storage and PUBLISH. Commands are
+// serialized over one connection, which is re-dialed after any error.
+type RedisClient struct {
+	Addr     string
+	Password string
+	DB       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// redis is set up in loadConfig when REDIS_URL is set.
+var redis *RedisClient
+
+// newRedisClient parses redis://[:password@]host[:port][/db].
+func newRedisClient(rawURL string) (*RedisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	client := &RedisClient{Addr: u.Host}
+	if u.Port() == "" {
+		client.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		client.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if client.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid database %q", db)
+		}
+	}
+	return client, nil
+}
+
+// Do runs one command and returns its reply: string for simple strings,
+// int64 for integers, []byte or nil for bulk strings, []interface{} for
+// arrays. Error replies come back as RedisError.
+func (c *RedisClient) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection is in an unknown state; start over next time.
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+// connect dials and authenticates. The caller must hold c.mu.
+func (c *RedisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", c.Addr, REDIS_TIMEOUT)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.rd = bufio.NewReader(conn)
+
+	var setup [][]string
+	if c.Password != "" {
+		setup = append(setup, []string{"AUTH", c.Password})
+	}
+	if c.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.DB)})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(args); err != nil {
+			conn.Close()
+			c.conn = nil
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// roundTrip writes one command and reads its reply. The caller must hold
+// c.mu.
+func (c *RedisClient) roundTrip(args []string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(REDIS_TIMEOUT))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(c.rd)
+}
+
+func readRESP(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, RedisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRESP(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
+
+// redisStorage keeps each object as a string key under a prefix. Write is
+// a single SET and Append maps onto APPEND, so both are atomic on the
+// server.
+type redisStorage struct {
+	client *RedisClient
+	prefix string
+}
+
+func (s redisStorage) Read(name string) ([]byte, error) {
+	reply, err := s.client.Do("GET", s.prefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, os.ErrNotExist
+	}
+	return reply.([]byte), nil
+}
+
+func (s redisStorage) Write(name string, data []byte) error {
+	_, err := s.client.Do("SET", s.prefix+name, string(data))
+	return err
+}
+
+func (s redisStorage) Append(name string, data []byte) error {
+	_, err := s.client.Do("APPEND", s.prefix+name, string(data))
+	return err
+}
+
+func (s redisStorage) Truncate(name string) error {
+	_, err := s.client.Do("DEL", s.prefix+name)
+	return err
+}
+
+// publishRedis publishes event as JSON on REDIS_CHANNEL, for other services
+// to follow joins, leaves and outages as they happen. Does nothing without
+// REDIS_URL and REDIS_CHANNEL.
+func publishRedis(event Event) {
+	if redis == nil || config.RedisChannel == "" {
+		return
+	}
+
+	data, err := marshalEvent(event)
+	if err != nil {
+		log.Printf("Error encoding event: %v", err)
+		return
+	}
+	if _, err := redis.Do("PUBLISH", config.RedisChannel, string(data)); err != nil {
+		log.Printf("Error publishing event to Redis: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis answers the handful of commands RedisClient sends, keeping
+// string keys in memory and recording PUBLISHed messages.
+type fakeRedis struct {
+	net.Listener
+
+	mu        sync.Mutex
+	keys      map[string]string
+	published []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{Listener: listener, keys: map[string]string{}}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+
+	for {
+		reply, err := readRESP(rd)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+
+		f.mu.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "AUTH", "SELECT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "GET":
+			if value, ok := f.keys[args[1]]; ok {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "SET":
+			f.keys[args[1]] = args[2]
+			fmt.Fprint(conn, "+OK\r\n")
+		case "APPEND":
+			f.keys[args[1]] += args[2]
+			fmt.Fprintf(conn, ":%d\r\n", len(f.keys[args[1]]))
+		case "DEL":
+			delete(f.keys, args[1])
+			fmt.Fprint(conn, ":1\r\n")
+		case "PUBLISH":
+			f.published = append(f.published, args[1]+" "+args[2])
+			fmt.Fprint(conn, ":0\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+func useFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	f := newFakeRedis(t)
+	client, err := newRedisClient("redis://:secret@" + f.Addr().String() + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousConfig, previousStorage := config, storage
+	redis = client
+	config.RedisPrefix = "test:"
+	config.RedisChannel = "minecraft"
+	storage = redisStorage{client: client, prefix: config.RedisPrefix}
+	t.Cleanup(func() {
+		config, storage, redis = previousConfig, previousStorage, nil
+	})
+	return f
+}
+
+func TestNewRedisClient(t *testing.T) {
+	client, err := newRedisClient("redis://:pw@cache/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Addr != "cache:6379" || client.Password != "pw" || client.DB != 3 {
+		t.Fatalf("client = %+v", client)
+	}
+
+	if _, err := newRedisClient("http://cache"); err == nil {
+		t.Fatal("accepted a non-redis URL")
+	}
+}
+
+func TestRedisStorage(t *testing.T) {
+	useTempStore(t, 0)
+	f := useFakeRedis(t)
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 1000})
+	flushStore()
+	insertStatus(StatusEntry{Online: false, LastChecked: 2000})
+	flushStore()
+
+	if f.keys["test:"+JOURNAL_FILE] == "" {
+		t.Fatalf("journal not written, keys: %v", f.keys)
+	}
+
+	store = &StatusStore{}
+	loadStore()
+	if len(store.Entries) != 2 || store.Entries[1].Online {
+		t.Fatalf("entries after reload = %+v", store.Entries)
+	}
+
+	store.mu.Lock()
+	writeSnapshot()
+	store.mu.Unlock()
+	if _, ok := f.keys["test:"+JOURNAL_FILE]; ok {
+		t.Fatal("journal not cleared after snapshot")
+	}
+}
+
+func TestPublishRedis(t *testing.T) {
+	f := useFakeRedis(t)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dispatchEvents(context.Background(), []Event{{Kind: EventPlayerJoined, Player: "steve", Time: at}})
+
+	want := `minecraft {"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","player":"steve","time":"2024-01-02T03:04:05Z"}`
+	if len(f.published) != 1 || f.published[0] != want {
+		t.Fatalf("published = %q, want %q", f.published, want)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The functions in this file turn events into the HTML text sent to
+// Telegram. They don't do any I/O, so testdata/golden covers every event
+// type in every language (go test -update rewrites the files).
+
+// htmlEscaper replaces "&" in a single pass with the rest, so entities it
+// produces are never escaped twice.
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\"", "&quot;",
+	"'", "&#39;",
+)
+
+func escapeHtml(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+func bold(s string) string {
+	return fmt.Sprintf("<b>%s</b>", escapeHtml(s))
+}
+
+// boldList renders names as a comma-separated list of bold names.
+func boldList(names []string) string {
+	boldNames := make([]string, len(names))
+	for i, name := range names {
+		boldNames[i] = bold(name)
+	}
+	return joinStrings(boldNames, ", ")
+}
+
+// captionFuncs are available in caption templates: {{esc .Name}} escapes a
+// value, {{bold .Name}} escapes and bolds it.
+var captionFuncs = template.FuncMap{
+	"esc":  escapeHtml,
+	"bold": bold,
+}
+
+// captionTemplates are the captions of files the bot sends, per language;
+// languages without one fall back to DEFAULT_LANGUAGE.
+var captionTemplates = map[string]map[string]string{
+	"uk": {
+		"chart":  "📈 {{bold .Title}}\n{{esc .Period}}",
+		"export": "📦 {{esc .Title}} ({{.Count}})",
+	},
+	"en": {
+		"chart":  "📈 {{bold .Title}}\n{{esc .Period}}",
+		"export": "📦 {{esc .Title}} ({{.Count}})",
+	},
+}
+
+// renderCaption executes the caption template name for lang with data.
+func renderCaption(lang, name string, data interface{}) (string, error) {
+	text, ok := captionTemplates[lang][name]
+	if !ok {
+		text, ok = captionTemplates[DEFAULT_LANGUAGE][name]
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown caption template %q", name)
+	}
+
+	tmpl, err := template.New(name).Funcs(captionFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// truncateCaption cuts a caption down to MAX_CAPTION_LENGTH characters.
+// Telegram counts characters after entity parsing, so this is conservative
+// for captions with tags.
+func truncateCaption(caption string) string {
+	return truncateRunes(caption, MAX_CAPTION_LENGTH)
+}
+
+// truncateRunes cuts s down to n characters, ending it with "…" if it was
+// longer.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// renderEvents is renderChatEvents for a chat with no templates of its
+// own, in lang.
+func renderEvents(lang string, events []Event) string {
+	return renderChatEvents(ChatSettings{Language: lang}, events)
+}
+
+// renderPlayersChange is the message for players joining or leaving (key
+// players.joined or players.left), from the chat's template when it has
+// one.
+func renderPlayersChange(settings ChatSettings, key string, players []string) string {
+	data := MessageTemplateData{Players: boldList(players), Count: len(players)}
+	if text, ok := renderMessageTemplate(settings, key, data); ok {
+		return text
+	}
+	if len(players) == 1 {
+		return tr(settings.language(), key+".single", boldList(players))
+	}
+	return tr(settings.language(), key, boldList(players))
+}
+
+// renderServerDown is the alert for the server going down, with what past
+// outages say about its length. A scheduled-looking restart only gets a
+// short note.
+func renderServerDown(settings ChatSettings, event Event) string {
+	lang := settings.language()
+	if event.Label == LABEL_SCHEDULED_RESTART {
+		return tr(lang, "server.scheduled_restart")
+	}
+
+	text, ok := renderMessageTemplate(settings, "server.down", MessageTemplateData{})
+	if !ok {
+		text = tr(lang, "server.down")
+	}
+	if forecast := event.Forecast; forecast != nil {
+		recent := make([]string, len(forecast.RecentMinutes))
+		for i, minutes := range forecast.RecentMinutes {
+			recent[i] = fmt.Sprint(minutes)
+		}
+		text += "\n" + trn(lang, "server.forecast", forecast.TypicalMinutes, forecast.TypicalMinutes)
+		text += "\n" + trn(lang, "server.forecast_recent", len(recent), len(recent), strings.Join(recent, ", "))
+	}
+	if event.Backup != "" {
+		text += "\n" + tr(lang, "server.backup_up", escapeHtml(event.Backup))
+	}
+	return text
+}
+
+// renderChatTitle is the chat title showing whether the server is up.
+func renderChatTitle(settings ChatSettings, online bool, players int) string {
+	lang := settings.language()
+	if text, ok := renderMessageTemplate(settings, "title", MessageTemplateData{Online: online, Count: players}); ok {
+		return text
+	}
+	if online {
+		return tr(lang, "title.online")
+	}
+	return tr(lang, "title.offline")
+}
+
+// renderStartupSummary is the note posted when the monitor starts: the
+// server's current state and how long ago its last incident began (zero
+// lastIncident if none is on record).
+func renderStartupSummary(lang string, online bool, players int, lastIncident, now time.Time) string {
+	status := "🔴"
+	if online {
+		status = "🟢"
+	}
+	parts := []string{tr(lang, "startup.header", status)}
+	if online {
+		parts = append(parts, trn(lang, "startup.players", players, players))
+	}
+	if lastIncident.IsZero() {
+		parts = append(parts, tr(lang, "startup.no_incidents"))
+	} else {
+		parts = append(parts, tr(lang, "startup.last_incident", renderAgo(lang, now.Sub(lastIncident))))
+	}
+	return strings.Join(parts, ", ") + "."
+}
+
+// renderLiveStatus is the pinned live status message: up or down, who is
+// playing and when the server was last checked.
+func renderLiveStatus(lang string, latest *StatusEntry) string {
+	lines := []string{tr(lang, "server.down")}
+	if latest.Online {
+		count := max(latest.PlayerCount, len(latest.Players))
+		lines = []string{trn(lang, "status.online", count, count)}
+		if players := dedupePlayers(latest.Players); len(players) > 0 {
+			lines = append(lines, tr(lang, "status.players", boldList(players)))
+		}
+	}
+	checked := time.UnixMilli(latest.LastChecked).Format("2006-01-02 15:04:05")
+	lines = append(lines, tr(lang, "live.checked", checked))
+	return joinStrings(lines, "\n")
+}
+
+// renderStats is the /stats reply for the last days days.
+func renderStats(lang string, days int, times []PlayerTime) string {
+	header := tr(lang, "stats.today")
+	if days > 1 {
+		header = trn(lang, "stats.days", days, days)
+	}
+	if len(times) == 0 {
+		return header + "\n" + tr(lang, "stats.nobody")
+	}
+	lines := []string{header}
+	for _, t := range times {
+		hours, minutes := int(t.Time.Hours()), int(t.Time.Minutes())%60
+		lines = append(lines, tr(lang, "stats.line", bold(t.Player), hours, minutes))
+	}
+	return joinStrings(lines, "\n")
+}
+
+// renderServerUp is the notice for the server coming back, with how long
+// it was down when that is known.
+func renderServerUp(settings ChatSettings, event Event) string {
+	lang := settings.language()
+	lasted := time.Duration(event.DowntimeSeconds) * time.Second
+	var duration string
+	if lasted >= time.Minute {
+		duration = renderLasted(lang, lasted)
+	}
+	if text, ok := renderMessageTemplate(settings, "server.up", MessageTemplateData{Duration: duration}); ok {
+		return text
+	}
+	if duration == "" {
+		return tr(lang, "server.up")
+	}
+	return tr(lang, "server.up_after", duration)
+}
+
+// renderLasted says how long something lasted, in minutes, hours or days.
+func renderLasted(lang string, d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		n := int(d.Minutes())
+		return trn(lang, "lasted.minutes", n, n)
+	case d < 48*time.Hour:
+		n := int(d.Hours())
+		return trn(lang, "lasted.hours", n, n)
+	default:
+		n := int(d.Hours() / 24)
+		return trn(lang, "lasted.days", n, n)
+	}
+}
+
+// renderAgo says how long ago something happened, in minutes, hours or
+// days.
+func renderAgo(lang string, d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		n := int(d.Minutes())
+		return trn(lang, "ago.minutes", n, n)
+	case d < 48*time.Hour:
+		n := int(d.Hours())
+		return trn(lang, "ago.hours", n, n)
+	default:
+		n := int(d.Hours() / 24)
+		return trn(lang, "ago.days", n, n)
+	}
+}
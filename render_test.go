@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+// renderCases covers every event type the renderer knows about.
+var renderCases = map[string]func(lang string) string{
+	"joined_one": func(lang string) string {
+		return renderEvents(lang, playerEvents([]string{"steve"}, nil, time.Time{}))
+	},
+	"joined_many": func(lang string) string {
+		return renderEvents(lang, playerEvents([]string{"steve", "alex"}, nil, time.Time{}))
+	},
+	// 21 takes the Ukrainian "one" plural form, but it's still a list.
+	"joined_21": func(lang string) string {
+		var players []string
+		for i := 1; i <= 21; i++ {
+			players = append(players, fmt.Sprintf("player%d", i))
+		}
+		return renderEvents(lang, playerEvents(players, nil, time.Time{}))
+	},
+	"left_one": func(lang string) string {
+		return renderEvents(lang, playerEvents(nil, []string{"steve"}, time.Time{}))
+	},
+	"left_many": func(lang string) string {
+		return renderEvents(lang, playerEvents(nil, []string{"steve", "alex", "notch"}, time.Time{}))
+	},
+	"joined_and_left": func(lang string) string {
+		return renderEvents(lang, playerEvents([]string{"alex"}, []string{"steve"}, time.Time{}))
+	},
+	"escaping": func(lang string) string {
+		return renderEvents(lang, playerEvents([]string{"<b>&'\""}, nil, time.Time{}))
+	},
+	"server_down": func(lang string) string {
+		return renderEvents(lang, []Event{serverEvent(false, time.Time{})})
+	},
+	"server_down_forecast": func(lang string) string {
+		return renderEvents(lang, []Event{{Kind: EventServerDown, Forecast: &DowntimeForecast{TypicalMinutes: 12, RecentMinutes: []int{5, 9, 40}}}})
+	},
+	"server_down_forecast_one": func(lang string) string {
+		return renderEvents(lang, []Event{{Kind: EventServerDown, Forecast: &DowntimeForecast{TypicalMinutes: 1, RecentMinutes: []int{1}}}})
+	},
+	"server_down_backup": func(lang string) string {
+		return renderEvents(lang, []Event{{Kind: EventServerDown, Backup: "backup.example.com:25565"}})
+	},
+	"server_up": func(lang string) string {
+		return renderEvents(lang, []Event{serverEvent(true, time.Time{})})
+	},
+	"server_up_after": func(lang string) string {
+		return renderEvents(lang, []Event{{Kind: EventServerUp, DowntimeSeconds: 42 * 60}})
+	},
+	"server_up_after_hours": func(lang string) string {
+		return renderEvents(lang, []Event{{Kind: EventServerUp, DowntimeSeconds: 3 * 3600}})
+	},
+	"scheduled_restart": func(lang string) string {
+		return renderEvents(lang, []Event{{Kind: EventServerDown, Label: LABEL_SCHEDULED_RESTART}})
+	},
+	"startup_online": func(lang string) string {
+		now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+		return renderStartupSummary(lang, true, 3, now.Add(-50*time.Hour), now)
+	},
+	"startup_offline": func(lang string) string {
+		return renderStartupSummary(lang, false, 0, time.Time{}, time.Time{})
+	},
+	"startup_recent_incident": func(lang string) string {
+		now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+		return renderStartupSummary(lang, true, 1, now.Add(-21*time.Minute), now)
+	},
+	"live_online": func(lang string) string {
+		checked := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local).UnixMilli()
+		return renderLiveStatus(lang, &StatusEntry{Online: true, LastChecked: checked, Players: []string{"steve", "alex"}})
+	},
+	"live_offline": func(lang string) string {
+		checked := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local).UnixMilli()
+		return renderLiveStatus(lang, &StatusEntry{LastChecked: checked, Players: []string{}})
+	},
+	"title_online": func(lang string) string {
+		return renderChatTitle(ChatSettings{Language: lang}, true, 2)
+	},
+	"title_offline": func(lang string) string {
+		return renderChatTitle(ChatSettings{Language: lang}, false, 0)
+	},
+}
+
+func TestRenderGolden(t *testing.T) {
+	for lang := range messages {
+		for name, render := range renderCases {
+			path := filepath.Join("testdata", "golden", lang, name+".golden")
+			got := render(lang)
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Errorf("%s: %v (run go test -update to create it)", path, err)
+				continue
+			}
+			if got != string(want) {
+				t.Errorf("%s mismatch:\ngot:  %q\nwant: %q", path, got, want)
+			}
+		}
+	}
+}
+
+func TestRenderEventsEmpty(t *testing.T) {
+	if got := renderEvents(DEFAULT_LANGUAGE, nil); got != "" {
+		t.Fatalf("renderEvents(nil) = %q, want empty", got)
+	}
+}
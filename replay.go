@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ReplayedCheck is a stored check that produced events.
+type ReplayedCheck struct {
+	Time   time.Time
+	Events []Event
+}
+
+// replayHistory is the replay subcommand: `replay [since]` feeds the stored
+// history (the last day, or only the last since, e.g. "6h") back through
+// the event pipeline and prints what every chat following SERVER_HOST
+// would have been sent, rendered with the current toggles, templates and
+// restart windows. It's a dry run: nothing is sent to Telegram or the
+// integrations and neither the store nor the state is written, so new
+// notification rules can be tried on real past checks.
+func replayHistory(w io.Writer, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: replay [since]")
+	}
+
+	now := time.Now()
+	from := int64(0)
+	if len(args) == 1 {
+		since, err := time.ParseDuration(args[0])
+		if err != nil || since <= 0 {
+			return fmt.Errorf("invalid duration %q", args[0])
+		}
+		from = now.Add(-since).UnixMilli()
+	}
+
+	loadStore()
+	loadState()
+	entries := getRange(from, now.UnixMilli()+1)
+	checks := replayEvents(entries)
+
+	var chats []string
+	for _, chatID := range servedChats() {
+		if chatSettings(chatID).server() == "" {
+			chats = append(chats, chatID)
+		}
+	}
+
+	var joins, leaves, outages int
+	for _, check := range checks {
+		fmt.Fprintf(w, "%s\n", check.Time.Format("2006-01-02 15:04:05"))
+		for _, event := range check.Events {
+			switch event.Kind {
+			case EventPlayerJoined:
+				joins++
+			case EventPlayerLeft:
+				leaves++
+			case EventServerDown:
+				if event.policy == config.OnlinePolicy {
+					outages++
+				}
+			}
+			switch {
+			case event.Player != "":
+				fmt.Fprintf(w, "  %s %s\n", event.Kind, event.Player)
+			case event.Label != "":
+				fmt.Fprintf(w, "  %s [%s] (%s)\n", event.Kind, event.policy, event.Label)
+			default:
+				fmt.Fprintf(w, "  %s [%s]\n", event.Kind, event.policy)
+			}
+		}
+
+		for _, chatID := range chats {
+			settings := chatSettings(chatID)
+			var enabled []Event
+			for _, event := range check.Events {
+				if event.policy != "" && event.policy != settings.policy() {
+					continue
+				}
+				if settings.enabled(string(event.Kind)) {
+					enabled = append(enabled, event)
+				}
+			}
+			if message := renderChatEvents(settings, enabled); message != "" {
+				fmt.Fprintf(w, "  -> %s:\n%s\n", chatID, "     "+strings.ReplaceAll(message, "\n", "\n     "))
+			}
+		}
+	}
+
+	downtime := replayDowntime(entries)
+	fmt.Fprintf(w, "Replayed %d checks: %d with events, %d joins, %d leaves, %d outages, %v offline\n",
+		len(entries), len(checks), joins, leaves, outages, downtime.Round(time.Second))
+	return nil
+}
+
+// replayEvents derives the events checkServer would have emitted for
+// entries, which must be sorted by LastChecked, with server events for
+// every online policy. The first entry only sets the baseline. Failed checks always emptied the player list, so a
+// transition to offline also reports everyone as having left, like it did
+// live.
+func replayEvents(entries []StatusEntry) []ReplayedCheck {
+	var checks []ReplayedCheck
+	for i := 1; i < len(entries); i++ {
+		previous, current := entries[i-1], entries[i]
+		at := time.UnixMilli(current.LastChecked)
+
+		events := transitionEvents(func(policy string) ServerState {
+			return entryState(policy, &previous)
+		}, func(policy string) ServerState {
+			return entryState(policy, &current)
+		}, at)
+		for j := range events {
+			if _, ok := expectedRestart(at); ok && events[j].Kind == EventServerDown {
+				events[j].Label = LABEL_SCHEDULED_RESTART
+			}
+			if since, ok := offlineSince(events[j].policy, entries[:i]); ok && events[j].Kind == EventServerUp {
+				events[j].DowntimeSeconds = int64(at.Sub(since) / time.Second)
+			}
+		}
+		joined, left := diffPlayers(previous.Players, current.Players)
+		events = append(events, playerEvents(joined, left, at)...)
+
+		if len(events) > 0 {
+			checks = append(checks, ReplayedCheck{Time: at, Events: events})
+		}
+	}
+	return checks
+}
+
+// replayDowntime sums the time between a check finding the server offline
+// and the next one finding it online again; an outage still open at the
+// last entry counts up to it.
+func replayDowntime(entries []StatusEntry) time.Duration {
+	var total time.Duration
+	var downSince int64
+	down := false
+	for _, entry := range entries {
+		switch {
+		case !entry.Online && !down:
+			down, downSince = true, entry.LastChecked
+		case entry.Online && down:
+			down = false
+			total += time.Duration(entry.LastChecked-downSince) * time.Millisecond
+		}
+	}
+	if down {
+		total += time.Duration(entries[len(entries)-1].LastChecked-downSince) * time.Millisecond
+	}
+	return total
+}
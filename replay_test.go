@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayEvents(t *testing.T) {
+	useTempStore(t, 0)
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	at := func(minutes int) int64 { return base.Add(time.Duration(minutes) * time.Minute).UnixMilli() }
+	entries := []StatusEntry{
+		{Online: true, LastChecked: at(0), Players: []string{"steve"}},
+		{Online: true, LastChecked: at(1), Players: []string{"steve", "alex"}},
+		{Online: true, LastChecked: at(2), Players: []string{"steve", "alex"}},
+		{Online: false, LastChecked: at(3), Players: []string{}, Error: "timeout"},
+		{Online: true, LastChecked: at(13), Players: []string{}},
+	}
+
+	checks := replayEvents(entries)
+	if len(checks) != 3 {
+		t.Fatalf("checks = %+v", checks)
+	}
+	if e := checks[0].Events; len(e) != 1 || e[0].Kind != EventPlayerJoined || e[0].Player != "alex" {
+		t.Errorf("first check = %+v", e)
+	}
+	// Down under every policy, and everyone left.
+	if e := checks[1].Events; len(e) != 5 || e[0].Kind != EventServerDown || e[2].Kind != EventServerDown || e[3].Kind != EventPlayerLeft {
+		t.Errorf("second check = %+v", e)
+	}
+	// Back, but empty: offline still for ONLINE_PLAYERS.
+	if e := checks[2].Events; len(e) != 2 || e[0].Kind != EventServerUp || e[0].policy != ONLINE_REACHABLE ||
+		e[0].DowntimeSeconds != 600 || e[1].Kind != EventServerEmpty || e[1].policy != ONLINE_THREE_STATE {
+		t.Errorf("third check = %+v", e)
+	}
+	if got := replayDowntime(entries); got != 10*time.Minute {
+		t.Errorf("downtime = %v, want 10m", got)
+	}
+}
+
+func TestReplayHistoryIsDryRun(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+
+	base := time.Now().Add(-time.Hour)
+	insertStatus(StatusEntry{Online: true, LastChecked: base.UnixMilli(), Players: []string{}})
+	insertStatus(StatusEntry{Online: true, LastChecked: base.Add(time.Minute).UnixMilli(), Players: []string{"steve"}})
+	flushStore()
+
+	out := new(bytes.Buffer)
+	if err := replayHistory(out, []string{"2h"}); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{"player_joined steve", "-> -42:", "<b>steve</b>", "Replayed 2 checks: 1 with events, 1 joins"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q:\n%s", want, got)
+		}
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("replay called Telegram: %+v", fake.calls)
+	}
+
+	if err := replayHistory(out, []string{"soon"}); err == nil {
+		t.Error("replay accepted an invalid duration")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// RESTART_HISTORY_DAYS is how long downtimes are remembered for
+	// learning restart windows.
+	RESTART_HISTORY_DAYS = 14
+	// A downtime looks scheduled when, on at least RESTART_MIN_DAYS other
+	// days, a downtime no longer than RESTART_MAX_DURATION started within
+	// RESTART_TOLERANCE of the same time of day.
+	RESTART_MIN_DAYS     = 3
+	RESTART_MAX_DURATION = 30 * time.Minute
+	RESTART_TOLERANCE    = 15 * time.Minute
+
+	// LABEL_SCHEDULED_RESTART marks server_down events that match a learned
+	// restart window.
+	LABEL_SCHEDULED_RESTART = "scheduled-looking restart"
+)
+
+// Downtime is one outage of SERVER_HOST, in Unix seconds. End is 0 while
+// the server is still down.
+type Downtime struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end,omitempty"`
+}
+
+// recordTransition keeps state.Downtimes up to date with a server_up or
+// server_down at t, forgetting downtimes older than RESTART_HISTORY_DAYS.
+// For a server_up it returns how long the downtime it ended lasted, zero
+// if none was open.
+func recordTransition(online bool, t time.Time) time.Duration {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	var lasted time.Duration
+	if online {
+		if n := len(state.Downtimes); n > 0 && state.Downtimes[n-1].End == 0 {
+			open := &state.Downtimes[n-1]
+			// On the wall clock, like the incident's age (see
+			// Incident.elapsed), so both give an outage the same length;
+			// but it must not end before it began.
+			open.End = max(t.Unix(), open.Start)
+			lasted = time.Duration(open.End-open.Start) * time.Second
+		}
+	} else {
+		state.Downtimes = append(state.Downtimes, Downtime{Start: t.Unix()})
+	}
+
+	cutoff := t.AddDate(0, 0, -RESTART_HISTORY_DAYS).Unix()
+	i := sort.Search(len(state.Downtimes), func(i int) bool {
+		return state.Downtimes[i].Start >= cutoff
+	})
+	state.Downtimes = state.Downtimes[i:]
+	saveState()
+	return lasted
+}
+
+// expectedRestart reports whether a downtime starting at t matches
+// RESTART_SCHEDULE or a learned restart window, and the usual start time of
+// that window.
+func expectedRestart(t time.Time) (time.Time, bool) {
+	if at, ok := scheduledRestartNear(t); ok {
+		return at, true
+	}
+
+	state.mu.Lock()
+	downtimes := append([]Downtime(nil), state.Downtimes...)
+	state.mu.Unlock()
+
+	today := t.Format("2006-01-02")
+	days := map[string]bool{}
+	var offsets []time.Duration
+	for _, downtime := range downtimes {
+		start := time.Unix(downtime.Start, 0).In(t.Location())
+		day := start.Format("2006-01-02")
+		if day == today || days[day] {
+			continue
+		}
+		if downtime.End == 0 || time.Duration(downtime.End-downtime.Start)*time.Second > RESTART_MAX_DURATION {
+			continue
+		}
+		offset := timeOfDayDistance(start, t)
+		if offset.Abs() <= RESTART_TOLERANCE {
+			days[day] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	if len(days) < RESTART_MIN_DAYS {
+		return time.Time{}, false
+	}
+
+	var sum time.Duration
+	for _, offset := range offsets {
+		sum += offset
+	}
+	usual := t.Add(sum / time.Duration(len(offsets))).Truncate(time.Minute)
+	return usual, true
+}
+
+// timeOfDayDistance is how much earlier (positive) or later than t's time
+// of day a happened, wrapping around midnight.
+func timeOfDayDistance(a, t time.Time) time.Duration {
+	sinceMidnight := func(x time.Time) time.Duration {
+		h, m, s := x.Clock()
+		return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	}
+	d := sinceMidnight(a) - sinceMidnight(t)
+	switch {
+	case d > 12*time.Hour:
+		d -= 24 * time.Hour
+	case d < -12*time.Hour:
+		d += 24 * time.Hour
+	}
+	return d
+}
+
+// DowntimeForecast is what past outages say about how long the current one
+// may last, in whole minutes.
+type DowntimeForecast struct {
+	TypicalMinutes int   `json:"typicalMinutes"`
+	RecentMinutes  []int `json:"recentMinutes"`
+}
+
+// FORECAST_RECENT is how many of the latest outages a forecast lists.
+const FORECAST_RECENT = 3
+
+// forecastDowntime summarizes the finished downtimes in the state: the
+// median duration and the durations of the last FORECAST_RECENT, oldest
+// first. It returns nil without any finished downtime.
+func forecastDowntime() *DowntimeForecast {
+	state.mu.Lock()
+	var minutes []int
+	for _, downtime := range state.Downtimes {
+		if downtime.End != 0 {
+			minutes = append(minutes, int(math.Round(float64(downtime.End-downtime.Start)/60)))
+		}
+	}
+	state.mu.Unlock()
+
+	if len(minutes) == 0 {
+		return nil
+	}
+
+	forecast := &DowntimeForecast{RecentMinutes: minutes[max(0, len(minutes)-FORECAST_RECENT):]}
+	sorted := append([]int(nil), minutes...)
+	sort.Ints(sorted)
+	if n := len(sorted); n%2 == 1 {
+		forecast.TypicalMinutes = sorted[n/2]
+	} else {
+		forecast.TypicalMinutes = (sorted[n/2-1] + sorted[n/2] + 1) / 2
+	}
+	return forecast
+}
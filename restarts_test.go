@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpectedRestart(t *testing.T) {
+	useTempStore(t, 0)
+
+	day := time.Date(2024, 3, 10, 4, 0, 0, 0, time.Local)
+	record := func(start time.Time, duration time.Duration) {
+		recordTransition(false, start)
+		recordTransition(true, start.Add(duration))
+	}
+
+	// Two short downtimes near 04:00, one at 06:00 and a long outage at
+	// 04:00: not a pattern yet.
+	record(day.AddDate(0, 0, -5).Add(-10*time.Minute), 3*time.Minute)
+	record(day.AddDate(0, 0, -3).Add(5*time.Minute), 4*time.Minute)
+	record(day.AddDate(0, 0, -2).Add(2*time.Hour), 2*time.Minute)
+	record(day.AddDate(0, 0, -1), 2*time.Hour)
+
+	if _, ok := expectedRestart(day.Add(time.Minute)); ok {
+		t.Fatal("two matching days were enough")
+	}
+
+	// A third short downtime near 04:00 makes it one.
+	state.Downtimes = append([]Downtime{{
+		Start: day.AddDate(0, 0, -6).Unix(),
+		End:   day.AddDate(0, 0, -6).Add(time.Minute).Unix(),
+	}}, state.Downtimes...)
+
+	usual, ok := expectedRestart(day.Add(time.Minute))
+	if !ok {
+		t.Fatalf("no restart window learned from %+v", state.Downtimes)
+	}
+	if got := usual.Format("15:04"); got != "03:58" {
+		t.Fatalf("usual restart time = %s, want 03:58", got)
+	}
+	if _, ok := expectedRestart(day.Add(time.Hour)); ok {
+		t.Fatal("05:00 matched the 04:00 window")
+	}
+}
+
+func TestRecordTransitionForgetsOldDowntimes(t *testing.T) {
+	useTempStore(t, 0)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	recordTransition(false, start)
+	recordTransition(true, start.Add(time.Minute))
+	recordTransition(false, start.AddDate(0, 0, RESTART_HISTORY_DAYS+1))
+
+	if len(state.Downtimes) != 1 || state.Downtimes[0].End != 0 {
+		t.Fatalf("downtimes = %+v", state.Downtimes)
+	}
+}
+
+func TestForecastDowntime(t *testing.T) {
+	useTempStore(t, 0)
+
+	if forecast := forecastDowntime(); forecast != nil {
+		t.Fatalf("forecast without history = %+v", forecast)
+	}
+
+	start := time.Now().Add(-time.Hour * 24)
+	for i, minutes := range []int{40, 5, 9, 12} {
+		at := start.Add(time.Duration(i) * time.Hour)
+		recordTransition(false, at)
+		recordTransition(true, at.Add(time.Duration(minutes)*time.Minute))
+	}
+	recordTransition(false, time.Now())
+
+	forecast := forecastDowntime()
+	if forecast.TypicalMinutes != 11 || fmt.Sprint(forecast.RecentMinutes) != "[5 9 12]" {
+		t.Fatalf("forecast = %+v", forecast)
+	}
+}
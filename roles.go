@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Role is what a Telegram user may do with the bot. Each role includes the
+// ones below it.
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleOperator
+	RoleAdmin
+)
+
+var roleNames = []string{"none", "viewer", "operator", "admin"}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+func parseRole(s string) (Role, bool) {
+	for i, name := range roleNames {
+		if strings.EqualFold(s, name) {
+			return Role(i), true
+		}
+	}
+	return RoleNone, false
+}
+
+// requiredRole returns the role needed to run cmd, and false for commands
+// the bot doesn't know. COMMAND_ROLES overrides the registry's.
+func requiredRole(cmd string) (Role, bool) {
+	command, ok := commands[cmd]
+	if !ok {
+		return RoleNone, false
+	}
+	role := command.Role
+	if override, ok := parseRole(config.CommandRoles[cmd]); ok {
+		role = override
+	}
+	return role, true
+}
+
+// userRole resolves the role of user. TELEGRAM_ADMIN_IDS are always admins,
+// so nobody can lock them out; after that, roles granted with /grant win
+// over TELEGRAM_OPERATOR_IDS and TELEGRAM_VIEWER_IDS, and everyone else
+// gets DEFAULT_ROLE.
+func userRole(user *User) Role {
+	if user == nil {
+		return config.DefaultRole
+	}
+	if containsID(config.AdminIDs, user.ID) {
+		return RoleAdmin
+	}
+
+	state.mu.Lock()
+	granted, ok := state.Roles[strconv.FormatInt(user.ID, 10)]
+	state.mu.Unlock()
+	if role, valid := parseRole(granted); ok && valid {
+		return role
+	}
+
+	switch {
+	case containsID(config.OperatorIDs, user.ID):
+		return RoleOperator
+	case containsID(config.ViewerIDs, user.ID):
+		return RoleViewer
+	}
+	return config.DefaultRole
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGrantCommand implements /grant <user ID> <role>, and /grant <user
+// ID> default to drop a granted role again.
+func handleGrantCommand(ctx context.Context, msg *Message, args string) {
+	lang := replyLanguage(msg)
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		reply(ctx, msg, tr(lang, "grant.usage"))
+		return
+	}
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		reply(ctx, msg, tr(lang, "grant.usage"))
+		return
+	}
+	if containsID(config.AdminIDs, userID) {
+		reply(ctx, msg, tr(lang, "grant.fixed", userID))
+		return
+	}
+
+	key := strconv.FormatInt(userID, 10)
+	if strings.EqualFold(fields[1], "default") {
+		state.mu.Lock()
+		delete(state.Roles, key)
+		saveState()
+		state.mu.Unlock()
+
+		recordAudit(auditCommand(msg, "grant", args, "ok"))
+		reply(ctx, msg, tr(lang, "grant.reset", userID, userRole(&User{ID: userID})))
+		return
+	}
+
+	role, ok := parseRole(fields[1])
+	if !ok {
+		reply(ctx, msg, tr(lang, "grant.usage"))
+		return
+	}
+
+	state.mu.Lock()
+	if state.Roles == nil {
+		state.Roles = map[string]string{}
+	}
+	state.Roles[key] = role.String()
+	saveState()
+	state.mu.Unlock()
+
+	log.Printf("Granted %s to %d", role, userID)
+	recordAudit(auditCommand(msg, "grant", args, "ok"))
+	reply(ctx, msg, tr(lang, "grant.done", userID, role))
+}
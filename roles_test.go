@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+	config.OperatorIDs = []int64{2}
+	config.DefaultRole = RoleNone
+	config.CommandRoles = map[string]string{"diag": "operator"}
+
+	send := func(from int64, text string) string {
+		handleUpdate(context.Background(), Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: 7}, From: &User{ID: from}, Text: text,
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	if role := userRole(&User{ID: 2}); role != RoleOperator {
+		t.Fatalf("operator from env has role %s", role)
+	}
+	if text := send(3, "/diag"); text != "⛔ This command needs the operator role." {
+		t.Fatalf("/diag from a stranger: %q", text)
+	}
+
+	if text := send(2, "/grant 3 operator"); text != "⛔ This command needs the admin role." {
+		t.Fatalf("/grant from an operator: %q", text)
+	}
+	if text := send(1, "/grant 3 operator"); text != "✅ User <code>3</code> now has the operator role." {
+		t.Fatalf("/grant: %q", text)
+	}
+	if role := userRole(&User{ID: 3}); role != RoleOperator {
+		t.Fatalf("granted role = %s", role)
+	}
+
+	// Grants survive a restart, and win over the env lists.
+	send(1, "/grant 2 viewer")
+	state = &BotState{}
+	loadState()
+	if role := userRole(&User{ID: 2}); role != RoleViewer {
+		t.Fatalf("role after reload = %s", role)
+	}
+
+	if text := send(1, "/grant 2 default"); text != "✅ Dropped the granted role of user <code>2</code>; they now have the operator role." {
+		t.Fatalf("/grant default: %q", text)
+	}
+	if text := send(1, "/grant 1 none"); text != "⚠️ User <code>1</code> is an admin in TELEGRAM_ADMIN_IDS; their role can only be changed there." {
+		t.Fatalf("demoting an env admin: %q", text)
+	}
+}
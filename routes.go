@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChatRoute is an entry of TELEGRAM_CHATS: a chat the bot posts to and the
+// toggles (see chatToggles) it gets, all of them if Events is empty.
+type ChatRoute struct {
+	ChatID string
+	Events []string
+}
+
+// parseChatRoutes parses TELEGRAM_CHATS, comma-separated chat IDs each
+// optionally followed by the events it gets, e.g.
+// "-1001:player_joined|player_left,-1002:server_down|server_up".
+func parseChatRoutes(s string) ([]ChatRoute, error) {
+	var routes []ChatRoute
+	for _, item := range splitList(s) {
+		chatID, events, _ := strings.Cut(item, ":")
+		route := ChatRoute{ChatID: strings.TrimSpace(chatID)}
+		if route.ChatID == "" {
+			return nil, fmt.Errorf("no chat ID in %q", item)
+		}
+		for _, event := range strings.Split(events, "|") {
+			if event = strings.TrimSpace(event); event == "" {
+				continue
+			}
+			if !containsString(chatToggles, event) {
+				return nil, fmt.Errorf("unknown event %q for chat %s (one of %s)", event, route.ChatID, strings.Join(chatToggles, ", "))
+			}
+			route.Events = append(route.Events, event)
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// chatRoute returns the TELEGRAM_CHATS entry of chatID, following chat
+// migrations, and false if it has none.
+func chatRoute(chatID string) (ChatRoute, bool) {
+	for _, route := range config.ChatRoutes {
+		if route.ChatID == chatID || resolveChatID(route.ChatID) == chatID {
+			return route, true
+		}
+	}
+	return ChatRoute{}, false
+}
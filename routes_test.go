@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChatRoutes(t *testing.T) {
+	got, err := parseChatRoutes("-1001:player_joined|player_left, -1002:server_down|server_up,-1003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChatRoute{
+		{ChatID: "-1001", Events: []string{"player_joined", "player_left"}},
+		{ChatID: "-1002", Events: []string{"server_down", "server_up"}},
+		{ChatID: "-1003"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChatRoutes() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"-1001:crashed", ":server_down"} {
+		if _, err := parseChatRoutes(bad); err == nil {
+			t.Errorf("parseChatRoutes(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestAnnounceFollowsChatRoutes(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.ChatRoutes = []ChatRoute{
+		{ChatID: "-7", Events: []string{"player_joined", "player_left"}},
+		{ChatID: "-8", Events: []string{"server_down", "server_up", "title"}},
+	}
+
+	now := time.Now()
+	announce(context.Background(), "", false, 0, []Event{
+		{Kind: EventServerDown, Time: now},
+		{Kind: EventPlayerLeft, Player: "steve", Time: now},
+	})
+
+	sent := map[string]string{}
+	for _, call := range fake.callsTo("sendMessage") {
+		sent[call.Params["chat_id"].(string)] = call.Params["text"].(string)
+	}
+	if text := sent["-42"]; !strings.Contains(text, "steve") || !strings.Contains(text, tr("en", "server.down")) {
+		t.Errorf("main chat got %q, want everything", text)
+	}
+	if text := sent["-7"]; !strings.Contains(text, "steve") || strings.Contains(text, tr("en", "server.down")) {
+		t.Errorf("players chat got %q, want only steve leaving", text)
+	}
+	if text := sent["-8"]; strings.Contains(text, "steve") || !strings.Contains(text, tr("en", "server.down")) {
+		t.Errorf("alerts chat got %q, want only the server going down", text)
+	}
+
+	var titled []string
+	for _, call := range fake.callsTo("setChatTitle") {
+		titled = append(titled, call.Params["chat_id"].(string))
+	}
+	if !reflect.DeepEqual(titled, []string{"-42", "-8"}) {
+		t.Errorf("titles set in %v, want -42 and -8", titled)
+	}
+}
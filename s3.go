@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Client talks to an S3-compatible bucket (AWS, MinIO, ...) using
+// path-style URLs and Signature Version 4.
+type S3Client struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	HTTP      httpDoer
+	// Now is time.Now outside of tests.
+	Now func() time.Time
+}
+
+func newS3Client() *S3Client {
+	return &S3Client{
+		Endpoint:  strings.TrimRight(config.S3Endpoint, "/"),
+		Region:    config.S3Region,
+		Bucket:    config.S3Bucket,
+		AccessKey: config.S3AccessKey,
+		SecretKey: config.S3SecretKey,
+		HTTP:      &http.Client{Timeout: 60 * time.Second},
+		Now:       time.Now,
+	}
+}
+
+// Put uploads data as key.
+func (c *S3Client) Put(ctx context.Context, key string, data []byte) error {
+	_, err := c.do(ctx, "PUT", key, nil, data)
+	return err
+}
+
+// Get downloads key.
+func (c *S3Client) Get(ctx context.Context, key string) ([]byte, error) {
+	return c.do(ctx, "GET", key, nil, nil)
+}
+
+// Delete removes key.
+func (c *S3Client) Delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, "DELETE", key, nil, nil)
+	return err
+}
+
+// List returns the keys starting with prefix in lexical order. Only the
+// first page (up to 1000 keys) is read, which is plenty for backups with
+// retention.
+func (c *S3Client) List(ctx context.Context, prefix string) ([]string, error) {
+	body, err := c.do(ctx, "GET", "", url.Values{"list-type": {"2"}, "prefix": {prefix}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Contents []struct {
+			Key string
+		}
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parsing list response: %w", err)
+	}
+
+	keys := make([]string, 0, len(result.Contents))
+	for _, object := range result.Contents {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (c *S3Client) do(ctx context.Context, method, key string, query url.Values, body []byte) ([]byte, error) {
+	path := "/" + c.Bucket
+	if key != "" {
+		path += "/" + key
+	}
+	u, err := url.Parse(c.Endpoint + s3EscapePath(path))
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = s3EscapeQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, body)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var s3Err struct {
+			Code    string
+			Message string
+		}
+		xml.Unmarshal(data, &s3Err)
+		return nil, fmt.Errorf("S3 %s %s: %s %s %s", method, path, resp.Status, s3Err.Code, s3Err.Message)
+	}
+	return data, nil
+}
+
+// sign adds SigV4 headers to req, signing host and every header already
+// set on it.
+func (c *S3Client) sign(req *http.Request, body []byte) {
+	now := c.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := []byte("AWS4" + c.SecretKey)
+	for _, part := range []string{date, c.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, signedHeaders, signature))
+}
+
+// s3EscapePath escapes each segment of path the way SigV4 expects.
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.QueryEscape(segment), "+", "%20")
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3EscapeQuery encodes query sorted by key, with %20 for spaces as SigV4
+// requires.
+func s3EscapeQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Job is a periodic task run by the Scheduler. A run that comes due while
+// the previous one is still going is skipped, so a slow job can't pile up
+// copies of itself.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Jitter delays each run by a random amount up to Jitter, so jobs with
+	// the same interval don't all fire at once.
+	Jitter time.Duration
+	// NextInterval, if set, is asked for the delay before each run
+	// instead of using Interval.
+	NextInterval func() time.Duration
+	// RunAtStart jobs also run as soon as the scheduler starts, rather
+	// than only after the first interval.
+	RunAtStart bool
+	Run        func(ctx context.Context)
+
+	mu           sync.Mutex
+	interval     time.Duration
+	running      bool
+	lastRun      time.Time
+	lastDuration time.Duration
+	nextRun      time.Time
+	runs         int
+	skipped      int
+}
+
+// JobStatus is a snapshot of a job for /jobs.
+type JobStatus struct {
+	Name         string
+	Interval     time.Duration
+	Running      bool
+	LastRun      time.Time
+	LastDuration time.Duration
+	NextRun      time.Time
+	Runs         int
+	Skipped      int
+}
+
+// Scheduler runs every periodic job of the monitor.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs []*Job
+	wg   sync.WaitGroup
+}
+
+var scheduler = &Scheduler{}
+
+// Add registers job; jobs added after Run has started aren't scheduled.
+func (s *Scheduler) Add(job *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = append(s.jobs, job)
+}
+
+// Run schedules every job until ctx is cancelled, then waits for running
+// jobs to finish.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]*Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	var loops sync.WaitGroup
+	for _, job := range jobs {
+		loops.Add(1)
+		go func(job *Job) {
+			defer loops.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	loops.Wait()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	if job.RunAtStart {
+		s.start(ctx, job)
+	}
+	for {
+		interval := job.Interval
+		if job.NextInterval != nil {
+			interval = job.NextInterval()
+		}
+		delay := interval
+		if job.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(job.Jitter)))
+		}
+		job.mu.Lock()
+		job.interval = interval
+		job.nextRun = time.Now().Add(delay)
+		job.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.start(ctx, job)
+	}
+}
+
+// start runs job in the background unless it's still running.
+func (s *Scheduler) start(ctx context.Context, job *Job) {
+	job.mu.Lock()
+	if job.running {
+		job.skipped++
+		job.mu.Unlock()
+		log.Printf("Skipping job %s: the previous run is still going", job.Name)
+		return
+	}
+	job.running = true
+	job.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		start := time.Now()
+		job.Run(ctx)
+
+		job.mu.Lock()
+		job.running = false
+		job.lastRun = start
+		job.lastDuration = time.Since(start)
+		job.runs++
+		job.mu.Unlock()
+	}()
+}
+
+// Jobs returns the status of every job, in the order they were added.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]JobStatus, len(s.jobs))
+	for i, job := range s.jobs {
+		job.mu.Lock()
+		interval := job.Interval
+		if job.interval > 0 {
+			interval = job.interval
+		}
+		result[i] = JobStatus{
+			Name:         job.Name,
+			Interval:     interval,
+			Running:      job.running,
+			LastRun:      job.lastRun,
+			LastDuration: job.lastDuration,
+			NextRun:      job.nextRun,
+			Runs:         job.runs,
+			Skipped:      job.skipped,
+		}
+		job.mu.Unlock()
+	}
+	return result
+}
+
+// renderJobs is the /jobs reply.
+func renderJobs(lang string, jobs []JobStatus) string {
+	lines := []string{tr(lang, "jobs.header")}
+	for _, job := range jobs {
+		last := tr(lang, "jobs.never")
+		if !job.LastRun.IsZero() {
+			last = tr(lang, "jobs.last_run", job.LastRun.Format("15:04:05"), job.LastDuration.Round(time.Millisecond))
+		}
+		if job.Running {
+			last += ", " + tr(lang, "jobs.running")
+		}
+		next := "—"
+		if !job.NextRun.IsZero() {
+			next = job.NextRun.Format("15:04:05")
+		}
+		lines = append(lines, tr(lang, "jobs.line", escapeHtml(job.Name), job.Interval, last, next, job.Runs, job.Skipped))
+	}
+	return joinStrings(lines, "\n")
+}
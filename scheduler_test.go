@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	var running, maxRunning int32
+	release := make(chan struct{})
+	s := &Scheduler{}
+	s.Add(&Job{Name: "slow", Interval: 5 * time.Millisecond, Run: func(ctx context.Context) {
+		n := atomic.AddInt32(&running, 1)
+		if n > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, n)
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(release)
+	<-done
+
+	jobs := s.Jobs()
+	if maxRunning != 1 {
+		t.Fatalf("%d runs overlapped", maxRunning)
+	}
+	if len(jobs) != 1 || jobs[0].Runs != 1 || jobs[0].Skipped == 0 || jobs[0].Running || jobs[0].LastRun.IsZero() {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+}
+
+func TestSchedulerRunAtStart(t *testing.T) {
+	var runs int32
+	s := &Scheduler{}
+	s.Add(&Job{Name: "update", Interval: time.Hour, RunAtStart: true, Run: func(context.Context) {
+		atomic.AddInt32(&runs, 1)
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	if runs != 1 {
+		t.Fatalf("job ran %d times, want once right away", runs)
+	}
+}
+
+func TestRenderJobs(t *testing.T) {
+	at := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	got := renderJobs("en", []JobStatus{
+		{Name: "check", Interval: time.Minute, LastRun: at, LastDuration: 35 * time.Millisecond, NextRun: at.Add(time.Minute), Runs: 3, Skipped: 1},
+		{Name: "backup", Interval: 24 * time.Hour, Running: true},
+	})
+	want := []string{
+		"⏱ <b>Background jobs</b>",
+		"<b>check</b> every 1m0s: last run at 12:00:00 (35ms); next run 12:01:00; 3 runs, 1 skipped",
+		"<b>backup</b> every 24h0m0s: not run yet, running; next run —; 0 runs, 0 skipped",
+	}
+	if got != strings.Join(want, "\n") {
+		t.Fatalf("renderJobs:\n%s", got)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// checksInProgress holds the servers being checked right now, by the key
+// chats use for them ("" for SERVER_HOST). A check reads the previous
+// state, pings and writes the result back, so two overlapping checks of
+// one server would announce the same transition twice.
+var (
+	checksInProgressMu sync.Mutex
+	checksInProgress   = map[string]bool{}
+)
+
+// beginServerCheck claims server for a check. It returns false, and counts
+// the check as skipped, while another check of server is still running;
+// the caller must then skip its check. Otherwise endServerCheck must be
+// called once the check is done.
+func beginServerCheck(server string) bool {
+	checksInProgressMu.Lock()
+	defer checksInProgressMu.Unlock()
+
+	if checksInProgress[server] {
+		name := server
+		if name == "" {
+			name = config.ServerHost
+		}
+		log.Printf("Skipping check of %s: the previous one is still running", name)
+		metrics.Inc("minecraft_checks_skipped_total", "server", name)
+		return false
+	}
+	checksInProgress[server] = true
+	return true
+}
+
+func endServerCheck(server string) {
+	checksInProgressMu.Lock()
+	defer checksInProgressMu.Unlock()
+
+	delete(checksInProgress, server)
+}
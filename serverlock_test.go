@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestOverlappingChecksAreSkipped(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.ServerHost = "127.0.0.1"
+	config.ServerPort = fakeMinecraftServer(t, samplePacket(1))
+
+	if !beginServerCheck("") {
+		t.Fatal("first claim failed")
+	}
+	checkServer(context.Background())
+	if latest := getLatest(); latest != nil {
+		t.Fatalf("overlapping check stored %+v", latest)
+	}
+
+	var buf strings.Builder
+	metrics.WriteTo(&buf)
+	if !strings.Contains(buf.String(), `minecraft_checks_skipped_total{server="127.0.0.1"}`) {
+		t.Fatalf("skipped check not counted:\n%s", buf.String())
+	}
+
+	endServerCheck("")
+	if !beginServerCheck("") {
+		t.Fatal("claim after release failed")
+	}
+	endServerCheck("")
+}
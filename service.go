@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	SERVICE_NAME         = "lnudorm3-status"
+	SERVICE_DISPLAY_NAME = "lnudorm3 Minecraft status monitor"
+	// LAUNCHD_LABEL names the launchd agent on macOS.
+	LAUNCHD_LABEL = "com.lnudorm3.status"
+	// SERVICE_LOG_FILE is where an installed service logs, in its working
+	// directory.
+	SERVICE_LOG_FILE = "monitor.log"
+)
+
+var errServiceUnsupported = errors.New("service install is supported on Windows and macOS; use Docker or systemd elsewhere")
+
+// configKeys are the environment variables loadConfig looked at, so an
+// installed service can be given the same settings.
+var configKeys = map[string]bool{}
+
+// runServiceCommand handles "service install" and "service uninstall".
+// Installing registers the binary as a Windows service or a launchd agent
+// that starts in the current directory (where its data files live) with
+// the settings of the current environment. "service run <dir>" is how
+// Windows starts the installed service.
+func runServiceCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: service install|uninstall")
+	}
+
+	switch args[0] {
+	case "install":
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if err := installService(exe, dir, serviceEnvironment()); err != nil {
+			return err
+		}
+		fmt.Printf("Installed %s, logging to %s\n", SERVICE_NAME, SERVICE_LOG_FILE)
+		return nil
+	case "uninstall":
+		if err := uninstallService(); err != nil {
+			return err
+		}
+		fmt.Printf("Uninstalled %s\n", SERVICE_NAME)
+		return nil
+	case "run":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: service run <dir>")
+		}
+		return runService(ctx, args[1])
+	default:
+		return fmt.Errorf("unknown service command %q", args[0])
+	}
+}
+
+// serviceEnvironment is the part of the environment loadConfig used, as
+// sorted KEY=value pairs, plus the config file if there is one.
+func serviceEnvironment() []string {
+	var env []string
+	for key := range configKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	if configFile != "" {
+		path, err := filepath.Abs(configFile)
+		if err != nil {
+			path = configFile
+		}
+		env = append(env, CONFIG_FILE_ENV+"="+path)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// launchdPlist is the launchd agent definition: start at login, restart
+// when the monitor exits.
+func launchdPlist(exe, dir string, env []string) string {
+	escape := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", LAUNCHD_LABEL)
+	fmt.Fprintf(&b, "\t<key>ProgramArguments</key>\n\t<array>\n\t\t<string>%s</string>\n\t</array>\n", escape(exe))
+	fmt.Fprintf(&b, "\t<key>WorkingDirectory</key>\n\t<string>%s</string>\n", escape(dir))
+	if len(env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, pair := range env {
+			key, value, _ := strings.Cut(pair, "=")
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", escape(key), escape(value))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n\t<key>KeepAlive</key>\n\t<true/>\n")
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", escape(SERVICE_LOG_FILE))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", escape(SERVICE_LOG_FILE))
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
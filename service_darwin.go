@@ -0,0 +1,59 @@
+//go:build darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+func launchdPlistPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", LAUNCHD_LABEL+".plist"), nil
+}
+
+// installService writes the launchd agent for the current user and loads
+// it. The plist holds the settings, secrets included, so only the user can
+// read it.
+func installService(exe, dir string, env []string) error {
+	path, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(launchdPlist(exe, dir, env)), 0600); err != nil {
+		return err
+	}
+	return launchctl("load", "-w", path)
+}
+
+func uninstallService() error {
+	path, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+	if err := launchctl("unload", "-w", path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func runService(ctx context.Context, dir string) error {
+	return fmt.Errorf("service run is only used on Windows; launchd runs the monitor directly")
+}
+
+func launchctl(args ...string) error {
+	output, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %v: %s", args[0], err, output)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !darwin
+
+package main
+
+import "context"
+
+func installService(exe, dir string, env []string) error {
+	return errServiceUnsupported
+}
+
+func uninstallService() error {
+	return errServiceUnsupported
+}
+
+func runService(ctx context.Context, dir string) error {
+	return errServiceUnsupported
+}
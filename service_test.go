@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServiceEnvironment(t *testing.T) {
+	t.Setenv("SERVER_HOST", "mc.example.com")
+	t.Setenv("UNRELATED_SETTING", "x")
+	getEnv("SERVER_HOST", "")
+
+	env := serviceEnvironment()
+	joined := strings.Join(env, "\n")
+	if !strings.Contains(joined, "SERVER_HOST=mc.example.com") || strings.Contains(joined, "UNRELATED_SETTING") {
+		t.Fatalf("service environment = %q", env)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist("/Applications/status", "/Users/me/mc & co", []string{"TELEGRAM_CHAT_ID=-42"})
+	for _, want := range []string{
+		"<string>/Applications/status</string>",
+		"<key>WorkingDirectory</key>\n\t<string>/Users/me/mc &amp; co</string>",
+		"<key>TELEGRAM_CHAT_ID</key>\n\t\t<string>-42</string>",
+		"<key>KeepAlive</key>\n\t<true/>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist lacks %q:\n%s", want, plist)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// installService registers an automatically started service running
+// "service run <dir>", with env as its environment.
+func installService(exe, dir string, env []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(SERVICE_NAME); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", SERVICE_NAME)
+	}
+
+	s, err := m.CreateService(SERVICE_NAME, exe, mgr.Config{
+		DisplayName: SERVICE_DISPLAY_NAME,
+		StartType:   mgr.StartAutomatic,
+	}, "service", "run", dir)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// The service control manager passes this value to the service as its
+	// environment.
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Services\`+SERVICE_NAME, registry.SET_VALUE)
+	if err != nil {
+		s.Delete()
+		return err
+	}
+	defer key.Close()
+	if err := key.SetStringsValue("Environment", env); err != nil {
+		s.Delete()
+		return err
+	}
+
+	// Come back after a crash.
+	recovery := []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: time.Minute}}
+	if err := s.SetRecoveryActions(recovery, 24*60*60); err != nil {
+		log.Printf("Error setting recovery actions: %v", err)
+	}
+	return s.Start()
+}
+
+func uninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(SERVICE_NAME)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", SERVICE_NAME)
+	}
+	defer s.Close()
+
+	if _, err := s.Control(svc.Stop); err != nil {
+		log.Printf("Error stopping %s: %v", SERVICE_NAME, err)
+	}
+	return s.Delete()
+}
+
+// runService runs the monitor under the service control manager, in dir
+// and logging to SERVICE_LOG_FILE there.
+func runService(ctx context.Context, dir string) error {
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(SERVICE_LOG_FILE, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	log.SetOutput(logFile)
+
+	return svc.Run(SERVICE_NAME, &monitorService{ctx: ctx})
+}
+
+type monitorService struct {
+	ctx context.Context
+}
+
+func (m *monitorService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	done := make(chan struct{})
+	go func() {
+		runMonitor(ctx)
+		close(done)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				return false, 0
+			}
+		case <-done:
+			cancel()
+			return false, 1
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed web/settings.html
+var settingsHTML string
+
+var settingsTemplate = template.Must(template.New("settings").Parse(settingsHTML))
+
+// ScheduleSetting is a duration from the environment that the settings
+// page can override at runtime. Overrides are kept in the state by Key
+// and applied on top of the environment at startup.
+type ScheduleSetting struct {
+	Key  string
+	Help string
+	// Interval settings are delays between checks: below a second they'd
+	// ping the server and edit chat titles in a tight loop.
+	Interval bool
+	field    func() *time.Duration
+	// env is the value loadConfig read, restored when an override is
+	// removed.
+	env    time.Duration
+	loaded bool
+}
+
+// scheduleSettings are the schedules jobs read on every run, so changing
+// them takes effect from the next run on.
+var scheduleSettings = []*ScheduleSetting{
+	{Key: "CHECK_INTERVAL", Help: "between checks", Interval: true, field: func() *time.Duration { return &config.CheckInterval }},
+	{Key: "CHECK_INTERVAL_ACTIVE", Help: "between checks while players are online", Interval: true, field: func() *time.Duration { return &config.CheckIntervalActive }},
+	{Key: "CHECK_INTERVAL_IDLE", Help: "between checks once the server has been empty for IDLE_AFTER", Interval: true, field: func() *time.Duration { return &config.CheckIntervalIdle }},
+	{Key: "CHECK_INTERVAL_INCIDENT", Help: "between checks during an incident", Interval: true, field: func() *time.Duration { return &config.CheckIntervalIncident }},
+	{Key: "IDLE_AFTER", Help: "without players before checks slow down", field: func() *time.Duration { return &config.IdleAfter }},
+}
+
+// applySchedule puts the overrides saved in the state on top of the
+// environment's schedules.
+func applySchedule() {
+	state.mu.Lock()
+	overrides := state.Schedule
+	state.mu.Unlock()
+
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	for _, setting := range scheduleSettings {
+		if !setting.loaded {
+			setting.env, setting.loaded = *setting.field(), true
+		}
+		*setting.field() = setting.env
+		if value, ok := overrides[setting.Key]; ok {
+			if d, err := time.ParseDuration(value); err == nil {
+				*setting.field() = d
+			} else {
+				log.Printf("Ignoring invalid %s override %q: %v", setting.Key, value, err)
+			}
+		}
+	}
+}
+
+// adminAuthorized reports whether r carries API_TOKEN as a bearer token, or
+// as the password of HTTP basic auth so browsers can log in; without
+// API_TOKEN nobody is an admin.
+func adminAuthorized(r *http.Request) bool {
+	if config.APIToken == "" {
+		return false
+	}
+	if _, password, ok := r.BasicAuth(); ok && subtle.ConstantTimeCompare([]byte(password), []byte(config.APIToken)) == 1 {
+		return true
+	}
+	return checkBearerToken(r, config.APIToken)
+}
+
+// checkAdminAuth lets admins through and asks everyone else to log in.
+func checkAdminAuth(w http.ResponseWriter, r *http.Request) bool {
+	if adminAuthorized(r) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="lnudorm3-status", charset="UTF-8"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+	return false
+}
+
+// adminCSRF is the token of the admin pages' forms: browsers send basic
+// auth along with any cross-site post, so the form proves it came from the
+// page.
+func adminCSRF() string {
+	mac := hmac.New(sha256.New, []byte(config.APIToken))
+	mac.Write([]byte("settings"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type settingsPage struct {
+	Server    string
+	CSRF      string
+	Saved     bool
+	Errors    []string
+	Languages []string
+	Policies  []string
+	Schedule  []scheduleForm
+	Chats     []chatForm
+}
+
+type scheduleForm struct {
+	Key, Help, Value, Default string
+}
+
+type chatForm struct {
+	ID        string
+	Language  string
+	Policy    string
+	Toggles   []toggleForm
+	Templates []templateForm
+}
+
+type toggleForm struct {
+	Name    string
+	Enabled bool
+}
+
+type templateForm struct {
+	Key, Text string
+}
+
+// handleSettingsPage serves the settings editor at /admin/settings: the
+// schedules, and the language, online policy, event toggles and templates
+// of every chat, the same as /chatconfig edits. A post is validated as a
+// whole and applied right away, or not at all.
+func handleSettingsPage(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminAuth(w, r) {
+		return
+	}
+
+	page := settingsPage{Saved: r.URL.Query().Get("saved") == "1"}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("csrf")), []byte(adminCSRF())) != 1 {
+			http.Error(w, "invalid form token", http.StatusForbidden)
+			return
+		}
+		if page.Errors = saveSettingsForm(r); len(page.Errors) == 0 {
+			http.Redirect(w, r, r.URL.Path+"?saved=1", http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fillSettingsPage(&page)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := settingsTemplate.Execute(w, page); err != nil {
+		log.Printf("Error rendering the settings page: %v", err)
+	}
+}
+
+// fillSettingsPage puts the current settings into page.
+func fillSettingsPage(page *settingsPage) {
+	page.Server = net.JoinHostPort(config.ServerHost, strconv.Itoa(int(config.ServerPort)))
+	page.CSRF = adminCSRF()
+	page.Policies = onlinePolicies
+	for lang := range messages {
+		page.Languages = append(page.Languages, lang)
+	}
+	sort.Strings(page.Languages)
+
+	state.mu.Lock()
+	overrides := state.Schedule
+	state.mu.Unlock()
+	scheduleMu.RLock()
+	for _, setting := range scheduleSettings {
+		page.Schedule = append(page.Schedule, scheduleForm{
+			Key:     setting.Key,
+			Help:    setting.Help,
+			Value:   overrides[setting.Key],
+			Default: setting.env.String(),
+		})
+	}
+	scheduleMu.RUnlock()
+
+	for _, chatID := range servedChats() {
+		settings := chatSettings(chatID)
+		form := chatForm{ID: chatID, Language: settings.Language, Policy: settings.OnlinePolicy}
+		for _, toggle := range chatToggles {
+			form.Toggles = append(form.Toggles, toggleForm{Name: toggle, Enabled: settings.enabled(toggle)})
+		}
+		for _, key := range chatTemplateKeys {
+			form.Templates = append(form.Templates, templateForm{Key: key, Text: settings.Templates[key]})
+		}
+		page.Chats = append(page.Chats, form)
+	}
+}
+
+// saveSettingsForm validates the posted settings and applies them if they
+// are all valid, returning what is wrong otherwise.
+func saveSettingsForm(r *http.Request) []string {
+	var problems []string
+	form := r.PostForm
+
+	schedule := map[string]string{}
+	for _, setting := range scheduleSettings {
+		value := strings.TrimSpace(form.Get("schedule." + setting.Key))
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			problems = append(problems, fmt.Sprintf("%s: %q isn't a duration", setting.Key, value))
+			continue
+		}
+		// CHECK_INTERVAL is always used; the others can be 0 to fall
+		// back on it.
+		if setting.Interval && d < time.Second && (d > 0 || setting.Key == "CHECK_INTERVAL") {
+			problems = append(problems, setting.Key+": must be at least 1s")
+			continue
+		}
+		schedule[setting.Key] = value
+	}
+
+	chats := map[string]ChatSettings{}
+	for _, chatID := range servedChats() {
+		prefix := "chat." + chatID + "."
+		settings := chatSettings(chatID)
+
+		settings.Language = form.Get(prefix + "language")
+		if _, ok := messages[settings.Language]; settings.Language != "" && !ok {
+			problems = append(problems, fmt.Sprintf("chat %s: unknown language %q", chatID, settings.Language))
+		}
+		settings.OnlinePolicy = form.Get(prefix + "online")
+		if settings.OnlinePolicy != "" && !containsString(onlinePolicies, settings.OnlinePolicy) {
+			problems = append(problems, fmt.Sprintf("chat %s: unknown online policy %q", chatID, settings.OnlinePolicy))
+		}
+
+		enabled := form[prefix+"events"]
+		settings.DisabledEvents = nil
+		for _, toggle := range chatToggles {
+			if !containsString(enabled, toggle) {
+				settings.DisabledEvents = append(settings.DisabledEvents, toggle)
+			}
+		}
+
+		settings.Templates = map[string]string{}
+		for _, key := range chatTemplateKeys {
+			text := strings.TrimSpace(form.Get(prefix + "template." + key))
+			if text == "" {
+				continue
+			}
+			if _, err := renderChatTemplate(text, MessageTemplateData{}); err != nil {
+				problems = append(problems, fmt.Sprintf("chat %s: template %s: %v", chatID, key, err))
+				continue
+			}
+			settings.Templates[key] = text
+		}
+		chats[chatID] = settings
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	for chatID, edited := range chats {
+		updateChatSettings(chatID, func(s *ChatSettings) {
+			s.Language = edited.Language
+			s.OnlinePolicy = edited.OnlinePolicy
+			s.DisabledEvents = edited.DisabledEvents
+			s.Templates = edited.Templates
+		})
+	}
+	state.mu.Lock()
+	state.Schedule = schedule
+	saveState()
+	state.mu.Unlock()
+	applySchedule()
+
+	recordAudit(AuditEntry{Via: "web", Command: "settings", Result: "ok"})
+	log.Printf("Settings updated from the settings page")
+	return nil
+}
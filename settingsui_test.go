@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettingsPage(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.APIToken = "secret"
+	config.CheckIntervalActive = 0
+	t.Cleanup(func() {
+		for _, setting := range scheduleSettings {
+			setting.loaded = false
+		}
+	})
+	applySchedule()
+
+	do := func(method string, form url.Values, password string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/admin/settings", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if password != "" {
+			r.SetBasicAuth("admin", password)
+		}
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("GET", nil, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status %d", w.Code)
+	}
+	if w := do("GET", nil, "secret"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "chat.-42.language") {
+		t.Fatalf("GET: status %d, body %s", w.Code, w.Body)
+	}
+
+	form := url.Values{
+		"csrf":                             {adminCSRF()},
+		"schedule.CHECK_INTERVAL_ACTIVE":   {"15s"},
+		"chat.-42.language":                {"en"},
+		"chat.-42.online":                  {ONLINE_REACHABLE},
+		"chat.-42.events":                  {string(EventPlayerJoined), string(EventServerDown)},
+		"chat.-42.template.players.joined": {"👋 {{.Players}}"},
+	}
+	if w := do("POST", url.Values{"csrf": {"forged"}}, "secret"); w.Code != http.StatusForbidden {
+		t.Errorf("forged form: status %d", w.Code)
+	}
+
+	broken := url.Values{}
+	for key, values := range form {
+		broken[key] = values
+	}
+	broken.Set("chat.-42.template.players.joined", "{{.Nope")
+	if w := do("POST", broken, "secret"); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "players.joined") {
+		t.Errorf("broken template: status %d", w.Code)
+	}
+	if config.CheckIntervalActive != 0 || chatSettings("-42").Language != "" {
+		t.Fatal("an invalid form was partly applied")
+	}
+
+	if w := do("POST", form, "secret"); w.Code != http.StatusSeeOther {
+		t.Fatalf("POST: status %d, body %s", w.Code, w.Body)
+	}
+	settings := chatSettings("-42")
+	if settings.Language != "en" || settings.policy() != ONLINE_REACHABLE || settings.enabled(string(EventPlayerLeft)) ||
+		!settings.enabled(string(EventServerDown)) || settings.Templates["players.joined"] != "👋 {{.Players}}" {
+		t.Errorf("chat settings = %+v", settings)
+	}
+	if config.CheckIntervalActive != 15*time.Second {
+		t.Errorf("CHECK_INTERVAL_ACTIVE = %v, want the override applied", config.CheckIntervalActive)
+	}
+
+	// Clearing the override goes back to the environment's value.
+	form.Del("schedule.CHECK_INTERVAL_ACTIVE")
+	do("POST", form, "secret")
+	if config.CheckIntervalActive != 0 {
+		t.Errorf("CHECK_INTERVAL_ACTIVE = %v after clearing the override", config.CheckIntervalActive)
+	}
+}
+
+func TestSettingsFormRejectsShortIntervals(t *testing.T) {
+	useTempStore(t, 0)
+	useFakeTelegram(t)
+	config.CheckIntervalIncident = 0
+
+	for key, value := range map[string]string{
+		"CHECK_INTERVAL":          "0s",
+		"CHECK_INTERVAL_ACTIVE":   "1ns",
+		"CHECK_INTERVAL_IDLE":     "500ms",
+		"CHECK_INTERVAL_INCIDENT": "1ns",
+	} {
+		r := httptest.NewRequest("POST", "/admin/settings", nil)
+		r.PostForm = url.Values{"schedule." + key: {value}}
+		problems := saveSettingsForm(r)
+		if len(problems) != 1 || !strings.Contains(problems[0], key+": must be at least 1s") {
+			t.Errorf("%s=%s: problems %q", key, value, problems)
+		}
+	}
+	if config.CheckIntervalIncident != 0 {
+		t.Errorf("CHECK_INTERVAL_INCIDENT = %v, want the rejected form not applied", config.CheckIntervalIncident)
+	}
+
+	r := httptest.NewRequest("POST", "/admin/settings", nil)
+	r.PostForm = url.Values{"schedule.CHECK_INTERVAL_IDLE": {"0"}}
+	if problems := saveSettingsForm(r); len(problems) != 0 {
+		t.Errorf("CHECK_INTERVAL_IDLE=0 (fall back on CHECK_INTERVAL): problems %q", problems)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The parts of SFTP version 3 (draft-ietf-secsh-filexfer-02, what OpenSSH
+// speaks) needed to read a file.
+const (
+	SSH_FXP_INIT    = 1
+	SSH_FXP_VERSION = 2
+	SSH_FXP_OPEN    = 3
+	SSH_FXP_CLOSE   = 4
+	SSH_FXP_READ    = 5
+	SSH_FXP_STATUS  = 101
+	SSH_FXP_HANDLE  = 102
+	SSH_FXP_DATA    = 103
+
+	SSH_FXF_READ = 0x00000001
+	SSH_FX_EOF   = 1
+
+	SFTP_VERSION = 3
+	// SFTP_CHUNK is how much one READ asks for; servers may send less.
+	SFTP_CHUNK = 32 * 1024
+	// MAX_SFTP_PACKET and MAX_SFTP_FILE bound what a server can make us
+	// buffer.
+	MAX_SFTP_PACKET = 256 * 1024
+	MAX_SFTP_FILE   = 1024 * 1024
+)
+
+// sftpStatusError is an SSH_FXP_STATUS reply other than success.
+type sftpStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp error %d: %s", e.Code, e.Message)
+}
+
+// dialSSH connects to SFTP_ADDR. The host key must match SFTP_HOST_KEY
+// (an SHA256 fingerprint as printed by ssh-keygen -l); closing the
+// returned client also happens when ctx is done.
+func dialSSH(ctx context.Context) (*ssh.Client, error) {
+	var auth []ssh.AuthMethod
+	if config.SFTPKeyFile != "" {
+		key, err := os.ReadFile(config.SFTPKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("SFTP_KEY_FILE: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if config.SFTPPassword != "" {
+		auth = append(auth, ssh.Password(config.SFTPPassword))
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User: config.SFTPUser,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if fingerprint := ssh.FingerprintSHA256(key); fingerprint != config.SFTPHostKey {
+				return fmt.Errorf("host key %s doesn't match SFTP_HOST_KEY", fingerprint)
+			}
+			return nil
+		},
+		Timeout: TIMEOUT,
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", config.SFTPAddr)
+	if err != nil {
+		return nil, classifyNetError(err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, config.SFTPAddr, clientConfig)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// sftpReadFile reads path (relative to the SFTP user's home unless
+// absolute) from SFTP_ADDR.
+func sftpReadFile(ctx context.Context, path string) ([]byte, error) {
+	client, err := dialSSH(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: %w", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return nil, fmt.Errorf("sftp subsystem: %w", err)
+	}
+
+	conn := &sftpConn{w: stdin, r: stdout}
+	data, err := conn.readFile(path)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	return data, err
+}
+
+// runSSH runs command on SFTP_ADDR and returns its output.
+func runSSH(ctx context.Context, command string) ([]byte, error) {
+	client, err := dialSSH(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: %w", err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	output, err := session.Output(command)
+	if err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	return output, err
+}
+
+// shellQuote quotes s as one word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sftpConn runs SFTP requests one at a time over an SSH subsystem channel.
+type sftpConn struct {
+	w      io.Writer
+	r      io.Reader
+	nextID uint32
+}
+
+func (c *sftpConn) readFile(path string) ([]byte, error) {
+	if err := c.send(SSH_FXP_INIT, binary.BigEndian.AppendUint32(nil, SFTP_VERSION)); err != nil {
+		return nil, err
+	}
+	kind, _, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	if kind != SSH_FXP_VERSION {
+		return nil, fmt.Errorf("sftp: expected VERSION, got packet type %d", kind)
+	}
+
+	payload := appendSFTPString(nil, []byte(path))
+	payload = binary.BigEndian.AppendUint32(payload, SSH_FXF_READ)
+	payload = binary.BigEndian.AppendUint32(payload, 0) // no attributes
+	kind, reply, err := c.request(SSH_FXP_OPEN, payload)
+	if err != nil {
+		return nil, err
+	}
+	if kind != SSH_FXP_HANDLE {
+		return nil, fmt.Errorf("sftp: expected HANDLE, got packet type %d", kind)
+	}
+	handle, _, err := readSFTPString(reply)
+	if err != nil {
+		return nil, err
+	}
+	defer c.request(SSH_FXP_CLOSE, appendSFTPString(nil, handle))
+
+	var data []byte
+	for {
+		payload := appendSFTPString(nil, handle)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(len(data)))
+		payload = binary.BigEndian.AppendUint32(payload, SFTP_CHUNK)
+		kind, reply, err := c.request(SSH_FXP_READ, payload)
+		var status *sftpStatusError
+		if errors.As(err, &status) && status.Code == SSH_FX_EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if kind != SSH_FXP_DATA {
+			return nil, fmt.Errorf("sftp: expected DATA, got packet type %d", kind)
+		}
+		chunk, _, err := readSFTPString(reply)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) == 0 {
+			return data, nil
+		}
+		data = append(data, chunk...)
+		if len(data) > MAX_SFTP_FILE {
+			return nil, fmt.Errorf("sftp: %s is over %d bytes", path, MAX_SFTP_FILE)
+		}
+	}
+}
+
+// request sends a packet with a fresh request ID and returns the reply's
+// type and payload after the ID. STATUS replies are returned as errors,
+// except for success.
+func (c *sftpConn) request(kind byte, payload []byte) (byte, []byte, error) {
+	c.nextID++
+	id := c.nextID
+	if err := c.send(kind, append(binary.BigEndian.AppendUint32(nil, id), payload...)); err != nil {
+		return 0, nil, err
+	}
+
+	kind, reply, err := c.recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(reply) < 4 || binary.BigEndian.Uint32(reply) != id {
+		return 0, nil, errors.New("sftp: reply to a different request")
+	}
+	reply = reply[4:]
+
+	if kind == SSH_FXP_STATUS {
+		if len(reply) < 4 {
+			return 0, nil, errors.New("sftp: short STATUS packet")
+		}
+		code := binary.BigEndian.Uint32(reply)
+		if code == 0 {
+			return kind, nil, nil
+		}
+		message, _, _ := readSFTPString(reply[4:])
+		return 0, nil, &sftpStatusError{Code: code, Message: string(message)}
+	}
+	return kind, reply, nil
+}
+
+func (c *sftpConn) send(kind byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, kind)
+	_, err := c.w.Write(append(packet, payload...))
+	return err
+}
+
+func (c *sftpConn) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length == 0 || length > MAX_SFTP_PACKET {
+		return 0, nil, fmt.Errorf("sftp: packet of %d bytes", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+func appendSFTPString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func readSFTPString(b []byte) (s, rest []byte, err error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("sftp: short string")
+	}
+	length := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < length {
+		return nil, nil, errors.New("sftp: short string")
+	}
+	return bytes.Clone(b[4 : 4+length]), b[4+length:], nil
+}
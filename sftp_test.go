@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeSFTPServer serves file in chunks of chunk bytes to requests read from
+// r, answering on w, until the handle is closed.
+func fakeSFTPServer(t *testing.T, r io.Reader, w io.Writer, file string, chunk int) {
+	conn := &sftpConn{w: w, r: r}
+	for {
+		kind, payload, err := conn.recv()
+		if err != nil {
+			return
+		}
+		if kind == SSH_FXP_INIT {
+			conn.send(SSH_FXP_VERSION, binary.BigEndian.AppendUint32(nil, SFTP_VERSION))
+			continue
+		}
+		id := payload[:4]
+		reply := append([]byte(nil), id...)
+		switch kind {
+		case SSH_FXP_OPEN:
+			conn.send(SSH_FXP_HANDLE, appendSFTPString(reply, []byte("h1")))
+		case SSH_FXP_READ:
+			_, rest, _ := readSFTPString(payload[4:])
+			offset := int(binary.BigEndian.Uint64(rest))
+			if offset >= len(file) {
+				reply = binary.BigEndian.AppendUint32(reply, SSH_FX_EOF)
+				conn.send(SSH_FXP_STATUS, appendSFTPString(appendSFTPString(reply, []byte("EOF")), nil))
+				continue
+			}
+			end := min(offset+chunk, len(file))
+			conn.send(SSH_FXP_DATA, appendSFTPString(reply, []byte(file[offset:end])))
+		case SSH_FXP_CLOSE:
+			reply = binary.BigEndian.AppendUint32(reply, 0)
+			conn.send(SSH_FXP_STATUS, appendSFTPString(appendSFTPString(reply, nil), nil))
+			return
+		default:
+			t.Errorf("unexpected packet type %d", kind)
+			return
+		}
+	}
+}
+
+func TestSFTPReadFile(t *testing.T) {
+	requests, requestsW := io.Pipe()
+	replies, repliesW := io.Pipe()
+	file := strings.Repeat("difficulty=hard\n", 10)
+	go fakeSFTPServer(t, requests, repliesW, file, 7)
+
+	conn := &sftpConn{w: requestsW, r: replies}
+	data, err := conn.readFile("server.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != file {
+		t.Errorf("readFile() = %q, want %q", data, file)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SLA_MAX_GAP caps the time one check stands for, so a bot that was down
+// itself doesn't count the gap for or against the server.
+const SLA_MAX_GAP = 10 * time.Minute
+
+// SLA is an uptime expectation, e.g. up 95% of the time between 18:00 and
+// 24:00 every day.
+type SLA struct {
+	// Start and End are offsets from local midnight; End before Start
+	// wraps past midnight.
+	Start, End time.Duration
+	// Target is the least uptime in percent.
+	Target float64
+}
+
+// SLATally is a week of one SLA: the seconds of its window the bot
+// watched, and how many of them the server was up.
+type SLATally struct {
+	Up    int64 `json:"up"`
+	Total int64 `json:"total"`
+}
+
+func (s SLA) String() string {
+	return formatClock(s.Start) + "–" + formatClock(s.End)
+}
+
+// contains reports whether now falls in the SLA's daily window.
+func (s SLA) contains(now time.Time) bool {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	offset := now.Sub(midnight)
+	if s.Start <= s.End {
+		return offset >= s.Start && offset < s.End
+	}
+	return offset >= s.Start || offset < s.End
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// parseSLAs parses SLA: comma-separated "HH:MM-HH:MM=percent" windows,
+// e.g. "18:00-24:00=95".
+func parseSLAs(s string) ([]SLA, error) {
+	var slas []SLA
+	for _, item := range splitList(s) {
+		window, target, ok := strings.Cut(item, "=")
+		start, end, ok2 := strings.Cut(window, "-")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("%q isn't HH:MM-HH:MM=percent", item)
+		}
+		var sla SLA
+		var err error
+		if sla.Start, err = parseClock(start); err != nil {
+			return nil, err
+		}
+		if sla.End, err = parseClock(end); err != nil {
+			return nil, err
+		}
+		if sla.Start == sla.End {
+			return nil, fmt.Errorf("%q is an empty window", item)
+		}
+		sla.Target, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(target), "%"), 64)
+		if err != nil || sla.Target <= 0 || sla.Target > 100 {
+			return nil, fmt.Errorf("invalid target in %q", item)
+		}
+		slas = append(slas, sla)
+	}
+	return slas, nil
+}
+
+// parseClock parses HH:MM from 00:00 to 24:00.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err == nil {
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+	if strings.TrimSpace(s) == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
+
+// slaWeek is the ISO week now is in, e.g. "2024-W10".
+func slaWeek(now time.Time) string {
+	year, week := now.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// trackSLA counts the time since the previous check towards the SLAs whose
+// window now is in, up or not, and sends the admins the report of the past
+// week on the first check of a new one.
+func trackSLA(ctx context.Context, up bool, now time.Time) {
+	if len(config.SLAs) == 0 {
+		return
+	}
+
+	state.mu.Lock()
+	var report map[string]SLATally
+	reportWeek := state.SLAWeek
+	if week := slaWeek(now); state.SLAWeek != week {
+		if state.SLAWeek != "" {
+			report = state.SLATallies
+			if report == nil {
+				report = map[string]SLATally{}
+			}
+		}
+		state.SLAWeek, state.SLATallies = week, nil
+	}
+	if state.SLACheckedAt > 0 {
+		elapsed := int64(min(now.Sub(time.Unix(state.SLACheckedAt, 0)), SLA_MAX_GAP) / time.Second)
+		for _, sla := range config.SLAs {
+			if elapsed <= 0 || !sla.contains(now) {
+				continue
+			}
+			if state.SLATallies == nil {
+				state.SLATallies = map[string]SLATally{}
+			}
+			tally := state.SLATallies[sla.String()]
+			tally.Total += elapsed
+			if up {
+				tally.Up += elapsed
+			}
+			state.SLATallies[sla.String()] = tally
+		}
+	}
+	state.SLACheckedAt = now.Unix()
+	saveState()
+	state.mu.Unlock()
+
+	if report != nil {
+		notifyAdmins(ctx, renderSLAReport(config.Language, reportWeek, config.SLAs, report))
+	}
+}
+
+// renderSLAReport is the weekly report: each SLA's uptime against its
+// target, violations flagged.
+func renderSLAReport(lang, week string, slas []SLA, tallies map[string]SLATally) string {
+	var lines []string
+	violations := 0
+	for _, sla := range slas {
+		tally := tallies[sla.String()]
+		if tally.Total == 0 {
+			lines = append(lines, tr(lang, "sla.no_data", sla))
+			continue
+		}
+		uptime := float64(tally.Up) / float64(tally.Total) * 100
+		if uptime < sla.Target {
+			violations++
+			lines = append(lines, tr(lang, "sla.violated", sla, uptime, sla.Target))
+		} else {
+			lines = append(lines, tr(lang, "sla.met", sla, uptime, sla.Target))
+		}
+	}
+	header := tr(lang, "sla.header", week)
+	if violations > 0 {
+		header += "\n" + trn(lang, "sla.violations", violations, violations)
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseSLAs(t *testing.T) {
+	slas, err := parseSLAs("18:00-24:00=95, 22:00-02:00=99.5%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slas) != 2 || slas[0].String() != "18:00–24:00" || slas[0].Target != 95 || slas[1].Target != 99.5 {
+		t.Fatalf("parseSLAs() = %+v", slas)
+	}
+	at := func(hour int) time.Time { return time.Date(2024, 3, 4, hour, 30, 0, 0, time.Local) }
+	if !slas[0].contains(at(23)) || slas[0].contains(at(17)) {
+		t.Error("18:00–24:00 window is wrong")
+	}
+	if !slas[1].contains(at(1)) || !slas[1].contains(at(23)) || slas[1].contains(at(12)) {
+		t.Error("22:00–02:00 window doesn't wrap past midnight")
+	}
+
+	for _, bad := range []string{"18:00-24:00", "18:00=95", "18:00-18:00=95", "18:00-25:00=95", "18:00-24:00=150"} {
+		if _, err := parseSLAs(bad); err == nil {
+			t.Errorf("parseSLAs(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestTrackSLAWeeklyReport(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminChatID = "-100"
+	config.SLAs, _ = parseSLAs("18:00-24:00=95,00:00-06:00=90")
+
+	// Sunday evening, ISO week 2024-W09: up 50 of 60 minutes.
+	now := time.Date(2024, 3, 3, 19, 0, 0, 0, time.Local)
+	trackSLA(context.Background(), true, now)
+	for i := 1; i <= 60; i++ {
+		trackSLA(context.Background(), i <= 10 || i > 20, now.Add(time.Duration(i)*time.Minute))
+	}
+	if sent := fake.callsTo("sendMessage"); len(sent) != 0 {
+		t.Fatalf("reported mid-week: %+v", sent)
+	}
+
+	trackSLA(context.Background(), true, time.Date(2024, 3, 4, 9, 0, 0, 0, time.Local))
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want the weekly report", len(sent))
+	}
+	want := "📋 <b>Uptime report</b> for week 2024-W09\n" +
+		"⚠️ 1 SLA violated\n" +
+		"❌ 18:00–24:00: 83.3% (target 95%)\n" +
+		"➖ 00:00–06:00: no data"
+	if sent[0].Params["text"] != want {
+		t.Errorf("report:\n%s\nwant:\n%s", sent[0].Params["text"], want)
+	}
+}
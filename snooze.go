@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SNOOZE_MAX caps how long /snooze can mute a chat.
+const SNOOZE_MAX = 7 * 24 * time.Hour
+
+func (s ChatSettings) snoozed(now time.Time) bool {
+	return s.SnoozedUntil != 0 && now.Unix() < s.SnoozedUntil
+}
+
+// criticalEvent tells events that get through a snooze: the server going
+// down (unless it looks like a scheduled restart) and coming back up.
+func criticalEvent(event Event) bool {
+	switch event.Kind {
+	case EventServerDown:
+		return event.Label == ""
+	case EventServerUp:
+		return true
+	}
+	return false
+}
+
+// handleSnoozeCommand mutes the chat's non-critical notifications:
+// "/snooze 2h" for a while, "/snooze off" to resume right away, and
+// "/snooze" alone shows how long it's muted.
+func handleSnoozeCommand(ctx context.Context, msg *Message, args string) {
+	chatID := resolveChatID(strconv.FormatInt(msg.Chat.ID, 10))
+	settings := chatSettings(chatID)
+	lang := replyLanguage(msg)
+
+	switch strings.ToLower(args) {
+	case "":
+		if settings.snoozed(time.Now()) {
+			reply(ctx, msg, tr(lang, "snooze.active", formatSnoozeEnd(settings.SnoozedUntil)))
+		} else {
+			reply(ctx, msg, tr(lang, "snooze.usage"))
+		}
+		return
+	case "off":
+		updateChatSettings(chatID, func(s *ChatSettings) { s.SnoozedUntil = 0 })
+		recordAudit(auditCommand(msg, "snooze", args, "ok"))
+		reply(ctx, msg, tr(lang, "snooze.resumed"))
+		return
+	}
+
+	d, err := time.ParseDuration(args)
+	if err != nil || d <= 0 || d > SNOOZE_MAX {
+		reply(ctx, msg, tr(lang, "snooze.usage"))
+		return
+	}
+	until := time.Now().Add(d).Unix()
+	updateChatSettings(chatID, func(s *ChatSettings) { s.SnoozedUntil = until })
+	recordAudit(auditCommand(msg, "snooze", args, "ok"))
+	reply(ctx, msg, tr(lang, "snooze.done", formatSnoozeEnd(until)))
+}
+
+func formatSnoozeEnd(until int64) string {
+	return time.Unix(until, 0).Format("2006-01-02 15:04")
+}
+
+// resumeSnoozed ends the snoozes that ran out by now and lets their chats
+// know notifications are back.
+func resumeSnoozed(ctx context.Context, now time.Time) {
+	state.mu.Lock()
+	var resumed []string
+	for chatID, settings := range state.Chats {
+		if settings.SnoozedUntil != 0 && !settings.snoozed(now) {
+			settings.SnoozedUntil = 0
+			resumed = append(resumed, chatID)
+		}
+	}
+	if len(resumed) > 0 {
+		saveState()
+	}
+	state.mu.Unlock()
+
+	for _, chatID := range resumed {
+		text := tr(chatSettings(chatID).language(), "snooze.resumed")
+		if _, err := telegram.SendMessage(ctx, chatID, text, &MessageOptions{DisableNotification: true}); err != nil {
+			log.Printf("Error sending Telegram message to %s: %v", chatID, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnooze(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{1}
+
+	ctx := context.Background()
+	send := func(text string) string {
+		handleUpdate(ctx, Update{Message: &Message{
+			MessageID: 1, Chat: Chat{ID: -42}, From: &User{ID: 1}, Text: text,
+		}})
+		replies := fake.callsTo("sendMessage")
+		return replies[len(replies)-1].Params["text"].(string)
+	}
+
+	if got := send("/snooze 30d"); !strings.HasPrefix(got, "Usage") {
+		t.Fatalf("/snooze 30d = %q", got)
+	}
+	if got := send("/snooze 2h"); !strings.HasPrefix(got, "🔕") {
+		t.Fatalf("/snooze 2h = %q", got)
+	}
+	sent := len(fake.callsTo("sendMessage"))
+
+	// Player events are muted, the server going down isn't.
+	now := time.Now()
+	announce(ctx, "", false, 0, []Event{
+		{Kind: EventPlayerLeft, Player: "steve", Time: now},
+		{Kind: EventServerDown, Time: now},
+	})
+	calls := fake.callsTo("sendMessage")[sent:]
+	if len(calls) != 1 || strings.Contains(calls[0].Params["text"].(string), "steve") {
+		t.Fatalf("snoozed chat got %+v", calls)
+	}
+	broadcast(ctx, "", func(lang string) string { return "note" }, nil)
+	if got := len(fake.callsTo("sendMessage")); got != sent+1 {
+		t.Fatalf("note sent to a snoozed chat")
+	}
+
+	resumeSnoozed(ctx, now.Add(time.Hour))
+	resumeSnoozed(ctx, now.Add(3*time.Hour))
+	resumeSnoozed(ctx, now.Add(4*time.Hour))
+	calls = fake.callsTo("sendMessage")[sent+1:]
+	if len(calls) != 1 || calls[0].Params["text"] != "🔔 Notifications resumed." {
+		t.Fatalf("resume notes = %+v", calls)
+	}
+	if chatSettings("-42").SnoozedUntil != 0 {
+		t.Fatal("snooze not cleared")
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteHistory keeps the status history in an SQLite database, for
+// STORE_BACKEND=sqlite. Checks are rows indexed by their check time, so
+// writes insert only the new rows and the history can be kept for
+// HISTORY_RETENTION_DAYS rather than a day; the in-memory store still
+// holds the last day for the hot paths and asks the database for anything
+// older. The bot state lives in the same database (see sqliteStorage).
+type SQLiteHistory struct {
+	db *sql.DB
+}
+
+// history is set up in loadConfig with STORE_BACKEND=sqlite.
+var history *SQLiteHistory
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS status (
+	id           TEXT PRIMARY KEY,
+	online       INTEGER NOT NULL,
+	last_checked INTEGER NOT NULL,
+	players      TEXT NOT NULL,
+	player_count INTEGER NOT NULL DEFAULT 0,
+	error        TEXT NOT NULL DEFAULT '',
+	latency_ms   INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS status_last_checked ON status (last_checked);
+CREATE TABLE IF NOT EXISTS objects (
+	name TEXT PRIMARY KEY,
+	data BLOB NOT NULL
+);`
+
+// checkSQLiteOptions reports settings STORE_BACKEND=sqlite can't honor.
+// STORAGE_KEY only encrypts the objects in the database: the rows of the
+// history, with the player names, are queried by SQL and stay readable to
+// anyone who can read the file. Backups to S3_BUCKET only hold the
+// in-memory last day, and restoring one doesn't touch the history table;
+// back up the database file instead.
+func checkSQLiteOptions(encrypted bool) error {
+	if encrypted {
+		return errors.New("STORAGE_KEY can't encrypt the SQLite history; use full-disk encryption, or STORE_BACKEND=file or redis")
+	}
+	if config.S3Bucket != "" {
+		return errors.New("S3_BUCKET backups would only hold the last day; back up the SQLITE_PATH database instead")
+	}
+	return nil
+}
+
+// openSQLiteHistory opens (creating if needed) the database at path.
+func openSQLiteHistory(path string) (*SQLiteHistory, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes writers, which SQLite would do anyway,
+	// without "database is locked" errors.
+	db.SetMaxOpenConns(1)
+	for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteHistory{db: db}, nil
+}
+
+// Insert adds entries, skipping IDs that are already stored.
+func (h *SQLiteHistory) Insert(entries []StatusEntry) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertRows(tx, entries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Replace makes the rows checked at or after from exactly entries, for when
+// the in-memory day was rewritten (forgotten players, imports).
+func (h *SQLiteHistory) Replace(from int64, entries []StatusEntry) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM status WHERE last_checked >= ?`, from); err != nil {
+		return err
+	}
+	if err := insertRows(tx, entries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRows(tx *sql.Tx, entries []StatusEntry) error {
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO status (id, online, last_checked, players, player_count, error, latency_ms) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		players, err := json.Marshal(entry.Players)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(string(entry.ID), entry.Online, entry.LastChecked, string(players), entry.PlayerCount, entry.Error, entry.LatencyMs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Latest returns the most recent entry, or nil if there is none.
+func (h *SQLiteHistory) Latest() (*StatusEntry, error) {
+	entries, err := h.query(`SELECT id, online, last_checked, players, player_count, error, latency_ms FROM status ORDER BY last_checked DESC LIMIT 1`)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// Range returns the entries checked within [from, to), oldest first.
+func (h *SQLiteHistory) Range(from, to int64) ([]StatusEntry, error) {
+	return h.query(`SELECT id, online, last_checked, players, player_count, error, latency_ms FROM status
+		WHERE last_checked >= ? AND last_checked < ? ORDER BY last_checked`, from, to)
+}
+
+func (h *SQLiteHistory) query(query string, args ...interface{}) ([]StatusEntry, error) {
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []StatusEntry{}
+	for rows.Next() {
+		var entry StatusEntry
+		var id, players string
+		if err := rows.Scan(&id, &entry.Online, &entry.LastChecked, &players, &entry.PlayerCount, &entry.Error, &entry.LatencyMs); err != nil {
+			return nil, err
+		}
+		entry.ID = EntryID(id)
+		if err := json.Unmarshal([]byte(players), &entry.Players); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteBefore removes the entries checked before ts and returns how many
+// there were.
+func (h *SQLiteHistory) DeleteBefore(ts int64) (int64, error) {
+	result, err := h.db.Exec(`DELETE FROM status WHERE last_checked < ?`, ts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ForgetPlayer removes player (case-insensitively) from the entries
+// checked before ts and returns how many mentioned them.
+func (h *SQLiteHistory) ForgetPlayer(player string, ts int64) (int, error) {
+	// LIKE narrows the rows down (case-insensitively for ASCII names);
+	// the exact match is done on the decoded list.
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(player) + "%"
+	entries, err := h.query(`SELECT id, online, last_checked, players, player_count, error, latency_ms FROM status
+		WHERE last_checked < ? AND players LIKE ? ESCAPE '\'`, ts, pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	count := 0
+	for _, entry := range entries {
+		kept := entry.Players[:0:0]
+		for _, name := range entry.Players {
+			if !strings.EqualFold(name, player) {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept) == len(entry.Players) {
+			continue
+		}
+		players, err := json.Marshal(kept)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE status SET players = ? WHERE id = ?`, string(players), string(entry.ID)); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, tx.Commit()
+}
+
+// Size is the size of the database file in bytes.
+func (h *SQLiteHistory) Size() (int64, error) {
+	var pages, pageSize int64
+	if err := h.db.QueryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, err
+	}
+	if err := h.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
+
+// sqliteStorage keeps the remaining objects (the bot state) as rows of the
+// history database.
+type sqliteStorage struct {
+	history *SQLiteHistory
+}
+
+func (s sqliteStorage) Read(name string) ([]byte, error) {
+	var data []byte
+	err := s.history.db.QueryRow(`SELECT data FROM objects WHERE name = ?`, name).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, os.ErrNotExist
+	}
+	return data, err
+}
+
+func (s sqliteStorage) Write(name string, data []byte) error {
+	_, err := s.history.db.Exec(`INSERT INTO objects (name, data) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET data = excluded.data`, name, data)
+	return err
+}
+
+func (s sqliteStorage) Append(name string, data []byte) error {
+	_, err := s.history.db.Exec(`INSERT INTO objects (name, data) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET data = data || excluded.data`, name, data)
+	return err
+}
+
+func (s sqliteStorage) Truncate(name string) error {
+	_, err := s.history.db.Exec(`DELETE FROM objects WHERE name = ?`, name)
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useSQLiteHistory points the store at a fresh SQLite history for the
+// test.
+func useSQLiteHistory(t *testing.T) {
+	t.Helper()
+
+	useTempStore(t, 0)
+	db, err := openSQLiteHistory(filepath.Join(t.TempDir(), "status.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history = db
+	storage = sqliteStorage{history: db}
+	t.Cleanup(func() {
+		db.db.Close()
+		history = nil
+		storage = fileStorage{}
+	})
+}
+
+func TestSQLiteHistoryKeepsOlderChecks(t *testing.T) {
+	useSQLiteHistory(t)
+	saved := config
+	t.Cleanup(func() { config = saved })
+	config.SaveInterval = 0
+
+	now := time.Now()
+	for _, ago := range []time.Duration{72 * time.Hour, 30 * time.Hour, time.Hour, time.Minute} {
+		insertStatus(StatusEntry{Online: ago != time.Hour, LastChecked: now.Add(-ago).UnixMilli(), Players: []string{"steve"}})
+	}
+	flushStore()
+
+	cleanupOld()
+	saveStore()
+	if len(store.Entries) != 2 {
+		t.Fatalf("memory holds %d entries, want the last day's 2", len(store.Entries))
+	}
+
+	if got := getRange(now.Add(-100*time.Hour).UnixMilli(), now.UnixMilli()); len(got) != 4 {
+		t.Errorf("getRange over 100h = %d entries, want 4", len(got))
+	}
+	if got := getRange(now.Add(-48*time.Hour).UnixMilli(), now.Add(-2*time.Hour).UnixMilli()); len(got) != 1 {
+		t.Errorf("getRange over the day before = %d entries, want 1", len(got))
+	}
+
+	// A restart loads the last day back from the database.
+	loadStore()
+	if len(store.Entries) != 2 || store.Entries[0].Online {
+		t.Errorf("reloaded entries = %+v", store.Entries)
+	}
+
+	if n := forgetPlayer("STEVE"); n != 4 {
+		t.Errorf("forgetPlayer = %d, want 4", n)
+	}
+	flushStore()
+	for _, entry := range getRange(0, now.UnixMilli()) {
+		if len(entry.Players) != 0 {
+			t.Errorf("entry %s still lists %v", entry.ID, entry.Players)
+		}
+	}
+
+	config.HistoryRetention = 48 * time.Hour
+	cleanupHistory()
+	if got := getRange(0, now.UnixMilli()); len(got) != 3 {
+		t.Errorf("after cleanup = %d entries, want 3", len(got))
+	}
+}
+
+func TestSQLiteHistoryLatestAfterLongPause(t *testing.T) {
+	useSQLiteHistory(t)
+
+	old := StatusEntry{ID: "a", Online: true, LastChecked: time.Now().Add(-48 * time.Hour).UnixMilli(), Players: []string{}}
+	if err := history.Insert([]StatusEntry{old}); err != nil {
+		t.Fatal(err)
+	}
+	loadStore()
+	if latest := getLatest(); latest == nil || latest.ID != "a" {
+		t.Errorf("getLatest = %+v", latest)
+	}
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	useSQLiteHistory(t)
+
+	if _, err := storage.Read(STATE_FILE); err == nil {
+		t.Fatal("Read of a missing object succeeded")
+	}
+	if err := storage.Append(JOURNAL_FILE, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Append(JOURNAL_FILE, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := storage.Read(JOURNAL_FILE); err != nil || string(data) != "ab" {
+		t.Errorf("Read = %q, %v", data, err)
+	}
+	if err := storage.Write(JOURNAL_FILE, []byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Truncate(JOURNAL_FILE); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Read(JOURNAL_FILE); err == nil {
+		t.Error("Read after Truncate succeeded")
+	}
+}
+
+func TestSQLiteHistoryIsReadableOnDisk(t *testing.T) {
+	useTempStore(t, 0)
+	path := filepath.Join(t.TempDir(), "status.db")
+	db, err := openSQLiteHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Insert([]StatusEntry{{ID: "1", Online: true, LastChecked: 1000, Players: []string{"steve"}, PlayerCount: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	db.db.Close()
+
+	// Whoever can read the file can read who played when, which is why
+	// STORAGE_KEY is refused with this backend.
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `["steve"]`) {
+		t.Fatal("player names not found in the database file")
+	}
+	if err := checkSQLiteOptions(true); err == nil {
+		t.Error("STORAGE_KEY accepted with STORE_BACKEND=sqlite")
+	}
+	if err := checkSQLiteOptions(false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLiteRefusesS3Backups(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+
+	config.S3Bucket = "backups"
+	if err := checkSQLiteOptions(false); err == nil {
+		t.Error("S3_BUCKET accepted with STORE_BACKEND=sqlite")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// SHUTDOWN_NOTICE_TIMEOUT bounds sending the shutdown notice, so a
+// Telegram outage can't hold up a container stop.
+const SHUTDOWN_NOTICE_TIMEOUT = 5 * time.Second
+
+// announceShutdown lets the chats know nobody is watching the server until
+// the monitor is back. ctx is the monitor's, already cancelled.
+func announceShutdown(ctx context.Context) {
+	if !config.ShutdownNotice {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), SHUTDOWN_NOTICE_TIMEOUT)
+	defer cancel()
+	broadcast(ctx, "", func(lang string) string {
+		return tr(lang, "shutdown.notice")
+	}, &MessageOptions{DisableNotification: true})
+}
+
+// announceStartup lets the chats know monitoring resumed, e.g. after the
+// host rebooted. It runs after the first check, so the status is fresh.
+func announceStartup(ctx context.Context) {
+	if !config.StartupSummary {
+		return
+	}
+
+	latest := getLatest()
+	if latest == nil {
+		return
+	}
+
+	var lastIncident time.Time
+	state.mu.Lock()
+	if n := len(state.Downtimes); n > 0 {
+		lastIncident = time.Unix(state.Downtimes[n-1].Start, 0)
+	}
+	state.mu.Unlock()
+
+	now := time.Now()
+	broadcast(ctx, "", func(lang string) string {
+		return renderStartupSummary(lang, latest.Online, len(latest.Players), lastIncident, now)
+	}, &MessageOptions{DisableNotification: true})
+}
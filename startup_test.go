@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdownFlushesAndAnnounces(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.StartupSummary = false
+	config.ShutdownNotice = true
+	config.CheckInterval = time.Hour
+	config.SaveInterval = time.Hour
+	previousScheduler := scheduler
+	scheduler = &Scheduler{}
+	t.Cleanup(func() { scheduler = previousScheduler })
+	useFakeProbe(t, func(context.Context) *PingResult {
+		return &PingResult{Status: &ServerStatus{Online: true}, CheckedAt: time.Now()}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runMonitor(ctx)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runMonitor didn't stop")
+	}
+
+	store.mu.Lock()
+	pending := len(store.pending)
+	store.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d entries weren't written", pending)
+	}
+	sent := fake.callsTo("sendMessage")
+	if len(sent) == 0 || sent[len(sent)-1].Params["text"] != "⏸ Monitoring is paused: the bot is stopping. There will be no alerts until it's back." {
+		t.Errorf("messages = %+v", sent)
+	}
+}
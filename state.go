@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+)
+
+const STATE_FILE = "state.json"
+
+// BotState is the bot's own persistent state, kept apart from the status
+// history in STATE_FILE.
+type BotState struct {
+	// ChatMigrations maps chat IDs of groups that became supergroups to
+	// their new IDs, so a stale TELEGRAM_CHAT_ID keeps working.
+	ChatMigrations map[string]string `json:"chatMigrations"`
+	// GrafanaAnnotationID is the downtime annotation waiting for its end.
+	GrafanaAnnotationID int64 `json:"grafanaAnnotationId,omitempty"`
+	// Roles maps Telegram user IDs to roles granted with /grant.
+	Roles map[string]string `json:"roles,omitempty"`
+	// Chats holds the per-chat settings of every group configured with
+	// /chatconfig, by chat ID.
+	Chats map[string]*ChatSettings `json:"chats,omitempty"`
+	// Servers remembers the last check of servers followed by some chat
+	// other than SERVER_HOST, by address.
+	Servers map[string]*ServerSnapshot `json:"servers,omitempty"`
+	// Downtimes of SERVER_HOST from the last RESTART_HISTORY_DAYS, oldest
+	// first, for learning restart windows.
+	Downtimes []Downtime `json:"downtimes,omitempty"`
+	// Baseline is what player counts usually look like, for anomaly
+	// detection.
+	Baseline *PlayerBaseline `json:"baseline,omitempty"`
+	// Incident is the outage being escalated, nil while the server is up.
+	Incident *Incident `json:"incident,omitempty"`
+	// NotifiedRelease is the newest release the admins were told about.
+	NotifiedRelease string `json:"notifiedRelease,omitempty"`
+	// Announced is the last event announced about each server and player
+	// per chat, to avoid repeating it after a crash.
+	Announced map[string]AnnouncedEvent `json:"announced,omitempty"`
+	// ServerIP is the address SERVER_HOST was last reached at, since
+	// ServerIPSince (Unix seconds).
+	ServerIP      string `json:"serverIp,omitempty"`
+	ServerIPSince int64  `json:"serverIpSince,omitempty"`
+	// Properties are the watched server.properties values last read.
+	Properties map[string]string `json:"properties,omitempty"`
+	// WorldSizes are the world size samples of the last WORLD_HISTORY;
+	// WorldSummaryAt and WorldWarnedAt (Unix seconds) are when the admins
+	// last got the weekly summary and the disk space warning.
+	WorldSizes     []WorldSample `json:"worldSizes,omitempty"`
+	WorldSummaryAt int64         `json:"worldSummaryAt,omitempty"`
+	WorldWarnedAt  int64         `json:"worldWarnedAt,omitempty"`
+	// LagSpikes are the server log's "Can't keep up!" lines of the last
+	// LAG_HISTORY; LagAlertedAt (Unix seconds) is when the admins were last
+	// alerted about them and LagSummaryDay the day of the last daily
+	// summary.
+	LagSpikes     []LagSpike `json:"lagSpikes,omitempty"`
+	LagAlertedAt  int64      `json:"lagAlertedAt,omitempty"`
+	LagSummaryDay string     `json:"lagSummaryDay,omitempty"`
+	// CrashReports are the crash reports already in CRASH_REPORTS_PATH.
+	// Empty isn't omitted: it tells an empty folder from the first check.
+	CrashReports []string `json:"crashReports"`
+	// SLATallies are this week's (SLAWeek) uptime in each SLA window, by
+	// window; SLACheckedAt (Unix seconds) is the check they last counted.
+	SLAWeek      string              `json:"slaWeek,omitempty"`
+	SLATallies   map[string]SLATally `json:"slaTallies,omitempty"`
+	SLACheckedAt int64               `json:"slaCheckedAt,omitempty"`
+	// ChartDay is the local date of the last daily chart.
+	ChartDay string `json:"chartDay,omitempty"`
+	// Schedule overrides schedules from the environment, by variable name
+	// (see scheduleSettings).
+	Schedule map[string]string `json:"schedule,omitempty"`
+	// Sessions are the players online, with when they joined (Unix
+	// seconds); Playtime is their past playtime in seconds, by local date
+	// and player, for the last PLAYTIME_DAYS days.
+	Sessions map[string]int64            `json:"sessions,omitempty"`
+	Playtime map[string]map[string]int64 `json:"playtime,omitempty"`
+	// GameEvents are the upcoming in-game events, soonest first.
+	GameEvents      []GameEvent `json:"gameEvents,omitempty"`
+	NextGameEventID int         `json:"nextGameEventId,omitempty"`
+	// JoinText is what admins added to /join with /setjoin.
+	JoinText string `json:"joinText,omitempty"`
+	// Modpack is the modpack version set with /setmodpack.
+	Modpack string `json:"modpack,omitempty"`
+	// LiveMessages are the pinned live status messages, by chat ID.
+	LiveMessages map[string]int64 `json:"liveMessages,omitempty"`
+
+	mu sync.Mutex
+}
+
+var state = &BotState{ChatMigrations: map[string]string{}}
+
+func loadState() {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	data, err := storage.Read(STATE_FILE)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading state file: %v", err)
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		log.Printf("Error parsing state file: %v", err)
+	}
+	if state.ChatMigrations == nil {
+		state.ChatMigrations = map[string]string{}
+	}
+}
+
+// saveState writes the state file. The caller must hold state.mu.
+func saveState() {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		log.Printf("Error marshaling state: %v", err)
+		return
+	}
+
+	if err := storage.Write(STATE_FILE, data); err != nil {
+		log.Printf("Error writing state file: %v", err)
+	}
+}
+
+// resolveChatID follows recorded migrations from chatID to the chat's
+// current ID.
+func resolveChatID(chatID string) string {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	// Bounded, in case the file was edited into a cycle.
+	for i := 0; i < 10; i++ {
+		next, ok := state.ChatMigrations[chatID]
+		if !ok {
+			break
+		}
+		chatID = next
+	}
+	return chatID
+}
+
+// groupChatID is the current ID of the configured group chat.
+func groupChatID() string {
+	return resolveChatID(config.TelegramChatID)
+}
+
+// handleChatMigration records a group→supergroup migration and lets the
+// admins know, so TELEGRAM_CHAT_ID can be updated at leisure.
+func handleChatMigration(oldChatID, newChatID string) {
+	state.mu.Lock()
+	state.ChatMigrations[oldChatID] = newChatID
+	if settings, ok := state.Chats[oldChatID]; ok {
+		state.Chats[newChatID] = settings
+		delete(state.Chats, oldChatID)
+	}
+	saveState()
+	state.mu.Unlock()
+
+	notify := newChatID
+	if config.AdminChatID != "" {
+		notify = resolveChatID(config.AdminChatID)
+	}
+
+	// Run separately: we're in the middle of the Telegram call that
+	// discovered the migration.
+	go func() {
+		text := tr(config.Language, "chat.migrated", escapeHtml(oldChatID), escapeHtml(newChatID))
+		if _, err := telegram.SendMessage(context.Background(), notify, text, nil); err != nil {
+			log.Printf("Error notifying about chat migration: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+// statsdConn is the UDP socket metrics are written to, opened on first use.
+var (
+	statsdMu   sync.Mutex
+	statsdConn net.Conn
+)
+
+// emitStatsd sends the metrics of one check over StatsD: online and players
+// gauges, connect/protocol timings and a failure counter tagged with the
+// error category. With STATSD_DOGSTATSD set, STATSD_TAGS are sent as
+// DogStatsD tags; plain StatsD has no tags, so the category goes into the
+// metric name instead. Does nothing without STATSD_ADDR.
+func emitStatsd(online bool, result *PingResult) {
+	if config.StatsdAddr == "" {
+		return
+	}
+
+	var lines []string
+	add := func(name, value, kind string, tags ...string) {
+		lines = append(lines, formatStatsdLine(config.StatsdPrefix+name, value, kind, append(tags, config.StatsdTags...)))
+	}
+
+	add("online", boolGauge(online), "g")
+	if result.Status != nil {
+		add("players", fmt.Sprint(result.Status.PlayerCount), "g")
+		add("connect_time", fmt.Sprint(result.Status.ConnectTime.Milliseconds()), "ms")
+		add("protocol_time", fmt.Sprint(result.Status.ProtocolTime.Milliseconds()), "ms")
+	}
+	if result.Err != nil {
+		category := errorCategory(result.Err)
+		if config.StatsdDogStatsd {
+			add("check_failures", "1", "c", "reason:"+category)
+		} else {
+			add("check_failures."+category, "1", "c")
+		}
+	}
+
+	if err := writeStatsd(strings.Join(lines, "\n")); err != nil {
+		log.Printf("Error sending StatsD metrics: %v", err)
+	}
+}
+
+// formatStatsdLine renders "name:value|kind", plus "|#tag,tag" when
+// DogStatsD tags are enabled.
+func formatStatsdLine(name, value, kind string, tags []string) string {
+	line := name + ":" + value + "|" + kind
+	if config.StatsdDogStatsd && len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
+
+func writeStatsd(packet string) error {
+	statsdMu.Lock()
+	defer statsdMu.Unlock()
+
+	if statsdConn == nil {
+		conn, err := net.Dial("udp", config.StatsdAddr)
+		if err != nil {
+			return err
+		}
+		statsdConn = conn
+	}
+
+	if _, err := statsdConn.Write([]byte(packet)); err != nil {
+		// Re-dial next time; the agent's address may have changed.
+		statsdConn.Close()
+		statsdConn = nil
+		return err
+	}
+	return nil
+}
+
+func boolGauge(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
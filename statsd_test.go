@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmitDogStatsd(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	previous := config
+	config.StatsdAddr = listener.LocalAddr().String()
+	config.StatsdPrefix = "mc."
+	config.StatsdDogStatsd = true
+	config.StatsdTags = []string{"env:test"}
+	t.Cleanup(func() {
+		config = previous
+		statsdConn = nil
+	})
+
+	emitStatsd(false, &PingResult{Err: ErrTimeout})
+
+	buf := make([]byte, 1024)
+	listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Split(string(buf[:n]), "\n")
+	want := []string{"mc.online:0|g|#env:test", "mc.check_failures:1|c|#reason:timeout,env:test"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("packet = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleStatusCommand reports whether the server is up and who is playing.
+// Chats following another server with /chatconfig get that server's status.
+func handleStatusCommand(ctx context.Context, msg *Message) {
+	settings := chatSettings(strconv.FormatInt(msg.Chat.ID, 10))
+	lang := replyLanguage(msg)
+
+	var result *PingResult
+	if server := settings.server(); server != "" {
+		result = pingServer(ctx, server)
+	} else {
+		result = cachedStatus(ctx, currentSchedule().Interval)
+	}
+	reply(ctx, msg, renderStatus(lang, result))
+}
+
+// pingServer probes a host:port server once, without retries.
+func pingServer(ctx context.Context, server string) *PingResult {
+	result := &PingResult{Attempts: 1}
+	host, port, err := splitServer(server)
+	if err == nil {
+		pingCtx, cancel := context.WithTimeout(ctx, TIMEOUT)
+		result.Status, err = transport.Probe(pingCtx, host, port)
+		cancel()
+	}
+	result.Err = err
+	result.CheckedAt = time.Now()
+	return result
+}
+
+// renderStatus is the /status reply: up or down, the player count and the
+// players' names when the server sent them.
+func renderStatus(lang string, result *PingResult) string {
+	if result.Err != nil || result.Status == nil {
+		return tr(lang, "status.offline", errorCategory(result.Err))
+	}
+
+	status := result.Status
+	text := trn(lang, "status.online", status.PlayerCount, status.PlayerCount)
+	if players := dedupePlayers(status.Players); len(players) > 0 {
+		names := make([]string, len(players))
+		for i, name := range players {
+			names[i] = escapeHtml(name)
+		}
+		text += "\n" + tr(lang, "status.players", strings.Join(names, ", "))
+	}
+	if status.Latency > 0 {
+		text += "\n" + tr(lang, "status.latency", status.Latency.Milliseconds())
+	}
+	return text
+}
+
+// latencyMs is the latency of a successful check in milliseconds, zero
+// for a failed one.
+func latencyMs(result *PingResult) int64 {
+	if result.Err != nil || result.Status == nil {
+		return 0
+	}
+	return result.Status.Latency.Milliseconds()
+}
+
+// renderPlayers is the Discord /players reply: who is playing, if the
+// server is up.
+func renderPlayers(lang string, result *PingResult) string {
+	if result.Err != nil || result.Status == nil {
+		return tr(lang, "status.offline", errorCategory(result.Err))
+	}
+	players := dedupePlayers(result.Status.Players)
+	switch {
+	case len(players) > 0:
+		names := make([]string, len(players))
+		for i, name := range players {
+			names[i] = bold(name)
+		}
+		return tr(lang, "status.players", strings.Join(names, ", "))
+	case result.Status.PlayerCount > 0:
+		// The server hid the names.
+		return trn(lang, "status.online", result.Status.PlayerCount, result.Status.PlayerCount)
+	}
+	return tr(lang, "players.nobody")
+}
+
+// CompactStatus is the /api/status/compact payload, small enough for home
+// screen widgets.
+type CompactStatus struct {
+	Text    string `json:"text"`
+	Online  bool   `json:"online"`
+	Players int    `json:"players"`
+	// Checked is when the server was last checked, in Unix seconds; 0 if
+	// it hasn't been yet.
+	Checked int64 `json:"checked"`
+}
+
+// compactStatus sums up the last stored check in one line of lang.
+func compactStatus(lang string) CompactStatus {
+	latest := getLatest()
+	if latest == nil {
+		return CompactStatus{Text: tr(lang, "compact.unknown")}
+	}
+	status := CompactStatus{Online: latest.Online, Checked: latest.LastChecked / 1000}
+	if latest.Online {
+		status.Players = max(latest.PlayerCount, len(latest.Players))
+		status.Text = trn(lang, "compact.online", status.Players, status.Players)
+	} else {
+		status.Text = tr(lang, "compact.offline")
+	}
+	return status
+}
+
+// statusLanguage is the ?lang= of r if it's a known language, LANGUAGE
+// otherwise.
+func statusLanguage(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); messages[lang] != nil {
+		return lang
+	}
+	return config.Language
+}
+
+// handleCompactStatusAPI serves compactStatus as JSON, for widgets such as
+// Scriptable or KWGT. Like the public dashboard it needs no token.
+func handleCompactStatusAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(compactStatus(statusLanguage(r)))
+}
+
+// handleStatusText serves the status as plain text for curl: the compact
+// line, who is playing and when the server was checked.
+func handleStatusText(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	status := compactStatus(statusLanguage(r))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, status.Text)
+	if latest := getLatest(); latest != nil {
+		if players := dedupePlayers(latest.Players); latest.Online && len(players) > 0 {
+			fmt.Fprintln(w, strings.Join(players, ", "))
+		}
+		fmt.Fprintln(w, time.UnixMilli(latest.LastChecked).UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderStatus(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		result *PingResult
+		want   string
+	}{
+		{"down", &PingResult{Err: fmt.Errorf("dial: %w", ErrRefused)}, "🔴 The server is down (refused)"},
+		{"empty", &PingResult{Status: &ServerStatus{Online: true}}, "🟢 The server is up, 0 players online"},
+		{"players", &PingResult{Status: &ServerStatus{Online: true, PlayerCount: 2, Players: []string{"Steve", "<Alex>"}}},
+			"🟢 The server is up, 2 players online\nPlaying: Steve, &lt;Alex&gt;"},
+		{"no sample", &PingResult{Status: &ServerStatus{Online: true, PlayerCount: 1}}, "🟢 The server is up, 1 player online"},
+	} {
+		if got := renderStatus("en", tt.result); got != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompactStatusEndpoints(t *testing.T) {
+	useTempStore(t, 0)
+	previous := config
+	t.Cleanup(func() { config = previous })
+	config.Language = "en"
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+
+	if got := get("/api/status/compact"); !strings.Contains(got, `"text":"⚪ Not checked yet"`) {
+		t.Errorf("before any check: %s", got)
+	}
+
+	checked := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	insertStatus(StatusEntry{Online: true, LastChecked: checked.UnixMilli(), Players: []string{"steve", "alex"}, PlayerCount: 2})
+	want := `{"text":"🟢 Online: 2 players","online":true,"players":2,"checked":1704164645}` + "\n"
+	if got := get("/api/status/compact"); got != want {
+		t.Errorf("compact = %s, want %s", got, want)
+	}
+	if got := get("/api/status/compact?lang=uk"); !strings.Contains(got, "Онлайн: 2 гравці") {
+		t.Errorf("compact in Ukrainian = %s", got)
+	}
+	if got, want := get("/status.txt"), "🟢 Online: 2 players\nsteve, alex\n2024-01-02T03:04:05Z\n"; got != want {
+		t.Errorf("status.txt = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// Storage is where the status history and the bot state are persisted,
+// addressed by file name (JSON_FILE, JOURNAL_FILE, STATE_FILE).
+type Storage interface {
+	// Read returns the named object, or an error satisfying os.IsNotExist
+	// if it doesn't exist yet.
+	Read(name string) ([]byte, error)
+	// Write replaces the named object; readers never see it half-written.
+	Write(name string, data []byte) error
+	// Append adds data to the end of the named object, creating it first
+	// if needed.
+	Append(name string, data []byte) error
+	// Truncate empties the named object; a missing one is not an error.
+	Truncate(name string) error
+}
+
+// storage is selected by STORE_BACKEND in loadConfig.
+var storage Storage = fileStorage{}
+
+// fileStorage keeps everything as files in the working directory.
+type fileStorage struct{}
+
+func (fileStorage) Read(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+func (fileStorage) Write(name string, data []byte) error {
+	return writeFileAtomic(name, data)
+}
+
+func (fileStorage) Append(name string, data []byte) error {
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (fileStorage) Truncate(name string) error {
+	if err := os.Truncate(name, 0); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data via a temporary file, so a crash
+// mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
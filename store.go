@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StatusEntry struct {
+	ID          EntryID  `json:"id"`
+	Online      bool     `json:"online"`
+	LastChecked int64    `json:"lastChecked"`
+	Players     []string `json:"players"`
+	// PlayerCount is the count the server reported, which can be more
+	// than Players when it hides some of them.
+	PlayerCount int `json:"playerCount,omitempty"`
+	// Error is the errorCategory of the failed ping, empty when it succeeded.
+	Error string `json:"error,omitempty"`
+	// LatencyMs is the round trip of the status exchange, when it
+	// succeeded.
+	LatencyMs int64 `json:"latencyMs,omitempty"`
+}
+
+// StatusStore keeps Entries sorted by LastChecked (ascending), so the latest
+// entry is always the last one and time ranges can be found with a binary
+// search instead of scanning the whole history.
+//
+// Persistence is split in two: JSON_FILE holds a full snapshot, and every
+// check only appends its new entries to JOURNAL_FILE. The snapshot is
+// rewritten (and the journal truncated) once the journal grows past
+// JOURNAL_COMPACT_ENTRIES or after cleanup removed entries, so a long
+// history isn't re-marshaled every CHECK_INTERVAL.
+type StatusStore struct {
+	Entries []StatusEntry `json:"entries"`
+	mu      sync.RWMutex
+
+	pending    []StatusEntry
+	journalLen int
+	compact    bool
+	lastSave   time.Time
+
+	// snapshotSize and journalSize are the bytes persisted in each, for
+	// the store size metric.
+	snapshotSize int64
+	journalSize  int64
+}
+
+// storeWarned is when the admins were last told about slow or large
+// writes; they hear about it at most once per STORE_WARN_REPEAT.
+var storeWarned time.Time
+
+const STORE_WARN_REPEAT = 24 * time.Hour
+
+func loadStore() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.pending = nil
+	store.journalLen = 0
+	store.compact = false
+	store.snapshotSize = 0
+	store.journalSize = 0
+
+	if history != nil {
+		loadHistory()
+		return
+	}
+
+	data, err := storage.Read(JSON_FILE)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading status file: %v", err)
+			return
+		}
+		store.Entries = []StatusEntry{}
+	} else if err := json.Unmarshal(data, store); err != nil {
+		log.Printf("Error parsing status file: %v", err)
+		store.Entries = []StatusEntry{}
+	} else {
+		store.snapshotSize = int64(len(data))
+	}
+
+	replayJournal()
+
+	for _, entry := range store.Entries {
+		entryIDs.Observe(entry.ID)
+	}
+
+	// Older files were written in insertion order only; make sure the
+	// index invariant holds before anything searches it.
+	sort.SliceStable(store.Entries, func(i, j int) bool {
+		return store.Entries[i].LastChecked < store.Entries[j].LastChecked
+	})
+}
+
+// loadHistory loads the last day from the SQLite history. The caller must
+// hold store.mu.
+func loadHistory() {
+	entries, err := history.Range(time.Now().UnixMilli()-ONE_DAY_IN_MS, math.MaxInt64)
+	if err != nil {
+		log.Printf("Error reading status history: %v", err)
+		entries = []StatusEntry{}
+	}
+	store.Entries = entries
+	if latest, err := history.Latest(); err == nil && latest != nil {
+		entryIDs.Observe(latest.ID)
+	}
+	for _, entry := range store.Entries {
+		entryIDs.Observe(entry.ID)
+	}
+}
+
+// replayJournal appends journaled entries that didn't make it into the
+// snapshot yet. The caller must hold store.mu.
+func replayJournal() {
+	data, err := storage.Read(JOURNAL_FILE)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading status journal: %v", err)
+		}
+		return
+	}
+	store.journalSize = int64(len(data))
+
+	// A crash between writing the snapshot and truncating the journal
+	// leaves entries in both; IDs tell them apart.
+	known := make(map[EntryID]bool, len(store.Entries))
+	for _, entry := range store.Entries {
+		known[entry.ID] = true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry StatusEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Most likely a line torn by a crash mid-write; the rest of
+			// the journal is still usable.
+			log.Printf("Skipping malformed journal line: %v", err)
+			continue
+		}
+		store.journalLen++
+		if !known[entry.ID] {
+			store.Entries = append(store.Entries, entry)
+			known[entry.ID] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading status journal: %v", err)
+	}
+}
+
+// saveStore persists changes made since the last write, but at most once per
+// config.SaveInterval; anything newer stays pending until the next call after
+// the interval has passed (main calls it on a ticker for exactly that).
+func saveStore() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if len(store.pending) == 0 && !store.compact {
+		return
+	}
+	if time.Since(store.lastSave) < config.SaveInterval {
+		return
+	}
+	persistStore()
+}
+
+// flushStore writes pending changes right away, ignoring SaveInterval.
+func flushStore() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	persistStore()
+}
+
+// persistStore appends pending entries to the journal, or rewrites the
+// snapshot when compaction is due. The caller must hold store.mu.
+func persistStore() {
+	store.lastSave = time.Now()
+
+	if history != nil {
+		persistHistory()
+		return
+	}
+	if store.compact || store.journalLen+len(store.pending) > JOURNAL_COMPACT_ENTRIES {
+		writeSnapshot()
+		return
+	}
+	if len(store.pending) == 0 {
+		return
+	}
+
+	start := time.Now()
+	written, err := appendJournal(store.pending)
+	if err != nil {
+		log.Printf("Error writing status journal: %v", err)
+		return
+	}
+	store.journalLen += len(store.pending)
+	store.journalSize += written
+	store.pending = store.pending[:0]
+	recordStoreWrite("journal", time.Since(start), store.snapshotSize+store.journalSize)
+}
+
+// writeSnapshot rewrites JSON_FILE with the full history and empties the
+// journal. The caller must hold store.mu.
+func writeSnapshot() {
+	start := time.Now()
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		log.Printf("Error marshaling status: %v", err)
+		return
+	}
+
+	if err := storage.Write(JSON_FILE, data); err != nil {
+		log.Printf("Error writing status file: %v", err)
+		return
+	}
+
+	if err := storage.Truncate(JOURNAL_FILE); err != nil {
+		log.Printf("Error truncating status journal: %v", err)
+	}
+	store.pending = store.pending[:0]
+	store.journalLen = 0
+	store.compact = false
+	store.snapshotSize = int64(len(data))
+	store.journalSize = 0
+	recordStoreWrite("snapshot", time.Since(start), store.snapshotSize)
+}
+
+// persistHistory inserts pending entries into the SQLite history, or
+// rewrites the day held in memory when it changed. The caller must hold
+// store.mu.
+func persistHistory() {
+	if len(store.pending) == 0 && !store.compact {
+		return
+	}
+
+	start := time.Now()
+	kind := "insert"
+	var err error
+	if store.compact && len(store.Entries) > 0 {
+		kind = "replace"
+		err = history.Replace(store.Entries[0].LastChecked, store.Entries)
+	} else {
+		err = history.Insert(store.pending)
+	}
+	if err != nil {
+		log.Printf("Error writing status history: %v", err)
+		return
+	}
+	store.pending = store.pending[:0]
+	store.compact = false
+
+	size, err := history.Size()
+	if err != nil {
+		log.Printf("Error reading the status history size: %v", err)
+	}
+	recordStoreWrite(kind, time.Since(start), size)
+}
+
+// appendJournal appends entries to the journal and returns the bytes
+// written.
+func appendJournal(entries []StatusEntry) (int64, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return 0, err
+		}
+	}
+	return int64(buf.Len()), storage.Append(JOURNAL_FILE, buf.Bytes())
+}
+
+// recordStoreWrite updates the store metrics after a write and warns when
+// it took longer than STORE_SLOW_WRITE or the store outgrew STORE_MAX_SIZE:
+// both mean the file backend is struggling with the history. The caller
+// must hold store.mu.
+func recordStoreWrite(kind string, took time.Duration, size int64) {
+	metrics.Set("minecraft_store_write_seconds", took.Seconds(), "kind", kind)
+	metrics.Inc("minecraft_store_writes_total", "kind", kind)
+	metrics.Set("minecraft_store_size_bytes", float64(size))
+
+	var text func(lang string) string
+	switch {
+	case config.StoreSlowWrite > 0 && took > config.StoreSlowWrite:
+		log.Printf("Warning: writing the status %s took %v", kind, took)
+		text = func(lang string) string {
+			return tr(lang, "store.slow", took.Round(time.Millisecond), config.StoreSlowWrite)
+		}
+	case config.StoreMaxSize > 0 && size > config.StoreMaxSize:
+		log.Printf("Warning: the status history is %s", formatBytes(size))
+		text = func(lang string) string {
+			return tr(lang, "store.large", formatBytes(size), formatBytes(config.StoreMaxSize))
+		}
+	default:
+		return
+	}
+
+	if time.Since(storeWarned) < STORE_WARN_REPEAT {
+		return
+	}
+	storeWarned = time.Now()
+	// Not while holding store.mu.
+	go notifyAdmins(context.Background(), text(config.Language))
+}
+
+// searchEntries returns the index of the first entry with LastChecked >= ts.
+// The caller must hold store.mu.
+func searchEntries(ts int64) int {
+	return sort.Search(len(store.Entries), func(i int) bool {
+		return store.Entries[i].LastChecked >= ts
+	})
+}
+
+func getLatest() *StatusEntry {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if len(store.Entries) == 0 {
+		// The monitor was off for more than a day.
+		if history != nil {
+			latest, err := history.Latest()
+			if err != nil {
+				log.Printf("Error reading status history: %v", err)
+			}
+			return latest
+		}
+		return nil
+	}
+
+	latest := store.Entries[len(store.Entries)-1]
+	return &latest
+}
+
+// getRange returns a copy of the entries checked within [from, to). With
+// the SQLite history, the part before the day in memory comes from there.
+func getRange(from, to int64) []StatusEntry {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	result := []StatusEntry{}
+	if history != nil {
+		older := to
+		if len(store.Entries) > 0 {
+			older = min(older, store.Entries[0].LastChecked)
+		}
+		if from < older {
+			entries, err := history.Range(from, older)
+			if err != nil {
+				log.Printf("Error reading status history: %v", err)
+			}
+			result = append(result, entries...)
+		}
+	}
+
+	lo := searchEntries(from)
+	hi := searchEntries(to)
+	if lo >= hi {
+		return result
+	}
+	return append(result, store.Entries[lo:hi]...)
+}
+
+// insertStatus adds entry to the store, assigning it a fresh ID.
+func insertStatus(entry StatusEntry) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry.ID = entryIDs.Next(time.Now())
+	lastChecked := entry.LastChecked
+
+	// Checks almost always arrive in order, so this is an append in practice;
+	// the search keeps the slice sorted if the clock ever goes backwards.
+	i := sort.Search(len(store.Entries), func(i int) bool {
+		return store.Entries[i].LastChecked > lastChecked
+	})
+	store.Entries = append(store.Entries, StatusEntry{})
+	copy(store.Entries[i+1:], store.Entries[i:])
+	store.Entries[i] = entry
+	store.pending = append(store.pending, entry)
+}
+
+func cleanupOld() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	cutoff := time.Now().Unix()*1000 - ONE_DAY_IN_MS
+	i := searchEntries(cutoff)
+
+	if i == 0 {
+		return
+	}
+
+	filtered := make([]StatusEntry, len(store.Entries)-i)
+	copy(filtered, store.Entries[i:])
+	store.Entries = filtered
+	// The SQLite history keeps older checks for HISTORY_RETENTION_DAYS
+	// instead (see cleanupHistory); only the memory is trimmed.
+	store.compact = history == nil
+}
+
+// cleanupHistory drops checks older than HISTORY_RETENTION_DAYS from the
+// SQLite history.
+func cleanupHistory() {
+	if history == nil || config.HistoryRetention <= 0 {
+		return
+	}
+	removed, err := history.DeleteBefore(time.Now().Add(-config.HistoryRetention).UnixMilli())
+	if err != nil {
+		log.Printf("Error cleaning up status history: %v", err)
+	} else if removed > 0 {
+		log.Printf("Removed %d checks older than %v from the status history", removed, config.HistoryRetention)
+	}
+}
+
+// forgetPlayer removes player (case-insensitively) from every entry and
+// returns how many entries mentioned them. The snapshot is rewritten on the
+// next save, which also drops the journal lines holding the name.
+func forgetPlayer(player string) int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	count := 0
+	for i := range store.Entries {
+		players := store.Entries[i].Players
+		kept := players[:0:0]
+		for _, name := range players {
+			if !strings.EqualFold(name, player) {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept) != len(players) {
+			store.Entries[i].Players = kept
+			count++
+		}
+	}
+	if count > 0 {
+		store.compact = true
+	}
+
+	if history != nil {
+		before := int64(math.MaxInt64)
+		if len(store.Entries) > 0 {
+			before = store.Entries[0].LastChecked
+		}
+		older, err := history.ForgetPlayer(player, before)
+		if err != nil {
+			log.Printf("Error forgetting %s in the status history: %v", player, err)
+		}
+		count += older
+	}
+	return count
+}
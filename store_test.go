@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// One year of history at the default CHECK_INTERVAL.
+const yearOfChecks = 365 * 24 * 60 * 2
+
+// Performance budget for the per-check store work (insertStatus + saveStore)
+// with a year of history loaded: a constant number of allocations, i.e.
+// nothing proportional to history size. BenchmarkCheckStoreYear tracks the
+// CPU side of the same budget.
+const checkStoreAllocBudget = 30
+
+// useTempStore points the store (and the bot state) at an empty temporary
+// directory and gives the store n entries spaced CHECK_INTERVAL apart.
+func useTempStore(tb testing.TB, n int) {
+	tb.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.Chdir(tb.TempDir()); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { os.Chdir(wd) })
+
+	state = &BotState{ChatMigrations: map[string]string{}}
+
+	store = &StatusStore{Entries: make([]StatusEntry, n)}
+	for i := range store.Entries {
+		store.Entries[i] = StatusEntry{
+			ID:          EntryID(strconv.Itoa(i + 1)),
+			Online:      true,
+			LastChecked: int64(i) * CHECK_INTERVAL.Milliseconds(),
+			Players:     []string{"steve", "alex"},
+		}
+	}
+}
+
+func TestStoreKeepsEntriesSorted(t *testing.T) {
+	useTempStore(t, 0)
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 3000})
+	insertStatus(StatusEntry{Online: true, LastChecked: 1000})
+	insertStatus(StatusEntry{Online: false, LastChecked: 2000})
+
+	for i, want := range []int64{1000, 2000, 3000} {
+		if got := store.Entries[i].LastChecked; got != want {
+			t.Fatalf("entry %d: LastChecked = %d, want %d", i, got, want)
+		}
+	}
+	if latest := getLatest(); latest.LastChecked != 3000 {
+		t.Fatalf("getLatest().LastChecked = %d, want 3000", latest.LastChecked)
+	}
+	if got := getRange(1500, 3000); len(got) != 1 || got[0].LastChecked != 2000 {
+		t.Fatalf("getRange(1500, 3000) = %+v, want the 2000 entry", got)
+	}
+}
+
+func TestStoreJournalRoundTrip(t *testing.T) {
+	useTempStore(t, 0)
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 1000, Players: []string{"steve"}})
+	saveStore()
+	insertStatus(StatusEntry{Online: false, LastChecked: 2000, Players: []string{}})
+	saveStore()
+
+	if _, err := os.Stat(JSON_FILE); !os.IsNotExist(err) {
+		t.Fatalf("snapshot written before compaction was needed (err=%v)", err)
+	}
+
+	store = &StatusStore{}
+	loadStore()
+	if len(store.Entries) != 2 || store.Entries[0].Players[0] != "steve" {
+		t.Fatalf("entries after reload = %+v", store.Entries)
+	}
+
+	store.compact = true
+	saveStore()
+	store = &StatusStore{}
+	loadStore()
+	if len(store.Entries) != 2 || store.journalLen != 0 {
+		t.Fatalf("after compaction: %d entries, journalLen %d", len(store.Entries), store.journalLen)
+	}
+}
+
+func TestCheckStoreAllocBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a year of history")
+	}
+	useTempStore(t, yearOfChecks)
+
+	ts := int64(yearOfChecks) * CHECK_INTERVAL.Milliseconds()
+	allocs := testing.AllocsPerRun(100, func() {
+		ts += CHECK_INTERVAL.Milliseconds()
+		insertStatus(StatusEntry{Online: true, LastChecked: ts, Players: []string{"steve", "alex"}})
+		saveStore()
+	})
+	if allocs > checkStoreAllocBudget {
+		t.Fatalf("insert+save allocates %.0f times per check, budget is %d", allocs, checkStoreAllocBudget)
+	}
+}
+
+func BenchmarkCheckStoreYear(b *testing.B) {
+	useTempStore(b, yearOfChecks)
+	ts := int64(yearOfChecks) * CHECK_INTERVAL.Milliseconds()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ts += CHECK_INTERVAL.Milliseconds()
+		insertStatus(StatusEntry{Online: true, LastChecked: ts, Players: []string{"steve", "alex"}})
+		saveStore()
+	}
+}
+
+func BenchmarkGetLatest(b *testing.B) {
+	useTempStore(b, yearOfChecks)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getLatest()
+	}
+}
+
+func BenchmarkGetRangeDay(b *testing.B) {
+	useTempStore(b, yearOfChecks)
+	to := int64(yearOfChecks) * CHECK_INTERVAL.Milliseconds()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getRange(to-ONE_DAY_IN_MS, to)
+	}
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	for _, n := range []int{2880, yearOfChecks} {
+		b.Run(fmt.Sprintf("entries=%d", n), func(b *testing.B) {
+			useTempStore(b, n)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.compact = true
+				saveStore()
+			}
+		})
+	}
+}
+
+func TestSaveStoreCoalesces(t *testing.T) {
+	useTempStore(t, 0)
+	config.SaveInterval = time.Hour
+	t.Cleanup(func() { config.SaveInterval = 0 })
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 1000})
+	saveStore()
+	insertStatus(StatusEntry{Online: true, LastChecked: 2000})
+	saveStore()
+
+	if len(store.pending) != 1 || store.journalLen != 1 {
+		t.Fatalf("pending=%d journalLen=%d, want the second write deferred", len(store.pending), store.journalLen)
+	}
+
+	flushStore()
+	if len(store.pending) != 0 || store.journalLen != 2 {
+		t.Fatalf("pending=%d journalLen=%d after flush", len(store.pending), store.journalLen)
+	}
+}
+
+func TestStoreWriteMetrics(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	metrics = newMetricSet()
+	t.Cleanup(func() { metrics = newMetricSet() })
+	storeWarned = time.Time{}
+	config.AdminChatID = "-100"
+	config.StoreMaxSize = 100
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 1000, Players: []string{"steve"}})
+	saveStore()
+	journal, _ := os.ReadFile(JOURNAL_FILE)
+	if size := metrics.families["minecraft_store_size_bytes"].series[""]; size != float64(len(journal)) {
+		t.Errorf("size after a journal write = %v, want %d", size, len(journal))
+	}
+	if n := metrics.families["minecraft_store_writes_total"].series[`{kind="journal"}`]; n != 1 {
+		t.Errorf("journal writes = %v, want 1", n)
+	}
+
+	insertStatus(StatusEntry{Online: true, LastChecked: 2000, Players: []string{"alex"}})
+	store.compact = true
+	saveStore()
+	snapshot, _ := os.ReadFile(JSON_FILE)
+	if size := metrics.families["minecraft_store_size_bytes"].series[""]; size != float64(len(snapshot)) {
+		t.Errorf("size after compaction = %v, want %d", size, len(snapshot))
+	}
+
+	// The warning is sent in the background, once.
+	deadline := time.Now().Add(time.Second)
+	for len(fake.callsTo("sendMessage")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["chat_id"] != "-100" {
+		t.Fatalf("sendMessage calls = %+v, want one warning to the admin chat", sent)
+	}
+}
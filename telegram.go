@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	TELEGRAM_API_URL = "https://api.telegram.org"
+	// TELEGRAM_TIMEOUT applies to calls whose context has no deadline.
+	TELEGRAM_TIMEOUT = 10 * time.Second
+	// TELEGRAM_POLL_TIMEOUT is how long getUpdates waits for new updates.
+	TELEGRAM_POLL_TIMEOUT = 25 * time.Second
+	// TELEGRAM_MAX_RETRIES bounds how often one call is retried after a 429.
+	TELEGRAM_MAX_RETRIES = 3
+	// MAX_CAPTION_LENGTH is Telegram's limit for photo/document captions.
+	MAX_CAPTION_LENGTH = 1024
+
+	STAGING_MIRROR   = "mirror"
+	STAGING_REDIRECT = "redirect"
+	STAGING_PREFIX   = "[TEST] "
+)
+
+// httpDoer is the part of *http.Client the Telegram client needs; tests can
+// substitute their own transport through it.
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// TelegramClient calls the Bot API. BaseURL, HTTP and Sleep are there so
+// tests can point it at a fake server and skip real waiting on rate limits.
+type TelegramClient struct {
+	Token   string
+	BaseURL string
+	HTTP    httpDoer
+	Sleep   func(ctx context.Context, d time.Duration)
+
+	// OnMigrate is called when a group turned into a supergroup and got a
+	// new chat ID. The failed call is then repeated with the new ID.
+	OnMigrate func(oldChatID, newChatID string)
+
+	// StagingChatID, if set, gets a STAGING_PREFIX copy of every message
+	// and title change for other chats; with StagingMode STAGING_REDIRECT
+	// it gets them instead of the real chat.
+	StagingChatID string
+	StagingMode   string
+}
+
+func newTelegramClient(token string) *TelegramClient {
+	return &TelegramClient{
+		Token:   token,
+		BaseURL: TELEGRAM_API_URL,
+		HTTP:    &http.Client{},
+		Sleep:   sleepContext,
+	}
+}
+
+// APIError is an unsuccessful Bot API response.
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+	// RetryAfter is set on 429 Too Many Requests.
+	RetryAfter time.Duration
+	// MigrateToChatID is set when the group was upgraded to a supergroup.
+	MigrateToChatID int64
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram API error: %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter      int   `json:"retry_after"`
+		MigrateToChatID int64 `json:"migrate_to_chat_id"`
+	} `json:"parameters"`
+}
+
+// call invokes method with payload encoded as JSON and decodes the result
+// into result (which may be nil). On 429 it waits for the advertised
+// retry_after and tries again, up to TELEGRAM_MAX_RETRIES times.
+func (c *TelegramClient) call(ctx context.Context, method string, payload map[string]interface{}, result interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	err = c.send(ctx, method, "application/json", data, result)
+	if newChatID, ok := c.migrated(payload["chat_id"], err); ok {
+		payload["chat_id"] = newChatID
+		return c.call(ctx, method, payload, result)
+	}
+	return err
+}
+
+// migrated reports the new chat ID if err says the chat chatID was
+// upgraded to a supergroup, after telling OnMigrate about it.
+func (c *TelegramClient) migrated(chatID interface{}, err error) (string, bool) {
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.MigrateToChatID == 0 {
+		return "", false
+	}
+
+	oldChatID := fmt.Sprint(chatID)
+	newChatID := strconv.FormatInt(apiErr.MigrateToChatID, 10)
+	if oldChatID == newChatID {
+		return "", false
+	}
+
+	log.Printf("Telegram chat %s was migrated to %s", oldChatID, newChatID)
+	if c.OnMigrate != nil {
+		c.OnMigrate(oldChatID, newChatID)
+	}
+	return newChatID, true
+}
+
+// send posts an already encoded body, retrying on 429 like call does.
+func (c *TelegramClient) send(ctx context.Context, method, contentType string, data []byte, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, method, contentType, data, result)
+		if err != nil {
+			code := 0
+			if apiErr, ok := err.(*APIError); ok {
+				code = apiErr.Code
+			}
+			metrics.Inc("minecraft_telegram_errors_total", "method", method, "code", strconv.Itoa(code))
+		}
+
+		apiErr, ok := err.(*APIError)
+		if !ok || apiErr.Code != http.StatusTooManyRequests || attempt >= TELEGRAM_MAX_RETRIES {
+			return err
+		}
+
+		log.Printf("Telegram rate limit on %s, retrying in %v", method, apiErr.RetryAfter)
+		c.Sleep(ctx, apiErr.RetryAfter)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *TelegramClient) do(ctx context.Context, method, contentType string, data []byte, result interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, TELEGRAM_TIMEOUT)
+		defer cancel()
+	}
+
+	url := fmt.Sprintf("%s/bot%s/%s", c.BaseURL, c.Token, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return fmt.Errorf("telegram API error: %s: %s - %s", method, resp.Status, string(body))
+	}
+
+	if !apiResp.OK {
+		apiErr := &APIError{
+			Method:      method,
+			Code:        apiResp.ErrorCode,
+			Description: apiResp.Description,
+		}
+		if apiResp.Parameters != nil {
+			apiErr.RetryAfter = time.Duration(apiResp.Parameters.RetryAfter) * time.Second
+			apiErr.MigrateToChatID = apiResp.Parameters.MigrateToChatID
+		}
+		return apiErr
+	}
+
+	if result != nil {
+		return json.Unmarshal(apiResp.Result, result)
+	}
+	return nil
+}
+
+// User, Chat and Message carry the fields of the Bot API types we use.
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	Username     string `json:"username"`
+	LanguageCode string `json:"language_code"`
+}
+
+type Chat struct {
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text"`
+}
+
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message"`
+	Data    string   `json:"data"`
+}
+
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message"`
+	CallbackQuery *CallbackQuery `json:"callback_query"`
+}
+
+// ChatMember is getChatMember's result; the can_* fields are only
+// meaningful for administrators.
+type ChatMember struct {
+	Status            string `json:"status"`
+	User              User   `json:"user"`
+	CanChangeInfo     bool   `json:"can_change_info"`
+	CanPinMessages    bool   `json:"can_pin_messages"`
+	CanDeleteMessages bool   `json:"can_delete_messages"`
+}
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// MessageOptions are the optional sendMessage parameters; nil means none.
+type MessageOptions struct {
+	ReplyToMessageID    int64
+	ReplyMarkup         *InlineKeyboardMarkup
+	DisableNotification bool
+}
+
+// apply adds the options to a request payload.
+func (o *MessageOptions) apply(payload map[string]interface{}) {
+	if o == nil {
+		return
+	}
+	if o.ReplyToMessageID != 0 {
+		payload["reply_to_message_id"] = o.ReplyToMessageID
+	}
+	if o.ReplyMarkup != nil {
+		payload["reply_markup"] = o.ReplyMarkup
+	}
+	if o.DisableNotification {
+		payload["disable_notification"] = true
+	}
+}
+
+// SendMessage sends an HTML-formatted message.
+func (c *TelegramClient) SendMessage(ctx context.Context, chatID, text string, opts *MessageOptions) (*Message, error) {
+	return c.staged(chatID, text, func(target, text string) (*Message, error) {
+		payload := map[string]interface{}{
+			"chat_id":    target,
+			"text":       text,
+			"parse_mode": "HTML",
+		}
+		opts.apply(payload)
+		// The replied-to message is in the real chat.
+		if target != chatID {
+			delete(payload, "reply_to_message_id")
+		}
+
+		var msg Message
+		if err := c.call(ctx, "sendMessage", payload, &msg); err != nil {
+			return nil, err
+		}
+		return &msg, nil
+	})
+}
+
+// staged calls send for chatID, honouring StagingChatID: the staging chat
+// gets text with STAGING_PREFIX as well (mirror) or instead (redirect). In
+// mirror mode the result is the real chat's; failing to mirror is only
+// logged.
+func (c *TelegramClient) staged(chatID, text string, send func(chatID, text string) (*Message, error)) (*Message, error) {
+	if c.StagingChatID == "" || chatID == c.StagingChatID {
+		return send(chatID, text)
+	}
+	if c.StagingMode == STAGING_REDIRECT {
+		return send(c.StagingChatID, STAGING_PREFIX+text)
+	}
+
+	msg, err := send(chatID, text)
+	if _, stagingErr := send(c.StagingChatID, STAGING_PREFIX+text); stagingErr != nil {
+		log.Printf("Error mirroring to the staging chat: %v", stagingErr)
+	}
+	return msg, err
+}
+
+// EditMessageText replaces the text (and keyboard, if markup isn't nil) of a
+// message the bot sent earlier.
+func (c *TelegramClient) EditMessageText(ctx context.Context, chatID string, messageID int64, text string, markup *InlineKeyboardMarkup) error {
+	payload := map[string]interface{}{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}
+	if markup != nil {
+		payload["reply_markup"] = markup
+	}
+	return c.call(ctx, "editMessageText", payload, nil)
+}
+
+func (c *TelegramClient) PinChatMessage(ctx context.Context, chatID string, messageID int64, silent bool) error {
+	return c.call(ctx, "pinChatMessage", map[string]interface{}{
+		"chat_id":              chatID,
+		"message_id":           messageID,
+		"disable_notification": silent,
+	}, nil)
+}
+
+// SendPhoto sends a photo given as a file_id or an HTTP URL, with an HTML
+// caption.
+func (c *TelegramClient) SendPhoto(ctx context.Context, chatID, photo, caption string) (*Message, error) {
+	return c.staged(chatID, caption, func(chatID, caption string) (*Message, error) {
+		var msg Message
+		err := c.call(ctx, "sendPhoto", map[string]interface{}{
+			"chat_id":    chatID,
+			"photo":      photo,
+			"caption":    truncateCaption(caption),
+			"parse_mode": "HTML",
+		}, &msg)
+		if err != nil {
+			return nil, err
+		}
+		return &msg, nil
+	})
+}
+
+// SendDocument sends a document given as a file_id or an HTTP URL, with an
+// HTML caption.
+func (c *TelegramClient) SendDocument(ctx context.Context, chatID, document, caption string) (*Message, error) {
+	return c.staged(chatID, caption, func(chatID, caption string) (*Message, error) {
+		var msg Message
+		err := c.call(ctx, "sendDocument", map[string]interface{}{
+			"chat_id":    chatID,
+			"document":   document,
+			"caption":    truncateCaption(caption),
+			"parse_mode": "HTML",
+		}, &msg)
+		if err != nil {
+			return nil, err
+		}
+		return &msg, nil
+	})
+}
+
+// InputFile is a file uploaded as part of a request, such as a generated
+// chart or an export.
+type InputFile struct {
+	Name string
+	Data []byte
+}
+
+// upload sends params plus file (under the field name fileField) as
+// multipart/form-data and decodes the resulting Message.
+func (c *TelegramClient) upload(ctx context.Context, method string, params map[string]string, fileField string, file InputFile) (*Message, error) {
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+
+	for name, value := range params {
+		if err := form.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	part, err := form.CreateFormFile(fileField, file.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(file.Data); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg Message
+	err = c.send(ctx, method, form.FormDataContentType(), body.Bytes(), &msg)
+	if newChatID, ok := c.migrated(params["chat_id"], err); ok {
+		params["chat_id"] = newChatID
+		return c.upload(ctx, method, params, fileField, file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// SendPhotoFile uploads a photo (PNG or JPEG) with an HTML caption.
+func (c *TelegramClient) SendPhotoFile(ctx context.Context, chatID string, photo InputFile, caption string) (*Message, error) {
+	return c.staged(chatID, caption, func(chatID, caption string) (*Message, error) {
+		return c.upload(ctx, "sendPhoto", map[string]string{
+			"chat_id":    chatID,
+			"caption":    truncateCaption(caption),
+			"parse_mode": "HTML",
+		}, "photo", photo)
+	})
+}
+
+// SendDocumentFile uploads any file as a document with an HTML caption.
+func (c *TelegramClient) SendDocumentFile(ctx context.Context, chatID string, document InputFile, caption string) (*Message, error) {
+	return c.staged(chatID, caption, func(chatID, caption string) (*Message, error) {
+		return c.upload(ctx, "sendDocument", map[string]string{
+			"chat_id":    chatID,
+			"caption":    truncateCaption(caption),
+			"parse_mode": "HTML",
+		}, "document", document)
+	})
+}
+
+func (c *TelegramClient) SetChatTitle(ctx context.Context, chatID, title string) error {
+	_, err := c.staged(chatID, title, func(target, title string) (*Message, error) {
+		return nil, c.call(ctx, "setChatTitle", map[string]interface{}{
+			"chat_id": target,
+			"title":   title,
+		}, nil)
+	})
+	return err
+}
+
+// AnswerCallbackQuery acknowledges an inline keyboard tap, optionally showing
+// text to the user who tapped.
+func (c *TelegramClient) AnswerCallbackQuery(ctx context.Context, callbackQueryID, text string, showAlert bool) error {
+	return c.call(ctx, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": callbackQueryID,
+		"text":              text,
+		"show_alert":        showAlert,
+	}, nil)
+}
+
+// SetChatMenuButton makes the bot's menu button in private chats open the
+// Mini App at url, labelled text.
+func (c *TelegramClient) SetChatMenuButton(ctx context.Context, text, url string) error {
+	return c.call(ctx, "setChatMenuButton", map[string]interface{}{
+		"menu_button": map[string]interface{}{
+			"type":    "web_app",
+			"text":    text,
+			"web_app": map[string]string{"url": url},
+		},
+	}, nil)
+}
+
+// SetMyDescription sets what an empty private chat with the bot shows, for
+// users with languageCode, or for everyone without one if it's "".
+func (c *TelegramClient) SetMyDescription(ctx context.Context, description, languageCode string) error {
+	return c.call(ctx, "setMyDescription", map[string]interface{}{
+		"description":   description,
+		"language_code": languageCode,
+	}, nil)
+}
+
+// SetMyShortDescription sets the bio on the bot's profile, for users with
+// languageCode, or for everyone without one if it's "".
+func (c *TelegramClient) SetMyShortDescription(ctx context.Context, shortDescription, languageCode string) error {
+	return c.call(ctx, "setMyShortDescription", map[string]interface{}{
+		"short_description": shortDescription,
+		"language_code":     languageCode,
+	}, nil)
+}
+
+// GetUpdates long-polls for updates after offset, waiting up to
+// TELEGRAM_POLL_TIMEOUT for one to arrive.
+func (c *TelegramClient) GetUpdates(ctx context.Context, offset int64) ([]Update, error) {
+	ctx, cancel := context.WithTimeout(ctx, TELEGRAM_POLL_TIMEOUT+TELEGRAM_TIMEOUT)
+	defer cancel()
+
+	var updates []Update
+	err := c.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(TELEGRAM_POLL_TIMEOUT / time.Second),
+		"allowed_updates": []string{"message", "callback_query"},
+	}, &updates)
+	return updates, err
+}
+
+func (c *TelegramClient) GetMe(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.call(ctx, "getMe", map[string]interface{}{}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *TelegramClient) GetChat(ctx context.Context, chatID string) (*Chat, error) {
+	var chat Chat
+	if err := c.call(ctx, "getChat", map[string]interface{}{"chat_id": chatID}, &chat); err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+func (c *TelegramClient) GetChatMember(ctx context.Context, chatID string, userID int64) (*ChatMember, error) {
+	var member ChatMember
+	err := c.call(ctx, "getChatMember", map[string]interface{}{
+		"chat_id": chatID,
+		"user_id": userID,
+	}, &member)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
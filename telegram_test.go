@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCall is one request received by fakeTelegram.
+type fakeCall struct {
+	Method string
+	Params map[string]interface{}
+}
+
+// fakeTelegram is an in-process stand-in for the Bot API. It answers the
+// methods TelegramClient implements plus getUpdates, and can be told to
+// rate-limit a method a number of times before succeeding.
+type fakeTelegram struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	calls     []fakeCall
+	rateLimit map[string]int
+	// fail makes the next call of a method fail with a 400 and the given
+	// description.
+	fail     map[string]string
+	migrated map[int64]int64
+	updates  []json.RawMessage
+	nextID   int
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+
+	f := &fakeTelegram{rateLimit: map[string]int{}, fail: map[string]string{}, migrated: map[int64]int64{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// client returns a TelegramClient talking to f that doesn't really sleep;
+// the requested waits are appended to slept.
+func (f *fakeTelegram) client(slept *[]time.Duration) *TelegramClient {
+	c := newTelegramClient("TOKEN")
+	c.BaseURL = f.URL
+	c.Sleep = func(ctx context.Context, d time.Duration) {
+		if slept != nil {
+			*slept = append(*slept, d)
+		}
+	}
+	return c
+}
+
+// useFakeTelegram points the global client and group chat at a fresh fake,
+// with everyone a viewer as in the default configuration.
+func useFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+
+	fake := newFakeTelegram(t)
+	previousClient, previousConfig := telegram, config
+	telegram = fake.client(nil)
+	config.TelegramChatID = "-42"
+	config.DefaultRole = RoleViewer
+	t.Cleanup(func() {
+		telegram, config = previousClient, previousConfig
+	})
+	return fake
+}
+
+// callsTo returns the recorded calls of method.
+func (f *fakeTelegram) callsTo(method string) []fakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var result []fakeCall
+	for _, call := range f.calls {
+		if call.Method == method {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// queueUpdate makes the next getUpdates return update.
+func (f *fakeTelegram) queueUpdate(update string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.updates = append(f.updates, json.RawMessage(update))
+}
+
+// fakeChatID turns a chat_id parameter into the numeric ID Telegram would
+// report back.
+func fakeChatID(param interface{}) int64 {
+	switch v := param.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		id, _ := strconv.ParseInt(v, 10, 64)
+		return id
+	}
+	return 0
+}
+
+func (f *fakeTelegram) serve(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "bot") {
+		http.NotFound(w, r)
+		return
+	}
+	method := parts[1]
+
+	params := map[string]interface{}{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(10 << 20); err == nil {
+			for name, values := range r.MultipartForm.Value {
+				params[name] = values[0]
+			}
+			for name, files := range r.MultipartForm.File {
+				params[name] = "upload:" + files[0].Filename
+			}
+		}
+	} else {
+		json.NewDecoder(r.Body).Decode(&params)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, fakeCall{Method: method, Params: params})
+
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	if f.rateLimit[method] > 0 {
+		f.rateLimit[method]--
+		reply(http.StatusTooManyRequests, map[string]interface{}{
+			"ok":          false,
+			"error_code":  429,
+			"description": "Too Many Requests: retry after 5",
+			"parameters":  map[string]interface{}{"retry_after": 5},
+		})
+		return
+	}
+
+	if description, ok := f.fail[method]; ok {
+		delete(f.fail, method)
+		reply(http.StatusBadRequest, map[string]interface{}{"ok": false, "error_code": 400, "description": description})
+		return
+	}
+
+	if newID, ok := f.migrated[fakeChatID(params["chat_id"])]; ok {
+		reply(http.StatusBadRequest, map[string]interface{}{
+			"ok":          false,
+			"error_code":  400,
+			"description": "Bad Request: group chat was upgraded to a supergroup chat",
+			"parameters":  map[string]interface{}{"migrate_to_chat_id": newID},
+		})
+		return
+	}
+
+	switch method {
+	case "sendMessage", "sendPhoto", "sendDocument":
+		f.nextID++
+		reply(http.StatusOK, map[string]interface{}{
+			"ok": true,
+			"result": map[string]interface{}{
+				"message_id": f.nextID,
+				"chat":       map[string]interface{}{"id": fakeChatID(params["chat_id"])},
+				"text":       params["text"],
+			},
+		})
+	case "setChatTitle", "editMessageText", "pinChatMessage", "answerCallbackQuery", "setChatMenuButton",
+		"setMyDescription", "setMyShortDescription":
+		reply(http.StatusOK, map[string]interface{}{"ok": true, "result": true})
+	case "getMe":
+		reply(http.StatusOK, map[string]interface{}{
+			"ok":     true,
+			"result": map[string]interface{}{"id": 1, "is_bot": true, "first_name": "Test", "username": "testbot"},
+		})
+	case "getChat":
+		reply(http.StatusOK, map[string]interface{}{
+			"ok":     true,
+			"result": map[string]interface{}{"id": fakeChatID(params["chat_id"]), "type": "supergroup", "title": "lnudorm3"},
+		})
+	case "getChatMember":
+		reply(http.StatusOK, map[string]interface{}{
+			"ok": true,
+			"result": map[string]interface{}{
+				"status":           "administrator",
+				"user":             map[string]interface{}{"id": params["user_id"]},
+				"can_change_info":  true,
+				"can_pin_messages": false,
+			},
+		})
+	case "getUpdates":
+		updates := f.updates
+		if updates == nil {
+			updates = []json.RawMessage{}
+		}
+		f.updates = nil
+		reply(http.StatusOK, map[string]interface{}{"ok": true, "result": updates})
+	default:
+		reply(http.StatusNotFound, map[string]interface{}{
+			"ok":          false,
+			"error_code":  404,
+			"description": "Not Found",
+		})
+	}
+}
+
+func TestTelegramSendMessage(t *testing.T) {
+	fake := newFakeTelegram(t)
+
+	msg, err := fake.client(nil).SendMessage(context.Background(), "42", "<b>hi</b>", &MessageOptions{ReplyToMessageID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.MessageID != 1 {
+		t.Fatalf("message_id = %d, want 1", msg.MessageID)
+	}
+
+	calls := fake.callsTo("sendMessage")
+	if len(calls) != 1 || calls[0].Params["chat_id"] != "42" || calls[0].Params["parse_mode"] != "HTML" ||
+		calls[0].Params["reply_to_message_id"] != float64(7) {
+		t.Fatalf("sendMessage calls = %+v", calls)
+	}
+}
+
+func TestTelegramRetriesAfterRateLimit(t *testing.T) {
+	fake := newFakeTelegram(t)
+	fake.rateLimit["setChatTitle"] = 2
+
+	var slept []time.Duration
+	if err := fake.client(&slept).SetChatTitle(context.Background(), "42", "title"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.callsTo("setChatTitle")) != 3 {
+		t.Fatalf("setChatTitle called %d times, want 3", len(fake.callsTo("setChatTitle")))
+	}
+	if len(slept) != 2 || slept[0] != 5*time.Second {
+		t.Fatalf("slept %v, want two waits of 5s", slept)
+	}
+}
+
+func TestTelegramRateLimitWaitStopsOnCancel(t *testing.T) {
+	fake := newFakeTelegram(t)
+	fake.rateLimit["sendMessage"] = 1
+	c := fake.client(nil)
+	c.Sleep = sleepContext
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.SendMessage(ctx, "42", "hi", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Fatalf("waited %v for a cancelled context, want the 5s retry_after cut short", waited)
+	}
+	if len(fake.callsTo("sendMessage")) != 1 {
+		t.Fatalf("sendMessage called %d times, want 1", len(fake.callsTo("sendMessage")))
+	}
+}
+
+func TestTelegramGivesUpOnPersistentRateLimit(t *testing.T) {
+	fake := newFakeTelegram(t)
+	fake.rateLimit["sendMessage"] = TELEGRAM_MAX_RETRIES + 1
+
+	_, err := fake.client(nil).SendMessage(context.Background(), "42", "hi", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want a 429 APIError", err)
+	}
+}
+
+func TestTelegramAPIError(t *testing.T) {
+	fake := newFakeTelegram(t)
+
+	err := fake.client(nil).call(context.Background(), "noSuchMethod", map[string]interface{}{}, nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 404 || apiErr.Method != "noSuchMethod" {
+		t.Fatalf("err = %v, want a 404 APIError", err)
+	}
+}
+
+func TestTelegramEditAndPin(t *testing.T) {
+	fake := newFakeTelegram(t)
+	client := fake.client(nil)
+	ctx := context.Background()
+
+	keyboard := &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{{Text: "ok", CallbackData: "ack"}}}}
+	if err := client.EditMessageText(ctx, "42", 5, "new text", keyboard); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PinChatMessage(ctx, "42", 5, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AnswerCallbackQuery(ctx, "cb1", "done", false); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := fake.callsTo("editMessageText")
+	if len(edit) != 1 || edit[0].Params["message_id"] != float64(5) || edit[0].Params["reply_markup"] == nil {
+		t.Fatalf("editMessageText calls = %+v", edit)
+	}
+	if pin := fake.callsTo("pinChatMessage"); len(pin) != 1 || pin[0].Params["disable_notification"] != true {
+		t.Fatalf("pinChatMessage calls = %+v", pin)
+	}
+}
+
+func TestTelegramUploadsPhoto(t *testing.T) {
+	fake := newFakeTelegram(t)
+
+	caption, err := renderCaption("uk", "chart", map[string]string{"Title": "<players>", "Period": "24h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	photo := InputFile{Name: "chart.png", Data: []byte("\x89PNG")}
+	if _, err := fake.client(nil).SendPhotoFile(context.Background(), "42", photo, caption); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.callsTo("sendPhoto")
+	if len(calls) != 1 || calls[0].Params["photo"] != "upload:chart.png" || calls[0].Params["caption"] != "📈 <b>&lt;players&gt;</b>\n24h" {
+		t.Fatalf("sendPhoto calls = %+v", calls)
+	}
+}
+
+func TestTelegramFollowsChatMigration(t *testing.T) {
+	fake := newFakeTelegram(t)
+	fake.migrated[-42] = -100042
+
+	client := fake.client(nil)
+	var migrations []string
+	client.OnMigrate = func(oldChatID, newChatID string) {
+		migrations = append(migrations, oldChatID+"->"+newChatID)
+	}
+
+	msg, err := client.SendMessage(context.Background(), "-42", "hi", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Chat.ID != -100042 {
+		t.Fatalf("message went to chat %d, want -100042", msg.Chat.ID)
+	}
+	if len(migrations) != 1 || migrations[0] != "-42->-100042" {
+		t.Fatalf("OnMigrate calls = %v", migrations)
+	}
+}
+
+func TestTelegramStagingChat(t *testing.T) {
+	fake := newFakeTelegram(t)
+	client := fake.client(nil)
+	client.StagingChatID = "-99"
+	ctx := context.Background()
+
+	msg, err := client.SendMessage(ctx, "-42", "hi", &MessageOptions{ReplyToMessageID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Chat.ID != -42 {
+		t.Fatalf("mirrored send returned chat %d, want -42", msg.Chat.ID)
+	}
+
+	client.StagingMode = STAGING_REDIRECT
+	if err := client.SetChatTitle(ctx, "-42", "title"); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 2 || sent[0].Params["chat_id"] != "-42" || sent[0].Params["text"] != "hi" ||
+		sent[1].Params["chat_id"] != "-99" || sent[1].Params["text"] != "[TEST] hi" || sent[1].Params["reply_to_message_id"] != nil {
+		t.Fatalf("sendMessage calls = %+v", sent)
+	}
+	titles := fake.callsTo("setChatTitle")
+	if len(titles) != 1 || titles[0].Params["chat_id"] != "-99" || titles[0].Params["title"] != "[TEST] title" {
+		t.Fatalf("setChatTitle calls = %+v", titles)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// messageTemplateNames name the TEMPLATES_<NAME> settings, e.g.
+// TEMPLATES_JOIN, or "templates: join:" in the config file, by the message
+// in chatTemplateKeys they set for every chat. A chat's own template, from
+// /chatconfig or the settings page, wins over them.
+var messageTemplateNames = map[string]string{
+	"players.joined": "join",
+	"players.left":   "leave",
+	"server.up":      "online",
+	"server.down":    "offline",
+	"title":          "title",
+}
+
+// MessageTemplateData is what message templates get: {{.Players}} (already
+// formatted) and {{.Count}} for joins and leaves, {{.Duration}} for how
+// long the server was down when it comes back ("" if under a minute), and
+// {{.Online}} and {{.Count}} for the title.
+type MessageTemplateData struct {
+	Players  string
+	Count    int
+	Duration string
+	Online   bool
+}
+
+// loadMessageTemplates reads the TEMPLATES_* settings, by message key.
+func loadMessageTemplates() (map[string]string, error) {
+	templates := map[string]string{}
+	for _, key := range chatTemplateKeys {
+		setting := "TEMPLATES_" + strings.ToUpper(messageTemplateNames[key])
+		text := getEnv(setting, "")
+		if text == "" {
+			continue
+		}
+		if _, err := renderChatTemplate(text, MessageTemplateData{}); err != nil {
+			return nil, fmt.Errorf("%s: %w", setting, err)
+		}
+		templates[key] = text
+	}
+	return templates, nil
+}
+
+// template is the template replacing the message key in the chat: its own,
+// else the one from TEMPLATES_*, else "" for the built-in message.
+func (s ChatSettings) template(key string) string {
+	if text, ok := s.Templates[key]; ok {
+		return text
+	}
+	return config.Templates[key]
+}
+
+// renderMessageTemplate renders the chat's template for key, and false if
+// there is none or it failed, for the caller to fall back to the built-in
+// message.
+func renderMessageTemplate(settings ChatSettings, key string, data MessageTemplateData) (string, bool) {
+	text := settings.template(key)
+	if text == "" {
+		return "", false
+	}
+	rendered, err := renderChatTemplate(text, data)
+	if err != nil {
+		log.Printf("Error rendering template %s: %v", key, err)
+		return "", false
+	}
+	return rendered, true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageTemplates(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	t.Setenv("TEMPLATES_JOIN", "➕ {{.Players}} ({{.Count}})")
+	t.Setenv("TEMPLATES_ONLINE", "Back{{if .Duration}} after {{.Duration}}{{end}}")
+	t.Setenv("TEMPLATES_TITLE", "{{if .Online}}🟢 {{.Count}}{{else}}🔴{{end}} Dorm 3")
+
+	templates, err := loadMessageTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Templates = templates
+
+	events := []Event{
+		{Kind: EventServerUp, DowntimeSeconds: 300},
+		{Kind: EventPlayerJoined, Player: "steve"},
+		{Kind: EventPlayerJoined, Player: "alex"},
+		{Kind: EventPlayerLeft, Player: "notch"},
+	}
+	want := "Back after 5 minutes\n➕ <b>steve</b>, <b>alex</b> (2)\n" + tr("en", "players.left.single", "<b>notch</b>")
+	if got := renderEvents("en", events); got != want {
+		t.Errorf("renderEvents:\n%s\nwant:\n%s", got, want)
+	}
+	if got := renderServerUp(ChatSettings{Language: "en"}, Event{Kind: EventServerUp, DowntimeSeconds: 10}); got != "Back" {
+		t.Errorf("renderServerUp = %q", got)
+	}
+	if got := renderChatTitle(ChatSettings{Language: "en"}, true, 3); got != "🟢 3 Dorm 3" {
+		t.Errorf("renderChatTitle = %q", got)
+	}
+	if got, want := renderServerDown(ChatSettings{Language: "en"}, Event{Kind: EventServerDown, Time: time.Now()}), tr("en", "server.down"); got != want {
+		t.Errorf("renderServerDown = %q, want the built-in %q", got, want)
+	}
+}
+
+func TestMessageTemplatesInvalid(t *testing.T) {
+	for _, text := range []string{"{{.Players", "{{.Nope}}"} {
+		t.Setenv("TEMPLATES_LEAVE", text)
+		if _, err := loadMessageTemplates(); err == nil {
+			t.Errorf("loadMessageTemplates accepted %q", text)
+		}
+	}
+}
+
+func TestMessageTemplatesUnderChatTemplates(t *testing.T) {
+	previous := config
+	defer func() { config = previous }()
+	t.Setenv("TEMPLATES_JOIN", "➕ {{.Players}}")
+	t.Setenv("TEMPLATES_TITLE", "Dorm 3 ({{.Count}})")
+	templates, err := loadMessageTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Templates = templates
+
+	settings := ChatSettings{Language: "en", Templates: map[string]string{"players.joined": "👋 {{.Players}}"}}
+	events := []Event{{Kind: EventPlayerJoined, Player: "steve"}}
+	if got := renderChatEvents(settings, events); got != "👋 <b>steve</b>" {
+		t.Errorf("chat template: %q, want it to win over TEMPLATES_JOIN", got)
+	}
+	if got := renderChatEvents(ChatSettings{Language: "en"}, events); got != "➕ <b>steve</b>" {
+		t.Errorf("no chat template: %q, want TEMPLATES_JOIN", got)
+	}
+	if got := renderChatTitle(settings, true, 2); got != "Dorm 3 (2)" {
+		t.Errorf("renderChatTitle = %q, want TEMPLATES_TITLE", got)
+	}
+	settings.Templates["title"] = "{{.Count}} on"
+	if got := renderChatTitle(settings, true, 2); got != "2 on" {
+		t.Errorf("renderChatTitle = %q, want the chat's template", got)
+	}
+}
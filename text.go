@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// FORMAT_CHAR starts a legacy Minecraft formatting code (§ followed by one of
+// 0-9a-fk-or).
+const FORMAT_CHAR = '§'
+
+// chatComponent is the JSON text format servers may use for the MOTD.
+type chatComponent struct {
+	Text          string          `json:"text"`
+	Color         string          `json:"color"`
+	Bold          bool            `json:"bold"`
+	Italic        bool            `json:"italic"`
+	Underlined    bool            `json:"underlined"`
+	Strikethrough bool            `json:"strikethrough"`
+	Obfuscated    bool            `json:"obfuscated"`
+	Extra         []chatComponent `json:"extra"`
+}
+
+// UnmarshalJSON accepts a bare string as well, which both the top-level
+// description and "extra" entries are allowed to be.
+func (c *chatComponent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = chatComponent{Text: text}
+		return nil
+	}
+
+	type plain chatComponent
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// legacyColorCodes maps chat component color names to their § codes.
+var legacyColorCodes = map[string]byte{
+	"black": '0', "dark_blue": '1', "dark_green": '2', "dark_aqua": '3',
+	"dark_red": '4', "dark_purple": '5', "gold": '6', "gray": '7',
+	"dark_gray": '8', "blue": '9', "green": 'a', "aqua": 'b',
+	"red": 'c', "light_purple": 'd', "yellow": 'e', "white": 'f',
+}
+
+// legacyText flattens a chat component into a single string using legacy §
+// codes for its formatting, the same shape old servers send directly.
+func legacyText(c chatComponent) string {
+	var sb strings.Builder
+	writeLegacyText(&sb, c)
+	return sb.String()
+}
+
+func writeLegacyText(sb *strings.Builder, c chatComponent) {
+	if code, ok := legacyColorCodes[c.Color]; ok {
+		sb.WriteRune(FORMAT_CHAR)
+		sb.WriteByte(code)
+	}
+	for _, f := range []struct {
+		on   bool
+		code byte
+	}{{c.Obfuscated, 'k'}, {c.Bold, 'l'}, {c.Strikethrough, 'm'}, {c.Underlined, 'n'}, {c.Italic, 'o'}} {
+		if f.on {
+			sb.WriteRune(FORMAT_CHAR)
+			sb.WriteByte(f.code)
+		}
+	}
+	sb.WriteString(c.Text)
+	for _, extra := range c.Extra {
+		writeLegacyText(sb, extra)
+	}
+}
+
+// stripFormatting removes legacy § formatting codes.
+func stripFormatting(s string) string {
+	if !strings.ContainsRune(s, FORMAT_CHAR) {
+		return s
+	}
+
+	var sb strings.Builder
+	skip := false
+	for _, r := range s {
+		switch {
+		case skip:
+			skip = false
+		case r == FORMAT_CHAR:
+			skip = true
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// sanitizeText turns text received from the server into something safe to
+// store and render: valid NFC-normalized UTF-8 without formatting codes or
+// control characters (newlines are kept, since MOTDs have two lines).
+func sanitizeText(s string) string {
+	return strings.TrimSpace(cleanRunes(stripFormatting(s)))
+}
+
+// cleanRunes does the Unicode part of sanitizeText, leaving § codes alone.
+func cleanRunes(s string) string {
+	s = strings.ToValidUTF8(s, "�")
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+	return norm.NFC.String(s)
+}
+
+// motdFormatTags maps legacy format codes to the closest Telegram HTML tag.
+// Telegram has no colors, so color codes only matter for the way they reset
+// formatting, like in the game.
+var motdFormatTags = []struct {
+	code byte
+	tag  string
+}{
+	{'l', "b"},
+	{'o', "i"},
+	{'n', "u"},
+	{'m', "s"},
+	{'k', "tg-spoiler"},
+}
+
+// motdHTML renders a MOTD with legacy § codes (see ServerStatus.RawMOTD) as
+// Telegram HTML, keeping bold/italic/underline/strikethrough and turning
+// obfuscated text into a spoiler.
+func motdHTML(raw string) string {
+	var sb strings.Builder
+	active := make([]bool, len(motdFormatTags))
+	open := make([]bool, len(motdFormatTags))
+
+	// closeAll closes open tags in reverse so the output stays properly
+	// nested; sync then reopens the active ones.
+	closeAll := func() {
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] {
+				sb.WriteString("</" + motdFormatTags[i].tag + ">")
+				open[i] = false
+			}
+		}
+	}
+	sync := func() {
+		closeAll()
+		for i, on := range active {
+			if on {
+				sb.WriteString("<" + motdFormatTags[i].tag + ">")
+				open[i] = true
+			}
+		}
+	}
+
+	dirty := false
+	lines := strings.Split(strings.TrimSpace(cleanRunes(raw)), "\n")
+	for n, line := range lines {
+		if n > 0 {
+			// Formatting carries over to the next line, but tags
+			// shouldn't span the line break.
+			closeAll()
+			sb.WriteString("\n")
+			dirty = true
+		}
+		runes := []rune(line)
+		for i := 0; i < len(runes); i++ {
+			if runes[i] != FORMAT_CHAR {
+				if dirty {
+					sync()
+					dirty = false
+				}
+				sb.WriteString(escapeHtml(string(runes[i])))
+				continue
+			}
+			if i+1 >= len(runes) {
+				break
+			}
+			i++
+			code := byte(unicode.ToLower(runes[i]))
+			switch {
+			case code == 'r' || (code >= '0' && code <= '9') || (code >= 'a' && code <= 'f'):
+				for f := range active {
+					active[f] = false
+				}
+				dirty = true
+			default:
+				for f, format := range motdFormatTags {
+					if format.code == code {
+						active[f] = true
+						dirty = true
+					}
+				}
+			}
+		}
+	}
+
+	closeAll()
+	return sb.String()
+}
+
+// sanitizePlayerName is sanitizeText for single-line values.
+func sanitizePlayerName(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(sanitizeText(s), "\n", " "))
+}
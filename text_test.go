@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSanitizeText(t *testing.T) {
+	cases := map[string]string{
+		"§aSteve§r":                "Steve",
+		"  bad\x00\x07name\u200b ": "badname",
+		"Cafe\u0301":               "Café",
+		"line one\n§7line two":     "line one\nline two",
+		"broken \xff utf8":         "broken � utf8",
+		"trailing format char §":   "trailing format char",
+	}
+	for in, want := range cases {
+		if got := sanitizeText(in); got != want {
+			t.Errorf("sanitizeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLegacyTextFromComponent(t *testing.T) {
+	var c chatComponent
+	raw := `{"text":"","extra":[{"text":"lnu","color":"gold","bold":true},"dorm3"]}`
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := legacyText(c), "§6§llnudorm3"; got != want {
+		t.Fatalf("legacyText = %q, want %q", got, want)
+	}
+}
+
+func TestMOTDHTML(t *testing.T) {
+	cases := map[string]string{
+		"plain <motd>":           "plain &lt;motd&gt;",
+		"§6§lgold bold§r normal": "<b>gold bold</b> normal",
+		"§lbold §oboth§a plain":  "<b>bold </b><b><i>both</i></b> plain",
+		"§kfoo§r\n§nline two":    "<tg-spoiler>foo</tg-spoiler>\n<u>line two</u>",
+		"§l§r§lnot empty tags":   "<b>not empty tags</b>",
+		"dangling §":             "dangling ",
+	}
+	for in, want := range cases {
+		if got := motdHTML(in); got != want {
+			t.Errorf("motdHTML(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
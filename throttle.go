@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// COMMAND_LIMIT_WINDOW is the window COMMAND_USER_LIMIT and
+// COMMAND_CHAT_LIMIT count commands in.
+const COMMAND_LIMIT_WINDOW = time.Minute
+
+// rateLimiter allows up to limit events per key within window, counting
+// them in a sliding log. A limit of 0 or less allows everything.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, hits: map[string][]time.Time{}}
+}
+
+// wait returns how long key has to wait before its next event is allowed,
+// 0 if it is allowed now. It doesn't record anything.
+func (l *rateLimiter) wait(key string, now time.Time) time.Duration {
+	if l.limit <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hits := l.prune(key, now)
+	if len(hits) < l.limit {
+		return 0
+	}
+	return hits[len(hits)-l.limit].Add(l.window).Sub(now)
+}
+
+// record counts an event for key.
+func (l *rateLimiter) record(key string, now time.Time) {
+	if l.limit <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hits[key] = append(l.prune(key, now), now)
+}
+
+// prune drops the hits of key that left the window. The caller must hold
+// l.mu.
+func (l *rateLimiter) prune(key string, now time.Time) []time.Time {
+	hits := l.hits[key]
+	i := 0
+	for i < len(hits) && now.Sub(hits[i]) >= l.window {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) == 0 {
+		delete(l.hits, key)
+	}
+	return hits
+}
+
+// userLimiter and chatLimiter are set up by loadConfig.
+var (
+	userLimiter *rateLimiter
+	chatLimiter *rateLimiter
+	// cooldownWarned keeps the cooldown reply to one per user per window,
+	// so the reply itself can't become the spam.
+	cooldownWarned = newRateLimiter(1, COMMAND_LIMIT_WINDOW)
+)
+
+// throttleCommand reports whether msg may run command now, within
+// COMMAND_USER_LIMIT, COMMAND_CHAT_LIMIT and the command's own Limit. If
+// not, the sender is told (once per window) how long to wait.
+func throttleCommand(ctx context.Context, msg *Message, command *Command) bool {
+	limiters := []*rateLimiter{command.limiter}
+	if userLimiter != nil && chatLimiter != nil {
+		limiters = append(limiters, userLimiter, chatLimiter)
+	}
+
+	now := time.Now()
+	userKey := "chat"
+	if msg.From != nil {
+		userKey = strconv.FormatInt(msg.From.ID, 10)
+	}
+	chatKey := strconv.FormatInt(msg.Chat.ID, 10)
+	// The chat limiter counts by chat, the others by user.
+	key := func(l *rateLimiter) string {
+		if l == chatLimiter {
+			return chatKey
+		}
+		return userKey
+	}
+
+	var wait time.Duration
+	for _, l := range limiters {
+		wait = max(wait, l.wait(key(l), now))
+	}
+	if wait <= 0 {
+		for _, l := range limiters {
+			l.record(key(l), now)
+		}
+		return true
+	}
+
+	if cooldownWarned.wait(userKey, now) == 0 {
+		cooldownWarned.record(userKey, now)
+		reply(ctx, msg, tr(replyLanguage(msg), "command.cooldown", int(math.Ceil(wait.Seconds()))))
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2, time.Minute)
+	start := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if wait := limiter.wait("u", start); wait != 0 {
+			t.Fatalf("hit %d: wait %v", i, wait)
+		}
+		limiter.record("u", start.Add(time.Duration(i)*10*time.Second))
+	}
+	if wait := limiter.wait("u", start.Add(20*time.Second)); wait != 40*time.Second {
+		t.Fatalf("over the limit: wait %v, want 40s", wait)
+	}
+	if wait := limiter.wait("other", start); wait != 0 {
+		t.Fatalf("another key waits %v", wait)
+	}
+	if wait := limiter.wait("u", start.Add(time.Minute)); wait != 0 {
+		t.Fatalf("after the window: wait %v", wait)
+	}
+}
+
+func TestThrottleCommand(t *testing.T) {
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	userLimiter, chatLimiter = newRateLimiter(2, COMMAND_LIMIT_WINDOW), newRateLimiter(3, COMMAND_LIMIT_WINDOW)
+	cooldownWarned = newRateLimiter(1, COMMAND_LIMIT_WINDOW)
+	t.Cleanup(func() { userLimiter, chatLimiter = nil, nil })
+
+	command := &Command{limiter: newRateLimiter(0, COMMAND_LIMIT_WINDOW)}
+	send := func(from int64) bool {
+		return throttleCommand(context.Background(), &Message{MessageID: 1, Chat: Chat{ID: 7}, From: &User{ID: from}}, command)
+	}
+
+	if !send(1) || !send(1) {
+		t.Fatal("first two commands throttled")
+	}
+	if send(1) || send(1) {
+		t.Fatal("user limit not applied")
+	}
+	if !send(2) {
+		t.Fatal("another user throttled")
+	}
+	if send(3) {
+		t.Fatal("chat limit not applied")
+	}
+
+	replies := fake.callsTo("sendMessage")
+	if len(replies) != 2 {
+		t.Fatalf("%d cooldown replies, want one per throttled user", len(replies))
+	}
+	if text := replies[0].Params["text"]; text != "⏳ Too many commands. Please try again in 60 s." {
+		t.Fatalf("cooldown reply = %q", text)
+	}
+}
+
+func TestThrottleCommandLimit(t *testing.T) {
+	useFakeTelegram(t)
+	config.Language = "en"
+	cooldownWarned = newRateLimiter(1, COMMAND_LIMIT_WINDOW)
+
+	chart := &Command{limiter: newRateLimiter(1, COMMAND_LIMIT_WINDOW)}
+	other := &Command{limiter: newRateLimiter(0, COMMAND_LIMIT_WINDOW)}
+	msg := &Message{MessageID: 1, Chat: Chat{ID: 7}, From: &User{ID: 1}}
+	if !throttleCommand(context.Background(), msg, chart) {
+		t.Fatal("first command throttled")
+	}
+	if throttleCommand(context.Background(), msg, chart) {
+		t.Fatal("the command's own limit not applied")
+	}
+	if !throttleCommand(context.Background(), msg, other) {
+		t.Fatal("a command without a limit throttled")
+	}
+}
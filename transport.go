@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transport asks a server for its status. Every check (the main server,
+// SECONDARY_SERVER and the servers chats follow) goes through it, so an
+// alternative way of probing only needs a new implementation here.
+type Transport interface {
+	// Probe returns the status of host:port; ctx carries the TIMEOUT.
+	Probe(ctx context.Context, host string, port uint16) (*ServerStatus, error)
+}
+
+// transport is selected by PROBE_TRANSPORT in loadConfig.
+var transport Transport = slpTransport{}
+
+// slpTransport is the Server List Ping every Minecraft server answers.
+type slpTransport struct{}
+
+func (slpTransport) Probe(ctx context.Context, host string, port uint16) (*ServerStatus, error) {
+	return pingMinecraftServer(ctx, host, port)
+}
+
+// MAX_HTTP_STATUS_SIZE caps the status document of httpTransport, the same
+// as the Status Response packet.
+const MAX_HTTP_STATUS_SIZE = MAX_RESPONSE_SIZE
+
+// httpTransport is experimental: it fetches the Server List Ping JSON from
+// a server plugin over HTTPS instead of the game port. URL may contain
+// {host} and {port}, replaced for each server probed.
+type httpTransport struct {
+	URL    string
+	Client *http.Client
+}
+
+func newHTTPTransport(url string) httpTransport {
+	return httpTransport{URL: url, Client: &http.Client{Timeout: TIMEOUT}}
+}
+
+func (t httpTransport) Probe(ctx context.Context, host string, port uint16) (*ServerStatus, error) {
+	url := strings.NewReplacer("{host}", host, "{port}", strconv.Itoa(int(port))).Replace(t.URL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request: %w", classifyNetError(err))
+	}
+	defer resp.Body.Close()
+	connectTime := time.Since(start)
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("status endpoint returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MAX_HTTP_STATUS_SIZE+1))
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", classifyNetError(err))
+	}
+	if len(body) > MAX_HTTP_STATUS_SIZE {
+		return nil, badPacket("status document is over %d bytes", MAX_HTTP_STATUS_SIZE)
+	}
+
+	status, err := parseStatusJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	status.ConnectTime = connectTime
+	status.ProtocolTime = time.Since(start) - connectTime
+	return status, nil
+}
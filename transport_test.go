@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if r.URL.Path == "/broken" {
+			fmt.Fprint(w, `{"players": {"online": 1}}`)
+			return
+		}
+		fmt.Fprint(w, `{"version": {"name": "1.20.4"}, "players": {"online": 2, "sample": [{"name": "Steve"}, {"name": "Alex"}]}}`)
+	}))
+	defer server.Close()
+
+	transport := newHTTPTransport(server.URL + "/status/{host}/{port}")
+	status, err := transport.Probe(context.Background(), "mc.example.com", 25565)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/status/mc.example.com/25565" {
+		t.Errorf("requested %q", path)
+	}
+	if status.PlayerCount != 2 || len(status.Players) != 2 || status.Players[0] != "Steve" {
+		t.Errorf("status = %+v", status)
+	}
+
+	transport.URL = server.URL + "/broken"
+	_, err = transport.Probe(context.Background(), "mc.example.com", 25565)
+	var bad *ErrBadPacket
+	if !errors.As(err, &bad) {
+		t.Errorf("broken document: err = %v, want *ErrBadPacket", err)
+	}
+}
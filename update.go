@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// UPDATE_FEED_URL is the project's latest-release endpoint.
+	UPDATE_FEED_URL = "https://api.github.com/repos/akchonya/lnudorm3-minecraft-status/releases/latest"
+	// CHANGELOG_LINES and CHANGELOG_CHARS cap the changelog summary.
+	CHANGELOG_LINES = 10
+	CHANGELOG_CHARS = 800
+)
+
+var updateHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// release is the part of a GitHub release we use.
+type release struct {
+	TagName string `json:"tag_name"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+// checkForUpdate fetches the latest release and tells the admins, in
+// private, about a version newer than the running one. Each release is
+// announced once; development builds never are.
+func checkForUpdate(ctx context.Context) {
+	if _, ok := parseVersion(version); !ok {
+		return
+	}
+
+	latest, err := fetchLatestRelease(ctx)
+	if err != nil {
+		log.Printf("Error checking for updates: %v", err)
+		return
+	}
+	if compareVersions(latest.TagName, version) <= 0 {
+		return
+	}
+
+	state.mu.Lock()
+	notified := state.NotifiedRelease == latest.TagName
+	state.mu.Unlock()
+	if notified {
+		return
+	}
+
+	log.Printf("A newer version is available: %s (running %s)", latest.TagName, version)
+	text := renderUpdateNotice(config.Language, latest)
+	for _, id := range config.AdminIDs {
+		chatID := strconv.FormatInt(id, 10)
+		if _, err := telegram.SendMessage(ctx, chatID, text, nil); err != nil {
+			log.Printf("Error notifying admin %s about the update: %v", chatID, err)
+		}
+	}
+
+	state.mu.Lock()
+	state.NotifiedRelease = latest.TagName
+	saveState()
+	state.mu.Unlock()
+}
+
+func fetchLatestRelease(ctx context.Context) (*release, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", config.UpdateFeedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := updateHTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release feed: %s", resp.Status)
+	}
+
+	var latest release
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return nil, err
+	}
+	if _, ok := parseVersion(latest.TagName); !ok {
+		return nil, fmt.Errorf("release feed: unexpected tag %q", latest.TagName)
+	}
+	return &latest, nil
+}
+
+func renderUpdateNotice(lang string, latest *release) string {
+	text := tr(lang, "update.available", escapeHtml(latest.TagName), escapeHtml(version))
+	if summary := changelogSummary(latest.Body); summary != "" {
+		text += "\n\n" + escapeHtml(summary)
+	}
+	if latest.HTMLURL != "" {
+		text += "\n\n" + escapeHtml(latest.HTMLURL)
+	}
+	return text
+}
+
+// changelogSummary is the start of the release notes: the first
+// CHANGELOG_LINES non-empty lines, at most CHANGELOG_CHARS long.
+func changelogSummary(body string) string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if len(lines) == CHANGELOG_LINES {
+			lines = append(lines, "…")
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	summary := strings.Join(lines, "\n")
+	if runes := []rune(summary); len(runes) > CHANGELOG_CHARS {
+		summary = string(runes[:CHANGELOG_CHARS]) + "…"
+	}
+	return summary
+}
+
+// parseVersion parses "v1.2.3" (the v and missing parts are optional)
+// into its numbers.
+func parseVersion(s string) ([3]int, bool) {
+	var parts [3]int
+	s = strings.TrimPrefix(s, "v")
+	// Pre-release and build suffixes don't take part in the comparison.
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, the same as or
+// newer than b. Unparsable versions compare as the oldest.
+func compareVersions(a, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
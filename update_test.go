@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.10.0", "v1.9.9", 1},
+		{"1.2", "v1.2.1", -1},
+		{"v2.0.0-rc1", "v2.0.0", 0},
+		{"dev", "v0.0.1", -1},
+	} {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminIDs = []int64{7}
+
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "v1.3.0", "html_url": "https://example.com/v1.3.0", "body": "## Changes\n\n- faster <pings>\n"}`))
+	}))
+	defer feed.Close()
+	config.UpdateFeedURL = feed.URL
+
+	previousVersion := version
+	defer func() { version = previousVersion }()
+
+	ctx := context.Background()
+	version = "dev"
+	checkForUpdate(ctx)
+	version = "v1.3.0"
+	checkForUpdate(ctx)
+	if sent := fake.callsTo("sendMessage"); len(sent) != 0 {
+		t.Fatalf("notified without a newer release: %+v", sent)
+	}
+
+	version = "v1.2.0"
+	checkForUpdate(ctx)
+	checkForUpdate(ctx)
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["chat_id"] != "7" {
+		t.Fatalf("sendMessage calls = %+v, want one to the admin", sent)
+	}
+	want := "🆕 A new version of the monitor is available: <b>v1.3.0</b> (running v1.2.0).\n\n## Changes\n- faster &lt;pings&gt;\n\nhttps://example.com/v1.3.0"
+	if text := sent[0].Params["text"].(string); text != want {
+		t.Fatalf("notice = %q", text)
+	}
+}
+
+func TestChangelogSummary(t *testing.T) {
+	body := strings.Repeat("- change\n", CHANGELOG_LINES+5)
+	if lines := strings.Split(changelogSummary(body), "\n"); len(lines) != CHANGELOG_LINES+1 || lines[CHANGELOG_LINES] != "…" {
+		t.Fatalf("summary lines = %q", lines)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, set at build time with
+//
+//	-ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.buildDate=2024-03-10T12:00:00Z"
+//
+// Builds without them fall back to what the Go toolchain recorded.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// BuildInfo identifies the running build.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+func buildInfo() BuildInfo {
+	info := BuildInfo{Version: version, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+func renderVersion(lang string, info BuildInfo) string {
+	unknown := tr(lang, "version.unknown")
+	commit, built := unknown, unknown
+	if info.Commit != "" {
+		commit = info.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+	}
+	if info.BuildDate != "" {
+		built = info.BuildDate
+	}
+	return tr(lang, "version.info", escapeHtml(info.Version), escapeHtml(commit), escapeHtml(built), escapeHtml(info.GoVersion))
+}
+
+func handleVersionCommand(ctx context.Context, msg *Message) {
+	reply(ctx, msg, renderVersion(replyLanguage(msg), buildInfo()))
+}
+
+// handleVersionAPI serves buildInfo as JSON.
+func handleVersionAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildInfo())
+}
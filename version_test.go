@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+
+	previous := [3]string{version, commit, buildDate}
+	version, commit, buildDate = "v1.4.0", "0123456789abcdef", "2024-03-10T12:00:00Z"
+	defer func() { version, commit, buildDate = previous[0], previous[1], previous[2] }()
+
+	rec := httptest.NewRecorder()
+	newHTTPMux().ServeHTTP(rec, httptest.NewRequest("GET", "/api/version", nil))
+	var info BuildInfo
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "v1.4.0" || info.Commit != "0123456789abcdef" || info.BuildDate != "2024-03-10T12:00:00Z" || info.GoVersion == "" {
+		t.Fatalf("/api/version = %+v", info)
+	}
+
+	handleUpdate(context.Background(), Update{Message: &Message{
+		MessageID: 1, Chat: Chat{ID: -42}, From: &User{ID: 1}, Text: "/version",
+	}})
+	sent := fake.callsTo("sendMessage")
+	want := "🏷 Version <b>v1.4.0</b>\nCommit: <code>0123456789ab</code>\nBuilt: 2024-03-10T12:00:00Z\nGo: " + info.GoVersion
+	if len(sent) != 1 || sent[0].Params["text"] != want {
+		t.Fatalf("/version replies = %+v", sent)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	EVENT_SCHEMA         = "lnudorm3-status/event"
+	EVENT_SCHEMA_VERSION = 1
+)
+
+// eventSchema documents EventPayload; /api/events/schema serves it.
+//
+//go:embed schema/event.v1.json
+var eventSchema []byte
+
+// EventPayload is an Event as it leaves the monitor, to webhooks, NATS,
+// Kafka and Redis, and as POST /api/events accepts it. The fields are
+// those of Event next to schema and version.
+//
+// Compatibility: within a version, fields and event kinds are only ever
+// added. Nothing is removed or renamed, and nothing changes type or
+// meaning; consumers must ignore fields and kinds they don't know. Any
+// other change is a new version, with its own schema file next to
+// schema/event.v1.json. validateEventPayload enforces the schema on
+// everything sent and received.
+type EventPayload struct {
+	Schema  string `json:"schema"`
+	Version int    `json:"version"`
+	Event
+}
+
+var knownEventKinds = map[EventKind]bool{
+	EventPlayerJoined: true,
+	EventPlayerLeft:   true,
+	EventServerUp:     true,
+	EventServerDown:   true,
+	EventServerEmpty:  true,
+}
+
+// marshalEvent encodes event for the outside world, refusing anything that
+// doesn't match the schema.
+func marshalEvent(event Event) ([]byte, error) {
+	data, err := json.Marshal(EventPayload{Schema: EVENT_SCHEMA, Version: EVENT_SCHEMA_VERSION, Event: event})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := validateEventPayload(data); err != nil {
+		return nil, fmt.Errorf("event doesn't match its schema: %w", err)
+	}
+	return data, nil
+}
+
+// validateEventPayload decodes data and checks it against the schema.
+// Unknown fields are fine; unknown kinds aren't, since this monitor
+// couldn't act on them.
+func validateEventPayload(data []byte) (*EventPayload, error) {
+	var payload EventPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if payload.Schema != EVENT_SCHEMA {
+		return nil, fmt.Errorf("schema is %q, want %q", payload.Schema, EVENT_SCHEMA)
+	}
+	if payload.Version != EVENT_SCHEMA_VERSION {
+		return nil, fmt.Errorf("unsupported version %d", payload.Version)
+	}
+	if !knownEventKinds[payload.Kind] {
+		return nil, fmt.Errorf("unknown kind %q", payload.Kind)
+	}
+	if payload.Time.IsZero() {
+		return nil, errors.New("time is required")
+	}
+	if (payload.Kind == EventPlayerJoined || payload.Kind == EventPlayerLeft) && payload.Player == "" {
+		return nil, fmt.Errorf("player is required for %s", payload.Kind)
+	}
+	return &payload, nil
+}
+
+var webhookHTTP = &http.Client{Timeout: 10 * time.Second}
+
+// postWebhooks sends each event to every WEBHOOK_URLS endpoint.
+func postWebhooks(ctx context.Context, events []Event) {
+	if len(config.WebhookURLs) == 0 {
+		return
+	}
+	for _, event := range events {
+		data, err := marshalEvent(event)
+		if err != nil {
+			log.Printf("Error encoding event for webhooks: %v", err)
+			continue
+		}
+		for _, url := range config.WebhookURLs {
+			if err := postWebhook(ctx, url, data); err != nil {
+				log.Printf("Error posting event to webhook %s: %v", url, err)
+			}
+		}
+	}
+}
+
+func postWebhook(ctx context.Context, url string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Schema", fmt.Sprintf("%s; version=%d", EVENT_SCHEMA, EVENT_SCHEMA_VERSION))
+
+	resp, err := webhookHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// handleEventsAPI ingests player events from outside, e.g. a server
+// plugin that knows about joins before the next check does. They are
+// announced and dispatched like detected ones; duplicates of what a check
+// finds are dropped by the usual dedupe. Server state is only ever
+// detected by the monitor itself.
+func handleEventsAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkBearerToken(r, config.APIToken) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY))
+	if err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	payload, err := validateEventPayload(data)
+	if err != nil {
+		http.Error(w, "invalid event: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	event := payload.Event
+	if event.Kind != EventPlayerJoined && event.Kind != EventPlayerLeft {
+		http.Error(w, "only player events can be ingested", http.StatusUnprocessableEntity)
+		return
+	}
+	if event.Player = sanitizePlayerName(event.Player); event.Player == "" {
+		http.Error(w, "invalid event: player is required", http.StatusUnprocessableEntity)
+		return
+	}
+
+	reachable, players := false, 0
+	if latest := getLatest(); latest != nil {
+		reachable, players = latest.Online || latest.Error == "", max(latest.PlayerCount, len(latest.Players))
+	}
+	announce(r.Context(), "", reachable, players, []Event{event})
+	dispatchEvents(r.Context(), []Event{event})
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleEventSchemaAPI serves the JSON Schema of EventPayload.
+func handleEventSchemaAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(eventSchema)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEventSchemaMatchesCode keeps schema/event.v1.json and the validation
+// in step: a kind or required field added on one side only fails here.
+func TestEventSchemaMatchesCode(t *testing.T) {
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties struct {
+			Schema  struct{ Const string }  `json:"schema"`
+			Version struct{ Const int }     `json:"version"`
+			Kind    struct{ Enum []string } `json:"kind"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(eventSchema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Properties.Schema.Const != EVENT_SCHEMA || schema.Properties.Version.Const != EVENT_SCHEMA_VERSION {
+		t.Errorf("schema file is for %s v%d", schema.Properties.Schema.Const, schema.Properties.Version.Const)
+	}
+
+	var kinds []string
+	for kind := range knownEventKinds {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	sort.Strings(schema.Properties.Kind.Enum)
+	if strings.Join(kinds, ",") != strings.Join(schema.Properties.Kind.Enum, ",") {
+		t.Errorf("kinds in code %v, in the schema %v", kinds, schema.Properties.Kind.Enum)
+	}
+	if strings.Join(schema.Required, ",") != "schema,version,kind,time" {
+		t.Errorf("required = %v", schema.Required)
+	}
+}
+
+func TestValidateEventPayload(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		ok   bool
+	}{
+		{`{"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","player":"steve","time":"2024-01-02T03:04:05Z"}`, true},
+		{`{"schema":"lnudorm3-status/event","version":1,"kind":"server_up","time":"2024-01-02T03:04:05Z","added_later":true}`, true},
+		{`{"schema":"lnudorm3-status/event","version":2,"kind":"server_up","time":"2024-01-02T03:04:05Z"}`, false},
+		{`{"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","time":"2024-01-02T03:04:05Z"}`, false},
+		{`{"schema":"lnudorm3-status/event","version":1,"kind":"server_exploded","time":"2024-01-02T03:04:05Z"}`, false},
+		{`{"schema":"lnudorm3-status/event","version":1,"kind":"server_up"}`, false},
+		{`{"kind":"server_up","time":"2024-01-02T03:04:05Z"}`, false},
+		{`{"schema":"lnudorm3-status/event","version":"1","kind":"server_up","time":"2024-01-02T03:04:05Z"}`, false},
+	} {
+		if _, err := validateEventPayload([]byte(tt.data)); (err == nil) != tt.ok {
+			t.Errorf("validateEventPayload(%s) = %v, want ok=%v", tt.data, err, tt.ok)
+		}
+	}
+}
+
+func TestPostWebhooks(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+
+	var body, header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, header = string(data), r.Header.Get("X-Event-Schema")
+	}))
+	defer server.Close()
+	config.WebhookURLs = []string{server.URL}
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	postWebhooks(context.Background(), []Event{{Kind: EventServerDown, Time: at, Backup: "backup.example.com:25565"}})
+	if want := `{"schema":"lnudorm3-status/event","version":1,"kind":"server_down","time":"2024-01-02T03:04:05Z","backup":"backup.example.com:25565"}`; body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+	if header != "lnudorm3-status/event; version=1" {
+		t.Errorf("X-Event-Schema = %q", header)
+	}
+}
+
+func TestEventsAPI(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.APIToken = "secret"
+
+	request := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/events", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		newHTTPMux().ServeHTTP(w, r)
+		return w
+	}
+
+	if w := request(`{"schema":"lnudorm3-status/event","version":1,"kind":"server_down","time":"2024-01-02T03:04:05Z"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("server event: status %d", w.Code)
+	}
+	if w := request(`{"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","time":"2024-01-02T03:04:05Z"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("no player: status %d", w.Code)
+	}
+	if w := request(`{"schema":"lnudorm3-status/event","version":1,"kind":"player_joined","player":"steve","time":"2024-01-02T03:04:05Z"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("player event: status %d, body %s", w.Code, w.Body)
+	}
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || !strings.Contains(sent[0].Params["text"].(string), "steve") {
+		t.Errorf("sendMessage calls = %+v, want the join announced", sent)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// WORLD_HISTORY is how long world size samples are kept.
+	WORLD_HISTORY = 30 * 24 * time.Hour
+	// WORLD_SUMMARY_INTERVAL is how often the admins get the world size
+	// summary, and the span its growth trend covers.
+	WORLD_SUMMARY_INTERVAL = 7 * 24 * time.Hour
+	// WORLD_WARN_REPEAT keeps the disk space warning to once a day.
+	WORLD_WARN_REPEAT = 24 * time.Hour
+)
+
+// WorldSample is one measurement of the world folder and the disk it's on.
+type WorldSample struct {
+	Time int64 `json:"time"` // Unix seconds
+	Size int64 `json:"size"` // bytes
+	Free int64 `json:"free"` // bytes left on the disk
+}
+
+// measureWorld sizes WORLD_PATH over SSH; tests swap it for a fake.
+var measureWorld = func(ctx context.Context) (WorldSample, error) {
+	path := shellQuote(config.WorldPath)
+	output, err := runSSH(ctx, "du -sk -- "+path+" && df -Pk -- "+path)
+	if err != nil {
+		return WorldSample{}, err
+	}
+	return parseWorldSample(string(output))
+}
+
+// parseWorldSample reads the output of du -sk followed by df -Pk, both in
+// KiB.
+func parseWorldSample(output string) (WorldSample, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 3 {
+		return WorldSample{}, fmt.Errorf("unexpected du/df output %q", output)
+	}
+	size, err := strconv.ParseInt(strings.Fields(lines[0])[0], 10, 64)
+	if err != nil {
+		return WorldSample{}, fmt.Errorf("du: %w", err)
+	}
+	// Filesystem 1024-blocks Used Available Capacity Mounted-on
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return WorldSample{}, fmt.Errorf("unexpected df output %q", lines[len(lines)-1])
+	}
+	free, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return WorldSample{}, fmt.Errorf("df: %w", err)
+	}
+	return WorldSample{Size: size * 1024, Free: free * 1024}, nil
+}
+
+// checkWorldSize measures the world, warns the admins when the disk is
+// projected to fill up within WORLD_DISK_WARN_DAYS, and sends them the
+// weekly summary when it's due.
+func checkWorldSize(ctx context.Context) {
+	measureCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	sample, err := measureWorld(measureCtx)
+	cancel()
+	if err != nil {
+		log.Printf("Error measuring the world size: %v", err)
+		return
+	}
+	now := time.Now()
+	sample.Time = now.Unix()
+
+	state.mu.Lock()
+	samples := append(state.WorldSizes, sample)
+	cutoff := now.Add(-WORLD_HISTORY).Unix()
+	for len(samples) > 0 && samples[0].Time < cutoff {
+		samples = samples[1:]
+	}
+	state.WorldSizes = samples
+	samples = append([]WorldSample(nil), samples...)
+
+	growth, ok := worldGrowth(samples, now)
+	warn := false
+	if ok && growth > 0 && config.WorldDiskWarnDays > 0 &&
+		float64(sample.Free)/growth < float64(config.WorldDiskWarnDays) &&
+		now.Sub(time.Unix(state.WorldWarnedAt, 0)) >= WORLD_WARN_REPEAT {
+		state.WorldWarnedAt = now.Unix()
+		warn = true
+	}
+	summary := false
+	if state.WorldSummaryAt == 0 {
+		// Nothing to summarize on the first measurement.
+		state.WorldSummaryAt = now.Unix()
+	} else if now.Sub(time.Unix(state.WorldSummaryAt, 0)) >= WORLD_SUMMARY_INTERVAL {
+		state.WorldSummaryAt = now.Unix()
+		summary = true
+	}
+	saveState()
+	state.mu.Unlock()
+
+	if warn {
+		days := int(float64(sample.Free) / growth)
+		log.Printf("The disk is projected to fill up in %d days", days)
+		notifyAdmins(ctx, trn(config.Language, "world.disk_warning", days, formatBytes(sample.Free), days))
+	}
+	if summary {
+		notifyAdmins(ctx, renderWorldSummary(config.Language, samples, now))
+	}
+}
+
+// worldGrowth is the world's growth in bytes per day over the last
+// WORLD_SUMMARY_INTERVAL, from the oldest sample in it to the newest. ok is
+// false until the samples span at least a day.
+func worldGrowth(samples []WorldSample, now time.Time) (perDay float64, ok bool) {
+	cutoff := now.Add(-WORLD_SUMMARY_INTERVAL).Unix()
+	var first *WorldSample
+	for i := range samples {
+		if samples[i].Time >= cutoff {
+			first = &samples[i]
+			break
+		}
+	}
+	if first == nil {
+		return 0, false
+	}
+	last := samples[len(samples)-1]
+	days := float64(last.Time-first.Time) / (24 * 60 * 60)
+	if days < 1 {
+		return 0, false
+	}
+	return float64(last.Size-first.Size) / days, true
+}
+
+func renderWorldSummary(lang string, samples []WorldSample, now time.Time) string {
+	if len(samples) == 0 {
+		return ""
+	}
+	last := samples[len(samples)-1]
+	lines := []string{
+		tr(lang, "world.summary", formatBytes(last.Size)),
+	}
+	growth, ok := worldGrowth(samples, now)
+	if ok {
+		lines = append(lines, tr(lang, "world.growth", formatSignedBytes(int64(growth*7)), formatSignedBytes(int64(growth))))
+	}
+	lines = append(lines, tr(lang, "world.free", formatBytes(last.Free)))
+	if ok && growth > 0 {
+		days := int(float64(last.Free) / growth)
+		lines = append(lines, trn(lang, "world.full_in", days, days))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatBytes renders n in binary units with one decimal, e.g. "1.5 GB".
+func formatBytes(n int64) string {
+	const units = "KMGTP"
+	if n < 1024 && n > -1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	unit := -1
+	for (value >= 1024 || value <= -1024) && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %cB", value, units[unit])
+}
+
+func formatSignedBytes(n int64) string {
+	if n >= 0 {
+		return "+" + formatBytes(n)
+	}
+	return "−" + formatBytes(-n)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWorldSample(t *testing.T) {
+	output := "2048\tworld\n" +
+		"Filesystem     1024-blocks     Used Available Capacity Mounted on\n" +
+		"/dev/sda1        41152736 30000000  11152736      73% /\n"
+	got, err := parseWorldSample(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Size != 2048*1024 || got.Free != 11152736*1024 {
+		t.Errorf("parseWorldSample() = %+v", got)
+	}
+}
+
+func TestRenderWorldSummary(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	day := int64(24 * 60 * 60)
+	samples := []WorldSample{
+		{Time: now.Unix() - 10*day, Size: 1 << 30, Free: 1 << 32},
+		{Time: now.Unix() - 7*day, Size: 2 << 30, Free: 3 << 30},
+		{Time: now.Unix(), Size: 2<<30 + 700<<20, Free: 2<<30 + 324<<20},
+	}
+	want := "🌍 <b>World size</b>: 2.7 GB\n" +
+		"Past week: +700.0 MB (≈+100.0 MB per day)\n" +
+		"Free disk space: 2.3 GB\n" +
+		"At this rate the disk fills up in about 23 days"
+	if got := renderWorldSummary("en", samples, now); got != want {
+		t.Errorf("renderWorldSummary:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCheckWorldSizeWarns(t *testing.T) {
+	useTempStore(t, 0)
+	fake := useFakeTelegram(t)
+	config.Language = "en"
+	config.AdminChatID = "-100"
+	config.WorldDiskWarnDays = 14
+
+	previous := measureWorld
+	defer func() { measureWorld = previous }()
+	measureWorld = func(context.Context) (WorldSample, error) {
+		return WorldSample{Size: 10 << 30, Free: 1 << 30}, nil
+	}
+
+	// A day ago the world was 1 GB smaller: the free GB lasts a day.
+	state.WorldSizes = []WorldSample{{Time: time.Now().Add(-25 * time.Hour).Unix(), Size: 9 << 30, Free: 2 << 30}}
+	state.WorldSummaryAt = time.Now().Unix()
+
+	checkWorldSize(context.Background())
+	checkWorldSize(context.Background())
+	sent := fake.callsTo("sendMessage")
+	if len(sent) != 1 || sent[0].Params["chat_id"] != "-100" {
+		t.Fatalf("sendMessage calls = %+v, want one warning to the admin chat", sent)
+	}
+	if text := sent[0].Params["text"].(string); !strings.Contains(text, "Running out of disk space") {
+		t.Errorf("warning = %q", text)
+	}
+}